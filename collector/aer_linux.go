@@ -0,0 +1,115 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noaer
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const aerSubsystem = "aer"
+
+// aerSeverities maps the per device AER statistics files to severities.
+var aerSeverities = []struct {
+	file, severity string
+}{
+	{"aer_dev_correctable", "correctable"},
+	{"aer_dev_nonfatal", "nonfatal"},
+	{"aer_dev_fatal", "fatal"},
+}
+
+type aerCollector struct {
+	errors *prometheus.Desc
+}
+
+func init() {
+	registerCollector(aerSubsystem, defaultDisabled, NewAERCollector)
+}
+
+// NewAERCollector returns a new Collector exposing PCIe Advanced Error Reporting statistics.
+func NewAERCollector() (Collector, error) {
+	return &aerCollector{
+		errors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, aerSubsystem, "errors_total"),
+			"Number of PCIe errors reported by the device via Advanced Error Reporting.",
+			[]string{"device", "severity", "error"}, nil,
+		),
+	}, nil
+}
+
+func (c *aerCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("bus/pci/devices/*"))
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range devices {
+		device := filepath.Base(dir)
+
+		for _, s := range aerSeverities {
+			// Only devices with AER capability, on kernels since 4.17,
+			// have these files.
+			file, err := os.Open(filepath.Join(dir, s.file))
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return err
+			}
+			counts, err := parseAERCounters(file)
+			file.Close()
+			if err != nil {
+				return fmt.Errorf("couldn't parse %s of %s: %s", s.file, device, err)
+			}
+			for name, v := range counts {
+				ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, v, device, s.severity, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseAERCounters parses the "<error> <count>" lines of an AER statistics
+// file. The TOTAL_ERR_* line is skipped as it is the sum of the others.
+func parseAERCounters(r io.Reader) (map[string]float64, error) {
+	counts := make(map[string]float64)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid line %q", scanner.Text())
+		}
+		if strings.HasPrefix(fields[0], "TOTAL_") {
+			continue
+		}
+		v, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		counts[fields[0]] = v
+	}
+
+	return counts, scanner.Err()
+}
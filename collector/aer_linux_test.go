@@ -0,0 +1,44 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseAERCounters(t *testing.T) {
+	file, err := os.Open("fixtures/sys/bus/pci/devices/0000:00:1c.0/aer_dev_correctable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	counts, err := parseAERCounters(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 8, len(counts); want != got {
+		t.Errorf("want %d counters, got %d", want, got)
+	}
+	if _, ok := counts["TOTAL_ERR_COR"]; ok {
+		t.Error("total should not be exposed")
+	}
+	for name, want := range map[string]float64{"RxErr": 2, "BadTLP": 1, "Timeout": 4} {
+		if got := counts[name]; want != got {
+			t.Errorf("want %s %f, got %f", name, want, got)
+		}
+	}
+}
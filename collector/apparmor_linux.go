@@ -0,0 +1,103 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noapparmor
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const apparmorSubsystem = "apparmor"
+
+var apparmorModes = []string{"enforce", "complain", "kill", "unconfined"}
+
+type apparmorCollector struct {
+	enabled  *prometheus.Desc
+	profiles *prometheus.Desc
+}
+
+func init() {
+	registerCollector(apparmorSubsystem, defaultDisabled, NewApparmorCollector)
+}
+
+// NewApparmorCollector returns a new Collector exposing the number of loaded
+// AppArmor profiles by mode.
+func NewApparmorCollector() (Collector, error) {
+	return &apparmorCollector{
+		enabled: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, apparmorSubsystem, "enabled"),
+			"Whether AppArmor is enabled, 1 if it is.",
+			nil, nil,
+		),
+		profiles: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, apparmorSubsystem, "profiles"),
+			"Number of loaded AppArmor profiles by mode.",
+			[]string{"mode"}, nil,
+		),
+	}, nil
+}
+
+func (c *apparmorCollector) Update(ch chan<- prometheus.Metric) error {
+	enabled, err := ioutil.ReadFile(sysFilePath("module/apparmor/parameters/enabled"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("Not collecting AppArmor statistics: %s", err)
+			return nil
+		}
+		return err
+	}
+	if strings.TrimSpace(string(enabled)) != "Y" {
+		ch <- prometheus.MustNewConstMetric(c.enabled, prometheus.GaugeValue, 0)
+		return nil
+	}
+	ch <- prometheus.MustNewConstMetric(c.enabled, prometheus.GaugeValue, 1)
+
+	// The profiles are only readable by root.
+	file, err := os.Open(sysFilePath("kernel/security/apparmor/profiles"))
+	if err != nil {
+		if os.IsNotExist(err) || os.IsPermission(err) {
+			log.Debugf("Not collecting AppArmor profiles: %s", err)
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	profiles := make(map[string]float64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		i := strings.LastIndex(line, " (")
+		if i < 0 || !strings.HasSuffix(line, ")") {
+			return fmt.Errorf("invalid AppArmor profile line %q", line)
+		}
+		profiles[line[i+2:len(line)-1]]++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for _, mode := range apparmorModes {
+		ch <- prometheus.MustNewConstMetric(c.profiles, prometheus.GaugeValue, profiles[mode], mode)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noaudit
+
+package collector
+
+import (
+	"fmt"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	auditSubsystem = "audit"
+
+	// auditGet is AUDIT_GET of linux/audit.h.
+	auditGet = 1000
+	// auditStatusLength is the length of struct audit_status up to the
+	// backlog field, which all supported kernels provide.
+	auditStatusLength = 32
+)
+
+// auditStatus is struct audit_status of linux/audit.h.
+type auditStatus struct {
+	enabled      uint32
+	failure      uint32
+	pid          uint32
+	rateLimit    uint32
+	backlogLimit uint32
+	lost         uint32
+	backlog      uint32
+}
+
+type auditCollector struct {
+	enabled      *prometheus.Desc
+	failure      *prometheus.Desc
+	daemon       *prometheus.Desc
+	rateLimit    *prometheus.Desc
+	backlogLimit *prometheus.Desc
+	lost         *prometheus.Desc
+	backlog      *prometheus.Desc
+}
+
+func init() {
+	registerCollector(auditSubsystem, defaultDisabled, NewAuditCollector)
+}
+
+// NewAuditCollector returns a new Collector exposing the kernel audit status.
+func NewAuditCollector() (Collector, error) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, auditSubsystem, name),
+			help, nil, nil,
+		)
+	}
+	return &auditCollector{
+		enabled:      desc("enabled", "Whether kernel auditing is enabled, 0 if disabled, 1 if enabled and 2 if the configuration is locked."),
+		failure:      desc("failure_mode", "Action on critical audit errors, 0 is silent, 1 printk and 2 panic."),
+		daemon:       desc("daemon_connected", "Whether an audit daemon is receiving the audit events, 1 if it is."),
+		rateLimit:    desc("rate_limit", "Maximum number of audit messages per second, 0 if unlimited."),
+		backlogLimit: desc("backlog_limit", "Maximum number of outstanding audit buffers."),
+		lost:         desc("lost_total", "Number of audit events lost, e.g. because of the rate limit or a full backlog."),
+		backlog:      desc("backlog", "Number of audit buffers waiting to be sent to the audit daemon."),
+	}, nil
+}
+
+func (c *auditCollector) Update(ch chan<- prometheus.Metric) error {
+	conn, err := netlink.Dial(unix.NETLINK_AUDIT, nil)
+	if err != nil {
+		return fmt.Errorf("couldn't connect to audit netlink: %s", err)
+	}
+	defer conn.Close()
+
+	// Requires CAP_AUDIT_CONTROL.
+	msgs, err := conn.Execute(netlink.Message{
+		Header: netlink.Header{
+			Type:  auditGet,
+			Flags: netlink.Request,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't get audit status: %s", err)
+	}
+	if len(msgs) == 0 {
+		return fmt.Errorf("no audit status received")
+	}
+	status, err := parseAuditStatus(msgs[0].Data)
+	if err != nil {
+		return err
+	}
+
+	daemon := 0.0
+	if status.pid != 0 {
+		daemon = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.enabled, prometheus.GaugeValue, float64(status.enabled))
+	ch <- prometheus.MustNewConstMetric(c.failure, prometheus.GaugeValue, float64(status.failure))
+	ch <- prometheus.MustNewConstMetric(c.daemon, prometheus.GaugeValue, daemon)
+	ch <- prometheus.MustNewConstMetric(c.rateLimit, prometheus.GaugeValue, float64(status.rateLimit))
+	ch <- prometheus.MustNewConstMetric(c.backlogLimit, prometheus.GaugeValue, float64(status.backlogLimit))
+	ch <- prometheus.MustNewConstMetric(c.lost, prometheus.CounterValue, float64(status.lost))
+	ch <- prometheus.MustNewConstMetric(c.backlog, prometheus.GaugeValue, float64(status.backlog))
+
+	return nil
+}
+
+func parseAuditStatus(b []byte) (*auditStatus, error) {
+	if len(b) < auditStatusLength {
+		return nil, fmt.Errorf("audit status too short: %d bytes", len(b))
+	}
+	// The first field is the mask of fields to set, only used for AUDIT_SET.
+	return &auditStatus{
+		enabled:      nlenc.Uint32(b[4:8]),
+		failure:      nlenc.Uint32(b[8:12]),
+		pid:          nlenc.Uint32(b[12:16]),
+		rateLimit:    nlenc.Uint32(b[16:20]),
+		backlogLimit: nlenc.Uint32(b[20:24]),
+		lost:         nlenc.Uint32(b[24:28]),
+		backlog:      nlenc.Uint32(b[28:32]),
+	}, nil
+}
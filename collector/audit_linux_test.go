@@ -0,0 +1,63 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/mdlayher/netlink/nlenc"
+)
+
+func TestParseAuditStatus(t *testing.T) {
+	if nlenc.NativeEndian() != binary.LittleEndian {
+		t.Skip("audit status fixture is little endian")
+	}
+
+	// struct audit_status of a Linux 5.x kernel with auditd running, up to
+	// backlog_wait_time_actual.
+	status := []byte{
+		0x00, 0x00, 0x00, 0x00, // mask
+		0x01, 0x00, 0x00, 0x00, // enabled
+		0x01, 0x00, 0x00, 0x00, // failure
+		0x1a, 0x04, 0x00, 0x00, // pid 1050
+		0x00, 0x00, 0x00, 0x00, // rate_limit
+		0x00, 0x20, 0x00, 0x00, // backlog_limit 8192
+		0x07, 0x00, 0x00, 0x00, // lost
+		0x03, 0x00, 0x00, 0x00, // backlog
+		0x02, 0x00, 0x00, 0x00, // feature_bitmap
+		0x60, 0xea, 0x00, 0x00, // backlog_wait_time 60000
+		0x00, 0x00, 0x00, 0x00, // backlog_wait_time_actual
+	}
+	got, err := parseAuditStatus(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &auditStatus{
+		enabled:      1,
+		failure:      1,
+		pid:          1050,
+		backlogLimit: 8192,
+		lost:         7,
+		backlog:      3,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want audit status %+v, got %+v", want, got)
+	}
+
+	if _, err := parseAuditStatus(status[:auditStatusLength-1]); err == nil {
+		t.Error("want error for truncated audit status")
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noautofs
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const autofsSubsystem = "autofs"
+
+type autofsCollector struct {
+	infoDesc          *prometheus.Desc
+	timeoutDesc       *prometheus.Desc
+	daemonRunningDesc *prometheus.Desc
+	activeMountsDesc  *prometheus.Desc
+}
+
+func init() {
+	registerCollector(autofsSubsystem, defaultDisabled, NewAutofsCollector)
+}
+
+// NewAutofsCollector returns a new Collector exposing the state of the
+// automounter mount points.
+func NewAutofsCollector() (Collector, error) {
+	labels := []string{"mountpoint"}
+	return &autofsCollector{
+		infoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, autofsSubsystem, "mount_info"),
+			"Automounter mount point with its map and whether it's a direct, indirect or offset mount, value is always 1.",
+			[]string{"mountpoint", "map", "type"}, nil,
+		),
+		timeoutDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, autofsSubsystem, "expire_timeout_seconds"),
+			"Time after which unused mounts are expired, 0 if they aren't.",
+			labels, nil,
+		),
+		daemonRunningDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, autofsSubsystem, "daemon_running"),
+			"1 if the automount daemon serving the mount point is running, 0 otherwise.",
+			labels, nil,
+		),
+		activeMountsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, autofsSubsystem, "active_mounts"),
+			"Number of filesystems currently mounted by the automounter on or below the mount point.",
+			labels, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *autofsCollector) Update(ch chan<- prometheus.Metric) error {
+	mf, err := os.Open(mountinfoPath())
+	if err != nil {
+		return err
+	}
+	defer mf.Close()
+	mounts, err := parseMountInfo(mf)
+	if err != nil {
+		return fmt.Errorf("couldn't parse mountinfo: %s", err)
+	}
+
+	for _, m := range mounts {
+		if m.fsType != "autofs" {
+			continue
+		}
+		options := autofsOptions(m.superOptions)
+
+		mountType := "indirect"
+		for _, t := range []string{"direct", "offset"} {
+			if _, ok := options[t]; ok {
+				mountType = t
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(c.infoDesc, prometheus.GaugeValue, 1, m.mountPoint, m.device, mountType)
+
+		if timeout, err := strconv.ParseFloat(options["timeout"], 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.timeoutDesc, prometheus.GaugeValue, timeout, m.mountPoint)
+		}
+
+		// The mount is served by the process group of the daemon, e.g.
+		// automount or systemd, which is the process ID of its leader.
+		if pgrp, ok := options["pgrp"]; ok {
+			running := 1.0
+			if _, err := os.Stat(procFilePath(pgrp)); err != nil {
+				if !os.IsNotExist(err) {
+					log.Debugf("Couldn't get automount daemon %s: %s", pgrp, err)
+					continue
+				}
+				running = 0
+			}
+			ch <- prometheus.MustNewConstMetric(c.daemonRunningDesc, prometheus.GaugeValue, running, m.mountPoint)
+		}
+
+		// Direct mounts are mounted on top of the autofs mount point,
+		// indirect ones below it.
+		active := 0.0
+		for _, other := range mounts {
+			if other.fsType == "autofs" {
+				continue
+			}
+			if other.mountPoint == m.mountPoint || strings.HasPrefix(other.mountPoint, strings.TrimSuffix(m.mountPoint, "/")+"/") {
+				active++
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(c.activeMountsDesc, prometheus.GaugeValue, active, m.mountPoint)
+	}
+	return nil
+}
+
+// autofsOptions returns the autofs mount options, e.g.
+// fd=5,pgrp=1234,timeout=300,minproto=5,maxproto=5,indirect, by name.
+func autofsOptions(set map[string]bool) map[string]string {
+	options := make(map[string]string, len(set))
+	for option := range set {
+		parts := strings.SplitN(option, "=", 2)
+		if len(parts) == 2 {
+			options[parts[0]] = parts[1]
+		} else {
+			options[parts[0]] = ""
+		}
+	}
+	return options
+}
@@ -17,6 +17,11 @@ package collector
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs/bcache"
@@ -53,7 +58,9 @@ func (c *bcacheCollector) Update(ch chan<- prometheus.Metric) error {
 	}
 
 	for _, s := range stats {
-		c.updateBcacheStats(ch, s)
+		if err := c.updateBcacheStats(ch, s); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -131,8 +138,77 @@ func bcachePeriodStatsToMetric(ps *bcache.PeriodStats, labelValue string) []bcac
 	return metrics
 }
 
+var (
+	bcacheBdevStates = []string{"no cache", "clean", "dirty", "inconsistent"}
+
+	bcacheBdevStateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "bcache", "backing_device_state"),
+		"State of the backing device.",
+		[]string{"uuid", "backing_device", "state"},
+		nil,
+	)
+)
+
+// bcacheWriteback holds the writeback details of a backing device, which are
+// not covered by the procfs bcache package.
+type bcacheWriteback struct {
+	rate  *float64
+	state string
+}
+
+// readBcacheWriteback reads the writeback rate and state of a backing device.
+// Files missing on older kernels are skipped.
+func readBcacheWriteback(uuid, bdev string) (bcacheWriteback, error) {
+	var (
+		dir = sysFilePath(filepath.Join("fs/bcache", uuid, bdev))
+		wb  bcacheWriteback
+	)
+
+	rate, err := ioutil.ReadFile(filepath.Join(dir, "writeback_rate"))
+	switch {
+	case err == nil:
+		v, err := bcacheDehumanize(strings.TrimSpace(string(rate)))
+		if err != nil {
+			return wb, fmt.Errorf("invalid writeback_rate for %s: %v", bdev, err)
+		}
+		wb.rate = &v
+	case !os.IsNotExist(err):
+		return wb, err
+	}
+
+	state, err := ioutil.ReadFile(filepath.Join(dir, "state"))
+	switch {
+	case err == nil:
+		wb.state = strings.TrimSpace(string(state))
+	case !os.IsNotExist(err):
+		return wb, err
+	}
+
+	return wb, nil
+}
+
+// bcacheDehumanize converts the human readable values printed by bcache,
+// e.g. "1.5M", to a plain number.
+func bcacheDehumanize(s string) (float64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty value")
+	}
+	mul := 1.0
+	if i := strings.IndexByte("kMGTPEZY", s[len(s)-1]); i >= 0 {
+		for ; i >= 0; i-- {
+			mul *= 1024
+		}
+		s = s[:len(s)-1]
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return v * mul, nil
+}
+
 // UpdateBcacheStats collects statistics for one bcache ID.
-func (c *bcacheCollector) updateBcacheStats(ch chan<- prometheus.Metric, s *bcache.Stats) {
+func (c *bcacheCollector) updateBcacheStats(ch chan<- prometheus.Metric, s *bcache.Stats) error {
 
 	const (
 		subsystem = "bcache"
@@ -227,6 +303,30 @@ func (c *bcacheCollector) updateBcacheStats(ch chan<- prometheus.Metric, s *bcac
 		metrics := bcachePeriodStatsToMetric(&bdev.Total, bdev.Name)
 		allMetrics = append(allMetrics, metrics...)
 
+		// writeback metrics in /sys/fs/bcache/<uuid>/<bdev>/
+		wb, err := readBcacheWriteback(s.Name, bdev.Name)
+		if err != nil {
+			return err
+		}
+		if wb.rate != nil {
+			allMetrics = append(allMetrics, bcacheMetric{
+				name:            "writeback_rate_bytes",
+				desc:            "Rate at which dirty data is currently written back to the backing device, in bytes per second.",
+				value:           *wb.rate,
+				metricType:      prometheus.GaugeValue,
+				extraLabel:      []string{"backing_device"},
+				extraLabelValue: bdev.Name,
+			})
+		}
+		if wb.state != "" {
+			for _, state := range bcacheBdevStates {
+				v := 0.0
+				if state == wb.state {
+					v = 1
+				}
+				ch <- prometheus.MustNewConstMetric(bcacheBdevStateDesc, prometheus.GaugeValue, v, s.Name, bdev.Name, state)
+			}
+		}
 	}
 
 	for _, cache := range s.Caches {
@@ -299,4 +399,5 @@ func (c *bcacheCollector) updateBcacheStats(ch chan<- prometheus.Metric, s *bcac
 			labelValues...,
 		)
 	}
+	return nil
 }
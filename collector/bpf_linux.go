@@ -0,0 +1,313 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nobpf
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"golang.org/x/sys/unix"
+)
+
+const bpfSubsystem = "bpf"
+
+var (
+	// bpfProgTypes and bpfMapTypes are the names of enum bpf_prog_type and
+	// enum bpf_map_type of linux/bpf.h.
+	bpfProgTypes = []string{
+		"unspec", "socket_filter", "kprobe", "sched_cls", "sched_act", "tracepoint", "xdp", "perf_event",
+		"cgroup_skb", "cgroup_sock", "lwt_in", "lwt_out", "lwt_xmit", "sock_ops", "sk_skb", "cgroup_device",
+		"sk_msg", "raw_tracepoint", "cgroup_sock_addr", "lwt_seg6local", "lirc_mode2", "sk_reuseport",
+		"flow_dissector", "cgroup_sysctl", "raw_tracepoint_writable", "cgroup_sockopt", "tracing",
+		"struct_ops", "ext", "lsm", "sk_lookup", "syscall", "netfilter",
+	}
+	bpfMapTypes = []string{
+		"unspec", "hash", "array", "prog_array", "perf_event_array", "percpu_hash", "percpu_array",
+		"stack_trace", "cgroup_array", "lru_hash", "lru_percpu_hash", "lpm_trie", "array_of_maps",
+		"hash_of_maps", "devmap", "sockmap", "cpumap", "xskmap", "sockhash", "cgroup_storage",
+		"reuseport_sockarray", "percpu_cgroup_storage", "queue", "stack", "sk_storage", "devmap_hash",
+		"struct_ops", "ringbuf", "inode_storage", "task_storage", "bloom_filter", "user_ringbuf",
+		"cgrp_storage",
+	}
+)
+
+// bpfIDAttr is the union bpf_attr for the BPF_*_GET_NEXT_ID and
+// BPF_*_GET_FD_BY_ID commands.
+type bpfIDAttr struct {
+	ID        uint32
+	NextID    uint32
+	OpenFlags uint32
+}
+
+// bpfInfoAttr is the union bpf_attr for the BPF_OBJ_GET_INFO_BY_FD command.
+type bpfInfoAttr struct {
+	FD      uint32
+	InfoLen uint32
+	Info    uint64
+}
+
+// bpfProgInfo is struct bpf_prog_info up to the run statistics added in
+// Linux 5.1. Older kernels only fill the fields they know.
+type bpfProgInfo struct {
+	Type                 uint32
+	ID                   uint32
+	Tag                  [8]byte
+	JitedProgLen         uint32
+	XlatedProgLen        uint32
+	JitedProgInsns       uint64
+	XlatedProgInsns      uint64
+	LoadTime             uint64
+	CreatedByUID         uint32
+	NrMapIDs             uint32
+	MapIDs               uint64
+	Name                 [16]byte
+	Ifindex              uint32
+	GPLCompatible        uint32
+	NetnsDev             uint64
+	NetnsIno             uint64
+	NrJitedKsyms         uint32
+	NrJitedFuncLens      uint32
+	JitedKsyms           uint64
+	JitedFuncLens        uint64
+	BTFID                uint32
+	FuncInfoRecSize      uint32
+	FuncInfo             uint64
+	NrFuncInfo           uint32
+	NrLineInfo           uint32
+	LineInfo             uint64
+	JitedLineInfo        uint64
+	NrJitedLineInfo      uint32
+	LineInfoRecSize      uint32
+	JitedLineInfoRecSize uint32
+	NrProgTags           uint32
+	ProgTags             uint64
+	RunTimeNs            uint64
+	RunCnt               uint64
+}
+
+// bpfMapInfo is the beginning of struct bpf_map_info.
+type bpfMapInfo struct {
+	Type       uint32
+	ID         uint32
+	KeySize    uint32
+	ValueSize  uint32
+	MaxEntries uint32
+	MapFlags   uint32
+	Name       [16]byte
+}
+
+type bpfCollector struct {
+	progInfoDesc    *prometheus.Desc
+	progMemlockDesc *prometheus.Desc
+	progRunsDesc    *prometheus.Desc
+	progRunTimeDesc *prometheus.Desc
+	mapInfoDesc     *prometheus.Desc
+	mapMemlockDesc  *prometheus.Desc
+	mapEntriesDesc  *prometheus.Desc
+}
+
+func init() {
+	registerCollector(bpfSubsystem, defaultDisabled, NewBPFCollector)
+}
+
+// NewBPFCollector returns a new Collector exposing the loaded BPF programs
+// and maps, which requires CAP_SYS_ADMIN.
+func NewBPFCollector() (Collector, error) {
+	labels := []string{"id"}
+	return &bpfCollector{
+		progInfoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bpfSubsystem, "program_info"),
+			"Loaded BPF program with its type, name and tag, value is always 1.",
+			[]string{"id", "type", "name", "tag"}, nil,
+		),
+		progMemlockDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bpfSubsystem, "program_memlock_bytes"),
+			"Memory locked by the BPF program.",
+			labels, nil,
+		),
+		progRunsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bpfSubsystem, "program_runs_total"),
+			"Number of runs of the BPF program while statistics were enabled with the kernel.bpf_stats_enabled sysctl.",
+			labels, nil,
+		),
+		progRunTimeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bpfSubsystem, "program_run_time_seconds_total"),
+			"Time spent running the BPF program while statistics were enabled with the kernel.bpf_stats_enabled sysctl.",
+			labels, nil,
+		),
+		mapInfoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bpfSubsystem, "map_info"),
+			"Loaded BPF map with its type and name, value is always 1.",
+			[]string{"id", "type", "name"}, nil,
+		),
+		mapMemlockDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bpfSubsystem, "map_memlock_bytes"),
+			"Memory locked by the BPF map.",
+			labels, nil,
+		),
+		mapEntriesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bpfSubsystem, "map_max_entries"),
+			"Maximum number of entries of the BPF map.",
+			labels, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *bpfCollector) Update(ch chan<- prometheus.Metric) error {
+	// The run statistics are only counted while enabled, since Linux 5.1.
+	statsEnabled := false
+	if v, err := readUintFromFile(procFilePath("sys/kernel/bpf_stats_enabled")); err == nil {
+		statsEnabled = v == 1
+	}
+
+	progIDs, err := bpfIDs(unix.BPF_PROG_GET_NEXT_ID)
+	if err != nil {
+		return fmt.Errorf("couldn't list BPF programs: %s", err)
+	}
+	for _, id := range progIDs {
+		var info bpfProgInfo
+		memlock, err := bpfObjectInfo(unix.BPF_PROG_GET_FD_BY_ID, id, unsafe.Pointer(&info), unsafe.Sizeof(info))
+		if err == unix.ENOENT {
+			// The program was unloaded in the meantime.
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("couldn't get BPF program %d: %s", id, err)
+		}
+		label := strconv.FormatUint(uint64(id), 10)
+		ch <- prometheus.MustNewConstMetric(c.progInfoDesc, prometheus.GaugeValue, 1,
+			label, bpfTypeName(bpfProgTypes, info.Type), bpfName(info.Name), hex.EncodeToString(info.Tag[:]))
+		ch <- prometheus.MustNewConstMetric(c.progMemlockDesc, prometheus.GaugeValue, memlock, label)
+		if statsEnabled {
+			ch <- prometheus.MustNewConstMetric(c.progRunsDesc, prometheus.CounterValue, float64(info.RunCnt), label)
+			ch <- prometheus.MustNewConstMetric(c.progRunTimeDesc, prometheus.CounterValue, float64(info.RunTimeNs)/1e9, label)
+		}
+	}
+
+	mapIDs, err := bpfIDs(unix.BPF_MAP_GET_NEXT_ID)
+	if err != nil {
+		return fmt.Errorf("couldn't list BPF maps: %s", err)
+	}
+	for _, id := range mapIDs {
+		var info bpfMapInfo
+		memlock, err := bpfObjectInfo(unix.BPF_MAP_GET_FD_BY_ID, id, unsafe.Pointer(&info), unsafe.Sizeof(info))
+		if err == unix.ENOENT {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("couldn't get BPF map %d: %s", id, err)
+		}
+		label := strconv.FormatUint(uint64(id), 10)
+		ch <- prometheus.MustNewConstMetric(c.mapInfoDesc, prometheus.GaugeValue, 1,
+			label, bpfTypeName(bpfMapTypes, info.Type), bpfName(info.Name))
+		ch <- prometheus.MustNewConstMetric(c.mapMemlockDesc, prometheus.GaugeValue, memlock, label)
+		ch <- prometheus.MustNewConstMetric(c.mapEntriesDesc, prometheus.GaugeValue, float64(info.MaxEntries), label)
+	}
+	return nil
+}
+
+// bpfIDs returns the IDs of all programs or maps.
+func bpfIDs(cmd uintptr) ([]uint32, error) {
+	var (
+		ids  []uint32
+		attr bpfIDAttr
+	)
+	for {
+		_, _, errno := unix.Syscall(unix.SYS_BPF, cmd, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr))
+		if errno == unix.ENOENT {
+			return ids, nil
+		}
+		if errno != 0 {
+			return nil, errno
+		}
+		ids = append(ids, attr.NextID)
+		attr.ID = attr.NextID
+	}
+}
+
+// bpfObjectInfo opens a program or map by ID, fills info with its
+// information and returns the memory it locks.
+func bpfObjectInfo(cmd uintptr, id uint32, info unsafe.Pointer, size uintptr) (float64, error) {
+	idAttr := bpfIDAttr{ID: id}
+	fd, _, errno := unix.Syscall(unix.SYS_BPF, cmd, uintptr(unsafe.Pointer(&idAttr)), unsafe.Sizeof(idAttr))
+	if errno != 0 {
+		return 0, errno
+	}
+	defer unix.Close(int(fd))
+
+	infoAttr := bpfInfoAttr{FD: uint32(fd), InfoLen: uint32(size), Info: uint64(uintptr(info))}
+	_, _, errno = unix.Syscall(unix.SYS_BPF, unix.BPF_OBJ_GET_INFO_BY_FD, uintptr(unsafe.Pointer(&infoAttr)), unsafe.Sizeof(infoAttr))
+	runtime.KeepAlive(info)
+	if errno != 0 {
+		return 0, errno
+	}
+
+	// The locked memory is only exposed in the fdinfo of the object, of the
+	// exporter itself.
+	fdinfo, err := os.Open(fmt.Sprintf("/proc/self/fdinfo/%d", fd))
+	if err != nil {
+		return 0, err
+	}
+	defer fdinfo.Close()
+	memlock, err := parseBPFMemlock(fdinfo)
+	if err != nil {
+		log.Debugf("Couldn't get memory locked by BPF object %d: %s", id, err)
+	}
+	return memlock, nil
+}
+
+// parseBPFMemlock returns the memlock field of the fdinfo of a BPF object.
+func parseBPFMemlock(r io.Reader) (float64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "memlock:" {
+			return strconv.ParseFloat(fields[1], 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no memlock field")
+}
+
+// bpfTypeName returns the name of a program or map type, or its number if
+// it's unknown.
+func bpfTypeName(names []string, t uint32) string {
+	if int(t) < len(names) {
+		return names[t]
+	}
+	return strconv.FormatUint(uint64(t), 10)
+}
+
+// bpfName returns the NUL terminated name of a program or map.
+func bpfName(name [16]byte) string {
+	if i := bytes.IndexByte(name[:], 0); i >= 0 {
+		return string(name[:i])
+	}
+	return string(name[:])
+}
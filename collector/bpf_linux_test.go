@@ -0,0 +1,59 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nobpf
+
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseBPFMemlock(t *testing.T) {
+	fdinfo := `pos:	0
+flags:	02000002
+mnt_id:	15
+ino:	2071
+prog_type:	8
+prog_jited:	1
+prog_tag:	3b185187f1855c4c
+memlock:	4096
+prog_id:	12
+`
+	memlock, err := parseBPFMemlock(strings.NewReader(fdinfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 4096.0; memlock != want {
+		t.Errorf("want memlock %f, got %f", want, memlock)
+	}
+
+	if _, err := parseBPFMemlock(strings.NewReader("pos:\t0\n")); err == nil {
+		t.Error("want error for fdinfo without memlock field")
+	}
+}
+
+func TestBPFNames(t *testing.T) {
+	var name [16]byte
+	copy(name[:], "sd_fw_ingress")
+	if got, want := bpfName(name), "sd_fw_ingress"; got != want {
+		t.Errorf("want name %q, got %q", want, got)
+	}
+	if got, want := bpfTypeName(bpfProgTypes, 8), "cgroup_skb"; got != want {
+		t.Errorf("want program type %q, got %q", want, got)
+	}
+	if got, want := bpfTypeName(bpfMapTypes, 1000), "1000"; got != want {
+		t.Errorf("want map type %q, got %q", want, got)
+	}
+}
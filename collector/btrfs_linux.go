@@ -0,0 +1,292 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nobtrfs
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const btrfsSubsystem = "btrfs"
+
+var (
+	btrfsAllocationTypes = []string{"data", "metadata", "system"}
+	btrfsScrubErrorTypes = []string{"read", "csum", "verify", "super", "malloc", "uncorrectable", "corrected"}
+)
+
+type btrfsCollector struct {
+	info            *prometheus.Desc
+	allocationSize  *prometheus.Desc
+	allocationUsed  *prometheus.Desc
+	globalRsvSize   *prometheus.Desc
+	deviceErrors    *prometheus.Desc
+	scrubStartTime  *prometheus.Desc
+	scrubDuration   *prometheus.Desc
+	scrubFinished   *prometheus.Desc
+	scrubScrubbed   *prometheus.Desc
+	scrubErrorCount *prometheus.Desc
+}
+
+// btrfsScrubStatus is the per-device state of the last scrub as recorded by
+// btrfs-progs in /var/lib/btrfs/scrub.status.<uuid>.
+type btrfsScrubStatus struct {
+	devid    string
+	values   map[string]uint64
+	finished bool
+}
+
+func init() {
+	registerCollector(btrfsSubsystem, defaultDisabled, NewBtrfsCollector)
+}
+
+// NewBtrfsCollector returns a new Collector exposing btrfs statistics.
+func NewBtrfsCollector() (Collector, error) {
+	return &btrfsCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, btrfsSubsystem, "info"),
+			"Filesystem information.",
+			[]string{"uuid", "label"}, nil,
+		),
+		allocationSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, btrfsSubsystem, "allocation_size_bytes"),
+			"Amount of space allocated to chunks of this type.",
+			[]string{"uuid", "type"}, nil,
+		),
+		allocationUsed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, btrfsSubsystem, "allocation_used_bytes"),
+			"Amount of space used within the chunks of this type.",
+			[]string{"uuid", "type"}, nil,
+		),
+		globalRsvSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, btrfsSubsystem, "global_rsv_size_bytes"),
+			"Size of the global reserve.",
+			[]string{"uuid"}, nil,
+		),
+		deviceErrors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, btrfsSubsystem, "device_errors_total"),
+			"Errors reported for a device of the filesystem.",
+			[]string{"uuid", "devid", "type"}, nil,
+		),
+		scrubStartTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, btrfsSubsystem, "scrub_start_time_seconds"),
+			"Start time of the last scrub of a device in unixtime.",
+			[]string{"uuid", "devid"}, nil,
+		),
+		scrubDuration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, btrfsSubsystem, "scrub_duration_seconds"),
+			"Duration of the last scrub of a device.",
+			[]string{"uuid", "devid"}, nil,
+		),
+		scrubFinished: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, btrfsSubsystem, "scrub_finished"),
+			"Whether the last scrub of a device ran to completion.",
+			[]string{"uuid", "devid"}, nil,
+		),
+		scrubScrubbed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, btrfsSubsystem, "scrub_scrubbed_bytes"),
+			"Amount of data and metadata verified by the last scrub of a device.",
+			[]string{"uuid", "devid"}, nil,
+		),
+		scrubErrorCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, btrfsSubsystem, "scrub_errors"),
+			"Errors found by the last scrub of a device.",
+			[]string{"uuid", "devid", "type"}, nil,
+		),
+	}, nil
+}
+
+func (c *btrfsCollector) Update(ch chan<- prometheus.Metric) error {
+	filesystems, err := filepath.Glob(sysFilePath("fs/btrfs/*-*-*-*-*"))
+	if err != nil {
+		return err
+	}
+
+	for _, fs := range filesystems {
+		uuid := filepath.Base(fs)
+
+		label, err := ioutil.ReadFile(filepath.Join(fs, "label"))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, uuid, strings.TrimSpace(string(label)))
+
+		if err := c.updateAllocation(ch, fs, uuid); err != nil {
+			return err
+		}
+		if err := c.updateDeviceErrors(ch, fs, uuid); err != nil {
+			return err
+		}
+		if err := c.updateScrubStatus(ch, uuid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *btrfsCollector) updateAllocation(ch chan<- prometheus.Metric, fs, uuid string) error {
+	for _, t := range btrfsAllocationTypes {
+		dir := filepath.Join(fs, "allocation", t)
+
+		size, err := readUintFromFile(filepath.Join(dir, "total_bytes"))
+		if err != nil {
+			return fmt.Errorf("couldn't get %s allocation of %s: %s", t, uuid, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.allocationSize, prometheus.GaugeValue, float64(size), uuid, t)
+
+		used, err := readUintFromFile(filepath.Join(dir, "bytes_used"))
+		if err != nil {
+			return fmt.Errorf("couldn't get %s usage of %s: %s", t, uuid, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.allocationUsed, prometheus.GaugeValue, float64(used), uuid, t)
+	}
+
+	rsv, err := readUintFromFile(filepath.Join(fs, "allocation", "global_rsv_size"))
+	if err != nil {
+		return fmt.Errorf("couldn't get global reserve of %s: %s", uuid, err)
+	}
+	ch <- prometheus.MustNewConstMetric(c.globalRsvSize, prometheus.GaugeValue, float64(rsv), uuid)
+
+	return nil
+}
+
+// updateDeviceErrors exposes the persistent device error counters, which are
+// available in sysfs since Linux 5.14.
+func (c *btrfsCollector) updateDeviceErrors(ch chan<- prometheus.Metric, fs, uuid string) error {
+	stats, err := filepath.Glob(filepath.Join(fs, "devinfo", "*", "error_stats"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range stats {
+		devid := filepath.Base(filepath.Dir(path))
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		errors, err := parseBtrfsErrorStats(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("couldn't parse error stats of %s device %s: %s", uuid, devid, err)
+		}
+
+		for t, v := range errors {
+			ch <- prometheus.MustNewConstMetric(c.deviceErrors, prometheus.CounterValue, float64(v), uuid, devid, t)
+		}
+	}
+
+	return nil
+}
+
+func (c *btrfsCollector) updateScrubStatus(ch chan<- prometheus.Metric, uuid string) error {
+	file, err := os.Open(rootfsFilePath(filepath.Join("var/lib/btrfs", "scrub.status."+uuid)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("No scrub status for btrfs filesystem %s", uuid)
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	devices, err := parseBtrfsScrubStatus(file, uuid)
+	if err != nil {
+		return fmt.Errorf("couldn't parse scrub status of %s: %s", uuid, err)
+	}
+
+	for _, d := range devices {
+		finished := 0.0
+		if d.finished {
+			finished = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.scrubStartTime, prometheus.GaugeValue, float64(d.values["t_start"]), uuid, d.devid)
+		ch <- prometheus.MustNewConstMetric(c.scrubDuration, prometheus.GaugeValue, float64(d.values["duration"]), uuid, d.devid)
+		ch <- prometheus.MustNewConstMetric(c.scrubFinished, prometheus.GaugeValue, finished, uuid, d.devid)
+		ch <- prometheus.MustNewConstMetric(c.scrubScrubbed, prometheus.GaugeValue,
+			float64(d.values["data_bytes_scrubbed"]+d.values["tree_bytes_scrubbed"]), uuid, d.devid)
+		for _, t := range btrfsScrubErrorTypes {
+			ch <- prometheus.MustNewConstMetric(c.scrubErrorCount, prometheus.GaugeValue, float64(d.values[t+"_errors"]), uuid, d.devid, t)
+		}
+	}
+
+	return nil
+}
+
+// parseBtrfsErrorStats parses the "<name>_errs <value>" lines of error_stats.
+func parseBtrfsErrorStats(r io.Reader) (map[string]uint64, error) {
+	stats := make(map[string]uint64)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		stats[strings.TrimSuffix(fields[0], "_errs")] = v
+	}
+
+	return stats, scanner.Err()
+}
+
+// parseBtrfsScrubStatus parses the scrub status file written by btrfs-progs.
+// Each device is a line of the form
+// <uuid>:<devid>|<key>:<value>|<key>:<value>...
+func parseBtrfsScrubStatus(r io.Reader, uuid string) ([]btrfsScrubStatus, error) {
+	var devices []btrfsScrubStatus
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, uuid+":") {
+			continue
+		}
+
+		parts := strings.Split(line, "|")
+		d := btrfsScrubStatus{
+			devid:  strings.TrimPrefix(parts[0], uuid+":"),
+			values: make(map[string]uint64),
+		}
+		for _, p := range parts[1:] {
+			kv := strings.SplitN(p, ":", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid scrub status field %q", p)
+			}
+			v, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for scrub status field %q: %s", kv[0], err)
+			}
+			d.values[kv[0]] = v
+		}
+		d.finished = d.values["finished"] == 1
+		devices = append(devices, d)
+	}
+
+	return devices, scanner.Err()
+}
@@ -0,0 +1,45 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nobtrfs
+
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseBtrfsScrubStatus(t *testing.T) {
+	const uuid = "0abb23a9-579b-43e6-ad30-227ef47fcb9d"
+	status := "scrub status:1\n" +
+		uuid + ":1|data_extents_scrubbed:12050|tree_extents_scrubbed:57|data_bytes_scrubbed:789217280|tree_bytes_scrubbed:933888|read_errors:0|csum_errors:0|verify_errors:0|no_csum:640|csum_discards:0|super_errors:0|malloc_errors:0|uncorrectable_errors:0|corrected_errors:0|last_physical:1103101952|t_start:1570939442|t_resumed:0|duration:4|canceled:0|finished:1\n" +
+		uuid + ":2|data_extents_scrubbed:100|tree_extents_scrubbed:0|data_bytes_scrubbed:4096|tree_bytes_scrubbed:0|read_errors:2|csum_errors:5|verify_errors:0|no_csum:0|csum_discards:0|super_errors:0|malloc_errors:0|uncorrectable_errors:1|corrected_errors:6|last_physical:4096|t_start:1570939442|t_resumed:0|duration:1|canceled:1|finished:0\n"
+
+	devices, err := parseBtrfsScrubStatus(strings.NewReader(status), uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 2, len(devices); want != got {
+		t.Fatalf("want %d devices, got %d", want, got)
+	}
+
+	d := devices[0]
+	if d.devid != "1" || !d.finished || d.values["t_start"] != 1570939442 || d.values["data_bytes_scrubbed"] != 789217280 {
+		t.Errorf("unexpected scrub status %+v", d)
+	}
+	d = devices[1]
+	if d.devid != "2" || d.finished || d.values["csum_errors"] != 5 || d.values["corrected_errors"] != 6 {
+		t.Errorf("unexpected scrub status %+v", d)
+	}
+}
@@ -11,14 +11,17 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// +build !nobuddyinfo
-// +build !netbsd
+// +build !nobuddyinfo,!netbsd
 
 package collector
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
 	"strconv"
+	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
@@ -32,6 +35,14 @@ const (
 type buddyinfoCollector struct {
 	fs   procfs.FS
 	desc *prometheus.Desc
+	// Fragmentation indexes from debugfs, keyed by file name.
+	extfragDescs map[string]*prometheus.Desc
+}
+
+// buddyinfoIndex is a line of the extfrag files in debugfs.
+type buddyinfoIndex struct {
+	node, zone string
+	values     []float64
 }
 
 func init() {
@@ -49,7 +60,19 @@ func NewBuddyinfoCollector() (Collector, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to open procfs: %v", err)
 	}
-	return &buddyinfoCollector{fs, desc}, nil
+	extfragDescs := map[string]*prometheus.Desc{
+		"extfrag_index": prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, buddyInfoSubsystem, "fragmentation_index"),
+			"External fragmentation index according to size. Values towards 0 mean an allocation would fail due to lack of memory, towards 1 due to fragmentation, -1 means it would succeed.",
+			[]string{"node", "zone", "size"}, nil,
+		),
+		"unusable_index": prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, buddyInfoSubsystem, "unusable_free_ratio"),
+			"Ratio of free memory which is unusable for allocations according to size.",
+			[]string{"node", "zone", "size"}, nil,
+		),
+	}
+	return &buddyinfoCollector{fs, desc, extfragDescs}, nil
 }
 
 // Update calls (*buddyinfoCollector).getBuddyInfo to get the platform specific
@@ -70,5 +93,69 @@ func (c *buddyinfoCollector) Update(ch chan<- prometheus.Metric) error {
 			)
 		}
 	}
+
+	for name, desc := range c.extfragDescs {
+		if err := updateBuddyinfoIndex(ch, name, desc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateBuddyinfoIndex exposes a fragmentation index from debugfs, which is
+// only available with CONFIG_COMPACTION and usually only readable by root.
+func updateBuddyinfoIndex(ch chan<- prometheus.Metric, name string, desc *prometheus.Desc) error {
+	file, err := os.Open(sysFilePath("kernel/debug/extfrag/" + name))
+	if err != nil {
+		if os.IsNotExist(err) || os.IsPermission(err) {
+			log.Debugf("Not collecting %s: %s", name, err)
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	indexes, err := parseBuddyinfoIndex(file)
+	if err != nil {
+		return fmt.Errorf("couldn't parse %s: %s", name, err)
+	}
+	for _, index := range indexes {
+		for size, value := range index.values {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value,
+				index.node, index.zone, strconv.Itoa(size))
+		}
+	}
 	return nil
 }
+
+// parseBuddyinfoIndex parses lines like
+// "Node 0, zone   Normal -1.000 -1.000 0.125 ...", with one value per order.
+func parseBuddyinfoIndex(r io.Reader) ([]buddyinfoIndex, error) {
+	var (
+		indexes []buddyinfoIndex
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 5 || fields[0] != "Node" || fields[2] != "zone" {
+			return nil, fmt.Errorf("invalid line %q", scanner.Text())
+		}
+
+		index := buddyinfoIndex{
+			node: strings.TrimSuffix(fields[1], ","),
+			zone: fields[3],
+		}
+		for _, f := range fields[4:] {
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return nil, err
+			}
+			index.values = append(index.values, v)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, scanner.Err()
+}
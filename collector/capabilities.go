@@ -0,0 +1,105 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	disableMissingCapabilities = kingpin.Flag("collector.disable-missing-capabilities", "Disable enabled collectors requiring capabilities the exporter lacks, instead of letting them fail on every scrape.").Bool()
+
+	capabilityMissingDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_capability_missing"),
+		"node_exporter: Whether a capability required by a collector is missing.",
+		[]string{"collector", "capability"},
+		nil,
+	)
+
+	// collectorCapabilities are the capabilities collectors can't work
+	// without, beyond reading the world readable files of procfs and sysfs.
+	collectorCapabilities = map[string][]string{
+		"audit":     {"CAP_AUDIT_CONTROL"},
+		"bpf":       {"CAP_SYS_ADMIN"},
+		"firewall":  {"CAP_NET_ADMIN"},
+		"kvm":       {"CAP_DAC_READ_SEARCH"},
+		"tracefs":   {"CAP_DAC_OVERRIDE"},
+		"wireguard": {"CAP_NET_ADMIN"},
+		"zswap":     {"CAP_DAC_READ_SEARCH"},
+	}
+
+	capabilityAuditOnce sync.Once
+	capabilityAudit     map[string][]capabilityCheck
+)
+
+// capabilityCheck is the result of checking whether the exporter has a
+// capability required by a collector.
+type capabilityCheck struct {
+	collector  string
+	capability string
+	missing    bool
+}
+
+// auditCapabilities checks the capabilities required by the enabled
+// collectors once and logs the missing ones. It returns the checks by
+// collector.
+func auditCapabilities() map[string][]capabilityCheck {
+	capabilityAuditOnce.Do(func() {
+		capabilityAudit = map[string][]capabilityCheck{}
+		effective, err := effectiveCapabilities()
+		if err != nil {
+			log.Debugf("Not checking capabilities required by collectors: %s", err)
+			return
+		}
+		for collector, capabilities := range collectorCapabilities {
+			if enabled, ok := collectorState[collector]; !ok || !*enabled {
+				continue
+			}
+			var missing []string
+			for _, capability := range capabilities {
+				check := capabilityCheck{collector: collector, capability: capability, missing: !effective[capability]}
+				capabilityAudit[collector] = append(capabilityAudit[collector], check)
+				if check.missing {
+					missing = append(missing, capability)
+				}
+			}
+			if len(missing) == 0 {
+				continue
+			}
+			sort.Strings(missing)
+			if *disableMissingCapabilities {
+				log.Warnf("Disabling %s collector, it requires %s, which the exporter lacks", collector, strings.Join(missing, ", "))
+			} else {
+				log.Warnf("The %s collector requires %s, which the exporter lacks, it will fail or miss metrics", collector, strings.Join(missing, ", "))
+			}
+		}
+	})
+	return capabilityAudit
+}
+
+// missingCapabilities returns whether any of the checks failed.
+func missingCapabilities(checks []capabilityCheck) bool {
+	for _, c := range checks {
+		if c.missing {
+			return true
+		}
+	}
+	return false
+}
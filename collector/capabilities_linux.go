@@ -0,0 +1,71 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// capabilityBits are the bits of the capabilities of linux/capability.h
+// required by collectors.
+var capabilityBits = map[string]uint{
+	"CAP_DAC_OVERRIDE":    1,
+	"CAP_DAC_READ_SEARCH": 2,
+	"CAP_NET_ADMIN":       12,
+	"CAP_SYS_ADMIN":       21,
+	"CAP_AUDIT_CONTROL":   30,
+}
+
+// effectiveCapabilities returns which of the capabilities required by
+// collectors the exporter has.
+func effectiveCapabilities() (map[string]bool, error) {
+	// The capabilities are those of the exporter, not of the processes of
+	// --path.procfs.
+	file, err := os.Open("/proc/self/status")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	mask, err := parseEffectiveCapabilities(file)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse /proc/self/status: %s", err)
+	}
+
+	effective := make(map[string]bool, len(capabilityBits))
+	for name, bit := range capabilityBits {
+		effective[name] = mask&(1<<bit) != 0
+	}
+	return effective, nil
+}
+
+// parseEffectiveCapabilities returns the hexadecimal CapEff mask of a
+// process status file.
+func parseEffectiveCapabilities(r io.Reader) (uint64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "CapEff:" {
+			return strconv.ParseUint(fields[1], 16, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no CapEff field")
+}
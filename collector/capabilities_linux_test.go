@@ -0,0 +1,50 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEffectiveCapabilities(t *testing.T) {
+	status := `Name:	node_exporter
+Umask:	0022
+State:	S (sleeping)
+CapInh:	0000000000000000
+CapPrm:	0000000000201006
+CapEff:	0000000000201006
+CapBnd:	000001ffffffffff
+NoNewPrivs:	0
+`
+	mask, err := parseEffectiveCapabilities(strings.NewReader(status))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"CAP_DAC_OVERRIDE":    true,
+		"CAP_DAC_READ_SEARCH": true,
+		"CAP_NET_ADMIN":       true,
+		"CAP_SYS_ADMIN":       true,
+		"CAP_AUDIT_CONTROL":   false,
+	} {
+		if got := mask&(1<<capabilityBits[name]) != 0; got != want {
+			t.Errorf("want %s %t, got %t", name, want, got)
+		}
+	}
+
+	if _, err := parseEffectiveCapabilities(strings.NewReader("Name:\tnode_exporter\n")); err == nil {
+		t.Error("want error for status without CapEff field")
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noceph
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const cephSubsystem = "ceph"
+
+// cephMDSSessionStates are the states of the sessions of a client with the
+// metadata servers of a CephFS.
+var cephMDSSessionStates = []string{"new", "opening", "open", "hung", "closing", "closed", "restarting", "reconnecting", "rejected"}
+
+type cephCollector struct {
+	rbdInfoDesc     *prometheus.Desc
+	osdRequestsDesc *prometheus.Desc
+	mdsRequestsDesc *prometheus.Desc
+	mdsSessionDesc  *prometheus.Desc
+	blocklistedDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector(cephSubsystem, defaultDisabled, NewCephCollector)
+}
+
+// NewCephCollector returns a new Collector exposing the RBD devices and the
+// state of the Ceph kernel clients.
+func NewCephCollector() (Collector, error) {
+	clientLabels := []string{"fsid", "client"}
+	return &cephCollector{
+		rbdInfoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cephSubsystem, "rbd_device_info"),
+			"RBD image mapped to the block device, whose I/O statistics are exposed by the diskstats collector, value is always 1.",
+			[]string{"device", "pool", "image", "snapshot"}, nil,
+		),
+		osdRequestsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cephSubsystem, "client_osd_requests_pending"),
+			"Number of requests of the client waiting for an OSD.",
+			clientLabels, nil,
+		),
+		mdsRequestsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cephSubsystem, "client_mds_requests_pending"),
+			"Number of requests of the CephFS client waiting for a metadata server.",
+			clientLabels, nil,
+		),
+		mdsSessionDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cephSubsystem, "client_mds_session_state"),
+			"States of the session of the CephFS client with the metadata server, 1 for the current one and 0 for the others.",
+			append(clientLabels, "mds", "state"), nil,
+		),
+		blocklistedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cephSubsystem, "client_blocklisted"),
+			"1 if the cluster blocklisted the client, 0 otherwise.",
+			clientLabels, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *cephCollector) Update(ch chan<- prometheus.Metric) error {
+	if err := c.updateRBD(ch); err != nil {
+		return err
+	}
+
+	// Each client, i.e. each mapped cluster, has a <fsid>.client<id>
+	// directory in the Ceph debugfs, which requires root.
+	clients, err := filepath.Glob(sysFilePath("kernel/debug/ceph/*.client*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range clients {
+		parts := strings.SplitN(filepath.Base(dir), ".", 2)
+		fsid, client := parts[0], parts[1]
+		if err := c.updateClient(ch, dir, fsid, client); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *cephCollector) updateRBD(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("bus/rbd/devices/*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range devices {
+		id := filepath.Base(dir)
+		attrs := map[string]string{}
+		for _, name := range []string{"pool", "name", "current_snap"} {
+			value, err := readTrimmedFile(filepath.Join(dir, name))
+			if err != nil {
+				return fmt.Errorf("couldn't get %s of RBD device %s: %s", name, id, err)
+			}
+			attrs[name] = value
+		}
+		// Images mapped without a snapshot show "-".
+		if attrs["current_snap"] == "-" {
+			attrs["current_snap"] = ""
+		}
+		ch <- prometheus.MustNewConstMetric(c.rbdInfoDesc, prometheus.GaugeValue, 1,
+			"rbd"+id, attrs["pool"], attrs["name"], attrs["current_snap"])
+	}
+	return nil
+}
+
+func (c *cephCollector) updateClient(ch chan<- prometheus.Metric, dir, fsid, client string) error {
+	osdc, err := os.Open(filepath.Join(dir, "osdc"))
+	if err != nil {
+		return fmt.Errorf("couldn't get OSD requests of %s: %s", client, err)
+	}
+	defer osdc.Close()
+	pending, err := parseCephOSDRequests(osdc)
+	if err != nil {
+		return fmt.Errorf("couldn't parse OSD requests of %s: %s", client, err)
+	}
+	ch <- prometheus.MustNewConstMetric(c.osdRequestsDesc, prometheus.GaugeValue, pending, fsid, client)
+
+	// The blocklist state is only exposed since Linux 5.5.
+	if status, err := ioutil.ReadFile(filepath.Join(dir, "status")); err == nil {
+		for _, line := range strings.Split(string(status), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 2 && fields[0] == "blocklisted:" {
+				blocklisted := 0.0
+				if fields[1] == "true" {
+					blocklisted = 1
+				}
+				ch <- prometheus.MustNewConstMetric(c.blocklistedDesc, prometheus.GaugeValue, blocklisted, fsid, client)
+			}
+		}
+	}
+
+	// Only CephFS clients have the MDS files.
+	mdsc, err := ioutil.ReadFile(filepath.Join(dir, "mdsc"))
+	if err != nil {
+		log.Debugf("Not collecting MDS state of %s: %s", client, err)
+		return nil
+	}
+	requests := strings.TrimSpace(string(mdsc))
+	pendingMDS := 0.0
+	if requests != "" {
+		pendingMDS = float64(len(strings.Split(requests, "\n")))
+	}
+	ch <- prometheus.MustNewConstMetric(c.mdsRequestsDesc, prometheus.GaugeValue, pendingMDS, fsid, client)
+
+	sessions, err := os.Open(filepath.Join(dir, "mds_sessions"))
+	if err != nil {
+		return fmt.Errorf("couldn't get MDS sessions of %s: %s", client, err)
+	}
+	defer sessions.Close()
+	states, err := parseCephMDSSessions(sessions)
+	if err != nil {
+		return fmt.Errorf("couldn't parse MDS sessions of %s: %s", client, err)
+	}
+	for mds, state := range states {
+		for _, s := range cephMDSSessionStates {
+			v := 0.0
+			if s == state {
+				v = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.mdsSessionDesc, prometheus.GaugeValue, v, fsid, client, mds, s)
+		}
+	}
+	return nil
+}
+
+// parseCephOSDRequests returns the number of pending OSD requests from the
+// osdc debugfs file. It starts with a "REQUESTS <n> homeless <n>" header
+// since Linux 4.7, while older kernels only list a line per request.
+func parseCephOSDRequests(r io.Reader) (float64, error) {
+	var (
+		scanner = bufio.NewScanner(r)
+		lines   float64
+	)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "REQUESTS" {
+			if len(fields) < 2 {
+				return 0, fmt.Errorf("invalid line %q", scanner.Text())
+			}
+			return strconv.ParseFloat(fields[1], 64)
+		}
+		lines++
+	}
+	return lines, scanner.Err()
+}
+
+// parseCephMDSSessions returns the session states by MDS from the
+// mds_sessions debugfs file, e.g.
+//
+//	global_id 4567
+//	name "client.admin"
+//	mds.0 open
+func parseCephMDSSessions(r io.Reader) (map[string]string, error) {
+	var (
+		scanner = bufio.NewScanner(r)
+		states  = map[string]string{}
+	)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && strings.HasPrefix(fields[0], "mds.") {
+			states[strings.TrimPrefix(fields[0], "mds.")] = fields[1]
+		}
+	}
+	return states, scanner.Err()
+}
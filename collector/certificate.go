@@ -0,0 +1,135 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nocertificate
+
+package collector
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var certificatePaths = kingpin.Flag("collector.certificate.path", "Glob pattern of PEM files to expose the certificate expiry of. Can be repeated.").Strings()
+
+type certificateCollector struct {
+	expiryDesc *prometheus.Desc
+	errorDesc  *prometheus.Desc
+}
+
+type certificate struct {
+	subject  string
+	notAfter float64
+}
+
+func init() {
+	registerCollector("certificate", defaultDisabled, NewCertificateCollector)
+}
+
+// NewCertificateCollector returns a new Collector exposing the expiry of the
+// certificates in the configured PEM files.
+func NewCertificateCollector() (Collector, error) {
+	return &certificateCollector{
+		expiryDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "certificate", "expiry_seconds"),
+			"Unixtime the certificate expires at.",
+			[]string{"path", "subject"}, nil,
+		),
+		errorDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "certificate", "read_error"),
+			"1 if there was an error reading or parsing the file, 0 otherwise.",
+			[]string{"path"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *certificateCollector) Update(ch chan<- prometheus.Metric) error {
+	var paths []string
+	for _, pattern := range *certificatePaths {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid certificate path pattern %q: %s", pattern, err)
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	seen := map[string]bool{}
+	for _, path := range paths {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		certs, err := readCertificates(path)
+		if err != nil {
+			log.Errorf("Error reading certificates from %q: %s", path, err)
+			ch <- prometheus.MustNewConstMetric(c.errorDesc, prometheus.GaugeValue, 1, path)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.errorDesc, prometheus.GaugeValue, 0, path)
+		for _, cert := range certs {
+			ch <- prometheus.MustNewConstMetric(c.expiryDesc, prometheus.GaugeValue, cert.notAfter, path, cert.subject)
+		}
+	}
+	return nil
+}
+
+// readCertificates returns the certificates of a PEM file, ignoring other
+// blocks such as keys. Of several certificates with the same subject, e.g.
+// after appending a renewed certificate, only the first one is returned.
+func readCertificates(path string) ([]certificate, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		certs    []certificate
+		subjects = map[string]bool{}
+		block    *pem.Block
+	)
+	for {
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		subject := cert.Subject.String()
+		if subjects[subject] {
+			log.Debugf("Skipping duplicate certificate %q in %q", subject, path)
+			continue
+		}
+		subjects[subject] = true
+		certs = append(certs, certificate{subject: subject, notAfter: float64(cert.NotAfter.Unix())})
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found")
+	}
+	return certs, nil
+}
@@ -0,0 +1,41 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nocertificate
+
+package collector
+
+import "testing"
+
+func TestReadCertificates(t *testing.T) {
+	certs, err := readCertificates("fixtures/certificate/bundle.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 2 {
+		t.Fatalf("expected 2 certificates, got %d", len(certs))
+	}
+	if want := "CN=etcd.example.com"; certs[0].subject != want {
+		t.Errorf("want subject %q, got %q", want, certs[0].subject)
+	}
+	if want := "CN=Example CA,O=Example"; certs[1].subject != want {
+		t.Errorf("want subject %q, got %q", want, certs[1].subject)
+	}
+	if certs[1].notAfter <= certs[0].notAfter {
+		t.Errorf("expected the CA to expire after the leaf certificate, got %v and %v", certs[1].notAfter, certs[0].notAfter)
+	}
+
+	if _, err := readCertificates("fixtures/certificate/invalid.pem"); err == nil {
+		t.Error("expected error for file without certificates")
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nocgroup
+
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	cgroupContainerRuntimes = kingpin.Flag("collector.cgroup.container-runtime", "Container runtime to resolve the IDs of container cgroups to container names and images with. Can be repeated.").Enums("docker", "containerd")
+	cgroupDockerSocket      = kingpin.Flag("collector.cgroup.docker-socket", "Path of the Docker daemon socket.").Default("/var/run/docker.sock").String()
+	cgroupContainerdState   = kingpin.Flag("collector.cgroup.containerd-state", "Path of the state directory of the containerd v2 runtime, holding the OCI bundles of the running containers.").Default("/run/containerd/io.containerd.runtime.v2.task").String()
+
+	// cgroupContainerIDRE matches the container ID in cgroup names like
+	// docker-<id>.scope, cri-containerd-<id>.scope or /docker/<id>.
+	cgroupContainerIDRE = regexp.MustCompile(`(?:^|[-/])([0-9a-f]{64})(?:\.scope)?$`)
+)
+
+// cgroupContainer is a container a cgroup belongs to.
+type cgroupContainer struct {
+	name  string
+	image string
+}
+
+// cgroupContainerID returns the ID of the container of a cgroup, or "" if the
+// cgroup doesn't belong to a container.
+func cgroupContainerID(cgroup string) string {
+	m := cgroupContainerIDRE.FindStringSubmatch(cgroup)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// containersByID returns the running containers of the given runtimes by ID.
+func containersByID(runtimes []string) (map[string]cgroupContainer, error) {
+	containers := map[string]cgroupContainer{}
+	for _, runtime := range runtimes {
+		var err error
+		switch runtime {
+		case "docker":
+			err = dockerContainers(*cgroupDockerSocket, containers)
+		case "containerd":
+			err = containerdContainers(*cgroupContainerdState, containers)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get %s containers: %s", runtime, err)
+		}
+	}
+	return containers, nil
+}
+
+// dockerContainers lists the running containers through the Docker Engine API.
+func dockerContainers(socket string, containers map[string]cgroupContainer) error {
+	client := http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	// The host is ignored, requests are sent to the socket.
+	resp, err := client.Get("http://docker/containers/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var list []struct {
+		ID    string `json:"Id"`
+		Names []string
+		Image string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return err
+	}
+	for _, c := range list {
+		var name string
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		containers[c.ID] = cgroupContainer{name: name, image: c.Image}
+	}
+	return nil
+}
+
+// containerdContainers reads the OCI bundles of the running containers from
+// the state directory of the containerd runtime, laid out as
+// <namespace>/<id>/config.json. Names and images are only known for
+// containers created through CRI, e.g. by the kubelet, or by nerdctl.
+func containerdContainers(state string, containers map[string]cgroupContainer) error {
+	configs, err := filepath.Glob(filepath.Join(state, "*", "*", "config.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range configs {
+		f, err := os.Open(path)
+		if err != nil {
+			// Containers may be removed in the meantime.
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		var spec struct {
+			Annotations map[string]string
+		}
+		err = json.NewDecoder(f).Decode(&spec)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("couldn't parse %s: %s", path, err)
+		}
+
+		c := cgroupContainer{
+			name:  spec.Annotations["io.kubernetes.cri.container-name"],
+			image: spec.Annotations["io.kubernetes.cri.image-name"],
+		}
+		if c.name == "" {
+			c.name = spec.Annotations["nerdctl/name"]
+		}
+		containers[filepath.Base(filepath.Dir(path))] = c
+	}
+	return nil
+}
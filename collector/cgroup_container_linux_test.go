@@ -0,0 +1,112 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nocgroup
+
+package collector
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCgroupContainerID(t *testing.T) {
+	id := strings.Repeat("0123456789abcdef", 4)
+	for cgroup, want := range map[string]string{
+		"/system.slice/docker-" + id + ".scope":                               id,
+		"/kubepods.slice/kubepods-pod1.slice/cri-containerd-" + id + ".scope": id,
+		"/docker/" + id:                          id,
+		"/system.slice/sshd.service":             "",
+		"/user.slice/user-" + id[:32] + ".slice": "",
+	} {
+		if got := cgroupContainerID(cgroup); got != want {
+			t.Errorf("%s: want %q, got %q", cgroup, want, got)
+		}
+	}
+}
+
+func TestDockerContainers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[{"Id":"abc","Names":["/web"],"Image":"nginx:1.17"},{"Id":"def","Names":[],"Image":"redis"}]`))
+	}))
+	server.Listener = l
+	server.Start()
+	defer server.Close()
+
+	containers := map[string]cgroupContainer{}
+	if err := dockerContainers(socket, containers); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]cgroupContainer{
+		"abc": {name: "web", image: "nginx:1.17"},
+		"def": {image: "redis"},
+	}
+	if !reflect.DeepEqual(containers, want) {
+		t.Errorf("want %v, got %v", want, containers)
+	}
+}
+
+func TestContainerdContainers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "containerd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for path, config := range map[string]string{
+		"k8s.io/abc/config.json":  `{"annotations":{"io.kubernetes.cri.container-name":"web","io.kubernetes.cri.image-name":"docker.io/library/nginx:1.17"}}`,
+		"default/def/config.json": `{"annotations":{"nerdctl/name":"redis"}}`,
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	containers := map[string]cgroupContainer{}
+	if err := containerdContainers(dir, containers); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]cgroupContainer{
+		"abc": {name: "web", image: "docker.io/library/nginx:1.17"},
+		"def": {name: "redis"},
+	}
+	if !reflect.DeepEqual(containers, want) {
+		t.Errorf("want %v, got %v", want, containers)
+	}
+}
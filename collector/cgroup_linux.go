@@ -0,0 +1,149 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nocgroup
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const cgroupSubsystem = "cgroup"
+
+var cgroupMaxDepth = kingpin.Flag("collector.cgroup.max-depth", "Maximum depth of the cgroup hierarchy to collect memory events for, 0 only collects the root cgroup. Kubernetes containers are at depth 4, e.g. kubepods.slice/kubepods-burstable.slice/<pod>.slice/<container>.scope, Docker containers at depth 2.").Default("4").Int()
+
+type cgroupCollector struct {
+	memoryEvents *prometheus.Desc
+}
+
+func init() {
+	registerCollector(cgroupSubsystem, defaultDisabled, NewCgroupCollector)
+}
+
+// NewCgroupCollector returns a new Collector exposing cgroup v2 memory events.
+func NewCgroupCollector() (Collector, error) {
+	labels := []string{"cgroup", "event"}
+	// Container labels are only added if a container runtime is configured,
+	// so that they aren't empty for all cgroups otherwise.
+	if len(*cgroupContainerRuntimes) > 0 {
+		labels = append(labels, "container", "image")
+	}
+	return &cgroupCollector{
+		memoryEvents: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cgroupSubsystem, "memory_events_total"),
+			"Number of memory events of the cgroup and its descendants, e.g. oom_kill or hitting the max limit.",
+			labels, nil,
+		),
+	}, nil
+}
+
+func (c *cgroupCollector) Update(ch chan<- prometheus.Metric) error {
+	root := sysFilePath("fs/cgroup")
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("Not collecting cgroup memory events, %s is not a cgroup v2 hierarchy", root)
+			return nil
+		}
+		return err
+	}
+
+	var containers map[string]cgroupContainer
+	if len(*cgroupContainerRuntimes) > 0 {
+		var err error
+		if containers, err = containersByID(*cgroupContainerRuntimes); err != nil {
+			return err
+		}
+	}
+
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// cgroups may be removed while walking the hierarchy.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		cgroup := "/"
+		if rel != "." {
+			cgroup += rel
+			if strings.Count(rel, string(filepath.Separator))+1 > *cgroupMaxDepth {
+				return filepath.SkipDir
+			}
+		}
+
+		// The root cgroup and cgroups without the memory controller enabled
+		// don't have memory.events.
+		file, err := os.Open(filepath.Join(path, "memory.events"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		defer file.Close()
+
+		events, err := parseCgroupMemoryEvents(file)
+		if err != nil {
+			return fmt.Errorf("couldn't parse memory events of %s: %s", cgroup, err)
+		}
+		labels := []string{cgroup, ""}
+		if containers != nil {
+			container := containers[cgroupContainerID(cgroup)]
+			labels = append(labels, container.name, container.image)
+		}
+		for event, v := range events {
+			labels[1] = event
+			ch <- prometheus.MustNewConstMetric(c.memoryEvents, prometheus.CounterValue, v, labels...)
+		}
+		return nil
+	})
+}
+
+// parseCgroupMemoryEvents parses the "<event> <count>" lines of memory.events.
+func parseCgroupMemoryEvents(r io.Reader) (map[string]float64, error) {
+	events := make(map[string]float64)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid line %q", scanner.Text())
+		}
+		v, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		events[fields[0]] = v
+	}
+
+	return events, scanner.Err()
+}
@@ -0,0 +1,329 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nochrony
+
+package collector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const chronySubsystem = "chrony"
+
+// Constants of chrony's command protocol, see candm.h in the chrony sources.
+const (
+	chronyProtocolVersion = 6
+	chronyPktTypeRequest  = 1
+	chronyPktTypeReply    = 2
+
+	chronyRequestNSources   = 14
+	chronyRequestSourceData = 15
+	chronyRequestTracking   = 33
+
+	chronyReplyNSources   = 2
+	chronyReplySourceData = 3
+	chronyReplyTracking   = 5
+
+	chronyRequestHeaderLength = 20
+	chronyReplyHeaderLength   = 28
+
+	// Requests are padded to the length of their reply, so the protocol
+	// can't be used for amplification attacks.
+	chronyNSourcesReplyLength   = chronyReplyHeaderLength + 4
+	chronySourceDataReplyLength = chronyReplyHeaderLength + 48
+	chronyTrackingReplyLength   = chronyReplyHeaderLength + 76
+)
+
+var (
+	chronyAddress = kingpin.Flag("collector.chrony.address", "Address of the chronyd command socket.").Default("127.0.0.1:323").String()
+	chronyTimeout = kingpin.Flag("collector.chrony.timeout", "Timeout for all requests to chronyd.").Default("5s").Duration()
+
+	chronyLeapStatuses = []string{"normal", "insert_second", "delete_second", "unsynchronised"}
+	chronySourceStates = []string{"sync", "unreach", "falseticker", "jittery", "candidate", "outlier"}
+	chronySourceModes  = []string{"server", "peer", "refclock"}
+)
+
+type chronyCollector struct {
+	trackingInfo, stratum, leapStatus, systemTimeOffset, lastOffset, rmsOffset,
+	frequency, residualFrequency, skew, rootDelay, rootDispersion, updateInterval typedDesc
+
+	sourceInfo, sourceState, sourceStratum, sourcePollInterval, sourceReachability,
+	sourceLastSampleAge, sourceLastOffset, sourceLastOffsetError typedDesc
+}
+
+// chronyTracking is the reply to the tracking request, like `chronyc tracking`.
+type chronyTracking struct {
+	refID             uint32
+	ipAddr            string
+	stratum           uint16
+	leapStatus        uint16
+	currentCorrection float64
+	lastOffset        float64
+	rmsOffset         float64
+	freqPPM           float64
+	residFreqPPM      float64
+	skewPPM           float64
+	rootDelay         float64
+	rootDispersion    float64
+	updateInterval    float64
+}
+
+// chronySource is the reply to the source data request, like `chronyc sources`.
+type chronySource struct {
+	name          string
+	poll          int16
+	stratum       uint16
+	state         uint16
+	mode          uint16
+	reachability  uint16
+	sinceSample   uint32
+	latestMeas    float64
+	latestMeasErr float64
+}
+
+func init() {
+	registerCollector(chronySubsystem, defaultDisabled, NewChronyCollector)
+}
+
+// NewChronyCollector returns a new Collector exposing the tracking and source
+// statistics of a local chronyd.
+func NewChronyCollector() (Collector, error) {
+	desc := func(name, help string, labels ...string) typedDesc {
+		return typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, chronySubsystem, name),
+			help, labels, nil,
+		), prometheus.GaugeValue}
+	}
+	return &chronyCollector{
+		trackingInfo:      desc("tracking_info", "Reference the system clock is synchronised to, value is always 1.", "reference_id", "reference"),
+		stratum:           desc("tracking_stratum", "Stratum of the local clock."),
+		leapStatus:        desc("tracking_leap_status", "Leap second status of the local clock.", "status"),
+		systemTimeOffset:  desc("tracking_system_time_offset_seconds", "Offset of the system clock to the chrony time, which is being slewed away."),
+		lastOffset:        desc("tracking_last_offset_seconds", "Estimated offset of the local clock on the last clock update."),
+		rmsOffset:         desc("tracking_rms_offset_seconds", "Long-term average of the offset of the local clock."),
+		frequency:         desc("tracking_frequency_ppm", "Rate by which the system clock would be wrong without correction by chrony, in ppm."),
+		residualFrequency: desc("tracking_residual_frequency_ppm", "Difference between the frequency suggested by the reference and the one currently used, in ppm."),
+		skew:              desc("tracking_skew_ppm", "Estimated error bound on the frequency, in ppm."),
+		rootDelay:         desc("tracking_root_delay_seconds", "Total network path delay to the stratum-1 computer."),
+		rootDispersion:    desc("tracking_root_dispersion_seconds", "Total dispersion accumulated through all computers back to the stratum-1 computer."),
+		updateInterval:    desc("tracking_update_interval_seconds", "Interval between the last two clock updates."),
+
+		sourceInfo:            desc("source_info", "Mode of the time source, value is always 1.", "source", "mode"),
+		sourceState:           desc("source_state", "Selection state of the time source.", "source", "state"),
+		sourceStratum:         desc("source_stratum", "Stratum of the time source.", "source"),
+		sourcePollInterval:    desc("source_poll_interval_seconds", "Interval in which the time source is polled.", "source"),
+		sourceReachability:    desc("source_reachability_ratio", "Ratio of the last 8 polls of the time source which were answered.", "source"),
+		sourceLastSampleAge:   desc("source_last_sample_age_seconds", "Time since the last good sample was received from the time source.", "source"),
+		sourceLastOffset:      desc("source_last_offset_seconds", "Offset of the local clock to the time source measured by the last sample.", "source"),
+		sourceLastOffsetError: desc("source_last_offset_error_seconds", "Estimated error of the offset measured by the last sample.", "source"),
+	}, nil
+}
+
+func (c *chronyCollector) Update(ch chan<- prometheus.Metric) error {
+	conn, err := net.Dial("udp", *chronyAddress)
+	if err != nil {
+		return fmt.Errorf("couldn't connect to chronyd: %s", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(*chronyTimeout)); err != nil {
+		return err
+	}
+
+	reply, err := chronyRequest(conn, chronyRequestTracking, nil, chronyReplyTracking, chronyTrackingReplyLength)
+	if err != nil {
+		return fmt.Errorf("couldn't get tracking: %s", err)
+	}
+	t := parseChronyTracking(reply)
+
+	// Reference clocks have no address but a descriptive reference ID.
+	reference := t.ipAddr
+	if reference == "" {
+		reference = strings.TrimRight(string(reply[0:4]), "\x00")
+	}
+	ch <- c.trackingInfo.mustNewConstMetric(1, fmt.Sprintf("%08X", t.refID), reference)
+	ch <- c.stratum.mustNewConstMetric(float64(t.stratum))
+	for i, status := range chronyLeapStatuses {
+		v := 0.0
+		if int(t.leapStatus) == i {
+			v = 1
+		}
+		ch <- c.leapStatus.mustNewConstMetric(v, status)
+	}
+	ch <- c.systemTimeOffset.mustNewConstMetric(t.currentCorrection)
+	ch <- c.lastOffset.mustNewConstMetric(t.lastOffset)
+	ch <- c.rmsOffset.mustNewConstMetric(t.rmsOffset)
+	ch <- c.frequency.mustNewConstMetric(t.freqPPM)
+	ch <- c.residualFrequency.mustNewConstMetric(t.residFreqPPM)
+	ch <- c.skew.mustNewConstMetric(t.skewPPM)
+	ch <- c.rootDelay.mustNewConstMetric(t.rootDelay)
+	ch <- c.rootDispersion.mustNewConstMetric(t.rootDispersion)
+	ch <- c.updateInterval.mustNewConstMetric(t.updateInterval)
+
+	reply, err = chronyRequest(conn, chronyRequestNSources, nil, chronyReplyNSources, chronyNSourcesReplyLength)
+	if err != nil {
+		return fmt.Errorf("couldn't get number of sources: %s", err)
+	}
+	n := binary.BigEndian.Uint32(reply)
+
+	for i := uint32(0); i < n; i++ {
+		index := make([]byte, 4)
+		binary.BigEndian.PutUint32(index, i)
+		reply, err := chronyRequest(conn, chronyRequestSourceData, index, chronyReplySourceData, chronySourceDataReplyLength)
+		if err != nil {
+			return fmt.Errorf("couldn't get source %d: %s", i, err)
+		}
+		s := parseChronySource(reply)
+
+		if int(s.mode) < len(chronySourceModes) {
+			ch <- c.sourceInfo.mustNewConstMetric(1, s.name, chronySourceModes[s.mode])
+		}
+		for j, state := range chronySourceStates {
+			v := 0.0
+			if int(s.state) == j {
+				v = 1
+			}
+			ch <- c.sourceState.mustNewConstMetric(v, s.name, state)
+		}
+		ch <- c.sourceStratum.mustNewConstMetric(float64(s.stratum), s.name)
+		ch <- c.sourcePollInterval.mustNewConstMetric(math.Pow(2, float64(s.poll)), s.name)
+		ch <- c.sourceReachability.mustNewConstMetric(float64(bits.OnesCount8(uint8(s.reachability)))/8, s.name)
+		ch <- c.sourceLastSampleAge.mustNewConstMetric(float64(s.sinceSample), s.name)
+		ch <- c.sourceLastOffset.mustNewConstMetric(s.latestMeas, s.name)
+		ch <- c.sourceLastOffsetError.mustNewConstMetric(s.latestMeasErr, s.name)
+	}
+
+	return nil
+}
+
+// chronyRequest sends a command to chronyd and returns the data of the reply
+// after validating its header.
+func chronyRequest(conn net.Conn, command uint16, data []byte, replyCode uint16, replyLength int) ([]byte, error) {
+	req := make([]byte, chronyRequestHeaderLength+len(data))
+	if len(req) < replyLength {
+		req = make([]byte, replyLength)
+	}
+	sequence := rand.Uint32()
+	req[0] = chronyProtocolVersion
+	req[1] = chronyPktTypeRequest
+	binary.BigEndian.PutUint16(req[4:], command)
+	binary.BigEndian.PutUint32(req[8:], sequence)
+	copy(req[chronyRequestHeaderLength:], data)
+
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	reply := make([]byte, 1024)
+	n, err := conn.Read(reply)
+	if err != nil {
+		return nil, err
+	}
+	reply = reply[:n]
+
+	switch {
+	case n < replyLength:
+		return nil, fmt.Errorf("reply too short: %d bytes", n)
+	case reply[0] != chronyProtocolVersion || reply[1] != chronyPktTypeReply:
+		return nil, fmt.Errorf("unexpected reply version %d type %d", reply[0], reply[1])
+	case binary.BigEndian.Uint32(reply[16:]) != sequence:
+		return nil, fmt.Errorf("unexpected reply sequence")
+	case binary.BigEndian.Uint16(reply[8:]) != 0:
+		return nil, fmt.Errorf("request failed with status %d", binary.BigEndian.Uint16(reply[8:]))
+	case binary.BigEndian.Uint16(reply[6:]) != replyCode:
+		return nil, fmt.Errorf("unexpected reply code %d", binary.BigEndian.Uint16(reply[6:]))
+	}
+	return reply[chronyReplyHeaderLength:], nil
+}
+
+func parseChronyTracking(b []byte) chronyTracking {
+	return chronyTracking{
+		refID:             binary.BigEndian.Uint32(b[0:]),
+		ipAddr:            parseChronyIPAddr(b[4:24]),
+		stratum:           binary.BigEndian.Uint16(b[24:]),
+		leapStatus:        binary.BigEndian.Uint16(b[26:]),
+		currentCorrection: parseChronyFloat(b[40:]),
+		lastOffset:        parseChronyFloat(b[44:]),
+		rmsOffset:         parseChronyFloat(b[48:]),
+		freqPPM:           parseChronyFloat(b[52:]),
+		residFreqPPM:      parseChronyFloat(b[56:]),
+		skewPPM:           parseChronyFloat(b[60:]),
+		rootDelay:         parseChronyFloat(b[64:]),
+		rootDispersion:    parseChronyFloat(b[68:]),
+		updateInterval:    parseChronyFloat(b[72:]),
+	}
+}
+
+func parseChronySource(b []byte) chronySource {
+	s := chronySource{
+		name:          parseChronyIPAddr(b[0:20]),
+		poll:          int16(binary.BigEndian.Uint16(b[20:])),
+		stratum:       binary.BigEndian.Uint16(b[22:]),
+		state:         binary.BigEndian.Uint16(b[24:]),
+		mode:          binary.BigEndian.Uint16(b[26:]),
+		reachability:  binary.BigEndian.Uint16(b[30:]),
+		sinceSample:   binary.BigEndian.Uint32(b[32:]),
+		latestMeas:    parseChronyFloat(b[40:]),
+		latestMeasErr: parseChronyFloat(b[44:]),
+	}
+	// Reference clocks are identified by their reference ID, e.g. "GPS".
+	if s.mode == 2 {
+		s.name = strings.TrimRight(string(b[0:4]), "\x00")
+	}
+	return s
+}
+
+// parseChronyIPAddr parses an IPAddr, 16 bytes of address followed by the
+// address family.
+func parseChronyIPAddr(b []byte) string {
+	switch binary.BigEndian.Uint16(b[16:]) {
+	case 1:
+		return net.IP(b[0:4]).String()
+	case 2:
+		return net.IP(b[0:16]).String()
+	}
+	return ""
+}
+
+// parseChronyFloat parses chrony's network float format, a 7 bit signed
+// exponent followed by a 25 bit signed coefficient.
+func parseChronyFloat(b []byte) float64 {
+	const (
+		expBits  = 7
+		coefBits = 32 - expBits
+	)
+	x := binary.BigEndian.Uint32(b)
+
+	exp := int32(x >> coefBits)
+	if exp >= 1<<(expBits-1) {
+		exp -= 1 << expBits
+	}
+	exp -= coefBits
+
+	coef := int32(x % (1 << coefBits))
+	if coef >= 1<<(coefBits-1) {
+		coef -= 1 << coefBits
+	}
+
+	return float64(coef) * math.Pow(2, float64(exp))
+}
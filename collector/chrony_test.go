@@ -0,0 +1,93 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nochrony
+
+package collector
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+func TestParseChronyFloat(t *testing.T) {
+	for _, tt := range []struct {
+		in   uint32
+		want float64
+	}{
+		{0, 0},
+		{1<<25 | 1<<23, 0.5},
+		{2<<25 | (1<<25 - 1<<23), -1},
+	} {
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, tt.in)
+		if got := parseChronyFloat(b); tt.want != got {
+			t.Errorf("want %f for %#x, got %f", tt.want, tt.in, got)
+		}
+	}
+}
+
+func TestChronyRequest(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	go func() {
+		req := make([]byte, 1024)
+		n, addr, err := server.ReadFrom(req)
+		if err != nil {
+			return
+		}
+		// Requests must be padded to the length of the reply.
+		if n != chronyTrackingReplyLength {
+			return
+		}
+		reply := make([]byte, chronyTrackingReplyLength)
+		reply[0] = chronyProtocolVersion
+		reply[1] = chronyPktTypeReply
+		binary.BigEndian.PutUint16(reply[6:], chronyReplyTracking)
+		copy(reply[16:20], req[8:12])
+
+		body := reply[chronyReplyHeaderLength:]
+		copy(body[4:8], net.ParseIP("192.0.2.1").To4())
+		binary.BigEndian.PutUint16(body[20:], 1)
+		binary.BigEndian.PutUint16(body[24:], 3)
+		binary.BigEndian.PutUint32(body[44:], 1<<25|1<<23)
+		server.WriteTo(reply, addr)
+	}()
+
+	conn, err := net.Dial("udp", server.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	reply, err := chronyRequest(conn, chronyRequestTracking, nil, chronyReplyTracking, chronyTrackingReplyLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracking := parseChronyTracking(reply)
+
+	if want, got := "192.0.2.1", tracking.ipAddr; want != got {
+		t.Errorf("want reference %s, got %s", want, got)
+	}
+	if want, got := uint16(3), tracking.stratum; want != got {
+		t.Errorf("want stratum %d, got %d", want, got)
+	}
+	if want, got := 0.5, tracking.lastOffset; want != got {
+		t.Errorf("want last offset %f, got %f", want, got)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nocifs
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const cifsSubsystem = "cifs"
+
+var (
+	cifsShareRE      = regexp.MustCompile(`^(\d+)\) (\S+)`)
+	cifsReconnectsRE = regexp.MustCompile(`^(\d+) session (\d+) share reconnects$`)
+)
+
+type cifsCollector struct {
+	sessions          *prometheus.Desc
+	shares            *prometheus.Desc
+	sessionReconnects *prometheus.Desc
+	shareReconnects   *prometheus.Desc
+	vfsOperations     *prometheus.Desc
+	smbs              *prometheus.Desc
+	operations        *prometheus.Desc
+	operationsFailed  *prometheus.Desc
+	bytes             *prometheus.Desc
+}
+
+// cifsStats is the content of /proc/fs/cifs/Stats.
+type cifsStats struct {
+	sessions          uint64
+	shares            uint64
+	sessionReconnects uint64
+	shareReconnects   uint64
+	vfsOperations     uint64
+	shareStats        []cifsShareStats
+}
+
+// cifsShareStats are the counters of a single share. SMB1 mounts only report
+// the number of operations sent, SMB2 and later also the failed ones. The
+// id is the number of the share in the file, which tells the same share
+// mounted several times, e.g. with multiuser, apart.
+type cifsShareStats struct {
+	id         string
+	share      string
+	smbs       uint64
+	smb1       bool
+	operations map[string]uint64
+	failed     map[string]uint64
+	bytes      map[string]uint64
+}
+
+func init() {
+	registerCollector(cifsSubsystem, defaultDisabled, NewCIFSCollector)
+}
+
+// NewCIFSCollector returns a new Collector exposing CIFS/SMB client statistics.
+func NewCIFSCollector() (Collector, error) {
+	return &cifsCollector{
+		sessions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cifsSubsystem, "sessions"),
+			"Number of CIFS sessions in use.",
+			nil, nil,
+		),
+		shares: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cifsSubsystem, "shares"),
+			"Number of unique CIFS mount targets in use.",
+			nil, nil,
+		),
+		sessionReconnects: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cifsSubsystem, "session_reconnects_total"),
+			"Number of times a CIFS session has been reconnected.",
+			nil, nil,
+		),
+		shareReconnects: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cifsSubsystem, "share_reconnects_total"),
+			"Number of times a CIFS share has been reconnected.",
+			nil, nil,
+		),
+		vfsOperations: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cifsSubsystem, "vfs_operations_total"),
+			"Number of VFS operations performed on CIFS mounts.",
+			nil, nil,
+		),
+		smbs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cifsSubsystem, "smbs_total"),
+			"Number of SMB requests sent for the share.",
+			[]string{"share", "id"}, nil,
+		),
+		operations: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cifsSubsystem, "operations_total"),
+			"Number of operations sent for the share by operation.",
+			[]string{"share", "id", "operation"}, nil,
+		),
+		operationsFailed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cifsSubsystem, "operations_failed_total"),
+			"Number of operations which failed for the share by operation.",
+			[]string{"share", "id", "operation"}, nil,
+		),
+		bytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cifsSubsystem, "bytes_total"),
+			"Number of bytes transferred for the share by operation.",
+			[]string{"share", "id", "operation"}, nil,
+		),
+	}, nil
+}
+
+func (c *cifsCollector) Update(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("fs/cifs/Stats"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("Not collecting CIFS statistics: %s", err)
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	stats, err := parseCIFSStats(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse CIFS stats: %s", err)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.sessions, prometheus.GaugeValue, float64(stats.sessions))
+	ch <- prometheus.MustNewConstMetric(c.shares, prometheus.GaugeValue, float64(stats.shares))
+	ch <- prometheus.MustNewConstMetric(c.sessionReconnects, prometheus.CounterValue, float64(stats.sessionReconnects))
+	ch <- prometheus.MustNewConstMetric(c.shareReconnects, prometheus.CounterValue, float64(stats.shareReconnects))
+	ch <- prometheus.MustNewConstMetric(c.vfsOperations, prometheus.CounterValue, float64(stats.vfsOperations))
+
+	for _, s := range stats.shareStats {
+		ch <- prometheus.MustNewConstMetric(c.smbs, prometheus.CounterValue, float64(s.smbs), s.share, s.id)
+		for op, v := range s.operations {
+			ch <- prometheus.MustNewConstMetric(c.operations, prometheus.CounterValue, float64(v), s.share, s.id, op)
+		}
+		for op, v := range s.failed {
+			ch <- prometheus.MustNewConstMetric(c.operationsFailed, prometheus.CounterValue, float64(v), s.share, s.id, op)
+		}
+		for op, v := range s.bytes {
+			ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.CounterValue, float64(v), s.share, s.id, op)
+		}
+	}
+
+	return nil
+}
+
+func parseCIFSStats(r io.Reader) (*cifsStats, error) {
+	var (
+		stats   = &cifsStats{}
+		share   *cifsShareStats
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if m := cifsShareRE.FindStringSubmatch(line); m != nil {
+			stats.shareStats = append(stats.shareStats, cifsShareStats{
+				id:         m[1],
+				share:      m[2],
+				operations: make(map[string]uint64),
+				failed:     make(map[string]uint64),
+				bytes:      make(map[string]uint64),
+			})
+			share = &stats.shareStats[len(stats.shareStats)-1]
+			continue
+		}
+
+		if share == nil {
+			if err := parseCIFSHeaderLine(stats, line); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := parseCIFSShareLine(share, line); err != nil {
+			return nil, fmt.Errorf("invalid line %q for share %s: %s", line, share.share, err)
+		}
+	}
+
+	return stats, scanner.Err()
+}
+
+// parseCIFSHeaderLine parses the global counters printed before the first share.
+func parseCIFSHeaderLine(stats *cifsStats, line string) error {
+	var err error
+	switch {
+	case strings.HasPrefix(line, "CIFS Session:"):
+		stats.sessions, err = strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CIFS Session:")), 10, 64)
+	case strings.HasPrefix(line, "Share (unique mount targets):"):
+		stats.shares, err = strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "Share (unique mount targets):")), 10, 64)
+	case strings.HasPrefix(line, "Total vfs operations:"):
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			return fmt.Errorf("invalid line %q", line)
+		}
+		stats.vfsOperations, err = strconv.ParseUint(fields[3], 10, 64)
+	default:
+		if m := cifsReconnectsRE.FindStringSubmatch(line); m != nil {
+			if stats.sessionReconnects, err = strconv.ParseUint(m[1], 10, 64); err != nil {
+				return err
+			}
+			stats.shareReconnects, err = strconv.ParseUint(m[2], 10, 64)
+		}
+	}
+	return err
+}
+
+// parseCIFSShareLine parses a counter line of a share. SMB2 and later print
+// one "<Operation>: <sent> total <failed> failed" line per operation, which
+// older kernels wrote as "<sent> sent <failed> failed", and the bytes read
+// and written on a "Bytes read: <n>  Bytes written: <n>" line. SMB1 prints
+// several "<Operation>: <count>" pairs per line, where a "Bytes" pair belongs
+// to the preceding operation.
+func parseCIFSShareLine(share *cifsShareStats, line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 5 && (fields[2] == "total" || fields[2] == "sent") && fields[4] == "failed" {
+		op := strings.TrimSuffix(fields[0], ":")
+		sent, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return err
+		}
+		failed, err := strconv.ParseUint(fields[3], 10, 64)
+		if err != nil {
+			return err
+		}
+		share.operations[op] = sent
+		share.failed[op] = failed
+		return nil
+	}
+
+	if len(fields) == 6 && fields[0] == "Bytes" && fields[1] == "read:" && fields[3] == "Bytes" && fields[4] == "written:" {
+		read, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return err
+		}
+		written, err := strconv.ParseUint(fields[5], 10, 64)
+		if err != nil {
+			return err
+		}
+		share.bytes["Reads"] = read
+		share.bytes["Writes"] = written
+		return nil
+	}
+
+	if fields[0] == "SMBs:" {
+		// Only SMB1 mounts print further counters on the SMBs line.
+		share.smb1 = len(fields) > 2
+	} else if !share.smb1 {
+		// Skip informational lines of newer kernels, e.g. open file counts.
+		return nil
+	}
+
+	var name, prev []string
+	for _, f := range fields {
+		v, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			name = append(name, strings.TrimSuffix(f, ":"))
+			continue
+		}
+		if len(name) == 0 {
+			return fmt.Errorf("value %d without name", v)
+		}
+
+		switch op := strings.Join(name, " "); {
+		case op == "SMBs":
+			share.smbs = v
+		case op == "Bytes" && len(prev) > 0:
+			share.bytes[strings.Join(prev, " ")] = v
+		default:
+			share.operations[op] = v
+		}
+		prev, name = name, nil
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseCIFSStats(t *testing.T) {
+	file, err := os.Open("fixtures/proc/fs/cifs/Stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	stats, err := parseCIFSStats(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := uint64(2), stats.sessions; want != got {
+		t.Errorf("want sessions %d, got %d", want, got)
+	}
+	if want, got := uint64(3), stats.shareReconnects; want != got {
+		t.Errorf("want share reconnects %d, got %d", want, got)
+	}
+	if want, got := uint64(1240), stats.vfsOperations; want != got {
+		t.Errorf("want vfs operations %d, got %d", want, got)
+	}
+	if want, got := 4, len(stats.shareStats); want != got {
+		t.Fatalf("want %d shares, got %d", want, got)
+	}
+
+	smb3 := stats.shareStats[0]
+	if want, got := `\\fileserver\projects`, smb3.share; want != got {
+		t.Errorf("want share %s, got %s", want, got)
+	}
+	if want, got := uint64(312), smb3.operations["Creates"]; want != got {
+		t.Errorf("want %d creates, got %d", want, got)
+	}
+	if want, got := uint64(17), smb3.failed["Creates"]; want != got {
+		t.Errorf("want %d failed creates, got %d", want, got)
+	}
+	if want, got := uint64(1048576), smb3.bytes["Reads"]; want != got {
+		t.Errorf("want %d bytes read, got %d", want, got)
+	}
+	if want, got := uint64(524288), smb3.bytes["Writes"]; want != got {
+		t.Errorf("want %d bytes written, got %d", want, got)
+	}
+
+	// Older kernels print "sent" instead of "total".
+	old := stats.shareStats[1]
+	if want, got := `\\fileserver\home`, old.share; want != got {
+		t.Errorf("want disconnected share %s, got %s", want, got)
+	}
+	if want, got := uint64(5), old.operations["Creates"]; want != got {
+		t.Errorf("want %d creates, got %d", want, got)
+	}
+	if _, ok := old.failed["Creates"]; !ok {
+		t.Error("want failed creates")
+	}
+
+	// The same share mounted twice is told apart by its id.
+	again := stats.shareStats[3]
+	if again.share != smb3.share || again.id == smb3.id {
+		t.Errorf("want share %s with another id than %s, got share %s with id %s", smb3.share, smb3.id, again.share, again.id)
+	}
+	if want, got := uint64(20), again.operations["Creates"]; want != got {
+		t.Errorf("want %d creates, got %d", want, got)
+	}
+
+	smb1 := stats.shareStats[2]
+	if want, got := uint64(81), smb1.smbs; want != got {
+		t.Errorf("want %d SMBs, got %d", want, got)
+	}
+	if want, got := uint64(28672), smb1.bytes["Reads"]; want != got {
+		t.Errorf("want %d bytes read, got %d", want, got)
+	}
+	if want, got := uint64(1), smb1.operations["Deletes"]; want != got {
+		t.Errorf("want %d deletes, got %d", want, got)
+	}
+	if _, ok := smb1.operations["T2 Renames"]; !ok {
+		t.Error("want T2 Renames operation")
+	}
+	if len(smb1.failed) != 0 {
+		t.Errorf("want no failed operations for SMB1, got %v", smb1.failed)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noclocksource
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	clocksourceSubsystem = "clocksource"
+
+	// ptpMaxSamples is PTP_MAX_SAMPLES of linux/ptp_clock.h.
+	ptpMaxSamples = 25
+	ptpSamples    = 5
+)
+
+// ptpSysOffsetRequest is PTP_SYS_OFFSET, _IOW('=', 5, struct ptp_sys_offset).
+var ptpSysOffsetRequest = ioctlWriteRequest('=', 5, unsafe.Sizeof(ptpSysOffset{}))
+
+// ioctlWriteRequest encodes an ioctl request number like the _IOW macro of
+// asm/ioctl.h of the architecture.
+func ioctlWriteRequest(typ, nr, size uintptr) uintptr {
+	switch runtime.GOARCH {
+	case "mips", "mipsle", "mips64", "mips64le", "ppc64", "ppc64le":
+		// _IOC_WRITE is 4 and the size has 13 bits.
+		return 4<<29 | size<<16 | typ<<8 | nr
+	}
+	return 1<<30 | size<<16 | typ<<8 | nr
+}
+
+// ioctlReadRequest encodes an ioctl request number like the _IOR macro of
+// asm/ioctl.h of the architecture.
+func ioctlReadRequest(typ, nr, size uintptr) uintptr {
+	switch runtime.GOARCH {
+	case "mips", "mipsle", "mips64", "mips64le", "ppc64", "ppc64le":
+		// _IOC_READ is 2 and the size has 13 bits.
+		return 2<<29 | size<<16 | typ<<8 | nr
+	}
+	return 2<<30 | size<<16 | typ<<8 | nr
+}
+
+// ptpClockTime is struct ptp_clock_time of linux/ptp_clock.h.
+type ptpClockTime struct {
+	sec      int64
+	nsec     uint32
+	reserved uint32
+}
+
+// ptpSysOffset is struct ptp_sys_offset of linux/ptp_clock.h. The timestamps
+// alternate between the system clock and the PTP clock, starting and ending
+// with the system clock.
+type ptpSysOffset struct {
+	nSamples uint32
+	rsv      [3]uint32
+	ts       [2*ptpMaxSamples + 1]ptpClockTime
+}
+
+type clocksourceCollector struct {
+	current   *prometheus.Desc
+	available *prometheus.Desc
+	ptpInfo   *prometheus.Desc
+	ptpOffset *prometheus.Desc
+}
+
+func init() {
+	registerCollector(clocksourceSubsystem, defaultDisabled, NewClocksourceCollector)
+}
+
+// NewClocksourceCollector returns a new Collector exposing the kernel clock
+// sources and the offsets of PTP hardware clocks.
+func NewClocksourceCollector() (Collector, error) {
+	return &clocksourceCollector{
+		current: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, clocksourceSubsystem, "current_info"),
+			"Clock source currently used by the kernel, value is always 1.",
+			[]string{"device", "clocksource"}, nil,
+		),
+		available: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, clocksourceSubsystem, "available_info"),
+			"Clock sources usable by the kernel, value is always 1. The TSC is removed once the kernel marks it as unstable.",
+			[]string{"device", "clocksource"}, nil,
+		),
+		ptpInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, clocksourceSubsystem, "ptp_info"),
+			"Name of the PTP hardware clock, value is always 1.",
+			[]string{"ptp", "clock_name"}, nil,
+		),
+		ptpOffset: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, clocksourceSubsystem, "ptp_offset_seconds"),
+			"Offset of the PTP hardware clock to the system clock.",
+			[]string{"ptp"}, nil,
+		),
+	}, nil
+}
+
+func (c *clocksourceCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("devices/system/clocksource/clocksource[0-9]*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range devices {
+		device := filepath.Base(dir)
+
+		current, err := ioutil.ReadFile(filepath.Join(dir, "current_clocksource"))
+		if err != nil {
+			return fmt.Errorf("couldn't get current clocksource of %s: %s", device, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.current, prometheus.GaugeValue, 1, device, strings.TrimSpace(string(current)))
+
+		available, err := ioutil.ReadFile(filepath.Join(dir, "available_clocksource"))
+		if err != nil {
+			return fmt.Errorf("couldn't get available clocksources of %s: %s", device, err)
+		}
+		for _, clocksource := range strings.Fields(string(available)) {
+			ch <- prometheus.MustNewConstMetric(c.available, prometheus.GaugeValue, 1, device, clocksource)
+		}
+	}
+
+	return c.updatePTP(ch)
+}
+
+func (c *clocksourceCollector) updatePTP(ch chan<- prometheus.Metric) error {
+	clocks, err := filepath.Glob(sysFilePath("class/ptp/ptp[0-9]*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range clocks {
+		ptp := filepath.Base(dir)
+
+		name, err := ioutil.ReadFile(filepath.Join(dir, "clock_name"))
+		if err != nil {
+			return fmt.Errorf("couldn't get clock_name of %s: %s", ptp, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.ptpInfo, prometheus.GaugeValue, 1, ptp, strings.TrimSpace(string(name)))
+
+		offset, err := readPTPOffset(filepath.Join("/dev", ptp))
+		if err != nil {
+			log.Debugf("Couldn't get offset of PTP clock %s: %s", ptp, err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.ptpOffset, prometheus.GaugeValue, offset, ptp)
+	}
+
+	return nil
+}
+
+// readPTPOffset returns the offset of the PTP clock to the system clock in
+// seconds, taken from the sample with the shortest system clock delay.
+func readPTPOffset(device string) (float64, error) {
+	f, err := os.Open(device)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	req := ptpSysOffset{nSamples: ptpSamples}
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), ptpSysOffsetRequest, uintptr(unsafe.Pointer(&req))); errno != 0 {
+		return 0, errno
+	}
+
+	return ptpOffset(&req), nil
+}
+
+// ptpOffset returns the offset in seconds of the sample with the shortest
+// system clock delay. The nanosecond timestamps don't fit in a float64, so
+// only their differences are converted.
+func ptpOffset(req *ptpSysOffset) float64 {
+	diff := func(a, b ptpClockTime) int64 {
+		return (a.sec-b.sec)*1e9 + int64(a.nsec) - int64(b.nsec)
+	}
+	var (
+		offset   int64
+		minDelay int64 = math.MaxInt64
+	)
+	for i := 0; i < ptpSamples; i++ {
+		before, phc, after := req.ts[2*i], req.ts[2*i+1], req.ts[2*i+2]
+		if delay := diff(after, before); delay < minDelay {
+			minDelay = delay
+			offset = diff(phc, before) - delay/2
+		}
+	}
+	return float64(offset) / 1e9
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"runtime"
+	"testing"
+	"unsafe"
+)
+
+func TestPTPSysOffsetRequest(t *testing.T) {
+	// PTP_SYS_OFFSET as defined by the kernel headers.
+	want := uintptr(0x43403d05)
+	switch runtime.GOARCH {
+	case "mips", "mipsle", "mips64", "mips64le", "ppc64", "ppc64le":
+		want = 0x83403d05
+	}
+	if got := ioctlWriteRequest('=', 5, unsafe.Sizeof(ptpSysOffset{})); got != want {
+		t.Errorf("want request %#x, got %#x", want, got)
+	}
+}
+
+func TestMTIOCGETRequest(t *testing.T) {
+	// MTIOCGET as defined by the kernel headers, with the size of struct
+	// mtget of the architecture.
+	want := uintptr(0x80006d02)
+	switch runtime.GOARCH {
+	case "mips", "mipsle", "mips64", "mips64le", "ppc64", "ppc64le":
+		want = 0x40006d02
+	}
+	want |= unsafe.Sizeof(mtget{}) << 16
+	if got := ioctlReadRequest('m', 2, unsafe.Sizeof(mtget{})); got != want {
+		t.Errorf("want request %#x, got %#x", want, got)
+	}
+}
+
+func TestPTPOffset(t *testing.T) {
+	clockTime := func(ns int64) ptpClockTime {
+		return ptpClockTime{sec: ns / 1e9, nsec: uint32(ns % 1e9)}
+	}
+	// Readings just below a full second of a recent time, which lose the
+	// nanoseconds as float64.
+	sys := int64(1565000000)*1e9 + 999990000
+	req := ptpSysOffset{nSamples: ptpSamples}
+	req.ts[0] = clockTime(sys)
+	for i := 0; i < ptpSamples; i++ {
+		// The system clock delay is shortest for the third sample, whose
+		// PTP clock is 1.5µs ahead, while the others are 1ms ahead.
+		delay, ahead := int64(10000), int64(1000000)
+		if i == 2 {
+			delay, ahead = 1000, 1500
+		}
+		req.ts[2*i+1] = clockTime(sys + delay/2 + ahead)
+		sys += delay
+		req.ts[2*i+2] = clockTime(sys)
+	}
+
+	if want, got := 1.5e-6, ptpOffset(&req); want != got {
+		t.Errorf("want offset %g, got %g", want, got)
+	}
+}
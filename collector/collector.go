@@ -72,7 +72,8 @@ func registerCollector(collector string, isDefaultEnabled bool, factory func() (
 
 // NodeCollector implements the prometheus.Collector interface.
 type NodeCollector struct {
-	Collectors map[string]Collector
+	Collectors       map[string]Collector
+	capabilityChecks []capabilityCheck
 }
 
 // NewNodeCollector creates a new NodeCollector.
@@ -88,9 +89,17 @@ func NewNodeCollector(filters ...string) (*NodeCollector, error) {
 		}
 		f[filter] = true
 	}
+	audit := auditCapabilities()
 	collectors := make(map[string]Collector)
+	var capabilityChecks []capabilityCheck
 	for key, enabled := range collectorState {
 		if *enabled {
+			if len(f) == 0 || f[key] {
+				capabilityChecks = append(capabilityChecks, audit[key]...)
+			}
+			if *disableMissingCapabilities && missingCapabilities(audit[key]) {
+				continue
+			}
 			collector, err := factories[key]()
 			if err != nil {
 				return nil, err
@@ -100,13 +109,14 @@ func NewNodeCollector(filters ...string) (*NodeCollector, error) {
 			}
 		}
 	}
-	return &NodeCollector{Collectors: collectors}, nil
+	return &NodeCollector{Collectors: collectors, capabilityChecks: capabilityChecks}, nil
 }
 
 // Describe implements the prometheus.Collector interface.
 func (n NodeCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- scrapeDurationDesc
 	ch <- scrapeSuccessDesc
+	ch <- capabilityMissingDesc
 }
 
 // Collect implements the prometheus.Collector interface.
@@ -120,6 +130,14 @@ func (n NodeCollector) Collect(ch chan<- prometheus.Metric) {
 		}(name, c)
 	}
 	wg.Wait()
+
+	for _, c := range n.capabilityChecks {
+		missing := 0.0
+		if c.missing {
+			missing = 1
+		}
+		ch <- prometheus.MustNewConstMetric(capabilityMissingDesc, prometheus.GaugeValue, missing, c.collector, c.capability)
+	}
 }
 
 func execute(name string, c Collector, ch chan<- prometheus.Metric) {
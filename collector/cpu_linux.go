@@ -16,9 +16,14 @@
 package collector
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
@@ -30,13 +35,21 @@ type cpuCollector struct {
 	fs                 procfs.FS
 	cpu                *prometheus.Desc
 	cpuInfo            *prometheus.Desc
+	cpuFlagInfo        *prometheus.Desc
+	cpuFlagsPattern    *regexp.Regexp
 	cpuGuest           *prometheus.Desc
+	cpuAll             *prometheus.Desc
+	cpuAllGuest        *prometheus.Desc
+	cpuOnline          *prometheus.Desc
+	cpuPossible        *prometheus.Desc
 	cpuCoreThrottle    *prometheus.Desc
 	cpuPackageThrottle *prometheus.Desc
 }
 
 var (
-	enableCPUInfo = kingpin.Flag("collector.cpu.info", "Enables metric cpu_info").Bool()
+	enableCPUInfo   = kingpin.Flag("collector.cpu.info", "Enables metric cpu_info").Bool()
+	cpuFlagsInclude = kingpin.Flag("collector.cpu.flags-include", "Regexp of CPU feature flags to expose as cpu_flag_info, e.g. to gate rollouts on the instruction set.").Default("aes|avx|avx2|avx512.*|sha_ni|sve|sve2|sha2").String()
+	enablePerCPU    = kingpin.Flag("collector.cpu.per-cpu", "Expose the time spent in each mode per CPU, otherwise only summed over all CPUs as cpu_all_cpus_seconds_total.").Default("true").Bool()
 )
 
 func init() {
@@ -49,6 +62,10 @@ func NewCPUCollector() (Collector, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to open procfs: %v", err)
 	}
+	flagsPattern, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", *cpuFlagsInclude))
+	if err != nil {
+		return nil, fmt.Errorf("invalid flags-include pattern: %s", err)
+	}
 	return &cpuCollector{
 		fs:  fs,
 		cpu: nodeCPUSecondsDesc,
@@ -57,11 +74,37 @@ func NewCPUCollector() (Collector, error) {
 			"CPU information from /proc/cpuinfo.",
 			[]string{"package", "core", "cpu", "vendor", "family", "model", "microcode", "cachesize"}, nil,
 		),
+		cpuFlagInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "flag_info"),
+			"CPU feature flag from /proc/cpuinfo supported by the first cpu, value is always 1.",
+			[]string{"flag"}, nil,
+		),
+		cpuFlagsPattern: flagsPattern,
 		cpuGuest: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "guest_seconds_total"),
 			"Seconds the cpus spent in guests (VMs) for each mode.",
 			[]string{"cpu", "mode"}, nil,
 		),
+		cpuAll: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "all_cpus_seconds_total"),
+			"Seconds all cpus spent in each mode, exposed instead of seconds_total with --no-collector.cpu.per-cpu.",
+			[]string{"mode"}, nil,
+		),
+		cpuAllGuest: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "all_cpus_guest_seconds_total"),
+			"Seconds all cpus spent in guests (VMs) for each mode, exposed instead of guest_seconds_total with --no-collector.cpu.per-cpu.",
+			[]string{"mode"}, nil,
+		),
+		cpuOnline: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "online_cpus"),
+			"Number of cpus which are online.",
+			nil, nil,
+		),
+		cpuPossible: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "possible_cpus"),
+			"Number of cpus which could be brought online, including hotpluggable ones.",
+			nil, nil,
+		),
 		cpuCoreThrottle: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "core_throttles_total"),
 			"Number of times this cpu core has been throttled.",
@@ -82,12 +125,36 @@ func (c *cpuCollector) Update(ch chan<- prometheus.Metric) error {
 			return err
 		}
 	}
+	if err := c.updateFlags(ch); err != nil {
+		return err
+	}
 	if err := c.updateStat(ch); err != nil {
 		return err
 	}
 	if err := c.updateThermalThrottle(ch); err != nil {
 		return err
 	}
+	return c.updateCounts(ch)
+}
+
+// updateCounts exposes the number of online and possible cpus from the CPU
+// lists in /sys/devices/system/cpu.
+func (c *cpuCollector) updateCounts(ch chan<- prometheus.Metric) error {
+	for file, desc := range map[string]*prometheus.Desc{
+		"online":   c.cpuOnline,
+		"possible": c.cpuPossible,
+	} {
+		list, err := readTrimmedFile(sysFilePath(filepath.Join("devices/system/cpu", file)))
+		if err != nil {
+			log.Debugf("Couldn't get %s cpus: %s", file, err)
+			continue
+		}
+		cpus, err := parseCPUList(list)
+		if err != nil {
+			return fmt.Errorf("invalid list of %s cpus %q: %s", file, list, err)
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(len(cpus)))
+	}
 	return nil
 }
 
@@ -113,6 +180,44 @@ func (c *cpuCollector) updateInfo(ch chan<- prometheus.Metric) error {
 	return nil
 }
 
+// updateFlags exposes the feature flags of the first cpu matching
+// --collector.cpu.flags-include.
+func (c *cpuCollector) updateFlags(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("cpuinfo"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	flags, err := parseCPUFlags(file)
+	if err != nil {
+		return fmt.Errorf("couldn't get cpu flags: %s", err)
+	}
+	for _, flag := range flags {
+		if c.cpuFlagsPattern.MatchString(flag) {
+			ch <- prometheus.MustNewConstMetric(c.cpuFlagInfo, prometheus.GaugeValue, 1, flag)
+		}
+	}
+	return nil
+}
+
+// parseCPUFlags returns the feature flags of the first cpu in /proc/cpuinfo,
+// called flags on x86 and Features on ARM.
+func parseCPUFlags(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch strings.TrimSpace(parts[0]) {
+		case "flags", "Features":
+			return strings.Fields(parts[1]), nil
+		}
+	}
+	return nil, scanner.Err()
+}
+
 // updateThermalThrottle reads /sys/devices/system/cpu/cpu* and expose thermal throttle statistics.
 func (c *cpuCollector) updateThermalThrottle(ch chan<- prometheus.Metric) error {
 	cpus, err := filepath.Glob(sysFilePath("devices/system/cpu/cpu[0-9]*"))
@@ -198,6 +303,22 @@ func (c *cpuCollector) updateStat(ch chan<- prometheus.Metric) error {
 		return err
 	}
 
+	if !*enablePerCPU {
+		cpuStat := stats.CPUTotal
+		ch <- prometheus.MustNewConstMetric(c.cpuAll, prometheus.CounterValue, cpuStat.User, "user")
+		ch <- prometheus.MustNewConstMetric(c.cpuAll, prometheus.CounterValue, cpuStat.Nice, "nice")
+		ch <- prometheus.MustNewConstMetric(c.cpuAll, prometheus.CounterValue, cpuStat.System, "system")
+		ch <- prometheus.MustNewConstMetric(c.cpuAll, prometheus.CounterValue, cpuStat.Idle, "idle")
+		ch <- prometheus.MustNewConstMetric(c.cpuAll, prometheus.CounterValue, cpuStat.Iowait, "iowait")
+		ch <- prometheus.MustNewConstMetric(c.cpuAll, prometheus.CounterValue, cpuStat.IRQ, "irq")
+		ch <- prometheus.MustNewConstMetric(c.cpuAll, prometheus.CounterValue, cpuStat.SoftIRQ, "softirq")
+		ch <- prometheus.MustNewConstMetric(c.cpuAll, prometheus.CounterValue, cpuStat.Steal, "steal")
+
+		ch <- prometheus.MustNewConstMetric(c.cpuAllGuest, prometheus.CounterValue, cpuStat.Guest, "user")
+		ch <- prometheus.MustNewConstMetric(c.cpuAllGuest, prometheus.CounterValue, cpuStat.GuestNice, "nice")
+		return nil
+	}
+
 	for cpuID, cpuStat := range stats.CPU {
 		cpuNum := fmt.Sprintf("%d", cpuID)
 		ch <- prometheus.MustNewConstMetric(c.cpu, prometheus.CounterValue, cpuStat.User, cpuNum, "user")
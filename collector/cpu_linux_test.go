@@ -0,0 +1,53 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nocpu
+
+package collector
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCPUFlags(t *testing.T) {
+	file, err := os.Open("fixtures/proc/cpuinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	flags, err := parseCPUFlags(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "fpu", flags[0]; want != got {
+		t.Errorf("want first flag %s, got %s", want, got)
+	}
+
+	arm := `processor	: 0
+BogoMIPS	: 50.00
+Features	: fp asimd evtstrm aes pmull sha1 sha2 crc32 atomics sve
+CPU implementer	: 0x41
+`
+	flags, err = parseCPUFlags(strings.NewReader(arm))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"fp", "asimd", "evtstrm", "aes", "pmull", "sha1", "sha2", "crc32", "atomics", "sve"}
+	if !reflect.DeepEqual(want, flags) {
+		t.Errorf("want flags %v, got %v", want, flags)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nocputopology
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const cputopologySubsystem = "cputopology"
+
+var cputopologySMTControls = []string{"on", "off", "forceoff", "notsupported", "notimplemented"}
+
+type cputopologyCollector struct {
+	info       *prometheus.Desc
+	online     *prometheus.Desc
+	isolated   *prometheus.Desc
+	nohzFull   *prometheus.Desc
+	smtActive  *prometheus.Desc
+	smtControl *prometheus.Desc
+}
+
+func init() {
+	registerCollector(cputopologySubsystem, defaultDisabled, NewCPUTopologyCollector)
+}
+
+// NewCPUTopologyCollector returns a new Collector exposing the CPU topology
+// and isolation configuration.
+func NewCPUTopologyCollector() (Collector, error) {
+	return &cputopologyCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cputopologySubsystem, "cpu_info"),
+			"Topology of the CPU, value is always 1.",
+			[]string{"cpu", "package", "die", "core", "thread_siblings"}, nil,
+		),
+		online: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cputopologySubsystem, "cpu_online"),
+			"Whether the CPU is online, 1 if it is.",
+			[]string{"cpu"}, nil,
+		),
+		isolated: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cputopologySubsystem, "cpu_isolated"),
+			"Whether the CPU is isolated from the scheduler via isolcpus, 1 if it is.",
+			[]string{"cpu"}, nil,
+		),
+		nohzFull: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cputopologySubsystem, "cpu_nohz_full"),
+			"Whether the CPU runs in adaptive-tick (nohz_full) mode, 1 if it does.",
+			[]string{"cpu"}, nil,
+		),
+		smtActive: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cputopologySubsystem, "smt_active"),
+			"Whether simultaneous multithreading is active, 1 if it is.",
+			nil, nil,
+		),
+		smtControl: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cputopologySubsystem, "smt_control"),
+			"Simultaneous multithreading control state.",
+			[]string{"state"}, nil,
+		),
+	}, nil
+}
+
+func (c *cputopologyCollector) Update(ch chan<- prometheus.Metric) error {
+	isolated, err := readCPUListFile(sysFilePath("devices/system/cpu/isolated"))
+	if err != nil {
+		return err
+	}
+	nohzFull, err := readCPUListFile(sysFilePath("devices/system/cpu/nohz_full"))
+	if err != nil {
+		return err
+	}
+
+	cpus, err := filepath.Glob(sysFilePath("devices/system/cpu/cpu[0-9]*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range cpus {
+		cpu := strings.TrimPrefix(filepath.Base(dir), "cpu")
+		n, err := strconv.Atoi(cpu)
+		if err != nil {
+			continue
+		}
+
+		// CPUs which can't be taken offline, usually cpu0, have no online file.
+		online, err := readUintFromFile(filepath.Join(dir, "online"))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return fmt.Errorf("couldn't get online state of cpu %s: %s", cpu, err)
+			}
+			online = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.online, prometheus.GaugeValue, float64(online), cpu)
+
+		var isIsolated, isNohzFull float64
+		if isolated[n] {
+			isIsolated = 1
+		}
+		if nohzFull[n] {
+			isNohzFull = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.isolated, prometheus.GaugeValue, isIsolated, cpu)
+		ch <- prometheus.MustNewConstMetric(c.nohzFull, prometheus.GaugeValue, isNohzFull, cpu)
+
+		// Offline CPUs don't have a topology.
+		attrs := make(map[string]string)
+		for _, name := range []string{"physical_package_id", "die_id", "core_id", "thread_siblings_list"} {
+			value, err := ioutil.ReadFile(filepath.Join(dir, "topology", name))
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("couldn't get %s of cpu %s: %s", name, cpu, err)
+			}
+			attrs[name] = strings.TrimSpace(string(value))
+		}
+		if attrs["physical_package_id"] == "" {
+			log.Debugf("CPU %s has no topology", cpu)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1,
+			cpu, attrs["physical_package_id"], attrs["die_id"], attrs["core_id"], attrs["thread_siblings_list"])
+	}
+
+	return c.updateSMT(ch)
+}
+
+// updateSMT exposes the SMT state, available since Linux 4.19.
+func (c *cputopologyCollector) updateSMT(ch chan<- prometheus.Metric) error {
+	active, err := readUintFromFile(sysFilePath("devices/system/cpu/smt/active"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("Not collecting SMT state: %s", err)
+			return nil
+		}
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.smtActive, prometheus.GaugeValue, float64(active))
+
+	control, err := ioutil.ReadFile(sysFilePath("devices/system/cpu/smt/control"))
+	if err != nil {
+		return err
+	}
+	current := strings.TrimSpace(string(control))
+	for _, s := range cputopologySMTControls {
+		v := 0.0
+		if s == current {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.smtControl, prometheus.GaugeValue, v, s)
+	}
+	return nil
+}
+
+// readCPUListFile reads a CPU list file, returning an empty set if it
+// doesn't exist.
+func readCPUListFile(path string) (map[int]bool, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[int]bool{}, nil
+		}
+		return nil, err
+	}
+	cpus, err := parseCPUList(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CPU list in %s: %s", path, err)
+	}
+	return cpus, nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCPUList(t *testing.T) {
+	for _, tt := range []struct {
+		list string
+		want map[int]bool
+	}{
+		{"", map[int]bool{}},
+		{"(null)\n", map[int]bool{}},
+		{"3\n", map[int]bool{3: true}},
+		{"0-2,8,10-11\n", map[int]bool{0: true, 1: true, 2: true, 8: true, 10: true, 11: true}},
+	} {
+		got, err := parseCPUList(tt.list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("want %v for %q, got %v", tt.want, tt.list, got)
+		}
+	}
+
+	if _, err := parseCPUList("0-a"); err == nil {
+		t.Error("expected error for invalid list")
+	}
+}
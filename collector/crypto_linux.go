@@ -0,0 +1,181 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nocrypto
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const cryptoSubsystem = "crypto"
+
+// cryptoTLSSessions maps the session counters of /proc/net/tls_stat to their
+// direction and mode.
+var cryptoTLSSessions = map[string][2]string{
+	"TlsCurrTxSw":     {"tx", "sw"},
+	"TlsCurrRxSw":     {"rx", "sw"},
+	"TlsCurrTxDevice": {"tx", "device"},
+	"TlsCurrRxDevice": {"rx", "device"},
+	"TlsTxSw":         {"tx", "sw"},
+	"TlsRxSw":         {"rx", "sw"},
+	"TlsTxDevice":     {"tx", "device"},
+	"TlsRxDevice":     {"rx", "device"},
+}
+
+type cryptoCollector struct {
+	tlsCurrentSessions *prometheus.Desc
+	tlsSessions        *prometheus.Desc
+	tlsDecryptErrors   *prometheus.Desc
+	tlsRxDeviceResyncs *prometheus.Desc
+	afAlgSockets       *prometheus.Desc
+}
+
+func init() {
+	registerCollector(cryptoSubsystem, defaultDisabled, NewCryptoCollector)
+}
+
+// NewCryptoCollector returns a new Collector exposing kernel TLS and AF_ALG
+// usage.
+func NewCryptoCollector() (Collector, error) {
+	return &cryptoCollector{
+		tlsCurrentSessions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cryptoSubsystem, "tls_current_sessions"),
+			"Number of kernel TLS sessions currently installed, by direction and whether encryption is done in software or offloaded to the device.",
+			[]string{"direction", "mode"}, nil,
+		),
+		tlsSessions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cryptoSubsystem, "tls_sessions_total"),
+			"Number of kernel TLS sessions installed, by direction and whether encryption is done in software or offloaded to the device.",
+			[]string{"direction", "mode"}, nil,
+		),
+		tlsDecryptErrors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cryptoSubsystem, "tls_decrypt_errors_total"),
+			"Number of kernel TLS records which failed to decrypt.",
+			nil, nil,
+		),
+		tlsRxDeviceResyncs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cryptoSubsystem, "tls_rx_device_resyncs_total"),
+			"Number of receive resync requests sent to devices offloading kernel TLS.",
+			nil, nil,
+		),
+		afAlgSockets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cryptoSubsystem, "af_alg_sockets"),
+			"Number of AF_ALG sockets in use to access the kernel crypto API from user space.",
+			nil, nil,
+		),
+	}, nil
+}
+
+func (c *cryptoCollector) Update(ch chan<- prometheus.Metric) error {
+	if err := c.updateTLS(ch); err != nil {
+		return err
+	}
+	return c.updateAFAlg(ch)
+}
+
+// updateTLS exposes /proc/net/tls_stat, available since Linux 5.3 if the tls
+// module is loaded.
+func (c *cryptoCollector) updateTLS(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("net/tls_stat"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("Not collecting kernel TLS statistics: %s", err)
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	stats, err := parseTLSStat(file)
+	if err != nil {
+		return fmt.Errorf("couldn't parse tls_stat: %s", err)
+	}
+
+	for name, v := range stats {
+		switch name {
+		case "TlsDecryptError":
+			ch <- prometheus.MustNewConstMetric(c.tlsDecryptErrors, prometheus.CounterValue, v)
+		case "TlsRxDeviceResync":
+			ch <- prometheus.MustNewConstMetric(c.tlsRxDeviceResyncs, prometheus.CounterValue, v)
+		default:
+			labels, ok := cryptoTLSSessions[name]
+			if !ok {
+				continue
+			}
+			if strings.HasPrefix(name, "TlsCurr") {
+				ch <- prometheus.MustNewConstMetric(c.tlsCurrentSessions, prometheus.GaugeValue, v, labels[0], labels[1])
+			} else {
+				ch <- prometheus.MustNewConstMetric(c.tlsSessions, prometheus.CounterValue, v, labels[0], labels[1])
+			}
+		}
+	}
+	return nil
+}
+
+// updateAFAlg exposes the number of AF_ALG sockets from /proc/net/protocols,
+// which only lists ALG if the af_alg module is loaded.
+func (c *cryptoCollector) updateAFAlg(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("net/protocols"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	sockets := 0.0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] != "ALG" {
+			continue
+		}
+		if sockets, err = strconv.ParseFloat(fields[2], 64); err != nil {
+			return fmt.Errorf("invalid ALG sockets %q in protocols: %s", fields[2], err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.afAlgSockets, prometheus.GaugeValue, sockets)
+
+	return nil
+}
+
+// parseTLSStat parses the "<name> <value>" lines of /proc/net/tls_stat.
+func parseTLSStat(r io.Reader) (map[string]float64, error) {
+	stats := make(map[string]float64)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid line %q", scanner.Text())
+		}
+		v, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		stats[fields[0]] = v
+	}
+
+	return stats, scanner.Err()
+}
@@ -0,0 +1,186 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nodirsize
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const dirSizeSubsystem = "dirsize"
+
+var (
+	dirSizePaths    = kingpin.Flag("collector.dirsize.path", "Directory to compute the disk usage of. Can be repeated.").Strings()
+	dirSizeInterval = kingpin.Flag("collector.dirsize.interval", "Interval between two scans of the directories.").Default("15m").Duration()
+
+	// Directories are scanned in the background independently of scrapes,
+	// so the scanner is started once even though collectors are created
+	// per request.
+	dirSizeScannerOnce sync.Once
+	dirSizeScannerInst *dirSizeScanner
+)
+
+// dirSize is the result of the last scan of a directory.
+type dirSize struct {
+	bytes     float64
+	files     float64
+	errors    float64
+	duration  time.Duration
+	timestamp time.Time
+}
+
+type dirSizeScanner struct {
+	mtx   sync.Mutex
+	sizes map[string]dirSize
+}
+
+type dirSizeCollector struct {
+	scanner      *dirSizeScanner
+	bytesDesc    *prometheus.Desc
+	filesDesc    *prometheus.Desc
+	errorsDesc   *prometheus.Desc
+	durationDesc *prometheus.Desc
+	lastScanDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector("dirsize", defaultDisabled, NewDirSizeCollector)
+}
+
+// NewDirSizeCollector returns a new Collector exposing the disk usage of the
+// configured directories as of their last scan.
+func NewDirSizeCollector() (Collector, error) {
+	// A ticker panics with a non-positive interval.
+	if *dirSizeInterval <= 0 {
+		return nil, fmt.Errorf("invalid --collector.dirsize.interval %s, must be positive", *dirSizeInterval)
+	}
+	dirSizeScannerOnce.Do(func() {
+		dirSizeScannerInst = &dirSizeScanner{sizes: map[string]dirSize{}}
+		go dirSizeScannerInst.run(*dirSizePaths)
+	})
+
+	labels := []string{"path"}
+	return &dirSizeCollector{
+		scanner: dirSizeScannerInst,
+		bytesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dirSizeSubsystem, "bytes"),
+			"Disk usage of the directory as of the last scan, counting hard linked files once.",
+			labels, nil,
+		),
+		filesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dirSizeSubsystem, "files"),
+			"Number of files and directories in the directory as of the last scan.",
+			labels, nil,
+		),
+		errorsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dirSizeSubsystem, "scan_errors"),
+			"Number of files and directories which couldn't be read during the last scan.",
+			labels, nil,
+		),
+		durationDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dirSizeSubsystem, "scan_duration_seconds"),
+			"Duration of the last scan of the directory.",
+			labels, nil,
+		),
+		lastScanDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dirSizeSubsystem, "last_scan_timestamp_seconds"),
+			"Unixtime the last scan of the directory finished.",
+			labels, nil,
+		),
+	}, nil
+}
+
+// run scans the directories one after another right away and then at every
+// interval.
+func (s *dirSizeScanner) run(paths []string) {
+	ticker := time.NewTicker(*dirSizeInterval)
+	defer ticker.Stop()
+	for {
+		for _, path := range paths {
+			size := scanDirSize(path)
+			s.mtx.Lock()
+			s.sizes[path] = size
+			s.mtx.Unlock()
+		}
+		<-ticker.C
+	}
+}
+
+// scanDirSize computes the disk usage of a directory like du, i.e. from the
+// allocated blocks rather than the apparent size of the files.
+func scanDirSize(path string) dirSize {
+	var (
+		begin = time.Now()
+		size  dirSize
+		// Hard linked files are only counted once.
+		inodes = map[[2]uint64]bool{}
+	)
+	filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			log.Debugf("Error scanning %q: %s", p, err)
+			size.errors++
+			return nil
+		}
+		size.files++
+		stat, ok := fi.Sys().(*syscall.Stat_t)
+		if !ok {
+			return nil
+		}
+		if stat.Nlink > 1 && !fi.IsDir() {
+			inode := [2]uint64{uint64(stat.Dev), stat.Ino}
+			if inodes[inode] {
+				return nil
+			}
+			inodes[inode] = true
+		}
+		size.bytes += float64(stat.Blocks) * 512
+		return nil
+	})
+	size.duration = time.Since(begin)
+	size.timestamp = time.Now()
+	return size
+}
+
+// Update implements the Collector interface.
+func (c *dirSizeCollector) Update(ch chan<- prometheus.Metric) error {
+	c.scanner.mtx.Lock()
+	defer c.scanner.mtx.Unlock()
+
+	paths := make([]string, 0, len(c.scanner.sizes))
+	for path := range c.scanner.sizes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		size := c.scanner.sizes[path]
+		ch <- prometheus.MustNewConstMetric(c.bytesDesc, prometheus.GaugeValue, size.bytes, path)
+		ch <- prometheus.MustNewConstMetric(c.filesDesc, prometheus.GaugeValue, size.files, path)
+		ch <- prometheus.MustNewConstMetric(c.errorsDesc, prometheus.GaugeValue, size.errors, path)
+		ch <- prometheus.MustNewConstMetric(c.durationDesc, prometheus.GaugeValue, size.duration.Seconds(), path)
+		ch <- prometheus.MustNewConstMetric(c.lastScanDesc, prometheus.GaugeValue, float64(size.timestamp.UnixNano())/1e9, path)
+	}
+	return nil
+}
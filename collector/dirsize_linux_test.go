@@ -0,0 +1,82 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNewDirSizeCollectorInterval(t *testing.T) {
+	orig := *dirSizeInterval
+	defer func() { *dirSizeInterval = orig }()
+
+	*dirSizeInterval = 0
+	if _, err := NewDirSizeCollector(); err == nil {
+		t.Error("want error for zero interval")
+	}
+}
+
+func TestScanDirSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dirsize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "a"), make([]byte, 10000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "sub", "b"), make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The hard link is counted as a file but its blocks only once.
+	if err := os.Link(filepath.Join(dir, "a"), filepath.Join(dir, "sub", "c")); err != nil {
+		t.Fatal(err)
+	}
+
+	var want float64
+	for _, p := range []string{"", "sub", "a", "sub/b"} {
+		var st syscall.Stat_t
+		if err := syscall.Stat(filepath.Join(dir, p), &st); err != nil {
+			t.Fatal(err)
+		}
+		want += float64(st.Blocks) * 512
+	}
+
+	size := scanDirSize(dir)
+	if size.bytes != want {
+		t.Errorf("want %g bytes, got %g", want, size.bytes)
+	}
+	if size.files != 5 {
+		t.Errorf("want 5 files, got %g", size.files)
+	}
+	if size.errors != 0 {
+		t.Errorf("want no errors, got %g", size.errors)
+	}
+	if time.Since(size.timestamp) > time.Minute {
+		t.Errorf("want recent scan timestamp, got %s", size.timestamp)
+	}
+
+	if size := scanDirSize(filepath.Join(dir, "missing")); size.errors != 1 || size.files != 0 {
+		t.Errorf("want one error for a missing directory, got %+v", size)
+	}
+}
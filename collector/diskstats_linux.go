@@ -35,7 +35,9 @@ const (
 )
 
 var (
-	ignoredDevices = kingpin.Flag("collector.diskstats.ignored-devices", "Regexp of devices to ignore for diskstats.").Default("^(ram|loop|fd|(h|s|v|xv)d[a-z]|nvme\\d+n\\d+p)\\d+$").String()
+	ignoredDevices  = kingpin.Flag("collector.diskstats.ignored-devices", "Regexp of devices to ignore for diskstats.").Default("^(ram|loop|fd|(h|s|v|xv)d[a-z]|nvme\\d+n\\d+p)\\d+$").String()
+	acceptedDevices = kingpin.Flag("collector.diskstats.device-whitelist", "Regexp of devices to return for diskstats. Devices must both match whitelist and not match ignored-devices to be included.").Default(".+").String()
+	ignoredTypes    = kingpin.Flag("collector.diskstats.ignored-types", "Regexp of device types (disk, partition, loop, ram, dm, md) to ignore for diskstats.").Default("").String()
 )
 
 type typedFactorDesc struct {
@@ -52,8 +54,10 @@ func (d *typedFactorDesc) mustNewConstMetric(value float64, labels ...string) pr
 }
 
 type diskstatsCollector struct {
-	ignoredDevicesPattern *regexp.Regexp
-	descs                 []typedFactorDesc
+	ignoredDevicesPattern  *regexp.Regexp
+	acceptedDevicesPattern *regexp.Regexp
+	ignoredTypesPattern    *regexp.Regexp
+	descs                  []typedFactorDesc
 }
 
 func init() {
@@ -65,8 +69,23 @@ func init() {
 func NewDiskstatsCollector() (Collector, error) {
 	var diskLabelNames = []string{"device"}
 
+	ignoredDevicesPattern, err := regexp.Compile(*ignoredDevices)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ignored-devices pattern: %s", err)
+	}
+	acceptedDevicesPattern, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", *acceptedDevices))
+	if err != nil {
+		return nil, fmt.Errorf("invalid device-whitelist pattern: %s", err)
+	}
+	ignoredTypesPattern, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", *ignoredTypes))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ignored-types pattern: %s", err)
+	}
+
 	return &diskstatsCollector{
-		ignoredDevicesPattern: regexp.MustCompile(*ignoredDevices),
+		ignoredDevicesPattern:  ignoredDevicesPattern,
+		acceptedDevicesPattern: acceptedDevicesPattern,
+		ignoredTypesPattern:    ignoredTypesPattern,
 		descs: []typedFactorDesc{
 			{
 				desc: readsCompletedDesc, valueType: prometheus.CounterValue,
@@ -127,6 +146,8 @@ func NewDiskstatsCollector() (Collector, error) {
 				), valueType: prometheus.CounterValue,
 				factor: .001,
 			},
+			// The discard statistics are only exposed since Linux 4.18 and the
+			// flush statistics since Linux 5.5, they are skipped on older kernels.
 			{
 				desc: prometheus.NewDesc(
 					prometheus.BuildFQName(namespace, diskSubsystem, "discards_completed_total"),
@@ -160,6 +181,23 @@ func NewDiskstatsCollector() (Collector, error) {
 				), valueType: prometheus.CounterValue,
 				factor: .001,
 			},
+			{
+				desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, diskSubsystem, "flush_requests_total"),
+					"The total number of flush requests completed successfully.",
+					diskLabelNames,
+					nil,
+				), valueType: prometheus.CounterValue,
+			},
+			{
+				desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, diskSubsystem, "flush_requests_time_seconds_total"),
+					"This is the total number of seconds spent by all flush requests.",
+					diskLabelNames,
+					nil,
+				), valueType: prometheus.CounterValue,
+				factor: .001,
+			},
 		},
 	}, nil
 }
@@ -171,10 +209,14 @@ func (c *diskstatsCollector) Update(ch chan<- prometheus.Metric) error {
 	}
 
 	for dev, stats := range diskStats {
-		if c.ignoredDevicesPattern.MatchString(dev) {
+		if c.ignoredDevicesPattern.MatchString(dev) || !c.acceptedDevicesPattern.MatchString(dev) {
 			log.Debugf("Ignoring device: %s", dev)
 			continue
 		}
+		if devType := diskstatsDeviceType(dev, diskStats); c.ignoredTypesPattern.MatchString(devType) {
+			log.Debugf("Ignoring device %s of type %s", dev, devType)
+			continue
+		}
 
 		for i, value := range stats {
 			// ignore unrecognized additional stats
@@ -191,6 +233,27 @@ func (c *diskstatsCollector) Update(ch chan<- prometheus.Metric) error {
 	return nil
 }
 
+// diskstatsDeviceType returns the type of a device. Partitions are named
+// after their disk followed by their number, separated by a "p" if the disk
+// name ends with a digit, e.g. sda1 or nvme0n1p1.
+func diskstatsDeviceType(dev string, diskStats map[string][]string) string {
+	disk := strings.TrimRight(dev, "0123456789")
+	if disk != dev {
+		if _, ok := diskStats[disk]; ok {
+			return "partition"
+		}
+		if _, ok := diskStats[strings.TrimSuffix(disk, "p")]; ok && strings.HasSuffix(disk, "p") {
+			return "partition"
+		}
+	}
+	for _, t := range []string{"loop", "ram", "dm", "md"} {
+		if strings.HasPrefix(dev, t) {
+			return t
+		}
+	}
+	return "disk"
+}
+
 func getDiskStats() (map[string][]string, error) {
 	file, err := os.Open(procFilePath(diskstatsFilename))
 	if err != nil {
@@ -41,4 +41,37 @@ func TestDiskStats(t *testing.T) {
 	if want, got := "11130", diskStats["sdb"][14]; want != got {
 		t.Errorf("want diskstats sdb %s, got %s", want, got)
 	}
+
+	if want, got := "1944", diskStats["sdb"][16]; want != got {
+		t.Errorf("want diskstats sdb flush time %s, got %s", want, got)
+	}
+}
+
+func TestDiskstatsDeviceType(t *testing.T) {
+	file, err := os.Open("fixtures/proc/diskstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	diskStats, err := parseDiskStats(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for dev, want := range map[string]string{
+		"sda":       "disk",
+		"sda1":      "partition",
+		"nvme0n1":   "disk",
+		"nvme0n1p2": "partition",
+		"mmcblk0p1": "partition",
+		"loop3":     "loop",
+		"ram12":     "ram",
+		"dm-4":      "dm",
+		"sr0":       "disk",
+	} {
+		if got := diskstatsDeviceType(dev, diskStats); want != got {
+			t.Errorf("want type of %s %s, got %s", dev, want, got)
+		}
+	}
 }
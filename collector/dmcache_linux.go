@@ -0,0 +1,284 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nodmcache
+
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	dmcacheSubsystem = "dmcache"
+
+	// Layout of struct dm_ioctl from linux/dm-ioctl.h.
+	dmIoctlSize      = 312
+	dmIoctlNameLen   = 128
+	dmIoctlNameStart = 48
+	dmBufferFullFlag = 1 << 8
+
+	// _IOWR(0xfd, cmd, struct dm_ioctl)
+	dmListDevices = 0xc0000000 | dmIoctlSize<<16 | 0xfd<<8 | 3
+	dmTableStatus = 0xc0000000 | dmIoctlSize<<16 | 0xfd<<8 | 12
+)
+
+// nativeEndian is the byte order used by the kernel for ioctl structs.
+var nativeEndian = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+type dmcacheCollector struct {
+	metadataUsed  *prometheus.Desc
+	metadataSize  *prometheus.Desc
+	cacheUsed     *prometheus.Desc
+	cacheSize     *prometheus.Desc
+	dirty         *prometheus.Desc
+	readHits      *prometheus.Desc
+	readMisses    *prometheus.Desc
+	writeHits     *prometheus.Desc
+	writeMisses   *prometheus.Desc
+	demotions     *prometheus.Desc
+	promotions    *prometheus.Desc
+	controlDevice string
+}
+
+// dmcacheStatus is the parsed status line of a dm-cache target, see
+// Documentation/device-mapper/cache.txt. Sizes are in bytes.
+type dmcacheStatus struct {
+	metadataUsed, metadataSize uint64
+	cacheUsed, cacheSize       uint64
+	dirty                      uint64
+	readHits, readMisses       uint64
+	writeHits, writeMisses     uint64
+	demotions, promotions      uint64
+}
+
+func init() {
+	registerCollector(dmcacheSubsystem, defaultDisabled, NewDmcacheCollector)
+}
+
+// NewDmcacheCollector returns a new Collector exposing dm-cache statistics.
+func NewDmcacheCollector() (Collector, error) {
+	labels := []string{"name"}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dmcacheSubsystem, name),
+			help, labels, nil,
+		)
+	}
+	return &dmcacheCollector{
+		metadataUsed:  desc("metadata_used_bytes", "Amount of metadata space in use."),
+		metadataSize:  desc("metadata_size_bytes", "Size of the metadata device."),
+		cacheUsed:     desc("cache_used_bytes", "Amount of cache space in use."),
+		cacheSize:     desc("cache_size_bytes", "Size of the cache device."),
+		dirty:         desc("dirty_bytes", "Amount of data in the cache which has not been written back to the origin device."),
+		readHits:      desc("read_hits_total", "Number of times a READ bio has been mapped to the cache."),
+		readMisses:    desc("read_misses_total", "Number of times a READ bio has been mapped to the origin."),
+		writeHits:     desc("write_hits_total", "Number of times a WRITE bio has been mapped to the cache."),
+		writeMisses:   desc("write_misses_total", "Number of times a WRITE bio has been mapped to the origin."),
+		demotions:     desc("demotions_total", "Number of times a block has been removed from the cache."),
+		promotions:    desc("promotions_total", "Number of times a block has been moved to the cache."),
+		controlDevice: "/dev/mapper/control",
+	}, nil
+}
+
+func (c *dmcacheCollector) Update(ch chan<- prometheus.Metric) error {
+	ctl, err := os.Open(c.controlDevice)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("Not collecting dm-cache statistics, %s does not exist", c.controlDevice)
+			return nil
+		}
+		return fmt.Errorf("couldn't open device-mapper control device: %s", err)
+	}
+	defer ctl.Close()
+
+	names, err := dmListDeviceNames(ctl)
+	if err != nil {
+		return fmt.Errorf("couldn't list device-mapper devices: %s", err)
+	}
+
+	for _, name := range names {
+		targets, err := dmTargetStatus(ctl, name)
+		if err != nil {
+			return fmt.Errorf("couldn't get status of device-mapper device %s: %s", name, err)
+		}
+		for _, t := range targets {
+			if t.targetType != "cache" {
+				continue
+			}
+			s, err := parseDmcacheStatus(t.status)
+			if err != nil {
+				log.Debugf("Skipping dm-cache device %s: %s", name, err)
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(c.metadataUsed, prometheus.GaugeValue, float64(s.metadataUsed), name)
+			ch <- prometheus.MustNewConstMetric(c.metadataSize, prometheus.GaugeValue, float64(s.metadataSize), name)
+			ch <- prometheus.MustNewConstMetric(c.cacheUsed, prometheus.GaugeValue, float64(s.cacheUsed), name)
+			ch <- prometheus.MustNewConstMetric(c.cacheSize, prometheus.GaugeValue, float64(s.cacheSize), name)
+			ch <- prometheus.MustNewConstMetric(c.dirty, prometheus.GaugeValue, float64(s.dirty), name)
+			ch <- prometheus.MustNewConstMetric(c.readHits, prometheus.CounterValue, float64(s.readHits), name)
+			ch <- prometheus.MustNewConstMetric(c.readMisses, prometheus.CounterValue, float64(s.readMisses), name)
+			ch <- prometheus.MustNewConstMetric(c.writeHits, prometheus.CounterValue, float64(s.writeHits), name)
+			ch <- prometheus.MustNewConstMetric(c.writeMisses, prometheus.CounterValue, float64(s.writeMisses), name)
+			ch <- prometheus.MustNewConstMetric(c.demotions, prometheus.CounterValue, float64(s.demotions), name)
+			ch <- prometheus.MustNewConstMetric(c.promotions, prometheus.CounterValue, float64(s.promotions), name)
+		}
+	}
+
+	return nil
+}
+
+type dmTarget struct {
+	targetType string
+	status     string
+}
+
+// dmIoctl issues a device-mapper ioctl for the named device and returns the
+// data area of the reply, growing the buffer until the kernel reply fits.
+func dmIoctl(ctl *os.File, cmd uintptr, name string) ([]byte, uint32, error) {
+	for size := 16 * 1024; ; size *= 2 {
+		buf := make([]byte, size)
+		// Protocol version 4.0.0, data area directly after the header.
+		nativeEndian.PutUint32(buf[0:], 4)
+		nativeEndian.PutUint32(buf[12:], uint32(size))
+		nativeEndian.PutUint32(buf[16:], dmIoctlSize)
+		copy(buf[dmIoctlNameStart:dmIoctlNameStart+dmIoctlNameLen-1], name)
+
+		_, _, errno := unix.Syscall(unix.SYS_IOCTL, ctl.Fd(), cmd, uintptr(unsafe.Pointer(&buf[0])))
+		if errno != 0 {
+			return nil, 0, errno
+		}
+		if nativeEndian.Uint32(buf[28:])&dmBufferFullFlag != 0 {
+			continue
+		}
+		dataStart := nativeEndian.Uint32(buf[16:])
+		dataSize := nativeEndian.Uint32(buf[12:])
+		if dataSize > uint32(size) || dataStart > dataSize {
+			return nil, 0, fmt.Errorf("invalid reply size %d", dataSize)
+		}
+		return buf[dataStart:dataSize], nativeEndian.Uint32(buf[20:]), nil
+	}
+}
+
+// dmListDeviceNames returns the names of all device-mapper devices.
+func dmListDeviceNames(ctl *os.File) ([]string, error) {
+	data, _, err := dmIoctl(ctl, dmListDevices, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// The reply is a chain of struct dm_name_list { u64 dev; u32 next; char name[]; }.
+	var names []string
+	for off := 0; off+12 <= len(data); {
+		next := int(nativeEndian.Uint32(data[off+8:]))
+		name := data[off+12:]
+		if i := bytes.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+		if next == 0 {
+			break
+		}
+		off += next
+	}
+	return names, nil
+}
+
+// dmTargetStatus returns the status line of every target of a device.
+func dmTargetStatus(ctl *os.File, name string) ([]dmTarget, error) {
+	data, count, err := dmIoctl(ctl, dmTableStatus, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each target is a struct dm_target_spec { u64 sector_start; u64 length;
+	// s32 status; u32 next; char target_type[16]; } followed by the status.
+	var targets []dmTarget
+	for i, off := uint32(0), 0; i < count && off+40 <= len(data); i++ {
+		targetType := data[off+24 : off+40]
+		status := data[off+40:]
+		if j := bytes.IndexByte(targetType, 0); j >= 0 {
+			targetType = targetType[:j]
+		}
+		if j := bytes.IndexByte(status, 0); j >= 0 {
+			status = status[:j]
+		}
+		targets = append(targets, dmTarget{targetType: string(targetType), status: string(status)})
+		off = int(nativeEndian.Uint32(data[off+20:]))
+	}
+	return targets, nil
+}
+
+// parseDmcacheStatus parses the status line of a cache target:
+// <metadata block size> <#used metadata blocks>/<#total metadata blocks>
+// <cache block size> <#used cache blocks>/<#total cache blocks>
+// <#read hits> <#read misses> <#write hits> <#write misses>
+// <#demotions> <#promotions> <#dirty> ...
+func parseDmcacheStatus(status string) (dmcacheStatus, error) {
+	var s dmcacheStatus
+
+	fields := strings.Fields(status)
+	if len(fields) < 11 {
+		return s, fmt.Errorf("unexpected cache status %q", status)
+	}
+
+	values := make([]uint64, 0, 13)
+	for _, f := range fields[:11] {
+		for _, p := range strings.Split(f, "/") {
+			v, err := strconv.ParseUint(p, 10, 64)
+			if err != nil {
+				return s, fmt.Errorf("invalid value %q in cache status: %s", p, err)
+			}
+			values = append(values, v)
+		}
+	}
+	if len(values) != 13 {
+		return s, fmt.Errorf("unexpected cache status %q", status)
+	}
+
+	// Block sizes are given in 512 byte sectors.
+	metadataBlock := values[0] * 512
+	cacheBlock := values[3] * 512
+
+	s.metadataUsed = values[1] * metadataBlock
+	s.metadataSize = values[2] * metadataBlock
+	s.cacheUsed = values[4] * cacheBlock
+	s.cacheSize = values[5] * cacheBlock
+	s.readHits = values[6]
+	s.readMisses = values[7]
+	s.writeHits = values[8]
+	s.writeMisses = values[9]
+	s.demotions = values[10]
+	s.promotions = values[11]
+	s.dirty = values[12] * cacheBlock
+
+	return s, nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nodmcache
+
+package collector
+
+import "testing"
+
+func TestParseDmcacheStatus(t *testing.T) {
+	s, err := parseDmcacheStatus("8 1111/61440 128 3230/262144 23891 24562 2893 11209 0 3230 12 1 writeback 2 migration_threshold 2048 smq 0 rw -")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := dmcacheStatus{
+		metadataUsed: 1111 * 8 * 512,
+		metadataSize: 61440 * 8 * 512,
+		cacheUsed:    3230 * 128 * 512,
+		cacheSize:    262144 * 128 * 512,
+		dirty:        12 * 128 * 512,
+		readHits:     23891,
+		readMisses:   24562,
+		writeHits:    2893,
+		writeMisses:  11209,
+		demotions:    0,
+		promotions:   3230,
+	}
+	if s != want {
+		t.Errorf("want %+v, got %+v", want, s)
+	}
+
+	if _, err := parseDmcacheStatus("Fail"); err == nil {
+		t.Error("expected error for failed cache status")
+	}
+}
@@ -71,9 +71,14 @@ func newDRBDStringPairMetric(name, desc, valueOK string) drbdStringPairMetric {
 }
 
 type drbdCollector struct {
-	numerical  map[string]drbdNumericalMetric
-	stringPair map[string]drbdStringPairMetric
-	connected  *prometheus.Desc
+	numerical       map[string]drbdNumericalMetric
+	stringPair      map[string]drbdStringPairMetric
+	connected       *prometheus.Desc
+	connectionState *prometheus.Desc
+	diskState       *prometheus.Desc
+	resyncRatio     *prometheus.Desc
+	resyncRemaining *prometheus.Desc
+	resyncSpeed     *prometheus.Desc
 }
 
 func init() {
@@ -176,6 +181,36 @@ func newDRBDCollector() (Collector, error) {
 			[]string{"device"},
 			nil,
 		),
+		connectionState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "drbd", "connection_state"),
+			"Connection state of the device, e.g. StandAlone after a split brain. Value is always 1.",
+			[]string{"device", "state"},
+			nil,
+		),
+		diskState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "drbd", "disk_state"),
+			"Disk state of the device on the node. Value is always 1.",
+			[]string{"device", "node", "state"},
+			nil,
+		),
+		resyncRatio: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "drbd", "resync_ratio"),
+			"Progress of the running resynchronization or online verification.",
+			[]string{"device"},
+			nil,
+		),
+		resyncRemaining: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "drbd", "resync_remaining_seconds"),
+			"Estimated time until the running resynchronization or online verification finishes.",
+			[]string{"device"},
+			nil,
+		),
+		resyncSpeed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "drbd", "resync_speed_bytes"),
+			"Current speed of the running resynchronization or online verification in bytes per second.",
+			[]string{"device"},
+			nil,
+		),
 	}, nil
 }
 
@@ -195,10 +230,26 @@ func (c *drbdCollector) Update(ch chan<- prometheus.Metric) error {
 	scanner := bufio.NewScanner(file)
 	scanner.Split(bufio.ScanWords)
 	device := "unknown"
+	// Resync progress is reported as "<key>: <value>" on separate lines
+	// below the device, e.g. "sync'ed: 10.5%" or "finish: 0:02:30".
+	pending := ""
 
 	for scanner.Scan() {
 		field := scanner.Text()
 
+		if pending != "" {
+			if err := c.updateResync(ch, device, pending, field); err != nil {
+				return err
+			}
+			pending = ""
+			continue
+		}
+		switch field {
+		case "sync'ed:", "verified:", "finish:", "speed:":
+			pending = field
+			continue
+		}
+
 		kv := strings.Split(field, ":")
 		if len(kv) != 2 {
 			log.Debugf("drbd: skipping invalid key:value pair %q", field)
@@ -247,6 +298,11 @@ func (c *drbdCollector) Update(ch chan<- prometheus.Metric) error {
 				"remote",
 			)
 
+			if kv[0] == "ds" {
+				ch <- prometheus.MustNewConstMetric(c.diskState, prometheus.GaugeValue, 1, device, "local", values[0])
+				ch <- prometheus.MustNewConstMetric(c.diskState, prometheus.GaugeValue, 1, device, "remote", values[1])
+			}
+
 			continue
 		}
 
@@ -263,6 +319,7 @@ func (c *drbdCollector) Update(ch chan<- prometheus.Metric) error {
 				connected,
 				device,
 			)
+			ch <- prometheus.MustNewConstMetric(c.connectionState, prometheus.GaugeValue, 1, device, kv[1])
 
 			continue
 		}
@@ -272,3 +329,28 @@ func (c *drbdCollector) Update(ch chan<- prometheus.Metric) error {
 
 	return scanner.Err()
 }
+
+func (c *drbdCollector) updateResync(ch chan<- prometheus.Metric, device, key, value string) error {
+	switch key {
+	case "sync'ed:", "verified:":
+		v, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil {
+			return fmt.Errorf("invalid resync progress %q: %s", value, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.resyncRatio, prometheus.GaugeValue, v/100, device)
+	case "finish:":
+		var h, m, s uint64
+		if _, err := fmt.Sscanf(value, "%d:%d:%d", &h, &m, &s); err != nil {
+			return fmt.Errorf("invalid resync finish time %q: %s", value, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.resyncRemaining, prometheus.GaugeValue, float64(h*3600+m*60+s), device)
+	case "speed:":
+		// The speed is given in K/sec with thousands separators.
+		v, err := strconv.ParseFloat(strings.Replace(value, ",", "", -1), 64)
+		if err != nil {
+			return fmt.Errorf("invalid resync speed %q: %s", value, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.resyncSpeed, prometheus.GaugeValue, v*1024, device)
+	}
+	return nil
+}
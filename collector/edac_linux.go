@@ -17,8 +17,11 @@ package collector
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -30,6 +33,7 @@ const (
 var (
 	edacMemControllerRE = regexp.MustCompile(`.*devices/system/edac/mc/mc([0-9]*)`)
 	edacMemCsrowRE      = regexp.MustCompile(`.*devices/system/edac/mc/mc[0-9]*/csrow([0-9]*)`)
+	edacMemDimmRE       = regexp.MustCompile(`.*devices/system/edac/mc/mc[0-9]*/(dimm|rank)([0-9]*)`)
 )
 
 type edacCollector struct {
@@ -37,6 +41,9 @@ type edacCollector struct {
 	ueCount      *prometheus.Desc
 	csRowCECount *prometheus.Desc
 	csRowUECount *prometheus.Desc
+	dimmInfo     *prometheus.Desc
+	dimmCECount  *prometheus.Desc
+	dimmUECount  *prometheus.Desc
 }
 
 func init() {
@@ -66,6 +73,21 @@ func NewEdacCollector() (Collector, error) {
 			"Total uncorrectable memory errors for this csrow.",
 			[]string{"controller", "csrow"}, nil,
 		),
+		dimmInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, edacSubsystem, "dimm_info"),
+			"Label and location of the DIMM, value is always 1.",
+			[]string{"controller", "dimm", "label", "location"}, nil,
+		),
+		dimmCECount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, edacSubsystem, "dimm_correctable_errors_total"),
+			"Total correctable memory errors for this DIMM.",
+			[]string{"controller", "dimm"}, nil,
+		),
+		dimmUECount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, edacSubsystem, "dimm_uncorrectable_errors_total"),
+			"Total uncorrectable memory errors for this DIMM.",
+			[]string{"controller", "dimm"}, nil,
+		),
 	}, nil
 }
 
@@ -135,7 +157,58 @@ func (c *edacCollector) Update(ch chan<- prometheus.Metric) error {
 			ch <- prometheus.MustNewConstMetric(
 				c.csRowUECount, prometheus.CounterValue, float64(value), controllerNumber, csrowNumber)
 		}
+
+		if err := c.updateDimms(ch, controller, controllerNumber); err != nil {
+			return err
+		}
 	}
 
 	return err
 }
+
+// updateDimms exposes the per DIMM error counts. Depending on the driver the
+// memory is described as dimmX or rankX directories.
+func (c *edacCollector) updateDimms(ch chan<- prometheus.Metric, controller, controllerNumber string) error {
+	dimms, err := filepath.Glob(controller + "/dimm[0-9]*")
+	if err != nil {
+		return err
+	}
+	ranks, err := filepath.Glob(controller + "/rank[0-9]*")
+	if err != nil {
+		return err
+	}
+	for _, dimm := range append(dimms, ranks...) {
+		dimmMatch := edacMemDimmRE.FindStringSubmatch(dimm)
+		if dimmMatch == nil {
+			continue
+		}
+		dimmNumber := dimmMatch[2]
+
+		attrs := make(map[string]string)
+		for _, name := range []string{"dimm_label", "dimm_location"} {
+			value, err := ioutil.ReadFile(filepath.Join(dimm, name))
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("couldn't get %s for controller/dimm %s/%s: %s", name, controllerNumber, dimmNumber, err)
+			}
+			attrs[name] = strings.TrimSpace(string(value))
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.dimmInfo, prometheus.GaugeValue, 1, controllerNumber, dimmNumber, attrs["dimm_label"], attrs["dimm_location"])
+
+		value, err := readUintFromFile(filepath.Join(dimm, "dimm_ce_count"))
+		if err != nil {
+			return fmt.Errorf("couldn't get dimm_ce_count for controller/dimm %s/%s: %s", controllerNumber, dimmNumber, err)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.dimmCECount, prometheus.CounterValue, float64(value), controllerNumber, dimmNumber)
+
+		value, err = readUintFromFile(filepath.Join(dimm, "dimm_ue_count"))
+		if err != nil {
+			return fmt.Errorf("couldn't get dimm_ue_count for controller/dimm %s/%s: %s", controllerNumber, dimmNumber, err)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.dimmUECount, prometheus.CounterValue, float64(value), controllerNumber, dimmNumber)
+	}
+
+	return nil
+}
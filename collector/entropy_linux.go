@@ -16,13 +16,23 @@
 package collector
 
 import (
+	"bufio"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
 )
 
 type entropyCollector struct {
-	entropyAvail *prometheus.Desc
+	entropyAvail           *prometheus.Desc
+	poolSize               *prometheus.Desc
+	readWakeupThreshold    *prometheus.Desc
+	writeWakeupThreshold   *prometheus.Desc
+	hwrngActive            *prometheus.Desc
+	jitterentropyAvailable *prometheus.Desc
 }
 
 func init() {
@@ -37,6 +47,31 @@ func NewEntropyCollector() (Collector, error) {
 			"Bits of available entropy.",
 			nil, nil,
 		),
+		poolSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "entropy", "pool_size_bits"),
+			"Bits of entropy the input pool can hold.",
+			nil, nil,
+		),
+		readWakeupThreshold: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "entropy", "read_wakeup_threshold_bits"),
+			"Bits of entropy required to wake up processes blocked reading /dev/random.",
+			nil, nil,
+		),
+		writeWakeupThreshold: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "entropy", "write_wakeup_threshold_bits"),
+			"Bits of entropy below which processes waiting to write to /dev/random are woken up.",
+			nil, nil,
+		),
+		hwrngActive: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "entropy", "hwrng_active"),
+			"Whether the hardware random number generator is the one feeding the entropy pool, 1 if it is.",
+			[]string{"rng"}, nil,
+		),
+		jitterentropyAvailable: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "entropy", "jitterentropy_available"),
+			"Whether the CPU jitter random number generator is available, 1 if it is.",
+			nil, nil,
+		),
 	}, nil
 }
 
@@ -48,5 +83,82 @@ func (c *entropyCollector) Update(ch chan<- prometheus.Metric) error {
 	ch <- prometheus.MustNewConstMetric(
 		c.entropyAvail, prometheus.GaugeValue, float64(value))
 
+	value, err = readUintFromFile(procFilePath("sys/kernel/random/poolsize"))
+	if err != nil {
+		return fmt.Errorf("couldn't get poolsize: %s", err)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.poolSize, prometheus.GaugeValue, float64(value))
+
+	for desc, name := range map[*prometheus.Desc]string{
+		c.readWakeupThreshold:  "read_wakeup_threshold",
+		c.writeWakeupThreshold: "write_wakeup_threshold",
+	} {
+		value, err := readUintFromFile(procFilePath("sys/kernel/random/" + name))
+		if err != nil {
+			log.Debugf("couldn't get %s: %s", name, err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value))
+	}
+
+	if err := c.updateHWRNG(ch); err != nil {
+		return err
+	}
+	return c.updateJitterentropy(ch)
+}
+
+// updateHWRNG exposes the hardware random number generators, e.g. a TPM or
+// virtio-rng, of which the current one feeds the entropy pool.
+func (c *entropyCollector) updateHWRNG(ch chan<- prometheus.Metric) error {
+	available, err := ioutil.ReadFile(sysFilePath("class/misc/hw_random/rng_available"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("Not collecting hardware random number generators: %s", err)
+			return nil
+		}
+		return err
+	}
+	current, err := ioutil.ReadFile(sysFilePath("class/misc/hw_random/rng_current"))
+	if err != nil {
+		return fmt.Errorf("couldn't get rng_current: %s", err)
+	}
+
+	for _, rng := range strings.Fields(string(available)) {
+		v := 0.0
+		if rng == strings.TrimSpace(string(current)) {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.hwrngActive, prometheus.GaugeValue, v, rng)
+	}
+	return nil
+}
+
+// updateJitterentropy checks /proc/crypto for the jitterentropy_rng driver.
+func (c *entropyCollector) updateJitterentropy(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("crypto"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("Not collecting jitterentropy state: %s", err)
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	available := 0.0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == "driver" && strings.TrimSpace(parts[1]) == "jitterentropy_rng" {
+			available = 1
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.jitterentropyAvailable, prometheus.GaugeValue, available)
+
 	return nil
 }
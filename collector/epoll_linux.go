@@ -0,0 +1,196 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noepoll
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/procfs"
+)
+
+const epollSubsystem = "epoll"
+
+type epollCollector struct {
+	fs                 procfs.FS
+	maxUserWatchesDesc *prometheus.Desc
+	instancesDesc      *prometheus.Desc
+	watchesDesc        *prometheus.Desc
+	eventfdsDesc       *prometheus.Desc
+}
+
+// epollUsage is the usage of epoll and eventfd of a user.
+type epollUsage struct {
+	instances, watches, eventfds float64
+}
+
+func init() {
+	registerCollector(epollSubsystem, defaultDisabled, NewEpollCollector)
+}
+
+// NewEpollCollector returns a new Collector exposing the epoll watches of
+// each user and their limit, and the eventfds of each user.
+func NewEpollCollector() (Collector, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %v", err)
+	}
+	return &epollCollector{
+		fs: fs,
+		maxUserWatchesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, epollSubsystem, "max_user_watches"),
+			"Maximum number of file descriptors a user can watch with all of their epoll instances.",
+			nil, nil,
+		),
+		instancesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, epollSubsystem, "instances"),
+			"Number of epoll file descriptors of the processes of the user.",
+			[]string{"uid"}, nil,
+		),
+		watchesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, epollSubsystem, "watches"),
+			"Number of file descriptors watched by the epoll instances of the processes of the user.",
+			[]string{"uid"}, nil,
+		),
+		eventfdsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "eventfd_instances"),
+			"Number of eventfd file descriptors of the processes of the user.",
+			[]string{"uid"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *epollCollector) Update(ch chan<- prometheus.Metric) error {
+	maxWatches, err := readUintFromFile(procFilePath("sys/fs/epoll/max_user_watches"))
+	if err != nil {
+		return fmt.Errorf("couldn't get epoll max_user_watches: %s", err)
+	}
+	ch <- prometheus.MustNewConstMetric(c.maxUserWatchesDesc, prometheus.GaugeValue, float64(maxWatches))
+
+	procs, err := c.fs.AllProcs()
+	if err != nil {
+		return fmt.Errorf("couldn't get processes: %s", err)
+	}
+	usage := map[string]*epollUsage{}
+	for _, p := range procs {
+		// The process may have exited in the meantime.
+		if err := epollProcessUsage(p.PID, usage); err != nil {
+			log.Debugf("Couldn't get epoll usage of process %d: %s", p.PID, err)
+		}
+	}
+
+	for uid, u := range usage {
+		ch <- prometheus.MustNewConstMetric(c.instancesDesc, prometheus.GaugeValue, u.instances, uid)
+		ch <- prometheus.MustNewConstMetric(c.watchesDesc, prometheus.GaugeValue, u.watches, uid)
+		ch <- prometheus.MustNewConstMetric(c.eventfdsDesc, prometheus.GaugeValue, u.eventfds, uid)
+	}
+	return nil
+}
+
+// epollProcessUsage adds the epoll instances, their watches and the eventfds
+// of a process to the usage of its real user, which the kernel charges the
+// watches to. File descriptors shared by several processes are counted once
+// per process.
+func epollProcessUsage(pid int, usage map[string]*epollUsage) error {
+	dir := procFilePath(strconv.Itoa(pid))
+	fds, err := ioutil.ReadDir(filepath.Join(dir, "fd"))
+	if err != nil {
+		return err
+	}
+
+	var u epollUsage
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+		if err != nil {
+			continue
+		}
+		switch target {
+		case "anon_inode:[eventpoll]":
+			u.instances++
+			fdinfo, err := os.Open(filepath.Join(dir, "fdinfo", fd.Name()))
+			if err != nil {
+				continue
+			}
+			watches, err := parseEpollWatches(fdinfo)
+			fdinfo.Close()
+			if err != nil {
+				return err
+			}
+			u.watches += watches
+		case "anon_inode:[eventfd]":
+			u.eventfds++
+		}
+	}
+	if u == (epollUsage{}) {
+		return nil
+	}
+
+	status, err := os.Open(filepath.Join(dir, "status"))
+	if err != nil {
+		return err
+	}
+	defer status.Close()
+	uid, err := parseStatusUID(status)
+	if err != nil {
+		return err
+	}
+	if usage[uid] == nil {
+		usage[uid] = &epollUsage{}
+	}
+	usage[uid].instances += u.instances
+	usage[uid].watches += u.watches
+	usage[uid].eventfds += u.eventfds
+	return nil
+}
+
+// parseEpollWatches counts the watched file descriptors of an epoll instance
+// in its fdinfo file, which have a line starting with tfd: each.
+func parseEpollWatches(r io.Reader) (float64, error) {
+	var (
+		scanner = bufio.NewScanner(r)
+		watches float64
+	)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "tfd:") {
+			watches++
+		}
+	}
+	return watches, scanner.Err()
+}
+
+// parseStatusUID returns the real user ID from /proc/<pid>/status.
+func parseStatusUID(r io.Reader) (string, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "Uid:" {
+			return fields[1], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no Uid line in status")
+}
@@ -0,0 +1,123 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noext4
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const ext4Subsystem = "ext4"
+
+// ext4Attribute maps a file in /sys/fs/ext4/<device>/ to a metric.
+type ext4Attribute struct {
+	file       string
+	desc       *prometheus.Desc
+	valueType  prometheus.ValueType
+	multiplier float64
+	optional   bool
+}
+
+type ext4Collector struct {
+	attributes []ext4Attribute
+}
+
+func init() {
+	registerCollector(ext4Subsystem, defaultDisabled, NewExt4Collector)
+}
+
+// NewExt4Collector returns a new Collector exposing ext4 statistics.
+func NewExt4Collector() (Collector, error) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ext4Subsystem, name),
+			help, []string{"device"}, nil,
+		)
+	}
+	return &ext4Collector{
+		attributes: []ext4Attribute{
+			{
+				file:       "errors_count",
+				desc:       desc("errors_total", "Number of filesystem errors recorded in the superblock."),
+				valueType:  prometheus.CounterValue,
+				multiplier: 1,
+			},
+			{
+				file:       "first_error_time",
+				desc:       desc("first_error_time_seconds", "Time of the first recorded filesystem error in unixtime, 0 if none."),
+				valueType:  prometheus.GaugeValue,
+				multiplier: 1,
+			},
+			{
+				file:       "last_error_time",
+				desc:       desc("last_error_time_seconds", "Time of the last recorded filesystem error in unixtime, 0 if none."),
+				valueType:  prometheus.GaugeValue,
+				multiplier: 1,
+			},
+			{
+				file:       "lifetime_write_kbytes",
+				desc:       desc("lifetime_written_bytes_total", "Number of bytes written to the filesystem over its lifetime."),
+				valueType:  prometheus.CounterValue,
+				multiplier: 1024,
+			},
+			{
+				file:       "warning_count",
+				desc:       desc("warnings_total", "Number of warnings logged by the filesystem since it was mounted."),
+				valueType:  prometheus.CounterValue,
+				multiplier: 1,
+				optional:   true,
+			},
+			{
+				file:       "msg_count",
+				desc:       desc("messages_total", "Number of messages logged by the filesystem since it was mounted."),
+				valueType:  prometheus.CounterValue,
+				multiplier: 1,
+				optional:   true,
+			},
+		},
+	}, nil
+}
+
+func (c *ext4Collector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("fs/ext4/*"))
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range devices {
+		// Skip the global "features" directory.
+		if _, err := os.Stat(filepath.Join(dir, "errors_count")); os.IsNotExist(err) {
+			continue
+		}
+		device := filepath.Base(dir)
+
+		for _, a := range c.attributes {
+			value, err := readUintFromFile(filepath.Join(dir, a.file))
+			if err != nil {
+				if a.optional && os.IsNotExist(err) {
+					continue
+				}
+				return fmt.Errorf("couldn't get %s for %s: %s", a.file, device, err)
+			}
+			ch <- prometheus.MustNewConstMetric(a.desc, a.valueType, float64(value)*a.multiplier, device)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nofibrechannel
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const fibrechannelSubsystem = "fibrechannel"
+
+var (
+	fibrechannelPortStates = []string{"online", "offline", "linkdown", "blocked", "bypassed", "diagnostics", "error", "loopback", "notpresent", "unknown"}
+
+	// fibrechannelCounters maps the files in statistics/ to metric names.
+	fibrechannelCounters = []struct {
+		file, name, help string
+	}{
+		{"link_failure_count", "link_failure_total", "Number of link failures."},
+		{"loss_of_sync_count", "loss_of_sync_total", "Number of times synchronization was lost."},
+		{"loss_of_signal_count", "loss_of_signal_total", "Number of times the signal was lost."},
+		{"invalid_crc_count", "invalid_crc_total", "Number of frames received with an invalid CRC."},
+		{"invalid_tx_word_count", "invalid_tx_words_total", "Number of invalid transmission words received."},
+		{"prim_seq_protocol_err_count", "prim_seq_protocol_errors_total", "Number of primitive sequence protocol errors."},
+		{"nos_count", "nos_total", "Number of not operational sequences received."},
+		{"lip_count", "lip_total", "Number of loop initialization primitives."},
+		{"error_frames", "error_frames_total", "Number of frames received in error."},
+		{"dumped_frames", "dumped_frames_total", "Number of frames dropped."},
+		{"tx_frames", "tx_frames_total", "Number of frames transmitted."},
+		{"rx_frames", "rx_frames_total", "Number of frames received."},
+		{"tx_words", "tx_words_total", "Number of words transmitted."},
+		{"rx_words", "rx_words_total", "Number of words received."},
+	}
+)
+
+type fibrechannelCollector struct {
+	info      *prometheus.Desc
+	portState *prometheus.Desc
+	speed     *prometheus.Desc
+	counters  []*prometheus.Desc
+}
+
+func init() {
+	registerCollector(fibrechannelSubsystem, defaultDisabled, NewFibreChannelCollector)
+}
+
+// NewFibreChannelCollector returns a new Collector exposing Fibre Channel HBA statistics.
+func NewFibreChannelCollector() (Collector, error) {
+	c := &fibrechannelCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fibrechannelSubsystem, "info"),
+			"Non-numeric data from /sys/class/fc_host/<fc_host>, value is always 1.",
+			[]string{"fc_host", "port_name", "node_name", "fabric_name", "port_type"}, nil,
+		),
+		portState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fibrechannelSubsystem, "port_state"),
+			"State of the Fibre Channel port.",
+			[]string{"fc_host", "state"}, nil,
+		),
+		speed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fibrechannelSubsystem, "speed_bytes"),
+			"Negotiated speed of the Fibre Channel port in bytes per second.",
+			[]string{"fc_host"}, nil,
+		),
+	}
+	for _, counter := range fibrechannelCounters {
+		c.counters = append(c.counters, prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fibrechannelSubsystem, counter.name),
+			counter.help, []string{"fc_host"}, nil,
+		))
+	}
+	return c, nil
+}
+
+func (c *fibrechannelCollector) Update(ch chan<- prometheus.Metric) error {
+	hosts, err := filepath.Glob(sysFilePath("class/fc_host/host*"))
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range hosts {
+		host := filepath.Base(dir)
+
+		attrs := make(map[string]string)
+		for _, name := range []string{"port_name", "node_name", "fabric_name", "port_type", "port_state", "speed"} {
+			value, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("couldn't get %s of %s: %s", name, host, err)
+			}
+			attrs[name] = strings.TrimSpace(string(value))
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1,
+			host, attrs["port_name"], attrs["node_name"], attrs["fabric_name"], attrs["port_type"])
+
+		state := strings.ToLower(strings.Replace(attrs["port_state"], " ", "", -1))
+		for _, s := range fibrechannelPortStates {
+			v := 0.0
+			if s == state {
+				v = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.portState, prometheus.GaugeValue, v, host, s)
+		}
+
+		if speed, ok := parseFibreChannelSpeed(attrs["speed"]); ok {
+			ch <- prometheus.MustNewConstMetric(c.speed, prometheus.GaugeValue, speed, host)
+		}
+
+		for i, counter := range fibrechannelCounters {
+			value, err := ioutil.ReadFile(filepath.Join(dir, "statistics", counter.file))
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return fmt.Errorf("couldn't get %s of %s: %s", counter.file, host, err)
+			}
+			// Counters are reported in hex, all bits set if the HBA doesn't support them.
+			v, err := strconv.ParseUint(strings.TrimSpace(string(value)), 0, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s of %s: %s", counter.file, host, err)
+			}
+			if v == ^uint64(0) {
+				log.Debugf("Skipping unsupported Fibre Channel counter %s of %s", counter.file, host)
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(c.counters[i], prometheus.CounterValue, float64(v), host)
+		}
+	}
+
+	return nil
+}
+
+// parseFibreChannelSpeed converts the speed attribute, e.g. "16 Gbit", to
+// bytes per second.
+func parseFibreChannelSpeed(speed string) (float64, bool) {
+	fields := strings.Fields(speed)
+	if len(fields) != 2 || fields[1] != "Gbit" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	return v * 1e9 / 8, true
+}
@@ -0,0 +1,137 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nofilestat
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const fileStatSubsystem = "filestat"
+
+var fileStatPaths = kingpin.Flag("collector.filestat.path", "Glob pattern of files and directories to expose the stat of. Can be repeated.").Strings()
+
+type fileStatCollector struct {
+	existsDesc  *prometheus.Desc
+	sizeDesc    *prometheus.Desc
+	mtimeDesc   *prometheus.Desc
+	modeDesc    *prometheus.Desc
+	entriesDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector("filestat", defaultDisabled, NewFileStatCollector)
+}
+
+// NewFileStatCollector returns a new Collector exposing the size,
+// modification time and mode of the configured files and directories.
+func NewFileStatCollector() (Collector, error) {
+	return &fileStatCollector{
+		existsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fileStatSubsystem, "exists"),
+			"1 if the file exists, 0 if it or a pattern doesn't match anything.",
+			[]string{"path"}, nil,
+		),
+		sizeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fileStatSubsystem, "size_bytes"),
+			"Size of the file.",
+			[]string{"path"}, nil,
+		),
+		mtimeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fileStatSubsystem, "modify_time_seconds"),
+			"Unixtime the file was last modified.",
+			[]string{"path"}, nil,
+		),
+		modeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fileStatSubsystem, "mode"),
+			"Permission bits of the file.",
+			[]string{"path"}, nil,
+		),
+		entriesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fileStatSubsystem, "directory_entries"),
+			"Number of entries of the directory.",
+			[]string{"path"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *fileStatCollector) Update(ch chan<- prometheus.Metric) error {
+	seen := map[string]bool{}
+	for _, pattern := range *fileStatPaths {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid filestat path pattern %q: %s", pattern, err)
+		}
+		// Patterns matching nothing, e.g. a missing sentinel file, are
+		// exposed as not existing.
+		if len(paths) == 0 {
+			paths = []string{pattern}
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			c.updateFile(ch, path)
+		}
+	}
+	return nil
+}
+
+func (c *fileStatCollector) updateFile(ch chan<- prometheus.Metric, path string) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("Couldn't stat %q: %s", path, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.existsDesc, prometheus.GaugeValue, 0, path)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.existsDesc, prometheus.GaugeValue, 1, path)
+	ch <- prometheus.MustNewConstMetric(c.sizeDesc, prometheus.GaugeValue, float64(fi.Size()), path)
+	ch <- prometheus.MustNewConstMetric(c.mtimeDesc, prometheus.GaugeValue, float64(fi.ModTime().UnixNano())/1e9, path)
+	ch <- prometheus.MustNewConstMetric(c.modeDesc, prometheus.GaugeValue, float64(fi.Mode().Perm()), path)
+
+	if fi.IsDir() {
+		entries, err := readDirNames(path)
+		if err != nil {
+			log.Errorf("Couldn't read directory %q: %s", path, err)
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(c.entriesDesc, prometheus.GaugeValue, float64(len(entries)), path)
+	}
+}
+
+// readDirNames returns the names of the entries of a directory without
+// stat'ing them, which matters for large spool directories.
+func readDirNames(path string) ([]string, error) {
+	dir, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+	return dir.Readdirnames(-1)
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestFileStatCollector(t *testing.T) {
+	// Git doesn't keep the modification time and permissions of the
+	// fixtures.
+	mtime := time.Unix(1565000000, 0)
+	for path, mode := range map[string]os.FileMode{
+		"fixtures/filestat/a.conf": 0644,
+		"fixtures/filestat/b.conf": 0600,
+		"fixtures/filestat/spool":  0755,
+	} {
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	orig := *fileStatPaths
+	defer func() { *fileStatPaths = orig }()
+	*fileStatPaths = []string{
+		"fixtures/filestat/*.conf",
+		"fixtures/filestat/spool",
+		"fixtures/filestat/missing.flag",
+		// Paths matched by several patterns are only exposed once.
+		"fixtures/filestat/a.conf",
+	}
+
+	c, err := NewFileStatCollector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectorAdapter{c})
+	rw := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(rw, &http.Request{})
+
+	want := `# HELP node_filestat_directory_entries Number of entries of the directory.
+# TYPE node_filestat_directory_entries gauge
+node_filestat_directory_entries{path="fixtures/filestat/spool"} 3
+# HELP node_filestat_exists 1 if the file exists, 0 if it or a pattern doesn't match anything.
+# TYPE node_filestat_exists gauge
+node_filestat_exists{path="fixtures/filestat/a.conf"} 1
+node_filestat_exists{path="fixtures/filestat/b.conf"} 1
+node_filestat_exists{path="fixtures/filestat/missing.flag"} 0
+node_filestat_exists{path="fixtures/filestat/spool"} 1
+# HELP node_filestat_mode Permission bits of the file.
+# TYPE node_filestat_mode gauge
+node_filestat_mode{path="fixtures/filestat/a.conf"} 420
+node_filestat_mode{path="fixtures/filestat/b.conf"} 384
+node_filestat_mode{path="fixtures/filestat/spool"} 493
+# HELP node_filestat_modify_time_seconds Unixtime the file was last modified.
+# TYPE node_filestat_modify_time_seconds gauge
+node_filestat_modify_time_seconds{path="fixtures/filestat/a.conf"} 1.565e+09
+node_filestat_modify_time_seconds{path="fixtures/filestat/b.conf"} 1.565e+09
+node_filestat_modify_time_seconds{path="fixtures/filestat/spool"} 1.565e+09
+`
+	got := rw.Body.String()
+	// The size of a directory depends on the filesystem.
+	var lines []string
+	for _, line := range strings.SplitAfter(got, "\n") {
+		if strings.HasPrefix(line, "node_filestat_size_bytes") && !strings.Contains(line, ".conf") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	got = strings.Join(lines, "")
+	want += `# HELP node_filestat_size_bytes Size of the file.
+# TYPE node_filestat_size_bytes gauge
+node_filestat_size_bytes{path="fixtures/filestat/a.conf"} 10
+node_filestat_size_bytes{path="fixtures/filestat/b.conf"} 15
+`
+	if want != got {
+		t.Errorf("want:\n\n%s\n\ngot:\n\n%s", want, got)
+	}
+}
@@ -38,6 +38,16 @@ var (
 		"collector.filesystem.ignored-fs-types",
 		"Regexp of filesystem types to ignore for filesystem collector.",
 	).Default(defIgnoredFSTypes).String()
+	aggregateMemoryBacked = kingpin.Flag(
+		"collector.filesystem.aggregate-memory-backed",
+		"Expose memory-backed filesystems like tmpfs summed by filesystem type instead of per mount point.",
+	).Default("false").Bool()
+
+	// memoryBackedFSTypes are filesystem types storing their files in memory.
+	memoryBackedFSTypes = regexp.MustCompile(`^(devtmpfs|ramfs|tmpfs)$`)
+	// memoryBackedDevices are RAM disks, whose filesystems are stored in
+	// memory as well.
+	memoryBackedDevices = regexp.MustCompile(`^/dev/z?ram[0-9]+$`)
 
 	filesystemLabelNames = []string{"device", "mountpoint", "fstype"}
 )
@@ -48,10 +58,9 @@ type filesystemCollector struct {
 	sizeDesc, freeDesc, availDesc *prometheus.Desc
 	filesDesc, filesFreeDesc      *prometheus.Desc
 	roDesc, deviceErrorDesc       *prometheus.Desc
-}
-
-type filesystemLabels struct {
-	device, mountPoint, fsType, options string
+	memoryBackedDesc              *prometheus.Desc
+	memoryBackedSizeDesc          *prometheus.Desc
+	memoryBackedUsedDesc          *prometheus.Desc
 }
 
 type filesystemStats struct {
@@ -59,6 +68,14 @@ type filesystemStats struct {
 	size, free, avail float64
 	files, filesFree  float64
 	ro, deviceError   float64
+	// dev identifies the filesystem of memory-backed mounts, so that bind
+	// mounts are only counted once when aggregating them. It's 0 if unknown.
+	dev uint64
+}
+
+// memoryBacked returns whether the filesystem is stored in memory.
+func (l filesystemLabels) memoryBacked() bool {
+	return memoryBackedFSTypes.MatchString(l.fsType) || memoryBackedDevices.MatchString(l.device)
 }
 
 func init() {
@@ -113,6 +130,24 @@ func NewFilesystemCollector() (Collector, error) {
 		filesystemLabelNames, nil,
 	)
 
+	memoryBackedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "memory_backed"),
+		"Whether the filesystem is stored in memory, like tmpfs or a RAM disk, so that its used space is memory used.",
+		filesystemLabelNames, nil,
+	)
+
+	memoryBackedSizeDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "memory_backed_size_bytes"),
+		"Summed size limit of the memory-backed filesystems of the type.",
+		[]string{"fstype"}, nil,
+	)
+
+	memoryBackedUsedDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "memory_backed_used_bytes"),
+		"Summed memory used by the memory-backed filesystems of the type.",
+		[]string{"fstype"}, nil,
+	)
+
 	return &filesystemCollector{
 		ignoredMountPointsPattern: mountPointPattern,
 		ignoredFSTypesPattern:     filesystemsTypesPattern,
@@ -123,6 +158,9 @@ func NewFilesystemCollector() (Collector, error) {
 		filesFreeDesc:             filesFreeDesc,
 		roDesc:                    roDesc,
 		deviceErrorDesc:           deviceErrorDesc,
+		memoryBackedDesc:          memoryBackedDesc,
+		memoryBackedSizeDesc:      memoryBackedSizeDesc,
+		memoryBackedUsedDesc:      memoryBackedUsedDesc,
 	}, nil
 }
 
@@ -133,12 +171,30 @@ func (c *filesystemCollector) Update(ch chan<- prometheus.Metric) error {
 	}
 	// Make sure we expose a metric once, even if there are multiple mounts
 	seen := map[filesystemLabels]bool{}
+	aggregated := map[string]*filesystemStats{}
+	seenDevs := map[uint64]bool{}
 	for _, s := range stats {
 		if seen[s.labels] {
 			continue
 		}
 		seen[s.labels] = true
 
+		memoryBacked := s.labels.memoryBacked()
+		if *aggregateMemoryBacked && memoryBacked {
+			if s.deviceError > 0 || (s.dev != 0 && seenDevs[s.dev]) {
+				continue
+			}
+			seenDevs[s.dev] = true
+			a, ok := aggregated[s.labels.fsType]
+			if !ok {
+				a = &filesystemStats{}
+				aggregated[s.labels.fsType] = a
+			}
+			a.size += s.size
+			a.free += s.free
+			continue
+		}
+
 		ch <- prometheus.MustNewConstMetric(
 			c.deviceErrorDesc, prometheus.GaugeValue,
 			s.deviceError, s.labels.device, s.labels.mountPoint, s.labels.fsType,
@@ -171,6 +227,19 @@ func (c *filesystemCollector) Update(ch chan<- prometheus.Metric) error {
 			c.roDesc, prometheus.GaugeValue,
 			s.ro, s.labels.device, s.labels.mountPoint, s.labels.fsType,
 		)
+		memoryBackedValue := 0.0
+		if memoryBacked {
+			memoryBackedValue = 1
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.memoryBackedDesc, prometheus.GaugeValue,
+			memoryBackedValue, s.labels.device, s.labels.mountPoint, s.labels.fsType,
+		)
+	}
+
+	for fsType, a := range aggregated {
+		ch <- prometheus.MustNewConstMetric(c.memoryBackedSizeDesc, prometheus.GaugeValue, a.size, fsType)
+		ch <- prometheus.MustNewConstMetric(c.memoryBackedUsedDesc, prometheus.GaugeValue, a.size-a.free, fsType)
 	}
 	return nil
 }
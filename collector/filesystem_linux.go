@@ -16,26 +16,39 @@
 package collector
 
 import (
-	"bufio"
-	"fmt"
-	"io"
-	"os"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/prometheus/common/log"
 	"golang.org/x/sys/unix"
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 const (
-	defIgnoredMountPoints = "^/(dev|proc|sys|var/lib/docker/.+)($|/)"
+	// All mount points below /dev are ignored except /dev/shm, Go regexps
+	// have no negative lookahead.
+	defIgnoredMountPoints = "^/(dev(/([^s]|s[^h]|sh[^m]|shm.).*|/s|/sh|/)?|(proc|sys|var/lib/docker/.+)(/.*)?)$"
 	defIgnoredFSTypes     = "^(autofs|binfmt_misc|bpf|cgroup2?|configfs|debugfs|devpts|devtmpfs|fusectl|hugetlbfs|iso9660|mqueue|nsfs|overlay|proc|procfs|pstore|rpc_pipefs|securityfs|selinuxfs|squashfs|sysfs|tracefs)$"
-	mountTimeout          = 30 * time.Second
 )
 
-var stuckMounts = make(map[string]struct{})
-var stuckMountsMtx = &sync.Mutex{}
+var (
+	mountTimeout = kingpin.Flag("collector.filesystem.mount-timeout",
+		"How long to wait for a statfs call before marking the mount point as unresponsive.").Default("30s").Duration()
+	statWorkerCount = kingpin.Flag("collector.filesystem.stat-workers",
+		"How many statfs calls to run concurrently.").Default("4").Int()
+
+	stuckMounts    = make(map[string]struct{})
+	stuckMountsMtx = &sync.Mutex{}
+
+	// filesystemStatfs is replaced in tests to simulate unresponsive mounts.
+	filesystemStatfs = unix.Statfs
+)
+
+type statfsResult struct {
+	buf *unix.Statfs_t
+	err error
+}
 
 // GetStats returns filesystem stats.
 func (c *filesystemCollector) GetStats() ([]filesystemStats, error) {
@@ -43,133 +56,137 @@ func (c *filesystemCollector) GetStats() ([]filesystemStats, error) {
 	if err != nil {
 		return nil, err
 	}
-	stats := []filesystemStats{}
-	for _, labels := range mps {
-		if c.ignoredMountPointsPattern.MatchString(labels.mountPoint) {
-			log.Debugf("Ignoring mount point: %s", labels.mountPoint)
-			continue
-		}
-		if c.ignoredFSTypesPattern.MatchString(labels.fsType) {
-			log.Debugf("Ignoring fs type: %s", labels.fsType)
-			continue
-		}
-		stuckMountsMtx.Lock()
-		if _, ok := stuckMounts[labels.mountPoint]; ok {
-			stats = append(stats, filesystemStats{
-				labels:      labels,
-				deviceError: 1,
-			})
-			log.Debugf("Mount point %q is in an unresponsive state", labels.mountPoint)
-			stuckMountsMtx.Unlock()
-			continue
+
+	workers := *statWorkerCount
+	if workers < 1 {
+		workers = 1
+	}
+
+	labelChan := make(chan filesystemLabels)
+	statChan := make(chan filesystemStats)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for labels := range labelChan {
+				statChan <- c.processStat(labels)
+			}
+		}()
+	}
+
+	go func() {
+		for _, labels := range mps {
+			if c.ignoredMountPointsPattern.MatchString(labels.mountPoint) {
+				log.Debugf("Ignoring mount point: %s", labels.mountPoint)
+				continue
+			}
+			if c.ignoredFSTypesPattern.MatchString(labels.fsType) {
+				log.Debugf("Ignoring fs type: %s", labels.fsType)
+				continue
+			}
+			labelChan <- labels
 		}
-		stuckMountsMtx.Unlock()
+		close(labelChan)
+		wg.Wait()
+		close(statChan)
+	}()
 
-		// The success channel is used do tell the "watcher" that the stat
-		// finished successfully. The channel is closed on success.
-		success := make(chan struct{})
-		go stuckMountWatcher(labels.mountPoint, success)
+	stats := []filesystemStats{}
+	for s := range statChan {
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
 
+// processStat calls statfs for a single mount point. The call runs in its own
+// goroutine so that an unresponsive mount only costs mountTimeout. Such a
+// mount point is reported with a device error and not queried again until
+// the pending statfs call returns.
+func (c *filesystemCollector) processStat(labels filesystemLabels) filesystemStats {
+	stuckMountsMtx.Lock()
+	if _, ok := stuckMounts[labels.mountPoint]; ok {
+		stuckMountsMtx.Unlock()
+		log.Debugf("Mount point %q is in an unresponsive state", labels.mountPoint)
+		return filesystemStats{
+			labels:      labels,
+			deviceError: 1,
+		}
+	}
+	stuckMountsMtx.Unlock()
+
+	// The result channel is buffered so that a late statfs call can finish
+	// after nobody is waiting for it anymore.
+	result := make(chan statfsResult, 1)
+	go func() {
 		buf := new(unix.Statfs_t)
-		err = unix.Statfs(rootfsFilePath(labels.mountPoint), buf)
+		err := filesystemStatfs(rootfsFilePath(labels.mountPoint), buf)
 		stuckMountsMtx.Lock()
-		close(success)
 		// If the mount has been marked as stuck, unmark it and log it's recovery.
 		if _, ok := stuckMounts[labels.mountPoint]; ok {
 			log.Debugf("Mount point %q has recovered, monitoring will resume", labels.mountPoint)
 			delete(stuckMounts, labels.mountPoint)
 		}
+		// Sending while holding the lock ensures the mount can't be marked
+		// as stuck after this call returned.
+		result <- statfsResult{buf: buf, err: err}
 		stuckMountsMtx.Unlock()
+	}()
 
-		if err != nil {
-			stats = append(stats, filesystemStats{
+	var r statfsResult
+	select {
+	case r = <-result:
+	case <-time.After(*mountTimeout):
+		stuckMountsMtx.Lock()
+		select {
+		case r = <-result:
+			// The result came in just after the timeout was reached, don't label the mount as stuck.
+			stuckMountsMtx.Unlock()
+		default:
+			log.Debugf("Mount point %q timed out, it is being labeled as stuck and will not be monitored", labels.mountPoint)
+			stuckMounts[labels.mountPoint] = struct{}{}
+			stuckMountsMtx.Unlock()
+			return filesystemStats{
 				labels:      labels,
 				deviceError: 1,
-			})
-			log.Debugf("Error on statfs() system call for %q: %s", rootfsFilePath(labels.mountPoint), err)
-			continue
-		}
-
-		var ro float64
-		for _, option := range strings.Split(labels.options, ",") {
-			if option == "ro" {
-				ro = 1
-				break
 			}
 		}
-
-		stats = append(stats, filesystemStats{
-			labels:    labels,
-			size:      float64(buf.Blocks) * float64(buf.Bsize),
-			free:      float64(buf.Bfree) * float64(buf.Bsize),
-			avail:     float64(buf.Bavail) * float64(buf.Bsize),
-			files:     float64(buf.Files),
-			filesFree: float64(buf.Ffree),
-			ro:        ro,
-		})
 	}
-	return stats, nil
-}
 
-// stuckMountWatcher listens on the given success channel and if the channel closes
-// then the watcher does nothing. If instead the timeout is reached, the
-// mount point that is being watched is marked as stuck.
-func stuckMountWatcher(mountPoint string, success chan struct{}) {
-	select {
-	case <-success:
-		// Success
-	case <-time.After(mountTimeout):
-		// Timed out, mark mount as stuck
-		stuckMountsMtx.Lock()
-		select {
-		case <-success:
-			// Success came in just after the timeout was reached, don't label the mount as stuck
-		default:
-			log.Debugf("Mount point %q timed out, it is being labeled as stuck and will not be monitored", mountPoint)
-			stuckMounts[mountPoint] = struct{}{}
+	if r.err != nil {
+		log.Debugf("Error on statfs() system call for %q: %s", rootfsFilePath(labels.mountPoint), r.err)
+		return filesystemStats{
+			labels:      labels,
+			deviceError: 1,
 		}
-		stuckMountsMtx.Unlock()
 	}
-}
 
-func mountPointDetails() ([]filesystemLabels, error) {
-	file, err := os.Open(procFilePath("1/mounts"))
-	if os.IsNotExist(err) {
-		// Fallback to `/proc/mounts` if `/proc/1/mounts` is missing due hidepid.
-		log.Debugf("Got %q reading root mounts, falling back to system mounts", err)
-		file, err = os.Open(procFilePath("mounts"))
-	}
-	if err != nil {
-		return nil, err
+	var ro float64
+	for _, option := range strings.Split(labels.options, ",") {
+		if option == "ro" {
+			ro = 1
+			break
+		}
 	}
-	defer file.Close()
-
-	return parseFilesystemLabels(file)
-}
-
-func parseFilesystemLabels(r io.Reader) ([]filesystemLabels, error) {
-	var filesystems []filesystemLabels
 
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		parts := strings.Fields(scanner.Text())
-
-		if len(parts) < 4 {
-			return nil, fmt.Errorf("malformed mount point information: %q", scanner.Text())
+	// Memory-backed filesystems don't hang, so they can be stat'ed
+	// directly.
+	var dev uint64
+	if labels.memoryBacked() {
+		var st unix.Stat_t
+		if err := unix.Stat(rootfsFilePath(labels.mountPoint), &st); err == nil {
+			dev = uint64(st.Dev)
 		}
-
-		// Ensure we handle the translation of \040 and \011
-		// as per fstab(5).
-		parts[1] = strings.Replace(parts[1], "\\040", " ", -1)
-		parts[1] = strings.Replace(parts[1], "\\011", "\t", -1)
-
-		filesystems = append(filesystems, filesystemLabels{
-			device:     parts[0],
-			mountPoint: rootfsStripPrefix(parts[1]),
-			fsType:     parts[2],
-			options:    parts[3],
-		})
 	}
 
-	return filesystems, scanner.Err()
+	return filesystemStats{
+		labels:    labels,
+		size:      float64(r.buf.Blocks) * float64(r.buf.Bsize),
+		free:      float64(r.buf.Bfree) * float64(r.buf.Bsize),
+		avail:     float64(r.buf.Bavail) * float64(r.buf.Bsize),
+		files:     float64(r.buf.Files),
+		filesFree: float64(r.buf.Ffree),
+		ro:        ro,
+		dev:       dev,
+	}
 }
@@ -16,9 +16,12 @@
 package collector
 
 import (
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	"golang.org/x/sys/unix"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -140,3 +143,109 @@ func TestPathRootfs(t *testing.T) {
 		}
 	}
 }
+
+func TestStuckMountIsSkipped(t *testing.T) {
+	if _, err := kingpin.CommandLine.Parse([]string{"--path.procfs", "./fixtures/proc", "--collector.filesystem.mount-timeout", "50ms"}); err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	defer func() { filesystemStatfs = unix.Statfs }()
+	filesystemStatfs = func(path string, buf *unix.Statfs_t) error {
+		if path == "/boot" {
+			<-release
+		}
+		return nil
+	}
+
+	c := &filesystemCollector{
+		ignoredMountPointsPattern: regexp.MustCompile("^$"),
+		ignoredFSTypesPattern:     regexp.MustCompile("^$"),
+	}
+	deviceErrors := func() map[string]float64 {
+		stats, err := c.GetStats()
+		if err != nil {
+			t.Fatal(err)
+		}
+		errs := make(map[string]float64)
+		for _, s := range stats {
+			errs[s.labels.mountPoint] = s.deviceError
+		}
+		return errs
+	}
+
+	errs := deviceErrors()
+	if errs["/boot"] != 1 {
+		t.Errorf("want device error for unresponsive mount, got %v", errs["/boot"])
+	}
+	if errs["/"] != 0 {
+		t.Errorf("want no device error for responsive mount, got %v", errs["/"])
+	}
+
+	// The mount stays flagged without another statfs call while stuck.
+	if errs := deviceErrors(); errs["/boot"] != 1 {
+		t.Errorf("want device error for stuck mount, got %v", errs["/boot"])
+	}
+
+	close(release)
+	for i := 0; ; i++ {
+		stuckMountsMtx.Lock()
+		_, stuck := stuckMounts["/boot"]
+		stuckMountsMtx.Unlock()
+		if !stuck {
+			break
+		}
+		if i > 100 {
+			t.Fatal("mount was not unmarked after statfs returned")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if errs := deviceErrors(); errs["/boot"] != 0 {
+		t.Errorf("want no device error for recovered mount, got %v", errs["/boot"])
+	}
+}
+
+func TestMemoryBacked(t *testing.T) {
+	for _, tc := range []struct {
+		labels filesystemLabels
+		want   bool
+	}{
+		{filesystemLabels{device: "tmpfs", mountPoint: "/dev/shm", fsType: "tmpfs"}, true},
+		{filesystemLabels{device: "none", mountPoint: "/mnt/ramfs", fsType: "ramfs"}, true},
+		{filesystemLabels{device: "/dev/zram1", mountPoint: "/var/tmp", fsType: "ext4"}, true},
+		{filesystemLabels{device: "/dev/ram0", mountPoint: "/mnt/ram", fsType: "ext2"}, true},
+		{filesystemLabels{device: "/dev/sda1", mountPoint: "/", fsType: "ext4"}, false},
+		{filesystemLabels{device: "/dev/mapper/tmpfs", mountPoint: "/tmp", fsType: "xfs"}, false},
+	} {
+		if got := tc.labels.memoryBacked(); got != tc.want {
+			t.Errorf("%v: want %t, got %t", tc.labels, tc.want, got)
+		}
+	}
+}
+
+func TestDefaultIgnoredMountPoints(t *testing.T) {
+	re := regexp.MustCompile(defIgnoredMountPoints)
+	for mountPoint, ignored := range map[string]bool{
+		"/":                            false,
+		"/dev":                         true,
+		"/dev/pts":                     true,
+		"/dev/hugepages":               true,
+		"/dev/mqueue":                  true,
+		"/dev/sda1":                    true,
+		"/dev/shm":                     false,
+		"/dev/shm/x":                   true,
+		"/dev/shmem":                   true,
+		"/devices":                     false,
+		"/proc":                        true,
+		"/proc/sys/fs/binfmt_misc":     true,
+		"/sys/fs/cgroup":               true,
+		"/system":                      false,
+		"/var/lib/docker/overlay2/abc": true,
+		"/var/lib/docker":              false,
+		"/home":                        false,
+	} {
+		if got := re.MatchString(mountPoint); got != ignored {
+			t.Errorf("%s: want ignored %t, got %t", mountPoint, ignored, got)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nofirewall
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	firewallSubsystem      = "firewall"
+	firewallCommandTimeout = 10 * time.Second
+)
+
+var (
+	firewallNftPath          = kingpin.Flag("collector.firewall.nft-path", "Path to the nft command used to collect named nftables counters. Disabled if empty.").Default("").String()
+	firewallIptablesSavePath = kingpin.Flag("collector.firewall.iptables-save-path", "Path to the iptables-save command used to collect iptables rule counters. Disabled if empty.").Default("").String()
+	firewallIptablesChains   = kingpin.Flag("collector.firewall.iptables-chains", "Regexp of <table>:<chain> to expose iptables rule counters for. Rules are labelled by their comment, or their position in the chain if they have none, rules with the same comment are summed.").Default("filter:(INPUT|FORWARD|OUTPUT)").String()
+
+	iptablesCommentRE = regexp.MustCompile(`--comment (?:"((?:[^"\\]|\\.)*)"|(\S+))`)
+)
+
+type firewallCollector struct {
+	nftPackets           *prometheus.Desc
+	nftBytes             *prometheus.Desc
+	iptablesPackets      *prometheus.Desc
+	iptablesBytes        *prometheus.Desc
+	iptablesChainPackets *prometheus.Desc
+	iptablesChainBytes   *prometheus.Desc
+	chainPattern         *regexp.Regexp
+}
+
+// nftCounter is a named counter of `nft -j list counters`.
+type nftCounter struct {
+	Family  string  `json:"family"`
+	Table   string  `json:"table"`
+	Name    string  `json:"name"`
+	Packets float64 `json:"packets"`
+	Bytes   float64 `json:"bytes"`
+}
+
+// iptablesCounter is the counter of an iptables rule or chain policy.
+type iptablesCounter struct {
+	table, chain, rule string
+	packets, bytes     float64
+}
+
+func init() {
+	registerCollector(firewallSubsystem, defaultDisabled, NewFirewallCollector)
+}
+
+// NewFirewallCollector returns a new Collector exposing nftables and iptables
+// packet and byte counters.
+func NewFirewallCollector() (Collector, error) {
+	pattern, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", *firewallIptablesChains))
+	if err != nil {
+		return nil, fmt.Errorf("invalid iptables-chains pattern: %s", err)
+	}
+	return &firewallCollector{
+		nftPackets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, firewallSubsystem, "nft_counter_packets_total"),
+			"Number of packets counted by the named nftables counter.",
+			[]string{"family", "table", "counter"}, nil,
+		),
+		nftBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, firewallSubsystem, "nft_counter_bytes_total"),
+			"Number of bytes counted by the named nftables counter.",
+			[]string{"family", "table", "counter"}, nil,
+		),
+		iptablesPackets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, firewallSubsystem, "iptables_rule_packets_total"),
+			"Number of packets matched by the iptables rule.",
+			[]string{"table", "chain", "rule"}, nil,
+		),
+		iptablesBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, firewallSubsystem, "iptables_rule_bytes_total"),
+			"Number of bytes matched by the iptables rule.",
+			[]string{"table", "chain", "rule"}, nil,
+		),
+		iptablesChainPackets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, firewallSubsystem, "iptables_chain_policy_packets_total"),
+			"Number of packets handled by the policy of the built-in iptables chain.",
+			[]string{"table", "chain", "policy"}, nil,
+		),
+		iptablesChainBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, firewallSubsystem, "iptables_chain_policy_bytes_total"),
+			"Number of bytes handled by the policy of the built-in iptables chain.",
+			[]string{"table", "chain", "policy"}, nil,
+		),
+		chainPattern: pattern,
+	}, nil
+}
+
+func (c *firewallCollector) Update(ch chan<- prometheus.Metric) error {
+	if *firewallNftPath != "" {
+		if err := c.updateNft(ch); err != nil {
+			return err
+		}
+	}
+	if *firewallIptablesSavePath != "" {
+		if err := c.updateIptables(ch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *firewallCollector) updateNft(ch chan<- prometheus.Metric) error {
+	out, err := firewallCommand(*firewallNftPath, "-j", "list", "counters")
+	if err != nil {
+		return err
+	}
+	counters, err := parseNftCounters(bytes.NewReader(out))
+	if err != nil {
+		return fmt.Errorf("couldn't parse nftables counters: %s", err)
+	}
+	for _, counter := range counters {
+		ch <- prometheus.MustNewConstMetric(c.nftPackets, prometheus.CounterValue, counter.Packets, counter.Family, counter.Table, counter.Name)
+		ch <- prometheus.MustNewConstMetric(c.nftBytes, prometheus.CounterValue, counter.Bytes, counter.Family, counter.Table, counter.Name)
+	}
+	return nil
+}
+
+func (c *firewallCollector) updateIptables(ch chan<- prometheus.Metric) error {
+	out, err := firewallCommand(*firewallIptablesSavePath, "-c")
+	if err != nil {
+		return err
+	}
+	policies, rules, err := parseIptablesSave(bytes.NewReader(out))
+	if err != nil {
+		return fmt.Errorf("couldn't parse iptables rules: %s", err)
+	}
+	for _, p := range policies {
+		if !c.chainPattern.MatchString(p.table + ":" + p.chain) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.iptablesChainPackets, prometheus.CounterValue, p.packets, p.table, p.chain, p.rule)
+		ch <- prometheus.MustNewConstMetric(c.iptablesChainBytes, prometheus.CounterValue, p.bytes, p.table, p.chain, p.rule)
+	}
+	for _, r := range rules {
+		if !c.chainPattern.MatchString(r.table + ":" + r.chain) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.iptablesPackets, prometheus.CounterValue, r.packets, r.table, r.chain, r.rule)
+		ch <- prometheus.MustNewConstMetric(c.iptablesBytes, prometheus.CounterValue, r.bytes, r.table, r.chain, r.rule)
+	}
+	return nil
+}
+
+func firewallCommand(path string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), firewallCommandTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %s", path, strings.Join(args, " "), err)
+	}
+	return out, nil
+}
+
+// parseNftCounters parses the JSON output of `nft -j list counters`.
+func parseNftCounters(r io.Reader) ([]nftCounter, error) {
+	var ruleset struct {
+		Nftables []struct {
+			Counter *nftCounter `json:"counter"`
+		} `json:"nftables"`
+	}
+	if err := json.NewDecoder(r).Decode(&ruleset); err != nil {
+		return nil, err
+	}
+
+	var counters []nftCounter
+	for _, object := range ruleset.Nftables {
+		if object.Counter != nil {
+			counters = append(counters, *object.Counter)
+		}
+	}
+	return counters, nil
+}
+
+// parseIptablesSave parses the output of `iptables-save -c` into the policy
+// counters of the built-in chains, labelled by policy, and the rule counters,
+// labelled by comment or position in the chain. Rules with the same comment
+// are summed up, so related rules can be grouped by commenting them alike.
+func parseIptablesSave(r io.Reader) ([]iptablesCounter, []iptablesCounter, error) {
+	var (
+		policies, rules []iptablesCounter
+		table           string
+		positions       = make(map[string]int)
+		seen            = make(map[string]int)
+		scanner         = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "*"):
+			table = line[1:]
+			positions = make(map[string]int)
+		case strings.HasPrefix(line, ":"):
+			// :INPUT ACCEPT [123:4567], user defined chains have no policy.
+			fields := strings.Fields(line[1:])
+			if len(fields) != 3 || fields[1] == "-" {
+				continue
+			}
+			packets, bytes, err := parseIptablesCounter(fields[2])
+			if err != nil {
+				return nil, nil, err
+			}
+			policies = append(policies, iptablesCounter{table, fields[0], fields[1], packets, bytes})
+		case strings.HasPrefix(line, "["):
+			// [10:600] -A INPUT -p tcp -m comment --comment "ssh" -j ACCEPT
+			fields := strings.Fields(line)
+			if len(fields) < 3 || fields[1] != "-A" {
+				return nil, nil, fmt.Errorf("invalid rule %q", line)
+			}
+			packets, bytes, err := parseIptablesCounter(fields[0])
+			if err != nil {
+				return nil, nil, err
+			}
+			chain := fields[2]
+			positions[chain]++
+			rule := strconv.Itoa(positions[chain])
+			if m := iptablesCommentRE.FindStringSubmatch(line); m != nil {
+				rule = m[1] + m[2]
+			}
+			// Rules sharing a comment are summed up.
+			if i, ok := seen[table+":"+chain+":"+rule]; ok {
+				rules[i].packets += packets
+				rules[i].bytes += bytes
+				continue
+			}
+			seen[table+":"+chain+":"+rule] = len(rules)
+			rules = append(rules, iptablesCounter{table, chain, rule, packets, bytes})
+		}
+	}
+
+	return policies, rules, scanner.Err()
+}
+
+// parseIptablesCounter parses a "[packets:bytes]" counter.
+func parseIptablesCounter(s string) (float64, float64, error) {
+	parts := strings.Split(strings.Trim(s, "[]"), ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid counter %q", s)
+	}
+	packets, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	bytes, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return packets, bytes, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseIptablesSave(t *testing.T) {
+	file, err := os.Open("fixtures/firewall/iptables-save")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	policies, rules, err := parseIptablesSave(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 5, len(policies); want != got {
+		t.Errorf("want %d policies, got %d", want, got)
+	}
+	if want, got := (iptablesCounter{"filter", "INPUT", "DROP", 1523, 91380}), policies[2]; want != got {
+		t.Errorf("want policy %v, got %v", want, got)
+	}
+
+	want := []iptablesCounter{
+		{"nat", "POSTROUTING", "1", 4, 240},
+		{"filter", "INPUT", "1", 88120, 10233400},
+		{"filter", "INPUT", "ssh access", 321, 19260},
+		{"filter", "INPUT", "node_exporter", 15, 900},
+		{"filter", "INPUT", "5", 1523, 91380},
+		{"filter", "LOGGING", "1", 1523, 91380},
+	}
+	if !reflect.DeepEqual(want, rules) {
+		t.Errorf("want rules %v, got %v", want, rules)
+	}
+}
+
+func TestParseNftCounters(t *testing.T) {
+	file, err := os.Open("fixtures/firewall/nft-counters.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	counters, err := parseNftCounters(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []nftCounter{
+		{Family: "inet", Table: "filter", Name: "ssh_drops", Packets: 42, Bytes: 2520},
+		{Family: "ip", Table: "web", Name: "http_in", Packets: 1000, Bytes: 64000},
+	}
+	if !reflect.DeepEqual(want, counters) {
+		t.Errorf("want counters %v, got %v", want, counters)
+	}
+}
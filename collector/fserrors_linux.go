@@ -0,0 +1,149 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nofserrors
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const fserrorsSubsystem = "fserrors"
+
+// nfsOperationErrors are the errors of an NFS operation on a mount.
+type nfsOperationErrors struct {
+	export, mountPoint, operation string
+	errors                        float64
+}
+
+type fserrorsCollector struct {
+	nfsOperationErrorsDesc *prometheus.Desc
+	diskIOErrorsDesc       *prometheus.Desc
+}
+
+func init() {
+	registerCollector(fserrorsSubsystem, defaultDisabled, NewFserrorsCollector)
+}
+
+// NewFserrorsCollector returns a new Collector exposing the errors returned
+// by NFS mounts and SCSI disks.
+func NewFserrorsCollector() (Collector, error) {
+	return &fserrorsCollector{
+		nfsOperationErrorsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fserrorsSubsystem, "nfs_operation_errors_total"),
+			"Number of NFS operations that failed, e.g. with ESTALE or EIO.",
+			[]string{"export", "mountpoint", "operation"}, nil,
+		),
+		diskIOErrorsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fserrorsSubsystem, "disk_io_errors_total"),
+			"Number of I/O requests to the SCSI disk that completed with an error.",
+			[]string{"device"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *fserrorsCollector) Update(ch chan<- prometheus.Metric) error {
+	f, err := os.Open(procFilePath("self/mountstats"))
+	if err != nil {
+		return fmt.Errorf("couldn't open mountstats: %s", err)
+	}
+	defer f.Close()
+	ops, err := parseNFSOperationErrors(f)
+	if err != nil {
+		return fmt.Errorf("couldn't parse mountstats: %s", err)
+	}
+	for _, op := range ops {
+		ch <- prometheus.MustNewConstMetric(c.nfsOperationErrorsDesc, prometheus.CounterValue, op.errors, op.export, op.mountPoint, op.operation)
+	}
+
+	counts, err := filepath.Glob(sysFilePath("block/*/device/ioerr_cnt"))
+	if err != nil {
+		return err
+	}
+	for _, count := range counts {
+		device := filepath.Base(filepath.Dir(filepath.Dir(count)))
+		value, err := ioutil.ReadFile(count)
+		if err != nil {
+			log.Debugf("Couldn't read I/O errors of %s: %s", device, err)
+			continue
+		}
+		// The count is hexadecimal, e.g. 0x1a.
+		errors, err := strconv.ParseUint(strings.TrimSpace(string(value)), 0, 64)
+		if err != nil {
+			return fmt.Errorf("invalid I/O error count of %s: %s", device, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.diskIOErrorsDesc, prometheus.CounterValue, float64(errors), device)
+	}
+	return nil
+}
+
+// parseNFSOperationErrors parses the errors of the per-op statistics of the
+// NFS mounts in mountstats. They are the last of the nine counters of an
+// operation, which are only listed since Linux 5.3:
+//
+//	device 192.168.1.1:/srv mounted on /mnt with fstype nfs4 statvers=1.1
+//	...
+//		per-op statistics
+//		        NULL: 0 0 0 0 0 0 0 0 0
+//		        READ: 1298 1298 0 207680 1210292152 6 79386 79407 3
+func parseNFSOperationErrors(r io.Reader) ([]nfsOperationErrors, error) {
+	var (
+		ops                []nfsOperationErrors
+		export, mountPoint string
+		nfs, perOp         bool
+		seen               = map[string]bool{}
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 0:
+			perOp = false
+		case fields[0] == "device":
+			perOp = false
+			// device <export> mounted on <mountpoint> with fstype <type> ...
+			nfs = len(fields) >= 8 && (fields[7] == "nfs" || fields[7] == "nfs4")
+			if nfs {
+				export, mountPoint = fields[1], fields[4]
+			}
+		case nfs && len(fields) == 2 && fields[0] == "per-op" && fields[1] == "statistics":
+			perOp = true
+		case perOp && len(fields) == 10 && strings.HasSuffix(fields[0], ":"):
+			operation := strings.TrimSuffix(fields[0], ":")
+			// The same superblock may be mounted more than once.
+			key := export + "\x00" + mountPoint + "\x00" + operation
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			errors, err := strconv.ParseFloat(fields[9], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid errors of %s on %s: %s", operation, mountPoint, err)
+			}
+			ops = append(ops, nfsOperationErrors{export: export, mountPoint: rootfsStripPrefix(mountPoint), operation: operation, errors: errors})
+		}
+	}
+	return ops, scanner.Err()
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nofserrors
+
+package collector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNFSOperationErrors(t *testing.T) {
+	in := `device rootfs mounted on / with fstype rootfs
+device 192.168.1.1:/srv/new mounted on /mnt/new with fstype nfs4 statvers=1.1
+	opts:	rw,vers=4.2
+	per-op statistics
+	        NULL: 0 0 0 0 0 0 0 0 0
+	        READ: 1298 1298 0 207680 1210292152 6 79386 79407 3
+	     GETATTR: 20 20 0 2800 4160 0 18 19 7
+
+device 192.168.1.1:/srv/old mounted on /mnt/old with fstype nfs statvers=1.1
+	per-op statistics
+	        READ: 1298 1298 0 207680 1210292152 6 79386 79407
+
+device sysfs mounted on /sys with fstype sysfs
+`
+	ops, err := parseNFSOperationErrors(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []nfsOperationErrors{
+		{export: "192.168.1.1:/srv/new", mountPoint: "/mnt/new", operation: "NULL", errors: 0},
+		{export: "192.168.1.1:/srv/new", mountPoint: "/mnt/new", operation: "READ", errors: 3},
+		{export: "192.168.1.1:/srv/new", mountPoint: "/mnt/new", operation: "GETATTR", errors: 7},
+	}
+	if !reflect.DeepEqual(ops, want) {
+		t.Errorf("want %+v, got %+v", want, ops)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nofuse
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"golang.org/x/sys/unix"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const fuseSubsystem = "fuse"
+
+var (
+	fuseMountTimeout = kingpin.Flag("collector.fuse.mount-timeout", "How long to wait for a FUSE daemon to answer a statfs call before marking the mount point as unresponsive.").Default("5s").Duration()
+
+	// Mount points whose statfs call didn't return yet, which aren't
+	// queried again until it does.
+	fusePendingMtx sync.Mutex
+	fusePending    = map[string]bool{}
+
+	errFuseTimeout = errors.New("statfs timed out")
+)
+
+type fuseCollector struct {
+	waitingDesc       *prometheus.Desc
+	maxBackgroundDesc *prometheus.Desc
+	congestionDesc    *prometheus.Desc
+	connectedDesc     *prometheus.Desc
+	responsiveDesc    *prometheus.Desc
+}
+
+func init() {
+	registerCollector(fuseSubsystem, defaultDisabled, NewFuseCollector)
+}
+
+// NewFuseCollector returns a new Collector exposing the requests of the FUSE
+// connections and whether their daemons are alive.
+func NewFuseCollector() (Collector, error) {
+	labels := []string{"device", "mountpoint", "fstype"}
+	return &fuseCollector{
+		waitingDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fuseSubsystem, "requests_waiting"),
+			"Number of requests waiting to be answered by the FUSE daemon.",
+			labels, nil,
+		),
+		maxBackgroundDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fuseSubsystem, "max_background_requests"),
+			"Maximum number of background requests queued to the FUSE daemon.",
+			labels, nil,
+		),
+		congestionDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fuseSubsystem, "congestion_threshold_requests"),
+			"Number of background requests above which the FUSE connection is considered congested.",
+			labels, nil,
+		),
+		connectedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fuseSubsystem, "daemon_connected"),
+			"1 if the FUSE daemon is connected, 0 if it exited without the mount point being unmounted.",
+			labels, nil,
+		),
+		responsiveDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fuseSubsystem, "daemon_responsive"),
+			"1 if the FUSE daemon answered a statfs call in time, 0 if it didn't or isn't connected.",
+			labels, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *fuseCollector) Update(ch chan<- prometheus.Metric) error {
+	mf, err := os.Open(mountinfoPath())
+	if err != nil {
+		return err
+	}
+	defer mf.Close()
+	mounts, err := parseMountInfo(mf)
+	if err != nil {
+		return fmt.Errorf("couldn't parse mountinfo: %s", err)
+	}
+
+	for _, m := range mounts {
+		if m.fsType != "fuse" && m.fsType != "fuseblk" && !strings.HasPrefix(m.fsType, "fuse.") {
+			continue
+		}
+		labels := []string{m.device, m.mountPoint, m.fsType}
+
+		// The connections are named after the device number of the
+		// mount in the kernel's internal format.
+		id, err := fuseConnectionID(m.majorMinor)
+		if err != nil {
+			return err
+		}
+		dir := sysFilePath(filepath.Join("fs/fuse/connections", id))
+		for file, desc := range map[string]*prometheus.Desc{
+			"waiting":              c.waitingDesc,
+			"max_background":       c.maxBackgroundDesc,
+			"congestion_threshold": c.congestionDesc,
+		} {
+			value, err := readUintFromFile(filepath.Join(dir, file))
+			if err != nil {
+				// The fusectl filesystem may not be mounted.
+				log.Debugf("Couldn't get %s of FUSE connection %s: %s", file, id, err)
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value), labels...)
+		}
+
+		// The kernel denies statfs calls to other users' FUSE mounts without
+		// asking the daemon, unless they are mounted with allow_other.
+		switch err := fuseStatfs(rootfsFilePath(m.mountPoint)); err {
+		case nil:
+			ch <- prometheus.MustNewConstMetric(c.connectedDesc, prometheus.GaugeValue, 1, labels...)
+			ch <- prometheus.MustNewConstMetric(c.responsiveDesc, prometheus.GaugeValue, 1, labels...)
+		case unix.ENOTCONN:
+			ch <- prometheus.MustNewConstMetric(c.connectedDesc, prometheus.GaugeValue, 0, labels...)
+			ch <- prometheus.MustNewConstMetric(c.responsiveDesc, prometheus.GaugeValue, 0, labels...)
+		case errFuseTimeout:
+			ch <- prometheus.MustNewConstMetric(c.responsiveDesc, prometheus.GaugeValue, 0, labels...)
+		default:
+			log.Debugf("Error on statfs() system call for %q: %s", m.mountPoint, err)
+		}
+	}
+	return nil
+}
+
+// fuseConnectionID converts the major:minor device number of a mount to the
+// kernel's internal format, as used for the names of the FUSE connections.
+func fuseConnectionID(majorMinor string) (string, error) {
+	parts := strings.SplitN(majorMinor, ":", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid device number %q", majorMinor)
+	}
+	major, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return "", fmt.Errorf("invalid device number %q", majorMinor)
+	}
+	minor, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return "", fmt.Errorf("invalid device number %q", majorMinor)
+	}
+	return strconv.FormatUint(major<<20|minor, 10), nil
+}
+
+// fuseStatfs calls statfs on a FUSE mount point, which blocks until the
+// daemon answers. It returns errFuseTimeout if the call didn't return within
+// the timeout, in which case the mount point isn't queried again until it
+// returns.
+func fuseStatfs(mountPoint string) error {
+	fusePendingMtx.Lock()
+	if fusePending[mountPoint] {
+		fusePendingMtx.Unlock()
+		return errFuseTimeout
+	}
+	fusePending[mountPoint] = true
+	fusePendingMtx.Unlock()
+
+	// The result channel is buffered so that a late statfs call can finish
+	// after nobody is waiting for it anymore.
+	result := make(chan error, 1)
+	go func() {
+		var buf unix.Statfs_t
+		err := unix.Statfs(mountPoint, &buf)
+		fusePendingMtx.Lock()
+		delete(fusePending, mountPoint)
+		fusePendingMtx.Unlock()
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(*fuseMountTimeout):
+		log.Debugf("Mount point %q timed out, it will not be queried until the statfs call returns", mountPoint)
+		return errFuseTimeout
+	}
+}
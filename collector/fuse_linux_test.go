@@ -0,0 +1,37 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nofuse
+
+package collector
+
+import "testing"
+
+func TestFuseConnectionID(t *testing.T) {
+	for majorMinor, want := range map[string]string{
+		"0:52": "52",
+		"8:17": "8388625",
+	} {
+		id, err := fuseConnectionID(majorMinor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != want {
+			t.Errorf("%s: want connection %s, got %s", majorMinor, want, id)
+		}
+	}
+
+	if _, err := fuseConnectionID("52"); err == nil {
+		t.Error("expected error for invalid device number")
+	}
+}
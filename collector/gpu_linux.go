@@ -0,0 +1,392 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nogpu
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	gpuSubsystem      = "gpu"
+	gpuCommandTimeout = 10 * time.Second
+
+	// gpuNvidiaQuery are the --query-gpu fields parsed by parseNvidiaSmi.
+	gpuNvidiaQuery = "index,name,uuid,utilization.gpu,memory.used,memory.total,temperature.gpu,power.draw,clocks.gr,clocks.mem," +
+		"ecc.errors.corrected.aggregate.total,ecc.errors.uncorrected.aggregate.total,clocks_throttle_reasons.active"
+)
+
+var (
+	gpuNvidiaSmiPath = kingpin.Flag("collector.gpu.nvidia-smi-path", "Path to the nvidia-smi command used to collect NVIDIA GPU metrics. Disabled if empty.").Default("").String()
+
+	// gpuThrottleReasons are the nvmlClocksThrottleReason* bits of nvml.h.
+	gpuThrottleReasons = []struct {
+		name string
+		mask uint64
+	}{
+		{"gpu_idle", 0x1},
+		{"applications_clocks_setting", 0x2},
+		{"sw_power_cap", 0x4},
+		{"hw_slowdown", 0x8},
+		{"sync_boost", 0x10},
+		{"sw_thermal_slowdown", 0x20},
+		{"hw_thermal_slowdown", 0x40},
+		{"hw_power_brake_slowdown", 0x80},
+		{"display_clock_setting", 0x100},
+	}
+)
+
+// gpuStats are the statistics of a single GPU, only set if supported by it.
+type gpuStats struct {
+	gpu      string
+	vendor   string
+	name     string
+	uuid     string
+	metrics  map[string]float64
+	clocks   map[string]float64
+	ecc      map[string]float64
+	throttle *uint64
+}
+
+type gpuCollector struct {
+	info     *prometheus.Desc
+	metrics  map[string]typedDesc
+	clock    *prometheus.Desc
+	ecc      *prometheus.Desc
+	throttle *prometheus.Desc
+}
+
+func init() {
+	registerCollector(gpuSubsystem, defaultDisabled, NewGPUCollector)
+}
+
+// NewGPUCollector returns a new Collector exposing NVIDIA and AMD GPU statistics.
+func NewGPUCollector() (Collector, error) {
+	desc := func(name, help string, valueType prometheus.ValueType) typedDesc {
+		return typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, gpuSubsystem, name),
+			help, []string{"gpu"}, nil,
+		), valueType}
+	}
+	return &gpuCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, gpuSubsystem, "info"),
+			"Vendor, model and UUID of the GPU, value is always 1.",
+			[]string{"gpu", "vendor", "name", "uuid"}, nil,
+		),
+		metrics: map[string]typedDesc{
+			"utilization_ratio":   desc("utilization_ratio", "Fraction of time the GPU was busy.", prometheus.GaugeValue),
+			"memory_used_bytes":   desc("memory_used_bytes", "GPU memory in use.", prometheus.GaugeValue),
+			"memory_total_bytes":  desc("memory_total_bytes", "Total GPU memory.", prometheus.GaugeValue),
+			"temperature_celsius": desc("temperature_celsius", "Temperature of the GPU.", prometheus.GaugeValue),
+			"power_draw_watts":    desc("power_draw_watts", "Power draw of the GPU.", prometheus.GaugeValue),
+		},
+		clock: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, gpuSubsystem, "clock_hertz"),
+			"Current clock frequency of the GPU by clock domain.",
+			[]string{"gpu", "clock"}, nil,
+		),
+		ecc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, gpuSubsystem, "ecc_errors_total"),
+			"Number of ECC errors by type.",
+			[]string{"gpu", "type"}, nil,
+		),
+		throttle: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, gpuSubsystem, "clocks_throttle_reason"),
+			"Whether the GPU clocks are reduced for the reason, only exposed for NVIDIA GPUs.",
+			[]string{"gpu", "reason"}, nil,
+		),
+	}, nil
+}
+
+func (c *gpuCollector) Update(ch chan<- prometheus.Metric) error {
+	gpus, err := amdGPUStats()
+	if err != nil {
+		return err
+	}
+
+	if *gpuNvidiaSmiPath != "" {
+		nvidia, err := nvidiaGPUStats()
+		if err != nil {
+			return err
+		}
+		gpus = append(gpus, nvidia...)
+	}
+
+	for _, g := range gpus {
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, g.gpu, g.vendor, g.name, g.uuid)
+		for name, v := range g.metrics {
+			desc := c.metrics[name]
+			ch <- desc.mustNewConstMetric(v, g.gpu)
+		}
+		for clock, v := range g.clocks {
+			ch <- prometheus.MustNewConstMetric(c.clock, prometheus.GaugeValue, v, g.gpu, clock)
+		}
+		for typ, v := range g.ecc {
+			ch <- prometheus.MustNewConstMetric(c.ecc, prometheus.CounterValue, v, g.gpu, typ)
+		}
+		if g.throttle != nil {
+			for _, r := range gpuThrottleReasons {
+				v := 0.0
+				if *g.throttle&r.mask != 0 {
+					v = 1
+				}
+				ch <- prometheus.MustNewConstMetric(c.throttle, prometheus.GaugeValue, v, g.gpu, r.name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func nvidiaGPUStats() ([]gpuStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gpuCommandTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, *gpuNvidiaSmiPath, "--query-gpu="+gpuNvidiaQuery, "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s --query-gpu failed: %s", *gpuNvidiaSmiPath, err)
+	}
+	gpus, err := parseNvidiaSmi(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse nvidia-smi output: %s", err)
+	}
+	return gpus, nil
+}
+
+// parseNvidiaSmi parses the CSV output of nvidia-smi for gpuNvidiaQuery.
+// Fields not supported by a GPU are reported as "[N/A]" or "[Not Supported]"
+// and skipped.
+func parseNvidiaSmi(r io.Reader) ([]gpuStats, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = 13
+
+	var gpus []gpuStats
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		g := gpuStats{
+			gpu:     "nvidia" + record[0],
+			vendor:  "nvidia",
+			name:    record[1],
+			uuid:    record[2],
+			metrics: make(map[string]float64),
+			clocks:  make(map[string]float64),
+			ecc:     make(map[string]float64),
+		}
+		for i, field := range []struct {
+			set   map[string]float64
+			key   string
+			scale float64
+		}{
+			3:  {g.metrics, "utilization_ratio", 0.01},
+			4:  {g.metrics, "memory_used_bytes", 1024 * 1024},
+			5:  {g.metrics, "memory_total_bytes", 1024 * 1024},
+			6:  {g.metrics, "temperature_celsius", 1},
+			7:  {g.metrics, "power_draw_watts", 1},
+			8:  {g.clocks, "graphics", 1e6},
+			9:  {g.clocks, "memory", 1e6},
+			10: {g.ecc, "corrected", 1},
+			11: {g.ecc, "uncorrected", 1},
+		} {
+			if field.set == nil || strings.HasPrefix(record[i], "[") {
+				continue
+			}
+			v, err := strconv.ParseFloat(record[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q of GPU %s: %s", field.key, record[i], g.gpu, err)
+			}
+			field.set[field.key] = v * field.scale
+		}
+		if !strings.HasPrefix(record[12], "[") {
+			throttle, err := strconv.ParseUint(record[12], 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid throttle reasons %q of GPU %s: %s", record[12], g.gpu, err)
+			}
+			g.throttle = &throttle
+		}
+
+		gpus = append(gpus, g)
+	}
+	return gpus, nil
+}
+
+// amdGPUStats returns the statistics of the GPUs driven by amdgpu.
+func amdGPUStats() ([]gpuStats, error) {
+	cards, err := filepath.Glob(sysFilePath("class/drm/card[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var gpus []gpuStats
+	for _, card := range cards {
+		// Skip the connectors, e.g. card0-DP-1.
+		if strings.Contains(filepath.Base(card), "-") {
+			continue
+		}
+		device := filepath.Join(card, "device")
+		driver, err := os.Readlink(filepath.Join(device, "driver"))
+		if err != nil || filepath.Base(driver) != "amdgpu" {
+			continue
+		}
+		g, err := parseAMDGPU(filepath.Base(card), device)
+		if err != nil {
+			return nil, err
+		}
+		gpus = append(gpus, *g)
+	}
+	return gpus, nil
+}
+
+func parseAMDGPU(card, device string) (*gpuStats, error) {
+	g := &gpuStats{
+		gpu:     card,
+		vendor:  "amd",
+		metrics: make(map[string]float64),
+		clocks:  make(map[string]float64),
+		ecc:     make(map[string]float64),
+	}
+	// product_name and unique_id are only provided by recent kernels and
+	// GPUs.
+	g.name, _ = readTrimmedFile(filepath.Join(device, "product_name"))
+	g.uuid, _ = readTrimmedFile(filepath.Join(device, "unique_id"))
+
+	for file, metric := range map[string]struct {
+		key   string
+		scale float64
+	}{
+		"gpu_busy_percent":    {"utilization_ratio", 0.01},
+		"mem_info_vram_used":  {"memory_used_bytes", 1},
+		"mem_info_vram_total": {"memory_total_bytes", 1},
+	} {
+		v, err := readUintFromFile(filepath.Join(device, file))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("couldn't get %s of %s: %s", file, card, err)
+		}
+		g.metrics[metric.key] = float64(v) * metric.scale
+	}
+
+	hwmons, err := filepath.Glob(filepath.Join(device, "hwmon", "hwmon*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, hwmon := range hwmons {
+		if v, err := readUintFromFile(filepath.Join(hwmon, "temp1_input")); err == nil {
+			g.metrics["temperature_celsius"] = float64(v) / 1000
+		}
+		if v, err := readUintFromFile(filepath.Join(hwmon, "power1_average")); err == nil {
+			g.metrics["power_draw_watts"] = float64(v) / 1e6
+		}
+	}
+
+	for clock, file := range map[string]string{"graphics": "pp_dpm_sclk", "memory": "pp_dpm_mclk"} {
+		if v, ok := parseAMDGPUClock(filepath.Join(device, file)); ok {
+			g.clocks[clock] = v
+		}
+	}
+
+	// Each RAS block has its own counts, e.g. umc_err_count for the memory.
+	counts, err := filepath.Glob(filepath.Join(device, "ras", "*_err_count"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range counts {
+		ue, ce, err := parseAMDGPURASCount(file)
+		if err != nil {
+			log.Debugf("Skipping %s: %s", file, err)
+			continue
+		}
+		g.ecc["uncorrected"] += ue
+		g.ecc["corrected"] += ce
+	}
+
+	return g, nil
+}
+
+// parseAMDGPUClock returns the current level of a pp_dpm_* file, which lists
+// the levels as "1: 1000Mhz *" with the current one marked.
+func parseAMDGPUClock(file string) (float64, bool) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[2] != "*" {
+			continue
+		}
+		mhz, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(fields[1]), "mhz"), 64)
+		if err != nil {
+			return 0, false
+		}
+		return mhz * 1e6, true
+	}
+	return 0, false
+}
+
+// parseAMDGPURASCount parses the "ue: <n>" and "ce: <n>" lines of a RAS
+// error count file.
+func parseAMDGPURASCount(file string) (ue, ce float64, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		switch parts[0] {
+		case "ue":
+			ue = v
+		case "ce":
+			ce = v
+		}
+	}
+	return ue, ce, scanner.Err()
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseNvidiaSmi(t *testing.T) {
+	file, err := os.Open("fixtures/gpu/nvidia-smi.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gpus, err := parseNvidiaSmi(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 3, len(gpus); want != got {
+		t.Fatalf("want %d GPUs, got %d", want, got)
+	}
+
+	g := gpus[0]
+	if want, got := "nvidia0", g.gpu; want != got {
+		t.Errorf("want gpu %s, got %s", want, got)
+	}
+	if want, got := "Tesla V100-SXM2-16GB", g.name; want != got {
+		t.Errorf("want name %s, got %s", want, got)
+	}
+	wantMetrics := map[string]float64{
+		"utilization_ratio":   0.87,
+		"memory_used_bytes":   15232 * 1024 * 1024,
+		"memory_total_bytes":  16160 * 1024 * 1024,
+		"temperature_celsius": 71,
+		"power_draw_watts":    245.31,
+	}
+	if !reflect.DeepEqual(wantMetrics, g.metrics) {
+		t.Errorf("want metrics %v, got %v", wantMetrics, g.metrics)
+	}
+	if want, got := map[string]float64{"graphics": 1530e6, "memory": 877e6}, g.clocks; !reflect.DeepEqual(want, got) {
+		t.Errorf("want clocks %v, got %v", want, got)
+	}
+	if g.throttle == nil || *g.throttle != 0x4 {
+		t.Errorf("want throttle reasons 0x4, got %v", g.throttle)
+	}
+
+	// Unsupported fields are skipped.
+	g = gpus[2]
+	if _, ok := g.metrics["power_draw_watts"]; ok {
+		t.Errorf("want no power draw for %s", g.gpu)
+	}
+	if len(g.ecc) != 0 {
+		t.Errorf("want no ECC errors for %s, got %v", g.gpu, g.ecc)
+	}
+	if g.throttle != nil {
+		t.Errorf("want no throttle reasons for %s, got %d", g.gpu, *g.throttle)
+	}
+}
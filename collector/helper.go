@@ -30,3 +30,37 @@ func readUintFromFile(path string) (uint64, error) {
 	}
 	return value, nil
 }
+
+// readTrimmedFile returns the content of a file without surrounding whitespace.
+func readTrimmedFile(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	return strings.TrimSpace(string(content)), err
+}
+
+// parseCPUList parses a kernel CPU list like "0-3,8,10-11". Older kernels
+// print "(null)" for an empty nohz_full list.
+func parseCPUList(list string) (map[int]bool, error) {
+	cpus := make(map[int]bool)
+
+	list = strings.TrimSpace(list)
+	if list == "" || list == "(null)" {
+		return cpus, nil
+	}
+	for _, r := range strings.Split(list, ",") {
+		bounds := strings.SplitN(r, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, err
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus[cpu] = true
+		}
+	}
+	return cpus, nil
+}
@@ -0,0 +1,217 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nohwraid
+
+package collector
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const hwraidSubsystem = "hwraid"
+
+var (
+	// hwraidControllers are the sysfs attributes of the SCSI hosts of RAID
+	// controller drivers, an empty name if the driver doesn't have it.
+	hwraidControllers = map[string]struct {
+		firmware, outstanding, failed string
+	}{
+		"hpsa":         {"firmware_revision", "commands_outstanding", "lockup_detected"},
+		"megaraid_sas": {"", "fw_cmds_outstanding", "fw_crash_state"},
+		"mpt3sas":      {"version_fw", "", ""},
+		"smartpqi":     {"firmware_version", "", ""},
+	}
+
+	// hwraidVolumeStates are the states of the volumes of the RAID class.
+	hwraidVolumeStates = []string{"active", "degraded", "resyncing", "offline", "unknown"}
+)
+
+type hwraidCollector struct {
+	controllerInfoDesc   *prometheus.Desc
+	outstandingDesc      *prometheus.Desc
+	controllerFailedDesc *prometheus.Desc
+	volumeInfoDesc       *prometheus.Desc
+	volumeStateDesc      *prometheus.Desc
+	volumeResyncDesc     *prometheus.Desc
+}
+
+func init() {
+	registerCollector(hwraidSubsystem, defaultDisabled, NewHWRaidCollector)
+}
+
+// NewHWRaidCollector returns a new Collector exposing the state of hardware
+// RAID controllers and their volumes as far as their drivers expose it in
+// sysfs. The state of the logical and physical drives of MegaRAID and Smart
+// Array controllers is only available through their MFI and CISS passthrough
+// ioctls and isn't exposed.
+func NewHWRaidCollector() (Collector, error) {
+	return &hwraidCollector{
+		controllerInfoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, hwraidSubsystem, "controller_info"),
+			"RAID controller with its driver and firmware version, value is always 1.",
+			[]string{"host", "driver", "firmware_version"}, nil,
+		),
+		outstandingDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, hwraidSubsystem, "controller_commands_outstanding"),
+			"Number of commands the RAID controller is processing.",
+			[]string{"host"}, nil,
+		),
+		controllerFailedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, hwraidSubsystem, "controller_failed"),
+			"1 if the firmware of the RAID controller locked up or crashed, 0 otherwise.",
+			[]string{"host"}, nil,
+		),
+		volumeInfoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, hwraidSubsystem, "volume_info"),
+			"RAID volume with its RAID level, value is always 1.",
+			[]string{"device", "level"}, nil,
+		),
+		volumeStateDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, hwraidSubsystem, "volume_state"),
+			"States of the RAID volume, 1 for the current one and 0 for the others.",
+			[]string{"device", "state"}, nil,
+		),
+		volumeResyncDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, hwraidSubsystem, "volume_resync_ratio"),
+			"Progress of the resynchronization of the RAID volume.",
+			[]string{"device"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *hwraidCollector) Update(ch chan<- prometheus.Metric) error {
+	if err := c.updateControllers(ch); err != nil {
+		return err
+	}
+	if err := c.updateHPSAVolumes(ch); err != nil {
+		return err
+	}
+	return c.updateRaidClass(ch)
+}
+
+func (c *hwraidCollector) updateControllers(ch chan<- prometheus.Metric) error {
+	hosts, err := filepath.Glob(sysFilePath("class/scsi_host/host*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range hosts {
+		host := filepath.Base(dir)
+		driver, err := readTrimmedFile(filepath.Join(dir, "proc_name"))
+		if err != nil {
+			log.Debugf("Couldn't get driver of %s: %s", host, err)
+			continue
+		}
+		attrs, ok := hwraidControllers[driver]
+		if !ok {
+			continue
+		}
+
+		var firmware string
+		if attrs.firmware != "" {
+			if firmware, err = readTrimmedFile(filepath.Join(dir, attrs.firmware)); err != nil {
+				return fmt.Errorf("couldn't get firmware version of %s: %s", host, err)
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(c.controllerInfoDesc, prometheus.GaugeValue, 1, host, driver, firmware)
+
+		if attrs.outstanding != "" {
+			outstanding, err := readUintFromFile(filepath.Join(dir, attrs.outstanding))
+			if err != nil {
+				return fmt.Errorf("couldn't get outstanding commands of %s: %s", host, err)
+			}
+			ch <- prometheus.MustNewConstMetric(c.outstandingDesc, prometheus.GaugeValue, float64(outstanding), host)
+		}
+		if attrs.failed != "" {
+			state, err := readUintFromFile(filepath.Join(dir, attrs.failed))
+			if err != nil {
+				return fmt.Errorf("couldn't get firmware state of %s: %s", host, err)
+			}
+			failed := 0.0
+			if state != 0 {
+				failed = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.controllerFailedDesc, prometheus.GaugeValue, failed, host)
+		}
+	}
+	return nil
+}
+
+// updateHPSAVolumes exposes the RAID level of the logical drives of HP Smart
+// Array controllers, whose physical drives have the level N/A.
+func (c *hwraidCollector) updateHPSAVolumes(ch chan<- prometheus.Metric) error {
+	levels, err := filepath.Glob(sysFilePath("class/scsi_device/*/device/raid_level"))
+	if err != nil {
+		return err
+	}
+	for _, file := range levels {
+		level, err := readTrimmedFile(file)
+		if err != nil {
+			return fmt.Errorf("couldn't get RAID level: %s", err)
+		}
+		if level == "N/A" {
+			continue
+		}
+		device := filepath.Base(filepath.Dir(filepath.Dir(file)))
+		ch <- prometheus.MustNewConstMetric(c.volumeInfoDesc, prometheus.GaugeValue, 1, device, level)
+	}
+	return nil
+}
+
+// updateRaidClass exposes the volumes registered with the RAID class by the
+// drivers supporting it, e.g. mptspi for integrated RAID.
+func (c *hwraidCollector) updateRaidClass(ch chan<- prometheus.Metric) error {
+	volumes, err := filepath.Glob(sysFilePath("class/raid_devices/*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range volumes {
+		device := filepath.Base(dir)
+		if level, err := readTrimmedFile(filepath.Join(dir, "level")); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.volumeInfoDesc, prometheus.GaugeValue, 1, device, level)
+		}
+
+		state, err := readTrimmedFile(filepath.Join(dir, "state"))
+		if err != nil {
+			log.Debugf("Couldn't get state of RAID volume %s: %s", device, err)
+			continue
+		}
+		for _, s := range hwraidVolumeStates {
+			v := 0.0
+			if s == state {
+				v = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.volumeStateDesc, prometheus.GaugeValue, v, device, s)
+		}
+
+		// The resync progress is only known by some drivers, as a
+		// percentage like "42%".
+		resync, err := readTrimmedFile(filepath.Join(dir, "resync"))
+		if err != nil {
+			continue
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(resync, "%"), 64)
+		if err != nil {
+			return fmt.Errorf("invalid resync progress %q of RAID volume %s", resync, device)
+		}
+		ch <- prometheus.MustNewConstMetric(c.volumeResyncDesc, prometheus.GaugeValue, percent/100, device)
+	}
+	return nil
+}
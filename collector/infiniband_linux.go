@@ -11,22 +11,37 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// +build linux
-// +build !noinfiniband
+// +build linux,!noinfiniband
 
 package collector
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
 	"github.com/prometheus/procfs/sysfs"
 )
 
+// infinibandErrorCounters maps port error counters not parsed by procfs to
+// their metric names.
+var infinibandErrorCounters = map[string]string{
+	"excessive_buffer_overrun_errors": "excessive_buffer_overrun_errors_total",
+	"local_link_integrity_errors":     "local_link_integrity_errors_total",
+	"port_rcv_remote_physical_errors": "port_remote_physical_errors_received_total",
+	"port_rcv_switch_relay_errors":    "port_switch_relay_errors_received_total",
+	"symbol_error":                    "symbol_errors_total",
+	"VL15_dropped":                    "vl15_dropped_total",
+}
+
 type infinibandCollector struct {
-	fs          sysfs.FS
-	metricDescs map[string]*prometheus.Desc
+	fs            sysfs.FS
+	metricDescs   map[string]*prometheus.Desc
+	hwCounterDesc *prometheus.Desc
 }
 
 func init() {
@@ -53,8 +68,10 @@ func NewInfiniBandCollector() (Collector, error) {
 		"legacy_unicast_packets_transmitted_total":   "Number of unicast packets transmitted",
 		"legacy_data_transmitted_bytes_total":        "Number of data octets transmitted on all links",
 		"legacy_packets_transmitted_total":           "Number of data packets received on all links",
+		"excessive_buffer_overrun_errors_total":      "Number of times that OverrunErrors consecutive flow control update periods occurred",
 		"link_downed_total":                          "Number of times the link failed to recover from an error state and went down",
 		"link_error_recovery_total":                  "Number of times the link successfully recovered from an error state",
+		"local_link_integrity_errors_total":          "Number of times that the count of local physical errors exceeded the threshold",
 		"multicast_packets_received_total":           "Number of multicast packets received (including errors)",
 		"multicast_packets_transmitted_total":        "Number of multicast packets transmitted (including errors)",
 		"port_constraint_errors_received_total":      "Number of packets received on the switch physical port that are discarded",
@@ -66,9 +83,13 @@ func NewInfiniBandCollector() (Collector, error) {
 		"port_errors_received_total":                 "Number of packets containing an error that were received on this port",
 		"port_packets_received_total":                "Number of packets received on all VLs by this port (including errors)",
 		"port_packets_transmitted_total":             "Number of packets transmitted on all VLs from this port (including errors)",
+		"port_remote_physical_errors_received_total": "Number of packets marked with the EBP delimiter received on this port",
+		"port_switch_relay_errors_received_total":    "Number of packets received on this port that were discarded because they could not be forwarded by the switch relay",
 		"port_transmit_wait_total":                   "Number of ticks during which the port had data to transmit but no data was sent during the entire tick",
+		"symbol_errors_total":                        "Number of minor link errors detected on one or more physical lanes",
 		"unicast_packets_received_total":             "Number of unicast packets received (including errors)",
 		"unicast_packets_transmitted_total":          "Number of unicast packets transmitted (including errors)",
+		"vl15_dropped_total":                         "Number of incoming VL15 packets dropped due to resource limitations",
 	}
 
 	i.metricDescs = make(map[string]*prometheus.Desc)
@@ -82,6 +103,13 @@ func NewInfiniBandCollector() (Collector, error) {
 		)
 	}
 
+	i.hwCounterDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "infiniband", "hw_counter_total"),
+		"RDMA hardware counter of the port from hw_counters, e.g. out_of_sequence or the RoCE congestion counters",
+		[]string{"device", "port", "counter"},
+		nil,
+	)
+
 	return &i, nil
 }
 
@@ -129,7 +157,55 @@ func (c *infinibandCollector) Update(ch chan<- prometheus.Metric) error {
 			c.pushCounter(ch, "port_transmit_wait_total", port.Counters.PortXmitWait, port.Name, portStr)
 			c.pushCounter(ch, "unicast_packets_received_total", port.Counters.UnicastRcvPackets, port.Name, portStr)
 			c.pushCounter(ch, "unicast_packets_transmitted_total", port.Counters.UnicastXmitPackets, port.Name, portStr)
+
+			portPath := sysFilePath(filepath.Join("class/infiniband", port.Name, "ports", portStr))
+			c.updateErrorCounters(ch, portPath, port.Name, portStr)
+			if err := c.updateHWCounters(ch, portPath, port.Name, portStr); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// updateErrorCounters exposes the port error counters not parsed by procfs.
+// Devices without a performance management agent report "N/A (no PMA)",
+// those are skipped.
+func (c *infinibandCollector) updateErrorCounters(ch chan<- prometheus.Metric, portPath, deviceName, port string) {
+	for file, name := range infinibandErrorCounters {
+		value, err := readUintFromFile(filepath.Join(portPath, "counters", file))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Debugf("Skipping InfiniBand counter %s of %s port %s: %s", file, deviceName, port, err)
+			}
+			continue
+		}
+		c.pushMetric(ch, name, value, deviceName, port, prometheus.CounterValue)
+	}
+}
+
+// updateHWCounters exposes the driver specific RDMA counters, which are also
+// available for RoCE devices.
+func (c *infinibandCollector) updateHWCounters(ch chan<- prometheus.Metric, portPath, deviceName, port string) error {
+	files, err := ioutil.ReadDir(filepath.Join(portPath, "hw_counters"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, f := range files {
+		// lifespan is the update interval of the counters in milliseconds.
+		if f.IsDir() || f.Name() == "lifespan" {
+			continue
+		}
+		value, err := readUintFromFile(filepath.Join(portPath, "hw_counters", f.Name()))
+		if err != nil {
+			return fmt.Errorf("couldn't get hw counter %s of %s port %s: %s", f.Name(), deviceName, port, err)
 		}
+		ch <- prometheus.MustNewConstMetric(c.hwCounterDesc, prometheus.CounterValue, float64(value), deviceName, port, f.Name())
 	}
 
 	return nil
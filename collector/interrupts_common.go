@@ -16,10 +16,21 @@
 
 package collector
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var interruptsPerCPUInclude = kingpin.Flag("collector.interrupts.per-cpu-include", "Regexp of interrupt types or devices to expose per CPU, all others are summed over all CPUs.").Default(".*").String()
 
 type interruptsCollector struct {
-	desc typedDesc
+	desc          typedDesc
+	allCPUs       typedDesc
+	softirqs      typedDesc
+	perCPUPattern *regexp.Regexp
 }
 
 func init() {
@@ -28,11 +39,26 @@ func init() {
 
 // NewInterruptsCollector returns a new Collector exposing interrupts stats.
 func NewInterruptsCollector() (Collector, error) {
+	pattern, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", *interruptsPerCPUInclude))
+	if err != nil {
+		return nil, fmt.Errorf("invalid per-cpu-include pattern: %s", err)
+	}
 	return &interruptsCollector{
 		desc: typedDesc{prometheus.NewDesc(
 			namespace+"_interrupts_total",
 			"Interrupt details.",
 			interruptLabelNames, nil,
 		), prometheus.CounterValue},
+		allCPUs: typedDesc{prometheus.NewDesc(
+			namespace+"_interrupts_all_cpus_total",
+			"Interrupt details summed over all CPUs, for interrupts not matching --collector.interrupts.per-cpu-include.",
+			interruptLabelNames[1:], nil,
+		), prometheus.CounterValue},
+		softirqs: typedDesc{prometheus.NewDesc(
+			namespace+"_interrupts_softirqs_total",
+			"Number of softirqs handled by type.",
+			[]string{"cpu", "type"}, nil,
+		), prometheus.CounterValue},
+		perCPUPattern: pattern,
 	}, nil
 }
@@ -25,6 +25,7 @@ import (
 	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
 )
 
 var (
@@ -37,15 +38,80 @@ func (c *interruptsCollector) Update(ch chan<- prometheus.Metric) (err error) {
 		return fmt.Errorf("couldn't get interrupts: %s", err)
 	}
 	for name, interrupt := range interrupts {
+		perCPU := c.perCPUPattern.MatchString(name) ||
+			(interrupt.devices != "" && c.perCPUPattern.MatchString(interrupt.devices))
+
+		var sum float64
 		for cpuNo, value := range interrupt.values {
 			fv, err := strconv.ParseFloat(value, 64)
 			if err != nil {
 				return fmt.Errorf("invalid value %s in interrupts: %s", value, err)
 			}
-			ch <- c.desc.mustNewConstMetric(fv, strconv.Itoa(cpuNo), name, interrupt.info, interrupt.devices)
+			if perCPU {
+				ch <- c.desc.mustNewConstMetric(fv, strconv.Itoa(cpuNo), name, interrupt.info, interrupt.devices)
+			}
+			sum += fv
+		}
+		if !perCPU {
+			ch <- c.allCPUs.mustNewConstMetric(sum, name, interrupt.info, interrupt.devices)
+		}
+	}
+
+	return c.updateSoftirqs(ch)
+}
+
+func (c *interruptsCollector) updateSoftirqs(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("softirqs"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("Not collecting softirqs: %s", err)
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	softirqs, err := parseSoftirqs(file)
+	if err != nil {
+		return fmt.Errorf("couldn't get softirqs: %s", err)
+	}
+	for softirq, values := range softirqs {
+		for cpuNo, value := range values {
+			ch <- c.softirqs.mustNewConstMetric(value, strconv.Itoa(cpuNo), softirq)
+		}
+	}
+	return nil
+}
+
+// parseSoftirqs parses /proc/softirqs into the per CPU counts of each softirq type.
+func parseSoftirqs(r io.Reader) (map[string][]float64, error) {
+	var (
+		softirqs = map[string][]float64{}
+		scanner  = bufio.NewScanner(r)
+	)
+
+	if !scanner.Scan() {
+		return nil, errors.New("softirqs empty")
+	}
+	cpuNum := len(strings.Fields(scanner.Text())) // one header per cpu
+
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) != cpuNum+1 {
+			return nil, fmt.Errorf("invalid line %q", scanner.Text())
+		}
+		values := make([]float64, cpuNum)
+		for i, value := range parts[1:] {
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %s in softirqs: %s", value, err)
+			}
+			values[i] = v
 		}
+		softirqs[strings.TrimSuffix(parts[0], ":")] = values
 	}
-	return err
+
+	return softirqs, scanner.Err()
 }
 
 type interrupt struct {
@@ -38,3 +38,24 @@ func TestInterrupts(t *testing.T) {
 		t.Errorf("want interrupts %s, got %s", want, got)
 	}
 }
+
+func TestSoftirqs(t *testing.T) {
+	file, err := os.Open("fixtures/proc/softirqs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	softirqs, err := parseSoftirqs(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 10, len(softirqs); want != got {
+		t.Errorf("want %d softirq types, got %d", want, got)
+	}
+
+	if want, got := 50313.0, softirqs["NET_RX"][1]; want != got {
+		t.Errorf("want softirqs %f, got %f", want, got)
+	}
+}
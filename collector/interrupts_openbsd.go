@@ -104,11 +104,20 @@ func (c *interruptsCollector) Update(ch chan<- prometheus.Metric) error {
 		return fmt.Errorf("couldn't get interrupts: %s", err)
 	}
 	for dev, interrupt := range interrupts {
+		vector := fmt.Sprintf("%d", interrupt.vector)
+		if !c.perCPUPattern.MatchString(vector) && !c.perCPUPattern.MatchString(dev) {
+			var sum float64
+			for _, value := range interrupt.values {
+				sum += value
+			}
+			ch <- c.allCPUs.mustNewConstMetric(sum, vector, dev)
+			continue
+		}
 		for cpuNo, value := range interrupt.values {
 			ch <- c.desc.mustNewConstMetric(
 				value,
 				strconv.Itoa(cpuNo),
-				fmt.Sprintf("%d", interrupt.vector),
+				vector,
 				dev,
 			)
 		}
@@ -16,20 +16,52 @@
 package collector
 
 import (
+	"bufio"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 	"github.com/prometheus/procfs"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	fullIpvsBackendLabels = []string{
+		"local_address",
+		"local_port",
+		"remote_address",
+		"remote_port",
+		"proto",
+		"local_mark",
+	}
+	ipvsLabels = kingpin.Flag("collector.ipvs.backend-labels", "Comma separated list of labels of the backend metrics, the metrics are summed up over the omitted labels, e.g. local_address,local_port,proto to aggregate the backends of each virtual service.").Default(strings.Join(fullIpvsBackendLabels, ",")).String()
 )
 
 type ipvsCollector struct {
 	Collector
 	fs                                                                          procfs.FS
+	backendLabels                                                               []string
 	backendConnectionsActive, backendConnectionsInact, backendWeight            typedDesc
 	connections, incomingPackets, outgoingPackets, incomingBytes, outgoingBytes typedDesc
+	serviceInfo                                                                 typedDesc
+}
+
+// ipvsService is a virtual service of /proc/net/ip_vs.
+type ipvsService struct {
+	localAddress, localPort, proto, localMark, scheduler string
+}
+
+type ipvsBackendStatus struct {
+	activeConn uint64
+	inactConn  uint64
+	weight     uint64
 }
 
 func init() {
@@ -44,19 +76,15 @@ func NewIPVSCollector() (Collector, error) {
 
 func newIPVSCollector() (*ipvsCollector, error) {
 	var (
-		ipvsBackendLabelNames = []string{
-			"local_address",
-			"local_port",
-			"remote_address",
-			"remote_port",
-			"proto",
-			"local_mark",
-		}
 		c         ipvsCollector
 		err       error
 		subsystem = "ipvs"
 	)
 
+	if c.backendLabels, err = c.parseIpvsLabels(*ipvsLabels); err != nil {
+		return nil, err
+	}
+
 	c.fs, err = procfs.NewFS(*procPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open procfs: %v", err)
@@ -90,17 +118,22 @@ func newIPVSCollector() (*ipvsCollector, error) {
 	c.backendConnectionsActive = typedDesc{prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, subsystem, "backend_connections_active"),
 		"The current active connections by local and remote address.",
-		ipvsBackendLabelNames, nil,
+		c.backendLabels, nil,
 	), prometheus.GaugeValue}
 	c.backendConnectionsInact = typedDesc{prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, subsystem, "backend_connections_inactive"),
 		"The current inactive connections by local and remote address.",
-		ipvsBackendLabelNames, nil,
+		c.backendLabels, nil,
 	), prometheus.GaugeValue}
 	c.backendWeight = typedDesc{prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, subsystem, "backend_weight"),
 		"The current backend weight by local and remote address.",
-		ipvsBackendLabelNames, nil,
+		c.backendLabels, nil,
+	), prometheus.GaugeValue}
+	c.serviceInfo = typedDesc{prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "service_info"),
+		"Scheduler of the virtual service, value is always 1.",
+		[]string{"local_address", "local_port", "proto", "local_mark", "scheduler"}, nil,
 	), prometheus.GaugeValue}
 
 	return &c, nil
@@ -127,22 +160,148 @@ func (c *ipvsCollector) Update(ch chan<- prometheus.Metric) error {
 		return fmt.Errorf("could not get backend status: %s", err)
 	}
 
+	sums := map[string]ipvsBackendStatus{}
+	labelValues := map[string][]string{}
 	for _, backend := range backendStats {
 		localAddress := ""
 		if backend.LocalAddress.String() != "<nil>" {
 			localAddress = backend.LocalAddress.String()
 		}
-		labelValues := []string{
-			localAddress,
-			strconv.FormatUint(uint64(backend.LocalPort), 10),
-			backend.RemoteAddress.String(),
-			strconv.FormatUint(uint64(backend.RemotePort), 10),
-			backend.Proto,
-			backend.LocalMark,
+		kv := make([]string, len(c.backendLabels))
+		for i, label := range c.backendLabels {
+			var labelValue string
+			switch label {
+			case "local_address":
+				labelValue = localAddress
+			case "local_port":
+				labelValue = strconv.FormatUint(uint64(backend.LocalPort), 10)
+			case "remote_address":
+				labelValue = backend.RemoteAddress.String()
+			case "remote_port":
+				labelValue = strconv.FormatUint(uint64(backend.RemotePort), 10)
+			case "proto":
+				labelValue = backend.Proto
+			case "local_mark":
+				labelValue = backend.LocalMark
+			}
+			kv[i] = labelValue
 		}
-		ch <- c.backendConnectionsActive.mustNewConstMetric(float64(backend.ActiveConn), labelValues...)
-		ch <- c.backendConnectionsInact.mustNewConstMetric(float64(backend.InactConn), labelValues...)
-		ch <- c.backendWeight.mustNewConstMetric(float64(backend.Weight), labelValues...)
+		key := strings.Join(kv, "-")
+		status := sums[key]
+		status.activeConn += backend.ActiveConn
+		status.inactConn += backend.InactConn
+		status.weight += backend.Weight
+		sums[key] = status
+		labelValues[key] = kv
+	}
+	for key, status := range sums {
+		kv := labelValues[key]
+		ch <- c.backendConnectionsActive.mustNewConstMetric(float64(status.activeConn), kv...)
+		ch <- c.backendConnectionsInact.mustNewConstMetric(float64(status.inactConn), kv...)
+		ch <- c.backendWeight.mustNewConstMetric(float64(status.weight), kv...)
+	}
+
+	return c.updateServices(ch)
+}
+
+func (c *ipvsCollector) updateServices(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("net/ip_vs"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	services, err := parseIPVSServices(file)
+	if err != nil {
+		return fmt.Errorf("could not get virtual services: %s", err)
+	}
+	for _, s := range services {
+		ch <- c.serviceInfo.mustNewConstMetric(1, s.localAddress, s.localPort, s.proto, s.localMark, s.scheduler)
 	}
 	return nil
 }
+
+func (c *ipvsCollector) parseIpvsLabels(labelString string) ([]string, error) {
+	labels := strings.Split(labelString, ",")
+	labelSet := make(map[string]bool, len(labels))
+	results := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if label != "" {
+			labelSet[label] = true
+		}
+	}
+
+	for _, label := range fullIpvsBackendLabels {
+		if labelSet[label] {
+			results = append(results, label)
+		}
+		delete(labelSet, label)
+	}
+
+	if len(labelSet) > 0 {
+		keys := make([]string, 0, len(labelSet))
+		for label := range labelSet {
+			keys = append(keys, label)
+		}
+		sort.Strings(keys)
+		return nil, fmt.Errorf("unknown IPVS backend labels: %q", strings.Join(keys, ", "))
+	}
+
+	return results, nil
+}
+
+// parseIPVSServices parses the virtual service lines of /proc/net/ip_vs,
+// which procfs only reports together with each backend.
+func parseIPVSServices(r io.Reader) ([]ipvsService, error) {
+	var (
+		services []ipvsService
+		scanner  = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) < 3 || strings.HasPrefix(line, " ") {
+			continue
+		}
+		switch fields[0] {
+		case "TCP", "UDP", "SCTP":
+			address, port, err := parseIPVSAddressPort(fields[1])
+			if err != nil {
+				return nil, err
+			}
+			services = append(services, ipvsService{address, port, fields[0], "", fields[2]})
+		case "FWM":
+			// The mark is kept as is, like the backend metrics do.
+			services = append(services, ipvsService{"", "0", fields[0], fields[1], fields[2]})
+		}
+	}
+
+	return services, scanner.Err()
+}
+
+// parseIPVSAddressPort parses an "<address>:<port>" pair, with the IPv4
+// address in hex and the IPv6 address in brackets, and the port in hex.
+func parseIPVSAddressPort(s string) (string, string, error) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return "", "", fmt.Errorf("invalid address %q", s)
+	}
+	port, err := strconv.ParseUint(s[i+1:], 16, 16)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid port in %q: %s", s, err)
+	}
+
+	address := s[:i]
+	if strings.HasPrefix(address, "[") {
+		ip := net.ParseIP(strings.Trim(address, "[]"))
+		if ip == nil {
+			return "", "", fmt.Errorf("invalid address %q", s)
+		}
+		return ip.String(), strconv.FormatUint(port, 10), nil
+	}
+	ip, err := hex.DecodeString(address)
+	if err != nil || len(ip) != net.IPv4len {
+		return "", "", fmt.Errorf("invalid address %q", s)
+	}
+	return net.IP(ip).String(), strconv.FormatUint(port, 10), nil
+}
@@ -18,11 +18,13 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -117,3 +119,76 @@ wantLoop:
 		t.Fatalf("Missing expected output line(s), first missing line is %s", want)
 	}
 }
+
+func TestIPVSCollectorBackendLabels(t *testing.T) {
+	if _, err := kingpin.CommandLine.Parse([]string{"--path.procfs", "fixtures/proc", "--collector.ipvs.backend-labels", "local_address,local_port,proto"}); err != nil {
+		t.Fatal(err)
+	}
+	defer kingpin.CommandLine.Parse([]string{})
+
+	collector, err := newIPVSCollector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := []string{"local_address", "local_port", "proto"}, collector.backendLabels; !reflect.DeepEqual(want, got) {
+		t.Fatalf("want labels %v, got %v", want, got)
+	}
+
+	ch := make(chan prometheus.Metric, 100)
+	if err := collector.Update(ch); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	active := map[string]float64{}
+	for m := range ch {
+		if m.Desc() != collector.backendConnectionsActive.desc {
+			continue
+		}
+		var metric dto.Metric
+		if err := m.Write(&metric); err != nil {
+			t.Fatal(err)
+		}
+		labels := make([]string, 0, len(metric.Label))
+		for _, l := range metric.Label {
+			labels = append(labels, l.GetValue())
+		}
+		active[strings.Join(labels, ",")] = metric.GetGauge().GetValue()
+	}
+	for labels, want := range map[string]float64{
+		"192.168.0.22,3306,TCP": 744,
+		"192.168.0.57,3306,TCP": 2997,
+		",0,FWM":                385,
+	} {
+		if got := active[labels]; want != got {
+			t.Errorf("want %f active connections for %s, got %f", want, labels, got)
+		}
+	}
+
+	if _, err := collector.parseIpvsLabels("local_address,unknown"); err == nil {
+		t.Error("expected error for unknown label")
+	}
+}
+
+func TestParseIPVSServices(t *testing.T) {
+	services, err := parseIPVSServices(strings.NewReader(`IP Virtual Server version 1.2.1 (size=4096)
+Prot LocalAddress:Port Scheduler Flags
+  -> RemoteAddress:Port Forward Weight ActiveConn InActConn
+TCP  C0A80016:0CEA wlc
+  -> C0A85216:0CEA      Tunnel  100    248        2
+UDP  [2620:0000:0000:0000:0000:0000:0000:0001]:0035 rr
+FWM  10001000 sh
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ipvsService{
+		{"192.168.0.22", "3306", "TCP", "", "wlc"},
+		{"2620::1", "53", "UDP", "", "rr"},
+		{"", "0", "FWM", "10001000", "sh"},
+	}
+	if !reflect.DeepEqual(want, services) {
+		t.Errorf("want services %v, got %v", want, services)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noirqaffinity
+
+package collector
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const irqAffinitySubsystem = "irq_affinity"
+
+var irqAffinityInclude = kingpin.Flag("collector.irqaffinity.include", "Regexp of IRQ numbers or devices to expose the affinity of.").Default(".+").String()
+
+type irqAffinityCollector struct {
+	infoDesc     *prometheus.Desc
+	checksumDesc *prometheus.Desc
+	cpuIRQsDesc  *prometheus.Desc
+	pattern      *regexp.Regexp
+}
+
+type irqAffinity struct {
+	irq       string
+	devices   string
+	affinity  string
+	effective string
+}
+
+func init() {
+	registerCollector("irqaffinity", defaultDisabled, NewIRQAffinityCollector)
+}
+
+// NewIRQAffinityCollector returns a new Collector exposing the CPUs the
+// selected IRQs are routed to.
+func NewIRQAffinityCollector() (Collector, error) {
+	pattern, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", *irqAffinityInclude))
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern: %s", err)
+	}
+	return &irqAffinityCollector{
+		infoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, irqAffinitySubsystem, "info"),
+			"IRQ with its devices, the CPUs it may be routed to and the CPUs it is actually routed to, value is always 1.",
+			[]string{"irq", "devices", "affinity", "effective_affinity"}, nil,
+		),
+		checksumDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, irqAffinitySubsystem, "checksum"),
+			"Checksum of the effective affinity of all selected IRQs, which changes whenever one of them moves to other CPUs.",
+			nil, nil,
+		),
+		cpuIRQsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, irqAffinitySubsystem, "cpu_irqs"),
+			"Number of selected IRQs routed to the CPU.",
+			[]string{"cpu"}, nil,
+		),
+		pattern: pattern,
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *irqAffinityCollector) Update(ch chan<- prometheus.Metric) error {
+	affinities, err := getIRQAffinities()
+	if err != nil {
+		return fmt.Errorf("couldn't get IRQ affinities: %s", err)
+	}
+
+	var (
+		checksum = fnv.New32a()
+		cpuIRQs  = map[int]float64{}
+	)
+	for _, a := range affinities {
+		if !c.pattern.MatchString(a.irq) && (a.devices == "" || !c.pattern.MatchString(a.devices)) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.infoDesc, prometheus.GaugeValue, 1, a.irq, a.devices, a.affinity, a.effective)
+		fmt.Fprintf(checksum, "%s:%s\n", a.irq, a.effective)
+
+		cpus, err := parseCPUList(a.effective)
+		if err != nil {
+			return fmt.Errorf("invalid effective affinity %q of IRQ %s: %s", a.effective, a.irq, err)
+		}
+		for cpu := range cpus {
+			cpuIRQs[cpu]++
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.checksumDesc, prometheus.GaugeValue, float64(checksum.Sum32()))
+
+	// CPUs without any of the selected IRQs are exposed as well, so that
+	// moving the last IRQ away from a CPU is visible.
+	cpus, err := filepath.Glob(sysFilePath("devices/system/cpu/cpu[0-9]*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range cpus {
+		cpu, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "cpu"))
+		if err != nil {
+			continue
+		}
+		if _, ok := cpuIRQs[cpu]; !ok {
+			cpuIRQs[cpu] = 0
+		}
+	}
+	for cpu, irqs := range cpuIRQs {
+		ch <- prometheus.MustNewConstMetric(c.cpuIRQsDesc, prometheus.GaugeValue, irqs, strconv.Itoa(cpu))
+	}
+	return nil
+}
+
+// getIRQAffinities returns the affinities of all IRQs sorted by number. The
+// devices of an IRQ are the names of the directories of its handlers.
+func getIRQAffinities() ([]irqAffinity, error) {
+	dirs, err := ioutil.ReadDir(procFilePath("irq"))
+	if err != nil {
+		return nil, err
+	}
+
+	var affinities []irqAffinity
+	for _, dir := range dirs {
+		if _, err := strconv.Atoi(dir.Name()); !dir.IsDir() || err != nil {
+			continue
+		}
+		path := procFilePath(filepath.Join("irq", dir.Name()))
+
+		affinity, err := readTrimmedFile(filepath.Join(path, "smp_affinity_list"))
+		if err != nil {
+			// IRQs without a handler may disappear while iterating.
+			log.Debugf("Couldn't get affinity of IRQ %s: %s", dir.Name(), err)
+			continue
+		}
+		// The effective affinity is only exposed since Linux 4.15 and not
+		// by all interrupt controllers.
+		effective, err := readTrimmedFile(filepath.Join(path, "effective_affinity_list"))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+			effective = affinity
+		}
+
+		handlers, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		var devices []string
+		for _, h := range handlers {
+			if h.IsDir() {
+				devices = append(devices, h.Name())
+			}
+		}
+
+		affinities = append(affinities, irqAffinity{
+			irq:       dir.Name(),
+			devices:   strings.Join(devices, " "),
+			affinity:  affinity,
+			effective: effective,
+		})
+	}
+
+	sort.Slice(affinities, func(i, j int) bool {
+		a, _ := strconv.Atoi(affinities[i].irq)
+		b, _ := strconv.Atoi(affinities[j].irq)
+		return a < b
+	})
+	return affinities, nil
+}
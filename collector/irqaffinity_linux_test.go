@@ -0,0 +1,43 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noirqaffinity
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+func TestGetIRQAffinities(t *testing.T) {
+	if _, err := kingpin.CommandLine.Parse([]string{"--path.procfs", "fixtures/proc"}); err != nil {
+		t.Fatal(err)
+	}
+	affinities, err := getIRQAffinities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []irqAffinity{
+		{irq: "0", devices: "timer", affinity: "0-3", effective: "0"},
+		{irq: "1", devices: "i8042", affinity: "0-3", effective: "0-3"},
+		{irq: "9", devices: "acpi", affinity: "0-3", effective: "1"},
+		{irq: "24", devices: "ahci", affinity: "2", effective: "2"},
+		{irq: "25", devices: "eth0-rx-0 eth0-tx-0", affinity: "0-1", effective: "1"},
+	}
+	if !reflect.DeepEqual(affinities, want) {
+		t.Errorf("want %v, got %v", want, affinities)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noiscsi
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	iscsiSubsystem      = "iscsi"
+	iscsiCommandTimeout = 10 * time.Second
+)
+
+var (
+	iscsiadmPath = kingpin.Flag("collector.iscsi.iscsiadm-path", "Path to the iscsiadm command used to collect session traffic and error statistics. Disabled if empty.").Default("").String()
+
+	iscsiSessionStates = []string{"logged_in", "failed", "free"}
+	iscsiStatsHeaderRE = regexp.MustCompile(`^Stats for session \[sid: (\d+),`)
+)
+
+type iscsiCollector struct {
+	info          *prometheus.Desc
+	state         *prometheus.Desc
+	txBytes       *prometheus.Desc
+	rxBytes       *prometheus.Desc
+	commands      *prometheus.Desc
+	digestErrors  *prometheus.Desc
+	timeoutErrors *prometheus.Desc
+}
+
+func init() {
+	registerCollector(iscsiSubsystem, defaultDisabled, NewISCSICollector)
+}
+
+// NewISCSICollector returns a new Collector exposing iSCSI initiator session statistics.
+func NewISCSICollector() (Collector, error) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, iscsiSubsystem, name),
+			help, []string{"session"}, nil,
+		)
+	}
+	return &iscsiCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, iscsiSubsystem, "session_info"),
+			"Target of the iSCSI session.",
+			[]string{"session", "target", "tpgt"}, nil,
+		),
+		state: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, iscsiSubsystem, "session_state"),
+			"State of the iSCSI session.",
+			[]string{"session", "state"}, nil,
+		),
+		txBytes:       desc("session_transmit_bytes_total", "Number of data bytes transmitted by the session."),
+		rxBytes:       desc("session_receive_bytes_total", "Number of data bytes received by the session."),
+		commands:      desc("session_commands_total", "Number of SCSI command PDUs sent by the session."),
+		digestErrors:  desc("session_digest_errors_total", "Number of PDUs received with a header or data digest error."),
+		timeoutErrors: desc("session_timeout_errors_total", "Number of connection timeouts of the session."),
+	}, nil
+}
+
+func (c *iscsiCollector) Update(ch chan<- prometheus.Metric) error {
+	sessions, err := filepath.Glob(sysFilePath("class/iscsi_session/session*"))
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range sessions {
+		session := filepath.Base(dir)
+
+		state, err := ioutil.ReadFile(filepath.Join(dir, "state"))
+		if err != nil {
+			return fmt.Errorf("couldn't get state of %s: %s", session, err)
+		}
+		target, err := ioutil.ReadFile(filepath.Join(dir, "targetname"))
+		if err != nil {
+			return fmt.Errorf("couldn't get target of %s: %s", session, err)
+		}
+		tpgt, err := ioutil.ReadFile(filepath.Join(dir, "tpgt"))
+		if err != nil {
+			return fmt.Errorf("couldn't get target portal group of %s: %s", session, err)
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1,
+			session, strings.TrimSpace(string(target)), strings.TrimSpace(string(tpgt)))
+
+		current := strings.ToLower(strings.TrimSpace(string(state)))
+		for _, s := range iscsiSessionStates {
+			v := 0.0
+			if s == current {
+				v = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, v, session, s)
+		}
+	}
+
+	if *iscsiadmPath == "" || len(sessions) == 0 {
+		return nil
+	}
+	return c.updateSessionStats(ch)
+}
+
+// updateSessionStats exposes the per session counters, which the kernel only
+// provides through netlink and iscsiadm reports as "iSCSI SNMP" statistics.
+func (c *iscsiCollector) updateSessionStats(ch chan<- prometheus.Metric) error {
+	ctx, cancel := context.WithTimeout(context.Background(), iscsiCommandTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, *iscsiadmPath, "-m", "session", "-s").Output()
+	if err != nil {
+		return fmt.Errorf("%s -m session -s failed: %s", *iscsiadmPath, err)
+	}
+	stats, err := parseIscsiadmStats(bytes.NewReader(out))
+	if err != nil {
+		return err
+	}
+
+	for session, s := range stats {
+		ch <- prometheus.MustNewConstMetric(c.txBytes, prometheus.CounterValue, float64(s["txdata_octets"]), session)
+		ch <- prometheus.MustNewConstMetric(c.rxBytes, prometheus.CounterValue, float64(s["rxdata_octets"]), session)
+		ch <- prometheus.MustNewConstMetric(c.commands, prometheus.CounterValue, float64(s["scsicmd_pdus"]), session)
+		ch <- prometheus.MustNewConstMetric(c.digestErrors, prometheus.CounterValue, float64(s["digest_err"]), session)
+		ch <- prometheus.MustNewConstMetric(c.timeoutErrors, prometheus.CounterValue, float64(s["timeout_err"]), session)
+	}
+
+	return nil
+}
+
+// parseIscsiadmStats parses the output of `iscsiadm -m session -s` into the
+// counters of each session, keyed by the sysfs session name.
+func parseIscsiadmStats(r io.Reader) (map[string]map[string]uint64, error) {
+	var (
+		stats   = make(map[string]map[string]uint64)
+		session map[string]uint64
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if m := iscsiStatsHeaderRE.FindStringSubmatch(line); m != nil {
+			session = make(map[string]uint64)
+			stats["session"+m[1]] = session
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if session == nil || len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value in iscsiadm line %q: %s", line, err)
+		}
+		session[strings.TrimSpace(parts[0])] = v
+	}
+
+	return stats, scanner.Err()
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseIscsiadmStats(t *testing.T) {
+	out := `Stats for session [sid: 1, target: iqn.2003-01.org.linux-iscsi.storage1:data, portal: 10.0.0.10,3260]
+iSCSI SNMP:
+	txdata_octets: 134217728
+	rxdata_octets: 268435456
+	noptx_pdus: 0
+	scsicmd_pdus: 4096
+	digest_err: 2
+	timeout_err: 1
+iSCSI Extended:
+	tx_sendpage_failures: 0
+Stats for session [sid: 2, target: iqn.2003-01.org.linux-iscsi.storage1:backup, portal: 10.0.0.11,3260]
+iSCSI SNMP:
+	txdata_octets: 0
+	rxdata_octets: 512
+	digest_err: 0
+	timeout_err: 7
+`
+	stats, err := parseIscsiadmStats(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 2, len(stats); want != got {
+		t.Fatalf("want %d sessions, got %d", want, got)
+	}
+
+	for session, want := range map[string]map[string]uint64{
+		"session1": {"txdata_octets": 134217728, "rxdata_octets": 268435456, "scsicmd_pdus": 4096, "digest_err": 2, "timeout_err": 1},
+		"session2": {"rxdata_octets": 512, "timeout_err": 7},
+	} {
+		for k, v := range want {
+			if got := stats[session][k]; got != v {
+				t.Errorf("want %s %s %d, got %d", session, k, v, got)
+			}
+		}
+	}
+
+	if _, err := parseIscsiadmStats(strings.NewReader("Stats for session [sid: 3, target: x]\n\ttxdata_octets: abc\n")); err == nil {
+		t.Error("expected error for invalid counter")
+	}
+}
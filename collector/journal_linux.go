@@ -0,0 +1,256 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nojournal
+
+package collector
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const journalSubsystem = "journal"
+
+var (
+	journalctlPath     = kingpin.Flag("collector.journal.journalctl-path", "Path of the journalctl binary used to follow the journal.").Default("journalctl").String()
+	journalUnitInclude = kingpin.Flag("collector.journal.unit-include", "Regexp of units to count messages per unit of, none if empty.").Default("").String()
+	journalUnitExclude = kingpin.Flag("collector.journal.unit-exclude", "Regexp of units not to count messages per unit of.").Default("").String()
+
+	// The journal is followed continuously, so it's only followed once
+	// even though collectors are created per request.
+	journalFollowerOnce sync.Once
+	journalFollowerErr  error
+	journalFollowerInst *journalFollower
+
+	// journalPriorities are the syslog priority names by level.
+	journalPriorities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+)
+
+type journalEntry struct {
+	cursor   string
+	priority string
+	unit     string
+}
+
+type journalFollower struct {
+	unitInclude *regexp.Regexp
+	unitExclude *regexp.Regexp
+
+	mtx          sync.Mutex
+	cursor       string
+	messages     map[string]float64
+	unitMessages map[string]map[string]float64
+}
+
+type journalCollector struct {
+	follower         *journalFollower
+	messagesDesc     *prometheus.Desc
+	unitMessagesDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector("journal", defaultDisabled, NewJournalCollector)
+}
+
+// NewJournalCollector returns a new Collector counting the messages logged
+// to the systemd journal since the exporter started.
+func NewJournalCollector() (Collector, error) {
+	journalFollowerOnce.Do(func() {
+		journalFollowerInst, journalFollowerErr = newJournalFollower()
+	})
+	if journalFollowerErr != nil {
+		return nil, journalFollowerErr
+	}
+
+	return &journalCollector{
+		follower: journalFollowerInst,
+		messagesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, journalSubsystem, "messages_total"),
+			"Number of messages logged to the journal by priority.",
+			[]string{"priority"}, nil,
+		),
+		unitMessagesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, journalSubsystem, "unit_messages_total"),
+			"Number of messages logged to the journal by unit and priority.",
+			[]string{"unit", "priority"}, nil,
+		),
+	}, nil
+}
+
+func newJournalFollower() (*journalFollower, error) {
+	f := &journalFollower{
+		messages:     make(map[string]float64, len(journalPriorities)),
+		unitMessages: map[string]map[string]float64{},
+	}
+	for _, p := range journalPriorities {
+		f.messages[p] = 0
+	}
+
+	var err error
+	if *journalUnitInclude != "" {
+		if f.unitInclude, err = regexp.Compile(*journalUnitInclude); err != nil {
+			return nil, fmt.Errorf("invalid journal unit include regexp: %s", err)
+		}
+	}
+	if *journalUnitExclude != "" {
+		if f.unitExclude, err = regexp.Compile(*journalUnitExclude); err != nil {
+			return nil, fmt.Errorf("invalid journal unit exclude regexp: %s", err)
+		}
+	}
+	if _, err := exec.LookPath(*journalctlPath); err != nil {
+		return nil, fmt.Errorf("couldn't find journalctl: %s", err)
+	}
+
+	go f.follow()
+	return f, nil
+}
+
+// follow runs journalctl, restarting it after the last read entry if it
+// exits, so that no message is counted twice.
+func (f *journalFollower) follow() {
+	for {
+		if err := f.run(); err != nil {
+			log.Errorf("Error following the journal: %s", err)
+		}
+		time.Sleep(10 * time.Second)
+	}
+}
+
+func (f *journalFollower) run() error {
+	args := []string{"--follow", "--output=json", "--output-fields=PRIORITY,_SYSTEMD_UNIT"}
+	f.mtx.Lock()
+	if f.cursor == "" {
+		args = append(args, "--lines=0")
+	} else {
+		args = append(args, "--after-cursor="+f.cursor)
+	}
+	f.mtx.Unlock()
+
+	cmd := exec.Command(*journalctlPath, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if err := f.read(stdout); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+	return cmd.Wait()
+}
+
+// read counts the entries written by journalctl in the JSON format, one
+// entry per line.
+func (f *journalFollower) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry, err := parseJournalEntry(scanner.Bytes())
+		if err != nil {
+			log.Debugf("Skipping journal entry: %s", err)
+			continue
+		}
+		f.count(entry)
+	}
+	return scanner.Err()
+}
+
+func (f *journalFollower) count(entry journalEntry) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if entry.cursor != "" {
+		f.cursor = entry.cursor
+	}
+	if entry.priority == "" {
+		return
+	}
+	f.messages[entry.priority]++
+
+	if entry.unit == "" || f.unitInclude == nil || !f.unitInclude.MatchString(entry.unit) {
+		return
+	}
+	if f.unitExclude != nil && f.unitExclude.MatchString(entry.unit) {
+		return
+	}
+	if _, ok := f.unitMessages[entry.unit]; !ok {
+		f.unitMessages[entry.unit] = map[string]float64{}
+	}
+	f.unitMessages[entry.unit][entry.priority]++
+}
+
+// parseJournalEntry parses an entry in the journal JSON format. Field values
+// are strings, or arrays of bytes for binary data which are ignored.
+func parseJournalEntry(line []byte) (journalEntry, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return journalEntry{}, fmt.Errorf("invalid entry %q: %s", line, err)
+	}
+	field := func(name string) string {
+		var value string
+		if raw, ok := fields[name]; ok {
+			json.Unmarshal(raw, &value)
+		}
+		return value
+	}
+
+	entry := journalEntry{cursor: field("__CURSOR"), unit: field("_SYSTEMD_UNIT")}
+	if p := field("PRIORITY"); p != "" {
+		level, err := strconv.Atoi(p)
+		if err != nil || level < 0 || level >= len(journalPriorities) {
+			return journalEntry{}, fmt.Errorf("invalid priority %q", p)
+		}
+		entry.priority = journalPriorities[level]
+	}
+	return entry, nil
+}
+
+// Update implements the Collector interface.
+func (c *journalCollector) Update(ch chan<- prometheus.Metric) error {
+	c.follower.mtx.Lock()
+	defer c.follower.mtx.Unlock()
+
+	for _, p := range journalPriorities {
+		ch <- prometheus.MustNewConstMetric(c.messagesDesc, prometheus.CounterValue, c.follower.messages[p], p)
+	}
+
+	units := make([]string, 0, len(c.follower.unitMessages))
+	for unit := range c.follower.unitMessages {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	for _, unit := range units {
+		for _, p := range journalPriorities {
+			if v, ok := c.follower.unitMessages[unit][p]; ok {
+				ch <- prometheus.MustNewConstMetric(c.unitMessagesDesc, prometheus.CounterValue, v, unit, p)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nojournal
+
+package collector
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestJournalFollowerRead(t *testing.T) {
+	f := &journalFollower{
+		unitInclude:  regexp.MustCompile(`\.service$`),
+		unitExclude:  regexp.MustCompile(`^systemd-`),
+		messages:     map[string]float64{},
+		unitMessages: map[string]map[string]float64{},
+	}
+	entries := strings.Join([]string{
+		`{"__CURSOR":"s=1;i=1","PRIORITY":"3","_SYSTEMD_UNIT":"nginx.service"}`,
+		`{"__CURSOR":"s=1;i=2","PRIORITY":"3","_SYSTEMD_UNIT":"nginx.service"}`,
+		`{"__CURSOR":"s=1;i=3","PRIORITY":"6","_SYSTEMD_UNIT":"systemd-logind.service"}`,
+		`{"__CURSOR":"s=1;i=4","PRIORITY":"4","_SYSTEMD_UNIT":"session-1.scope"}`,
+		`{"__CURSOR":"s=1;i=5","PRIORITY":"2"}`,
+		`{"__CURSOR":"s=1;i=6","PRIORITY":"9"}`,
+		`not json`,
+		`{"__CURSOR":"s=1;i=7","PRIORITY":"6","_SYSTEMD_UNIT":[1,2,3]}`,
+	}, "\n")
+	if err := f.read(strings.NewReader(entries)); err != nil {
+		t.Fatal(err)
+	}
+
+	for priority, want := range map[string]float64{"err": 2, "info": 2, "warning": 1, "crit": 1} {
+		if got := f.messages[priority]; got != want {
+			t.Errorf("priority %s: want %v, got %v", priority, want, got)
+		}
+	}
+	if len(f.unitMessages) != 1 || f.unitMessages["nginx.service"]["err"] != 2 {
+		t.Errorf("unexpected unit messages %v", f.unitMessages)
+	}
+	if f.cursor != "s=1;i=7" {
+		t.Errorf("unexpected cursor %q", f.cursor)
+	}
+}
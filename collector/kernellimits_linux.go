@@ -0,0 +1,69 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nokernellimits
+
+package collector
+
+import (
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const kernelLimitsSubsystem = "kernel"
+
+type kernelLimitsCollector struct {
+	// descs are the gauges by the sysctl file they are read from.
+	descs map[string]*prometheus.Desc
+}
+
+func init() {
+	registerCollector("kernellimits", defaultDisabled, NewKernelLimitsCollector)
+}
+
+// NewKernelLimitsCollector returns a new Collector exposing system wide
+// kernel limits and their usage, where it's known system wide.
+func NewKernelLimitsCollector() (Collector, error) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, kernelLimitsSubsystem, name), help, nil, nil)
+	}
+	return &kernelLimitsCollector{
+		descs: map[string]*prometheus.Desc{
+			"sys/kernel/pid_max":     desc("pids_limit", "Maximum PID, each process and thread uses one, compare to node_load_tasks."),
+			"sys/kernel/threads-max": desc("threads_limit", "Maximum number of threads, compare to node_load_tasks."),
+			"sys/vm/max_map_count":   desc("map_count_limit", "Maximum number of memory map areas of a process."),
+			"sys/fs/aio-nr":          desc("aio_requests", "Number of asynchronous I/O requests allocated by io_setup calls."),
+			"sys/fs/aio-max-nr":      desc("aio_requests_limit", "Maximum number of asynchronous I/O requests, io_setup fails with EAGAIN beyond it."),
+		},
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *kernelLimitsCollector) Update(ch chan<- prometheus.Metric) error {
+	for file, desc := range c.descs {
+		value, err := readUintFromFile(procFilePath(file))
+		if err != nil {
+			// The aio files are missing if the kernel was built without
+			// CONFIG_AIO.
+			if os.IsNotExist(err) {
+				log.Debugf("Couldn't get %s: %s", file, err)
+				continue
+			}
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value))
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nokernelstalls
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const kernelStallsSubsystem = "kernel"
+
+type kernelStallsCollector struct {
+	hungTasksDesc        *prometheus.Desc
+	hungTaskWarningsDesc *prometheus.Desc
+	rcuStallsDesc        *prometheus.Desc
+	detectorDesc         *prometheus.Desc
+}
+
+func init() {
+	registerCollector("kernelstalls", defaultDisabled, NewKernelStallsCollector)
+}
+
+// NewKernelStallsCollector returns a new Collector exposing the number of
+// hung tasks and RCU stalls detected by the kernel.
+func NewKernelStallsCollector() (Collector, error) {
+	return &kernelStallsCollector{
+		hungTasksDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kernelStallsSubsystem, "hung_tasks_total"),
+			"Number of tasks detected to be blocked in uninterruptible sleep for longer than hung_task_timeout_secs.",
+			nil, nil,
+		),
+		hungTaskWarningsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kernelStallsSubsystem, "hung_task_warnings_remaining"),
+			"Number of hung task warnings the kernel logs before suppressing them, -1 if unlimited.",
+			nil, nil,
+		),
+		rcuStallsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kernelStallsSubsystem, "rcu_stalls_total"),
+			"Number of RCU CPU stall warnings.",
+			nil, nil,
+		),
+		detectorDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kernelStallsSubsystem, "lockup_detector_enabled"),
+			"1 if the soft or hard lockup detector is enabled, 0 otherwise.",
+			[]string{"detector"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *kernelStallsCollector) Update(ch chan<- prometheus.Metric) error {
+	// The counters are only exposed since Linux 6.12 and 6.3 respectively,
+	// the hung task detector requires CONFIG_DETECT_HUNG_TASK.
+	for _, m := range []struct {
+		path      string
+		desc      *prometheus.Desc
+		valueType prometheus.ValueType
+	}{
+		{procFilePath("sys/kernel/hung_task_detect_count"), c.hungTasksDesc, prometheus.CounterValue},
+		{procFilePath("sys/kernel/hung_task_warnings"), c.hungTaskWarningsDesc, prometheus.GaugeValue},
+		{sysFilePath("kernel/rcu_stall_count"), c.rcuStallsDesc, prometheus.CounterValue},
+	} {
+		value, err := readKernelStallsValue(m.path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				log.Debugf("Couldn't get %s: %s", m.path, err)
+				continue
+			}
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, value)
+	}
+
+	// The kernel doesn't count soft and hard lockups, it only logs them.
+	for detector, file := range map[string]string{
+		"soft": "sys/kernel/soft_watchdog",
+		"hard": "sys/kernel/nmi_watchdog",
+	} {
+		value, err := readKernelStallsValue(procFilePath(file))
+		if err != nil {
+			if os.IsNotExist(err) {
+				log.Debugf("Couldn't get %s lockup detector state: %s", detector, err)
+				continue
+			}
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(c.detectorDesc, prometheus.GaugeValue, value, detector)
+	}
+	return nil
+}
+
+// readKernelStallsValue reads a file containing a possibly negative integer.
+func readKernelStallsValue(path string) (float64, error) {
+	content, err := readTrimmedFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseInt(content, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s: %s", content, path, err)
+	}
+	return float64(value), nil
+}
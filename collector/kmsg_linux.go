@@ -0,0 +1,206 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nokmsg
+
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const kmsgSubsystem = "kmsg"
+
+var (
+	kmsgPath     = kingpin.Flag("collector.kmsg.path", "Path of the kernel log device.").Default("/dev/kmsg").String()
+	kmsgPatterns = kingpin.Flag("collector.kmsg.pattern", "Pattern to count kernel log messages of, as name=regexp. Can be repeated.").Strings()
+
+	// The kernel log is tailed continuously, so it's opened once even
+	// though collectors are created per request.
+	kmsgTailerOnce sync.Once
+	kmsgTailerErr  error
+	kmsgTailerInst *kmsgTailer
+)
+
+type kmsgPattern struct {
+	name   string
+	regexp *regexp.Regexp
+}
+
+type kmsgTailer struct {
+	patterns []kmsgPattern
+
+	mtx      sync.Mutex
+	messages float64
+	matches  map[string]float64
+}
+
+type kmsgCollector struct {
+	tailer       *kmsgTailer
+	messagesDesc *prometheus.Desc
+	matchesDesc  *prometheus.Desc
+}
+
+func init() {
+	registerCollector("kmsg", defaultDisabled, NewKmsgCollector)
+}
+
+// NewKmsgCollector returns a new Collector counting the kernel log messages
+// matching the configured patterns.
+func NewKmsgCollector() (Collector, error) {
+	kmsgTailerOnce.Do(func() {
+		kmsgTailerInst, kmsgTailerErr = newKmsgTailer()
+	})
+	if kmsgTailerErr != nil {
+		return nil, kmsgTailerErr
+	}
+
+	return &kmsgCollector{
+		tailer: kmsgTailerInst,
+		messagesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kmsgSubsystem, "messages_total"),
+			"Number of kernel log messages logged since the exporter started.",
+			nil, nil,
+		),
+		matchesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kmsgSubsystem, "matches_total"),
+			"Number of kernel log messages matching the pattern logged since the exporter started.",
+			[]string{"pattern"}, nil,
+		),
+	}, nil
+}
+
+func newKmsgTailer() (*kmsgTailer, error) {
+	patterns, err := parseKmsgPatterns(*kmsgPatterns)
+	if err != nil {
+		return nil, err
+	}
+	t := &kmsgTailer{
+		patterns: patterns,
+		matches:  make(map[string]float64, len(patterns)),
+	}
+	for _, p := range patterns {
+		t.matches[p.name] = 0
+	}
+
+	f, err := os.Open(*kmsgPath)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open kernel log: %s", err)
+	}
+	// Skip the messages still in the ring buffer, which were already
+	// counted by a previous instance or before a restart.
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("couldn't seek to the end of the kernel log: %s", err)
+	}
+	go t.tail(f)
+	return t, nil
+}
+
+// parseKmsgPatterns parses the name=regexp flags.
+func parseKmsgPatterns(flags []string) ([]kmsgPattern, error) {
+	var (
+		patterns []kmsgPattern
+		names    = map[string]bool{}
+	)
+	for _, flag := range flags {
+		parts := strings.SplitN(flag, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid kmsg pattern %q, expected name=regexp", flag)
+		}
+		if names[parts[0]] {
+			return nil, fmt.Errorf("duplicate kmsg pattern name %q", parts[0])
+		}
+		names[parts[0]] = true
+		re, err := regexp.Compile(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid kmsg pattern %q: %s", parts[0], err)
+		}
+		patterns = append(patterns, kmsgPattern{name: parts[0], regexp: re})
+	}
+	return patterns, nil
+}
+
+// tail reads the kernel log messages logged since the exporter started.
+// Each read returns exactly one record.
+func (t *kmsgTailer) tail(f *os.File) {
+	buf := make([]byte, 8192)
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			// EPIPE means that records were overwritten before they
+			// were read, reading continues with the next record.
+			if pathErr, ok := err.(*os.PathError); !ok || pathErr.Err != syscall.EPIPE {
+				log.Errorf("Error reading kernel log: %s", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		message, err := parseKmsgRecord(buf[:n])
+		if err != nil {
+			log.Debugf("Skipping kernel log record: %s", err)
+			continue
+		}
+		t.count(message)
+	}
+}
+
+func (t *kmsgTailer) count(message string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.messages++
+	for _, p := range t.patterns {
+		if p.regexp.MatchString(message) {
+			t.matches[p.name]++
+		}
+	}
+}
+
+// parseKmsgRecord returns the message of a /dev/kmsg record, which has the
+// format "priority,sequence,timestamp,flags[,...];message\n" followed by
+// optional continuation lines with key/value pairs.
+func parseKmsgRecord(record []byte) (string, error) {
+	i := bytes.IndexByte(record, ';')
+	if i < 0 {
+		return "", fmt.Errorf("invalid record %q", record)
+	}
+	message := record[i+1:]
+	if j := bytes.IndexByte(message, '\n'); j >= 0 {
+		message = message[:j]
+	}
+	return string(message), nil
+}
+
+// Update implements the Collector interface.
+func (c *kmsgCollector) Update(ch chan<- prometheus.Metric) error {
+	c.tailer.mtx.Lock()
+	defer c.tailer.mtx.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(c.messagesDesc, prometheus.CounterValue, c.tailer.messages)
+	for _, p := range c.tailer.patterns {
+		ch <- prometheus.MustNewConstMetric(c.matchesDesc, prometheus.CounterValue, c.tailer.matches[p.name], p.name)
+	}
+	return nil
+}
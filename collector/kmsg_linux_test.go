@@ -0,0 +1,61 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nokmsg
+
+package collector
+
+import "testing"
+
+func TestKmsgCount(t *testing.T) {
+	patterns, err := parseKmsgPatterns([]string{
+		"io_error=blk_update_request: I/O error",
+		"link_down=Link is Down",
+		"oom=(?i)out of memory",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tailer := &kmsgTailer{patterns: patterns, matches: map[string]float64{}}
+
+	for _, record := range []string{
+		"3,1234,5678901,-;blk_update_request: I/O error, dev sda, sector 2048\n SUBSYSTEM=block\n DEVICE=b8:0\n",
+		"6,1235,5678902,-;e1000e 0000:00:19.0 eth0: NIC Link is Down\n",
+		"6,1236,5678903,c;Out of memory: Killed process 1234 (stress)\n",
+		"6,1237,5678904,-;eth0: NIC Link is Up 1000 Mbps Full Duplex\n",
+	} {
+		message, err := parseKmsgRecord([]byte(record))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tailer.count(message)
+	}
+
+	if tailer.messages != 4 {
+		t.Errorf("expected 4 messages, got %v", tailer.messages)
+	}
+	for _, name := range []string{"io_error", "link_down", "oom"} {
+		if tailer.matches[name] != 1 {
+			t.Errorf("expected 1 match of %s, got %v", name, tailer.matches[name])
+		}
+	}
+
+	if _, err := parseKmsgRecord([]byte("invalid")); err == nil {
+		t.Error("expected error for invalid record")
+	}
+	for _, flag := range []string{"oom", "=x", "a=(", "a=x"} {
+		if _, err := parseKmsgPatterns([]string{"a=x", flag}); err == nil {
+			t.Errorf("expected error for pattern %q", flag)
+		}
+	}
+}
@@ -17,14 +17,20 @@ package collector
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
 )
 
 var (
 	ksmdFiles = []string{"full_scans", "merge_across_nodes", "pages_shared", "pages_sharing",
 		"pages_to_scan", "pages_unshared", "pages_volatile", "run", "sleep_millisecs"}
+
+	// ksmdOptionalFiles are only available on newer kernels.
+	ksmdOptionalFiles = []string{"ksm_zero_pages", "max_page_sharing", "stable_node_chains",
+		"stable_node_dups", "use_zero_pages"}
 )
 
 type ksmdCollector struct {
@@ -51,7 +57,7 @@ func NewKsmdCollector() (Collector, error) {
 	subsystem := "ksmd"
 	descs := make(map[string]*prometheus.Desc)
 
-	for _, n := range ksmdFiles {
+	for _, n := range append(ksmdFiles, ksmdOptionalFiles...) {
 		descs[n] = prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, subsystem, getCanonicalMetricName(n)),
 			fmt.Sprintf("ksmd '%s' file.", n), nil, nil)
@@ -78,5 +84,17 @@ func (c *ksmdCollector) Update(ch chan<- prometheus.Metric) error {
 		ch <- prometheus.MustNewConstMetric(c.metricDescs[n], t, v)
 	}
 
+	for _, n := range ksmdOptionalFiles {
+		val, err := readUintFromFile(sysFilePath(filepath.Join("kernel/mm/ksm", n)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				log.Debugf("ksmd: %s not available, skipping", n)
+				continue
+			}
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(c.metricDescs[n], prometheus.GaugeValue, float64(val))
+	}
+
 	return nil
 }
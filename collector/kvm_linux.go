@@ -0,0 +1,242 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nokvm
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/procfs"
+)
+
+const kvmSubsystem = "kvm"
+
+// kvmCounters are the per VM statistics files of the KVM debugfs, not all
+// of them exist on every architecture.
+var kvmCounters = map[string]string{
+	"exits":          "Number of VM exits.",
+	"halt_exits":     "Number of VM exits due to halt calls.",
+	"io_exits":       "Number of VM exits due to I/O port accesses.",
+	"mmio_exits":     "Number of VM exits due to memory mapped I/O accesses.",
+	"irq_exits":      "Number of VM exits due to external interrupts.",
+	"irq_injections": "Number of interrupts injected into the VM.",
+	"nmi_injections": "Number of non-maskable interrupts injected into the VM.",
+}
+
+// kvmVM is a running VM found in the KVM debugfs.
+type kvmVM struct {
+	name     string
+	pid      int
+	vcpus    int
+	memory   float64
+	counters map[string]float64
+}
+
+type kvmCollector struct {
+	fs               procfs.FS
+	vcpusDesc        *prometheus.Desc
+	memoryDesc       *prometheus.Desc
+	residentDesc     *prometheus.Desc
+	counterDescs     map[string]*prometheus.Desc
+	runningGuestDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector("kvm", defaultDisabled, NewKVMCollector)
+}
+
+// NewKVMCollector returns a new Collector exposing statistics of the VMs
+// running on a KVM host.
+func NewKVMCollector() (Collector, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %v", err)
+	}
+
+	labels := []string{"vm"}
+	c := &kvmCollector{
+		fs: fs,
+		runningGuestDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kvmSubsystem, "vms_running"),
+			"Number of running VMs.",
+			nil, nil,
+		),
+		vcpusDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kvmSubsystem, "vm_vcpus"),
+			"Number of virtual CPUs of the VM.",
+			labels, nil,
+		),
+		memoryDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kvmSubsystem, "vm_memory_bytes"),
+			"Memory configured for the VM with the QEMU -m option.",
+			labels, nil,
+		),
+		residentDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kvmSubsystem, "vm_memory_resident_bytes"),
+			"Resident memory of the process running the VM.",
+			labels, nil,
+		),
+		counterDescs: make(map[string]*prometheus.Desc, len(kvmCounters)),
+	}
+	for file, help := range kvmCounters {
+		c.counterDescs[file] = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kvmSubsystem, "vm_"+file+"_total"),
+			help, labels, nil,
+		)
+	}
+	return c, nil
+}
+
+// Update implements the Collector interface.
+func (c *kvmCollector) Update(ch chan<- prometheus.Metric) error {
+	vms, err := kvmVMs(sysFilePath("kernel/debug/kvm"), c.fs)
+	if err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.runningGuestDesc, prometheus.GaugeValue, float64(len(vms)))
+	for _, vm := range vms {
+		ch <- prometheus.MustNewConstMetric(c.vcpusDesc, prometheus.GaugeValue, float64(vm.vcpus), vm.name)
+		if vm.memory > 0 {
+			ch <- prometheus.MustNewConstMetric(c.memoryDesc, prometheus.GaugeValue, vm.memory, vm.name)
+		}
+		for file, value := range vm.counters {
+			ch <- prometheus.MustNewConstMetric(c.counterDescs[file], prometheus.CounterValue, value, vm.name)
+		}
+
+		proc, err := c.fs.Proc(vm.pid)
+		if err != nil {
+			log.Debugf("Couldn't get process of VM %s: %s", vm.name, err)
+			continue
+		}
+		status, err := proc.NewStatus()
+		if err != nil {
+			log.Debugf("Couldn't get status of VM %s: %s", vm.name, err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.residentDesc, prometheus.GaugeValue, float64(status.VmRSS), vm.name)
+	}
+	return nil
+}
+
+// kvmVMs returns the running VMs, which have a <pid>-<fd> directory in the
+// KVM debugfs with their statistics and a vcpu<n> directory per virtual CPU.
+// VMs are named after the QEMU -name option, or the directory otherwise.
+func kvmVMs(debugfs string, fs procfs.FS) ([]kvmVM, error) {
+	// Without access to the debugfs, which requires root, no VM would be
+	// found.
+	if _, err := os.Stat(debugfs); err != nil {
+		return nil, fmt.Errorf("couldn't access KVM debugfs: %s", err)
+	}
+	dirs, err := filepath.Glob(filepath.Join(debugfs, "*-*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var vms []kvmVM
+	for _, dir := range dirs {
+		base := filepath.Base(dir)
+		pid, err := strconv.Atoi(strings.SplitN(base, "-", 2)[0])
+		if err != nil {
+			continue
+		}
+		vm := kvmVM{name: base, pid: pid, counters: map[string]float64{}}
+
+		vcpus, err := filepath.Glob(filepath.Join(dir, "vcpu*"))
+		if err != nil {
+			return nil, err
+		}
+		vm.vcpus = len(vcpus)
+
+		for file := range kvmCounters {
+			if value, err := readUintFromFile(filepath.Join(dir, file)); err == nil {
+				vm.counters[file] = float64(value)
+			}
+		}
+
+		if proc, err := fs.Proc(pid); err == nil {
+			if cmdline, err := proc.CmdLine(); err == nil {
+				name, memory := parseQemuCmdline(cmdline)
+				if name != "" {
+					vm.name = name
+				}
+				vm.memory = memory
+			}
+		}
+		vms = append(vms, vm)
+	}
+	return vms, nil
+}
+
+// parseQemuCmdline returns the name and memory size in bytes of a VM from the
+// QEMU command line, e.g. "-name guest=web1,debug-threads=on" and
+// "-m size=4194304k,slots=16,maxmem=8G" as passed by libvirt, or "-name web1"
+// and "-m 4096".
+func parseQemuCmdline(cmdline []string) (string, float64) {
+	var (
+		name   string
+		memory float64
+	)
+	for i := 0; i+1 < len(cmdline); i++ {
+		switch cmdline[i] {
+		case "-name":
+			for _, option := range strings.Split(cmdline[i+1], ",") {
+				if strings.HasPrefix(option, "guest=") {
+					name = strings.TrimPrefix(option, "guest=")
+				} else if !strings.Contains(option, "=") && name == "" {
+					name = option
+				}
+			}
+		case "-m":
+			for _, option := range strings.Split(cmdline[i+1], ",") {
+				if option = strings.TrimPrefix(option, "size="); !strings.Contains(option, "=") {
+					memory = parseQemuSize(option)
+					break
+				}
+			}
+		}
+	}
+	return name, memory
+}
+
+// parseQemuSize parses a QEMU memory size, which is in MiB without suffix.
+func parseQemuSize(size string) float64 {
+	multiplier := 1024.0 * 1024
+	if size == "" {
+		return 0
+	}
+	switch strings.ToUpper(size[len(size)-1:]) {
+	case "K":
+		multiplier = 1024
+	case "M":
+	case "G":
+		multiplier = 1024 * 1024 * 1024
+	case "T":
+		multiplier = 1024 * 1024 * 1024 * 1024
+	default:
+		size += "M"
+	}
+	value, err := strconv.ParseFloat(size[:len(size)-1], 64)
+	if err != nil {
+		return 0
+	}
+	return value * multiplier
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nokvm
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/procfs"
+)
+
+func TestKVMVMs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"debug/kvm/exits":                    "123456\n",
+		"debug/kvm/3000-12/exits":            "100\n",
+		"debug/kvm/3000-12/io_exits":         "20\n",
+		"debug/kvm/3000-12/irq_injections":   "5\n",
+		"debug/kvm/3000-12/vcpu0/tsc-offset": "0\n",
+		"debug/kvm/3000-12/vcpu1/tsc-offset": "0\n",
+		"debug/kvm/4000-15/exits":            "7\n",
+		"debug/kvm/4000-15/vcpu0/tsc-offset": "0\n",
+		"proc/3000/cmdline":                  strings.Join([]string{"/usr/bin/qemu-system-x86_64", "-name", "guest=web1,debug-threads=on", "-m", "size=4194304k,slots=16,maxmem=8G", ""}, "\x00"),
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fs, err := procfs.NewFS(filepath.Join(dir, "proc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vms, err := kvmVMs(filepath.Join(dir, "debug/kvm"), fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []kvmVM{
+		{name: "web1", pid: 3000, vcpus: 2, memory: 4 << 30, counters: map[string]float64{"exits": 100, "io_exits": 20, "irq_injections": 5}},
+		{name: "4000-15", pid: 4000, vcpus: 1, counters: map[string]float64{"exits": 7}},
+	}
+	if !reflect.DeepEqual(vms, want) {
+		t.Errorf("want %+v, got %+v", want, vms)
+	}
+}
+
+func TestParseQemuCmdline(t *testing.T) {
+	for _, tc := range []struct {
+		cmdline string
+		name    string
+		memory  float64
+	}{
+		{"qemu-system-x86_64 -name db -m 2048", "db", 2 << 30},
+		{"qemu-system-x86_64 -m 2G -name db,process=qemu-db", "db", 2 << 30},
+		{"qemu-system-x86_64 -enable-kvm", "", 0},
+	} {
+		name, memory := parseQemuCmdline(strings.Fields(tc.cmdline))
+		if name != tc.name || memory != tc.memory {
+			t.Errorf("%q: want %q and %v, got %q and %v", tc.cmdline, tc.name, tc.memory, name, memory)
+		}
+	}
+}
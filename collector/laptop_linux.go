@@ -0,0 +1,165 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nolaptop
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const laptopSubsystem = "laptop"
+
+var (
+	// The states are kept across collector instances to count the changes
+	// between scrapes.
+	laptopStatesMtx sync.Mutex
+	laptopStates    = map[laptopSwitch]laptopState{}
+)
+
+// laptopSwitch is a lid, dock or AC adapter.
+type laptopSwitch struct {
+	kind, name string
+}
+
+type laptopState struct {
+	value, changes float64
+}
+
+type laptopCollector struct {
+	lidOpenDesc     *prometheus.Desc
+	lidChangesDesc  *prometheus.Desc
+	dockedDesc      *prometheus.Desc
+	dockChangesDesc *prometheus.Desc
+	acOnlineDesc    *prometheus.Desc
+	acChangesDesc   *prometheus.Desc
+}
+
+func init() {
+	registerCollector(laptopSubsystem, defaultDisabled, NewLaptopCollector)
+}
+
+// NewLaptopCollector returns a new Collector exposing the state of the lid,
+// docking station and AC adapter.
+func NewLaptopCollector() (Collector, error) {
+	desc := func(name, help, label string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, laptopSubsystem, name), help, []string{label}, nil)
+	}
+	return &laptopCollector{
+		lidOpenDesc:     desc("lid_open", "1 if the lid is open, 0 if it is closed.", "lid"),
+		lidChangesDesc:  desc("lid_changes_total", "Number of times the lid was seen opened or closed.", "lid"),
+		dockedDesc:      desc("docked", "1 if the system is docked, 0 otherwise.", "dock"),
+		dockChangesDesc: desc("dock_changes_total", "Number of times the system was seen docked or undocked.", "dock"),
+		acOnlineDesc:    desc("ac_online", "1 if the AC adapter is plugged in, 0 otherwise.", "adapter"),
+		acChangesDesc:   desc("ac_changes_total", "Number of times the AC adapter was seen plugged in or unplugged.", "adapter"),
+	}, nil
+}
+
+// Update implements the Collector interface. Changes are detected by
+// comparing the states between scrapes, so a change and its reversal within
+// a scrape interval, e.g. while the system is suspended, isn't counted.
+func (c *laptopCollector) Update(ch chan<- prometheus.Metric) error {
+	states := map[laptopSwitch]float64{}
+
+	lids, err := filepath.Glob(procFilePath("acpi/button/lid/*/state"))
+	if err != nil {
+		return err
+	}
+	for _, file := range lids {
+		open, err := readLidState(file)
+		if err != nil {
+			return err
+		}
+		states[laptopSwitch{"lid", filepath.Base(filepath.Dir(file))}] = open
+	}
+
+	docks, err := filepath.Glob(sysFilePath("devices/platform/dock.*/docked"))
+	if err != nil {
+		return err
+	}
+	for _, file := range docks {
+		docked, err := readUintFromFile(file)
+		if err != nil {
+			return fmt.Errorf("couldn't get dock state: %s", err)
+		}
+		states[laptopSwitch{"dock", filepath.Base(filepath.Dir(file))}] = float64(docked)
+	}
+
+	supplies, err := filepath.Glob(sysFilePath("class/power_supply/*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range supplies {
+		if t, err := readTrimmedFile(filepath.Join(dir, "type")); err != nil || t != "Mains" {
+			continue
+		}
+		online, err := readUintFromFile(filepath.Join(dir, "online"))
+		if err != nil {
+			log.Debugf("Couldn't get state of AC adapter %s: %s", filepath.Base(dir), err)
+			continue
+		}
+		states[laptopSwitch{"ac", filepath.Base(dir)}] = float64(online)
+	}
+
+	laptopStatesMtx.Lock()
+	defer laptopStatesMtx.Unlock()
+	for sw, value := range states {
+		state, ok := laptopStates[sw]
+		if ok && state.value != value {
+			state.changes++
+		}
+		state.value = value
+		laptopStates[sw] = state
+
+		var valueDesc, changesDesc *prometheus.Desc
+		switch sw.kind {
+		case "lid":
+			valueDesc, changesDesc = c.lidOpenDesc, c.lidChangesDesc
+		case "dock":
+			valueDesc, changesDesc = c.dockedDesc, c.dockChangesDesc
+		case "ac":
+			valueDesc, changesDesc = c.acOnlineDesc, c.acChangesDesc
+		}
+		ch <- prometheus.MustNewConstMetric(valueDesc, prometheus.GaugeValue, value, sw.name)
+		ch <- prometheus.MustNewConstMetric(changesDesc, prometheus.CounterValue, state.changes, sw.name)
+	}
+	return nil
+}
+
+// readLidState reads the state of an ACPI lid switch, which is e.g.
+// "state:      open".
+func readLidState(file string) (float64, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get lid state: %s", err)
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) != 2 || fields[0] != "state:" {
+		return 0, fmt.Errorf("invalid lid state %q", content)
+	}
+	switch fields[1] {
+	case "open":
+		return 1, nil
+	case "closed":
+		return 0, nil
+	}
+	return 0, fmt.Errorf("unknown lid state %q", fields[1])
+}
@@ -24,7 +24,11 @@ import (
 )
 
 type loadavgCollector struct {
-	metric []typedDesc
+	metric          []typedDesc
+	runnable        typedDesc
+	tasks           typedDesc
+	lastPID         typedDesc
+	uninterruptible typedDesc
 }
 
 func init() {
@@ -39,6 +43,10 @@ func NewLoadavgCollector() (Collector, error) {
 			{prometheus.NewDesc(namespace+"_load5", "5m load average.", nil, nil), prometheus.GaugeValue},
 			{prometheus.NewDesc(namespace+"_load15", "15m load average.", nil, nil), prometheus.GaugeValue},
 		},
+		runnable:        typedDesc{prometheus.NewDesc(namespace+"_load_runnable_tasks", "Number of currently runnable tasks.", nil, nil), prometheus.GaugeValue},
+		tasks:           typedDesc{prometheus.NewDesc(namespace+"_load_tasks", "Number of tasks, i.e. processes and threads.", nil, nil), prometheus.GaugeValue},
+		lastPID:         typedDesc{prometheus.NewDesc(namespace+"_load_last_pid", "PID of the most recently created task.", nil, nil), prometheus.GaugeValue},
+		uninterruptible: typedDesc{prometheus.NewDesc(namespace+"_load_uninterruptible_tasks", "Number of tasks in uninterruptible sleep, usually waiting for I/O, which count towards the load average.", nil, nil), prometheus.GaugeValue},
 	}, nil
 }
 
@@ -51,5 +59,5 @@ func (c *loadavgCollector) Update(ch chan<- prometheus.Metric) error {
 		log.Debugf("return load %d: %f", i, load)
 		ch <- c.metric[i].mustNewConstMetric(load)
 	}
-	return err
+	return c.updateTasks(ch)
 }
@@ -16,10 +16,16 @@
 package collector
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
 	"strconv"
 	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
 )
 
 // Read loadavg from /proc.
@@ -50,3 +56,81 @@ func parseLoad(data string) (loads []float64, err error) {
 	}
 	return loads, nil
 }
+
+// updateTasks exposes the task counts of /proc/loadavg and, if the scheduler
+// debug file is readable, the number of tasks in uninterruptible sleep.
+func (c *loadavgCollector) updateTasks(ch chan<- prometheus.Metric) error {
+	data, err := ioutil.ReadFile(procFilePath("loadavg"))
+	if err != nil {
+		return err
+	}
+	runnable, tasks, lastPID, err := parseLoadTasks(string(data))
+	if err != nil {
+		return err
+	}
+	ch <- c.runnable.mustNewConstMetric(runnable)
+	ch <- c.tasks.mustNewConstMetric(tasks)
+	ch <- c.lastPID.mustNewConstMetric(lastPID)
+
+	// The scheduler debug file moved to debugfs in Linux 5.13.
+	for _, path := range []string{procFilePath("sched_debug"), sysFilePath("kernel/debug/sched/debug")} {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Debugf("Couldn't get uninterruptible tasks: %s", err)
+			continue
+		}
+		defer file.Close()
+		uninterruptible, err := parseSchedDebugUninterruptible(file)
+		if err != nil {
+			return fmt.Errorf("couldn't parse %s: %s", path, err)
+		}
+		ch <- c.uninterruptible.mustNewConstMetric(uninterruptible)
+		break
+	}
+	return nil
+}
+
+// parseLoadTasks parses the number of runnable tasks, the number of tasks
+// and the last PID from /proc/loadavg, e.g. "0.21 0.37 0.39 1/719 19737".
+func parseLoadTasks(data string) (runnable, tasks, lastPID float64, err error) {
+	parts := strings.Fields(data)
+	if len(parts) < 5 {
+		return 0, 0, 0, fmt.Errorf("unexpected content in %s", procFilePath("loadavg"))
+	}
+	counts := strings.SplitN(parts[3], "/", 2)
+	if len(counts) != 2 {
+		return 0, 0, 0, fmt.Errorf("could not parse task counts '%s'", parts[3])
+	}
+	if runnable, err = strconv.ParseFloat(counts[0], 64); err != nil {
+		return 0, 0, 0, fmt.Errorf("could not parse runnable tasks '%s': %s", counts[0], err)
+	}
+	if tasks, err = strconv.ParseFloat(counts[1], 64); err != nil {
+		return 0, 0, 0, fmt.Errorf("could not parse tasks '%s': %s", counts[1], err)
+	}
+	if lastPID, err = strconv.ParseFloat(parts[4], 64); err != nil {
+		return 0, 0, 0, fmt.Errorf("could not parse last PID '%s': %s", parts[4], err)
+	}
+	return runnable, tasks, lastPID, nil
+}
+
+// parseSchedDebugUninterruptible sums the .nr_uninterruptible counts of the
+// CPUs in the scheduler debug file. The count of a single CPU can be negative
+// as tasks may wake up on another CPU than the one they went to sleep on.
+func parseSchedDebugUninterruptible(r io.Reader) (float64, error) {
+	var (
+		scanner = bufio.NewScanner(r)
+		sum     float64
+	)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[0] != ".nr_uninterruptible" {
+			continue
+		}
+		v, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid line %q", scanner.Text())
+		}
+		sum += v
+	}
+	return sum, scanner.Err()
+}
@@ -13,7 +13,10 @@
 
 package collector
 
-import "testing"
+import (
+	"os"
+	"testing"
+)
 
 func TestLoad(t *testing.T) {
 	want := []float64{0.21, 0.37, 0.39}
@@ -28,3 +31,29 @@ func TestLoad(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadTasks(t *testing.T) {
+	runnable, tasks, lastPID, err := parseLoadTasks("0.21 0.37 0.39 1/719 19737")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runnable != 1 || tasks != 719 || lastPID != 19737 {
+		t.Fatalf("want 1, 719 and 19737, got %f, %f and %f", runnable, tasks, lastPID)
+	}
+}
+
+func TestSchedDebugUninterruptible(t *testing.T) {
+	file, err := os.Open("fixtures/proc/sched_debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	uninterruptible, err := parseSchedDebugUninterruptible(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 4.0; want != uninterruptible {
+		t.Fatalf("want %f uninterruptible tasks, got %f", want, uninterruptible)
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build darwin dragonfly freebsd netbsd openbsd solaris
+// +build !noloadavg
+
+package collector
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// updateTasks is a no-op, the task counts are only exposed by Linux.
+func (c *loadavgCollector) updateTasks(ch chan<- prometheus.Metric) error {
+	return nil
+}
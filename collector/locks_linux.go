@@ -0,0 +1,209 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nolocks
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const locksSubsystem = "locks"
+
+var (
+	// /proc/locks doesn't tell when a lock was taken, so the age of a lock
+	// is the time since it was first seen. It's kept across requests as
+	// collectors are created per request.
+	locksFirstSeenMtx sync.Mutex
+	locksFirstSeen    = map[fileLock]time.Time{}
+)
+
+// fileLock is a lock of /proc/locks. Locks are numbered by their position,
+// so they are identified by their other fields.
+type fileLock struct {
+	class, lockType string
+	blocked         bool
+	pid             string
+	// device is the major:minor number of the filesystem, in decimal as in
+	// mountinfo.
+	device, inode string
+	start, end    string
+}
+
+type locksCollector struct {
+	heldDesc    *prometheus.Desc
+	blockedDesc *prometheus.Desc
+	oldestDesc  *prometheus.Desc
+}
+
+func init() {
+	registerCollector(locksSubsystem, defaultDisabled, NewLocksCollector)
+}
+
+// NewLocksCollector returns a new Collector exposing the file locks held and
+// waited for.
+func NewLocksCollector() (Collector, error) {
+	return &locksCollector{
+		heldDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, locksSubsystem, "held"),
+			"Number of file locks held by class, e.g. posix or flock, and type.",
+			[]string{"class", "type"}, nil,
+		),
+		blockedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, locksSubsystem, "blocked"),
+			"Number of file lock requests waiting for a conflicting lock by class and type.",
+			[]string{"class", "type"}, nil,
+		),
+		oldestDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, locksSubsystem, "oldest_held_seconds"),
+			"Age of the oldest file lock held on the filesystem, since it was first seen. Filesystems not found in the mount table are identified by their device number.",
+			[]string{"mountpoint"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *locksCollector) Update(ch chan<- prometheus.Metric) error {
+	f, err := os.Open(procFilePath("locks"))
+	if err != nil {
+		return fmt.Errorf("couldn't open locks: %s", err)
+	}
+	defer f.Close()
+	locks, err := parseLocks(f)
+	if err != nil {
+		return fmt.Errorf("couldn't parse locks: %s", err)
+	}
+
+	mountPoints := map[string]string{}
+	if mf, err := os.Open(mountinfoPath()); err == nil {
+		mounts, err := parseMountInfo(mf)
+		mf.Close()
+		if err != nil {
+			return fmt.Errorf("couldn't parse mountinfo: %s", err)
+		}
+		for _, m := range mounts {
+			if _, ok := mountPoints[m.majorMinor]; !ok {
+				mountPoints[m.majorMinor] = rootfsStripPrefix(m.mountPoint)
+			}
+		}
+	} else {
+		log.Debugf("Couldn't open mountinfo: %s", err)
+	}
+
+	type classType struct{ class, lockType string }
+	var (
+		held    = map[classType]float64{}
+		blocked = map[classType]float64{}
+		oldest  = map[string]time.Duration{}
+		now     = time.Now()
+	)
+	locksFirstSeenMtx.Lock()
+	seen := make(map[fileLock]time.Time, len(locks))
+	for _, l := range locks {
+		key := classType{l.class, l.lockType}
+		if l.blocked {
+			blocked[key]++
+			continue
+		}
+		held[key]++
+
+		firstSeen, ok := locksFirstSeen[l]
+		if !ok {
+			firstSeen = now
+		}
+		seen[l] = firstSeen
+		mountPoint, ok := mountPoints[l.device]
+		if !ok {
+			mountPoint = l.device
+		}
+		if age := now.Sub(firstSeen); age >= oldest[mountPoint] {
+			oldest[mountPoint] = age
+		}
+	}
+	// Released locks are forgotten.
+	locksFirstSeen = seen
+	locksFirstSeenMtx.Unlock()
+
+	for key, v := range held {
+		ch <- prometheus.MustNewConstMetric(c.heldDesc, prometheus.GaugeValue, v, key.class, key.lockType)
+	}
+	for key, v := range blocked {
+		ch <- prometheus.MustNewConstMetric(c.blockedDesc, prometheus.GaugeValue, v, key.class, key.lockType)
+	}
+	for mountPoint, age := range oldest {
+		ch <- prometheus.MustNewConstMetric(c.oldestDesc, prometheus.GaugeValue, age.Seconds(), mountPoint)
+	}
+	return nil
+}
+
+// parseLocks parses the lines of /proc/locks, e.g.
+//
+//	1: POSIX  ADVISORY  WRITE 1234 08:01:1311 0 EOF
+//	1: -> POSIX  ADVISORY  WRITE 5678 08:01:1311 0 EOF
+//	2: FLOCK  ADVISORY  READ  910 00:19:4711 0 EOF
+//
+// where requests waiting for a lock follow the lock with an arrow. The device
+// is printed in hexadecimal.
+func parseLocks(r io.Reader) ([]fileLock, error) {
+	var locks []fileLock
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		blocked := len(fields) > 1 && fields[1] == "->"
+		if blocked {
+			fields = append(fields[:1], fields[2:]...)
+		}
+		if len(fields) != 8 {
+			return nil, fmt.Errorf("malformed line %q", scanner.Text())
+		}
+
+		file := strings.SplitN(fields[5], ":", 3)
+		if len(file) != 3 {
+			return nil, fmt.Errorf("malformed file %q", fields[5])
+		}
+		major, err := strconv.ParseUint(file[0], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("malformed file %q", fields[5])
+		}
+		minor, err := strconv.ParseUint(file[1], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("malformed file %q", fields[5])
+		}
+
+		locks = append(locks, fileLock{
+			class:    strings.ToLower(fields[1]),
+			lockType: strings.ToLower(fields[3]),
+			blocked:  blocked,
+			pid:      fields[4],
+			device:   fmt.Sprintf("%d:%d", major, minor),
+			inode:    file[2],
+			start:    fields[6],
+			end:      fields[7],
+		})
+	}
+	return locks, scanner.Err()
+}
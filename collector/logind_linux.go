@@ -86,6 +86,11 @@ func NewLogindCollector() (Collector, error) {
 	return &logindCollector{}, nil
 }
 
+// Describe sends the descriptor of the sessions metric, which is package level.
+func (lc *logindCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sessionsDesc
+}
+
 func (lc *logindCollector) Update(ch chan<- prometheus.Metric) error {
 	c, err := newDbus()
 	if err != nil {
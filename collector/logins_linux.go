@@ -0,0 +1,189 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nologins
+
+package collector
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/mdlayher/netlink/nlenc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	loginsSubsystem = "logins"
+
+	// Size and field offsets of struct utmp on Linux.
+	utmpSize       = 384
+	utmpLineOffset = 8
+	utmpLineSize   = 32
+	utmpHostOffset = 76
+	utmpHostSize   = 256
+
+	// utmpUserProcess is the ut_type of a login session.
+	utmpUserProcess = 7
+)
+
+var (
+	loginsBtmpPath = kingpin.Flag("collector.logins.btmp-path", "Path of the btmp file recording failed logins.").Default("/var/log/btmp").String()
+	loginsWtmpPath = kingpin.Flag("collector.logins.wtmp-path", "Path of the wtmp file recording logins.").Default("/var/log/wtmp").String()
+
+	// The files are read incrementally, so their state is kept across
+	// collectors, which are created per request.
+	loginsFilesMtx sync.Mutex
+	loginsFiles    = map[string]*utmpFile{}
+)
+
+// utmpFile holds the number of records by login method of a btmp or wtmp
+// file and how far it has been read.
+type utmpFile struct {
+	inode  uint64
+	offset int64
+	counts map[string]float64
+}
+
+type loginsCollector struct {
+	failedDesc    *prometheus.Desc
+	succeededDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector("logins", defaultDisabled, NewLoginsCollector)
+}
+
+// NewLoginsCollector returns a new Collector exposing the number of failed
+// and successful logins recorded in btmp and wtmp.
+func NewLoginsCollector() (Collector, error) {
+	return &loginsCollector{
+		failedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, loginsSubsystem, "failed_total"),
+			"Number of failed logins recorded in btmp by method, reset when the file is rotated.",
+			[]string{"method"}, nil,
+		),
+		succeededDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, loginsSubsystem, "succeeded_total"),
+			"Number of logins recorded in wtmp by method, reset when the file is rotated.",
+			[]string{"method"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *loginsCollector) Update(ch chan<- prometheus.Metric) error {
+	loginsFilesMtx.Lock()
+	defer loginsFilesMtx.Unlock()
+
+	for _, f := range []struct {
+		path         string
+		desc         *prometheus.Desc
+		sessionsOnly bool
+	}{
+		{*loginsBtmpPath, c.failedDesc, false},
+		{*loginsWtmpPath, c.succeededDesc, true},
+	} {
+		counts, err := readUtmpFile(f.path, f.sessionsOnly)
+		if err != nil {
+			if os.IsNotExist(err) || os.IsPermission(err) {
+				log.Debugf("Couldn't read %q: %s", f.path, err)
+				continue
+			}
+			return err
+		}
+
+		methods := make([]string, 0, len(counts))
+		for method := range counts {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			ch <- prometheus.MustNewConstMetric(f.desc, prometheus.CounterValue, counts[method], method)
+		}
+	}
+	return nil
+}
+
+// readUtmpFile counts the records added to a utmp formatted file since it was
+// last read, starting over when it has been rotated or truncated. Only login
+// sessions are counted if sessionsOnly is set, as wtmp also records logouts,
+// boots and runlevel changes. The caller has to hold loginsFilesMtx.
+func readUtmpFile(path string, sessionsOnly bool) (map[string]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var inode uint64
+	if stat, ok := fi.Sys().(*syscall.Stat_t); ok {
+		inode = stat.Ino
+	}
+
+	f, ok := loginsFiles[path]
+	if !ok || f.inode != inode || fi.Size() < f.offset {
+		f = &utmpFile{inode: inode, counts: map[string]float64{}}
+		loginsFiles[path] = f
+	}
+
+	if _, err := file.Seek(f.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	record := make([]byte, utmpSize)
+	for {
+		// A partially written record is read again on the next scrape.
+		if _, err := io.ReadFull(file, record); err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("couldn't read %q: %s", path, err)
+		}
+		f.offset += utmpSize
+
+		if sessionsOnly && nlenc.Uint16(record[0:2]) != utmpUserProcess {
+			continue
+		}
+		line := nlenc.String(record[utmpLineOffset : utmpLineOffset+utmpLineSize])
+		host := nlenc.String(record[utmpHostOffset : utmpHostOffset+utmpHostSize])
+		f.counts[loginMethod(line, host)]++
+	}
+	return f.counts, nil
+}
+
+// loginMethod derives the method of a login from its terminal line and
+// remote host. sshd records failed logins with a "ssh:notty" line, while
+// successful logins get a pseudo-terminal and are reported as remote.
+func loginMethod(line, host string) string {
+	switch {
+	case strings.HasPrefix(line, "ssh"):
+		return "ssh"
+	case host != "" && host != ":0" && !strings.HasPrefix(host, ":0."):
+		return "remote"
+	case strings.HasPrefix(line, "pts/"):
+		return "local"
+	case strings.HasPrefix(line, "tty"), strings.HasPrefix(line, "hvc"), line == "console":
+		return "console"
+	}
+	return "other"
+}
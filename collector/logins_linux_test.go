@@ -0,0 +1,96 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nologins
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mdlayher/netlink/nlenc"
+)
+
+func utmpRecord(typ uint16, line, host string) []byte {
+	record := make([]byte, utmpSize)
+	nlenc.PutUint16(record[0:2], typ)
+	copy(record[utmpLineOffset:], line)
+	copy(record[utmpHostOffset:], host)
+	return record
+}
+
+func TestReadUtmpFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "wtmp")
+
+	var content []byte
+	for _, r := range [][]byte{
+		utmpRecord(utmpUserProcess, "pts/0", "192.0.2.1"),
+		utmpRecord(8, "pts/0", ""),
+		utmpRecord(utmpUserProcess, "tty1", ""),
+		utmpRecord(2, "~", "5.0.0"),
+		utmpRecord(utmpUserProcess, "pts/1", ":0"),
+	} {
+		content = append(content, r...)
+	}
+	// A record which is still being written.
+	content = append(content, utmpRecord(utmpUserProcess, "pts/2", "192.0.2.2")[:100]...)
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loginsFilesMtx.Lock()
+	defer loginsFilesMtx.Unlock()
+
+	counts, err := readUtmpFile(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]float64{"remote": 1, "console": 1, "local": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("want %v, got %v", want, counts)
+	}
+
+	content = append(content[:5*utmpSize], utmpRecord(utmpUserProcess, "pts/2", "192.0.2.2")...)
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if counts, err = readUtmpFile(path, true); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]float64{"remote": 2, "console": 1, "local": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("want %v after append, got %v", want, counts)
+	}
+
+	// Rotation replaces the file.
+	rotated := filepath.Join(dir, "wtmp.new")
+	if err := ioutil.WriteFile(rotated, utmpRecord(6, "ssh:notty", "192.0.2.3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(rotated, path); err != nil {
+		t.Fatal(err)
+	}
+	if counts, err = readUtmpFile(path, false); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]float64{"ssh": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("want %v after rotation, got %v", want, counts)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noloop
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const loopSubsystem = "loop"
+
+type loopCollector struct {
+	info   *prometheus.Desc
+	size   *prometheus.Desc
+	offset *prometheus.Desc
+}
+
+func init() {
+	registerCollector(loopSubsystem, defaultDisabled, NewLoopCollector)
+}
+
+// NewLoopCollector returns a new Collector exposing loop device statistics.
+func NewLoopCollector() (Collector, error) {
+	return &loopCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, loopSubsystem, "info"),
+			"File backing the loop device, value is always 1.",
+			[]string{"device", "backing_file"}, nil,
+		),
+		size: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, loopSubsystem, "size_bytes"),
+			"Size of the loop device.",
+			[]string{"device"}, nil,
+		),
+		offset: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, loopSubsystem, "offset_bytes"),
+			"Offset of the loop device within the backing file.",
+			[]string{"device"}, nil,
+		),
+	}, nil
+}
+
+func (c *loopCollector) Update(ch chan<- prometheus.Metric) error {
+	// The loop directory only exists for devices bound to a backing file.
+	devices, err := filepath.Glob(sysFilePath("block/loop*/loop"))
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range devices {
+		device := filepath.Base(filepath.Dir(dir))
+
+		backingFile, err := ioutil.ReadFile(filepath.Join(dir, "backing_file"))
+		if err != nil {
+			return fmt.Errorf("couldn't get backing file of %s: %s", device, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, device, strings.TrimSpace(string(backingFile)))
+
+		sectors, err := readUintFromFile(filepath.Join(filepath.Dir(dir), "size"))
+		if err != nil {
+			return fmt.Errorf("couldn't get size of %s: %s", device, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(sectors*512), device)
+
+		offset, err := readUintFromFile(filepath.Join(dir, "offset"))
+		if err != nil {
+			return fmt.Errorf("couldn't get offset of %s: %s", device, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.offset, prometheus.GaugeValue, float64(offset), device)
+	}
+
+	return nil
+}
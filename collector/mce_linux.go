@@ -0,0 +1,263 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nomce
+
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	mceSubsystem = "mce"
+
+	// mceRecordTrigger is the hist trigger counting the machine check
+	// records by CPU and bank.
+	mceRecordTrigger = "hist:keys=cpu,bank"
+)
+
+var (
+	mceBankRecords = kingpin.Flag("collector.mce.bank-records", "Count machine check records per CPU and bank with a hist trigger on the mce/mce_record tracepoint, which requires CONFIG_HIST_TRIGGERS and is left in place.").Bool()
+
+	mceRecordRE = regexp.MustCompile(`^\{ cpu: +(\d+), bank: +(\d+) \} hitcount: +(\d+)$`)
+)
+
+type mceCollector struct {
+	exceptions        *prometheus.Desc
+	polls             *prometheus.Desc
+	banks             *prometheus.Desc
+	checkInterval     *prometheus.Desc
+	ignoreCorrectable *prometheus.Desc
+	bankRecords       *prometheus.Desc
+}
+
+func init() {
+	registerCollector(mceSubsystem, defaultDisabled, NewMCECollector)
+}
+
+// NewMCECollector returns a new Collector exposing machine check exception statistics.
+func NewMCECollector() (Collector, error) {
+	if *mceBankRecords {
+		// Adding the same trigger again fails with EEXIST, e.g. after a
+		// restart.
+		trigger := filepath.Join(tracefsDir(), "events/mce/mce_record/trigger")
+		err := ioutil.WriteFile(trigger, []byte(mceRecordTrigger), 0644)
+		if err != nil && !os.IsExist(err) {
+			return nil, fmt.Errorf("couldn't add hist trigger to event mce/mce_record: %s", err)
+		}
+	}
+	return &mceCollector{
+		exceptions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, mceSubsystem, "exceptions_total"),
+			"Number of machine check exceptions raised on the CPU.",
+			[]string{"cpu"}, nil,
+		),
+		polls: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, mceSubsystem, "polls_total"),
+			"Number of times the machine check banks of the CPU were polled for corrected errors.",
+			[]string{"cpu"}, nil,
+		),
+		banks: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, mceSubsystem, "banks"),
+			"Number of machine check banks of the CPU.",
+			[]string{"cpu"}, nil,
+		),
+		checkInterval: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, mceSubsystem, "check_interval_seconds"),
+			"Interval in which the machine check banks are polled for corrected errors.",
+			[]string{"cpu"}, nil,
+		),
+		ignoreCorrectable: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, mceSubsystem, "ignore_correctable"),
+			"Whether corrected errors are ignored, 1 if they are.",
+			[]string{"cpu"}, nil,
+		),
+		bankRecords: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, mceSubsystem, "bank_records_total"),
+			"Number of machine check records logged for the bank of the CPU since the collector added its hist trigger.",
+			[]string{"cpu", "bank"}, nil,
+		),
+	}, nil
+}
+
+func (c *mceCollector) Update(ch chan<- prometheus.Metric) error {
+	if err := c.updateInterrupts(ch); err != nil {
+		return err
+	}
+	if *mceBankRecords {
+		if err := c.updateBankRecords(ch); err != nil {
+			return err
+		}
+	}
+
+	// The machinecheck devices only exist on x86.
+	cpus, err := filepath.Glob(sysFilePath("devices/system/machinecheck/machinecheck[0-9]*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range cpus {
+		cpu := strings.TrimPrefix(filepath.Base(dir), "machinecheck")
+
+		banks, err := filepath.Glob(filepath.Join(dir, "bank[0-9]*"))
+		if err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(c.banks, prometheus.GaugeValue, float64(len(banks)), cpu)
+
+		interval, err := readUintFromFile(filepath.Join(dir, "check_interval"))
+		if err != nil {
+			return fmt.Errorf("couldn't get check_interval of cpu %s: %s", cpu, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.checkInterval, prometheus.GaugeValue, float64(interval), cpu)
+
+		ignore, err := readUintFromFile(filepath.Join(dir, "ignore_ce"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("couldn't get ignore_ce of cpu %s: %s", cpu, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.ignoreCorrectable, prometheus.GaugeValue, float64(ignore), cpu)
+	}
+
+	return nil
+}
+
+// updateInterrupts exposes the per CPU machine check counts, which the kernel
+// reports as the MCE and MCP rows of /proc/interrupts.
+func (c *mceCollector) updateInterrupts(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("interrupts"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	counts, err := parseMCEInterrupts(file)
+	if err != nil {
+		return fmt.Errorf("couldn't parse interrupts: %s", err)
+	}
+	if len(counts) == 0 {
+		log.Debugf("No machine check counts found in interrupts")
+		return nil
+	}
+
+	for cpu, v := range counts["MCE"] {
+		ch <- prometheus.MustNewConstMetric(c.exceptions, prometheus.CounterValue, v, cpu)
+	}
+	for cpu, v := range counts["MCP"] {
+		ch <- prometheus.MustNewConstMetric(c.polls, prometheus.CounterValue, v, cpu)
+	}
+	return nil
+}
+
+// parseMCEInterrupts returns the values of the MCE and MCP rows of
+// /proc/interrupts by CPU. The header only has columns for online CPUs,
+// e.g. "CPU0 CPU2" if CPU1 is offline.
+func parseMCEInterrupts(r io.Reader) (map[string]map[string]float64, error) {
+	var (
+		counts  = make(map[string]map[string]float64)
+		scanner = bufio.NewScanner(r)
+	)
+
+	if !scanner.Scan() {
+		return nil, errors.New("interrupts empty")
+	}
+	var cpus []string
+	for _, field := range strings.Fields(scanner.Text()) {
+		if !strings.HasPrefix(field, "CPU") {
+			return nil, fmt.Errorf("invalid header field %q", field)
+		}
+		cpus = append(cpus, strings.TrimPrefix(field, "CPU"))
+	}
+
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < len(cpus)+1 || (parts[0] != "MCE:" && parts[0] != "MCP:") {
+			continue
+		}
+		values := make(map[string]float64, len(cpus))
+		for i, field := range parts[1 : len(cpus)+1] {
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q in %s row: %s", field, parts[0], err)
+			}
+			values[cpus[i]] = v
+		}
+		counts[strings.TrimSuffix(parts[0], ":")] = values
+	}
+
+	return counts, scanner.Err()
+}
+
+// updateBankRecords exposes the machine check records counted by the hist
+// trigger. The bank files of the machinecheck devices only hold the
+// control masks of the banks, not counts.
+func (c *mceCollector) updateBankRecords(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(filepath.Join(tracefsDir(), "events/mce/mce_record/hist"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	records, err := parseMCERecordHist(file)
+	if err != nil {
+		return fmt.Errorf("couldn't parse histogram of event mce/mce_record: %s", err)
+	}
+	for _, r := range records {
+		ch <- prometheus.MustNewConstMetric(c.bankRecords, prometheus.CounterValue, r.count, r.cpu, r.bank)
+	}
+	return nil
+}
+
+type mceBankRecord struct {
+	cpu   string
+	bank  string
+	count float64
+}
+
+// parseMCERecordHist returns the entries of the hist file of the
+// mce/mce_record event, e.g.
+//
+//	{ cpu:          2, bank:          4 } hitcount:          3
+func parseMCERecordHist(r io.Reader) ([]mceBankRecord, error) {
+	var (
+		records []mceBankRecord
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		m := mceRecordRE.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+		count, err := strconv.ParseFloat(m[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid line %q", scanner.Text())
+		}
+		records = append(records, mceBankRecord{cpu: m[1], bank: m[2], count: count})
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMCEInterrupts(t *testing.T) {
+	file, err := os.Open("fixtures/proc/interrupts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	counts, err := parseMCEInterrupts(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]float64{
+		"MCE": {"0": 0, "1": 0, "2": 0, "3": 0},
+		"MCP": {"0": 2406, "1": 2399, "2": 2399, "3": 2399},
+	}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("want counts %v, got %v", want, counts)
+	}
+}
+
+func TestParseMCEInterruptsOfflineCPU(t *testing.T) {
+	// CPU1 and CPU3 are offline.
+	interrupts := `           CPU0       CPU2       CPU4
+  0:         36          0          0   IO-APIC   2-edge      timer
+MCE:          1          0          3   Machine check exceptions
+MCP:         12         11         10   Machine check polls
+`
+	counts, err := parseMCEInterrupts(strings.NewReader(interrupts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]float64{
+		"MCE": {"0": 1, "2": 0, "4": 3},
+		"MCP": {"0": 12, "2": 11, "4": 10},
+	}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("want counts %v, got %v", want, counts)
+	}
+}
+
+func TestParseMCERecordHist(t *testing.T) {
+	hist := `# event histogram
+#
+# trigger info: hist:keys=cpu,bank:vals=hitcount:sort=hitcount:size=2048 [active]
+#
+
+{ cpu:          2, bank:          4 } hitcount:          1
+{ cpu:          0, bank:         17 } hitcount:          3
+
+Totals:
+    Hits: 4
+    Entries: 2
+    Dropped: 0
+`
+	records, err := parseMCERecordHist(strings.NewReader(hist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []mceBankRecord{
+		{cpu: "2", bank: "4", count: 1},
+		{cpu: "0", bank: "17", count: 3},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("want records %+v, got %+v", want, records)
+	}
+}
@@ -16,8 +16,14 @@
 package collector
 
 import (
+	"bufio"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
@@ -88,8 +94,97 @@ var (
 		[]string{"device"},
 		nil,
 	)
+
+	syncActionDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "md", "sync_action"),
+		"Indicates the current sync action of md-device.",
+		[]string{"device", "action"},
+		nil,
+	)
+
+	syncCompletedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "md", "sync_completed_ratio"),
+		"Fraction of the current sync action that has completed.",
+		[]string{"device"},
+		nil,
+	)
+
+	syncSpeedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "md", "sync_speed_bytes"),
+		"Current speed of the sync action in bytes per second.",
+		[]string{"device"},
+		nil,
+	)
+
+	mismatchDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "md", "mismatch_sectors"),
+		"Number of sectors found to be inconsistent by the last check or repair.",
+		[]string{"device"},
+		nil,
+	)
+
+	memberStateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "md", "member_state"),
+		"Indicates the state flags of a member device of md-device.",
+		[]string{"device", "member", "state"},
+		nil,
+	)
+
+	memberErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "md", "member_read_errors_total"),
+		"Number of read errors on a member device that were corrected without failing it.",
+		[]string{"device", "member"},
+		nil,
+	)
+
+	bitmapPagesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "md", "bitmap_pages"),
+		"Number of pages of the write-intent bitmap of md-device.",
+		[]string{"device"},
+		nil,
+	)
+
+	bitmapPagesUsedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "md", "bitmap_pages_used"),
+		"Number of allocated pages of the write-intent bitmap of md-device.",
+		[]string{"device"},
+		nil,
+	)
+
+	bitmapChunkDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "md", "bitmap_chunk_size_bytes"),
+		"Size of a chunk tracked by the write-intent bitmap of md-device.",
+		[]string{"device"},
+		nil,
+	)
+
+	mdSyncActions  = []string{"idle", "resync", "recover", "check", "repair", "reshape", "frozen"}
+	mdMemberStates = []string{"faulty", "in_sync", "spare", "writemostly", "blocked", "write_error", "want_replacement", "replacement"}
+
+	mdstatDeviceRE = regexp.MustCompile(`^(md\S+)\s*:`)
+	mdstatBitmapRE = regexp.MustCompile(`bitmap: (\d+)/(\d+) pages \[\d+KB\], (\d+)KB chunk`)
 )
 
+// mdBitmap holds the write-intent bitmap usage reported in /proc/mdstat.
+type mdBitmap struct {
+	pagesUsed uint64
+	pages     uint64
+	chunkSize uint64
+}
+
+// Describe sends the descriptors of the md metrics, which are package level.
+func (c *mdadmCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		activeDesc, inActiveDesc, recoveringDesc, resyncDesc,
+		disksDesc, disksTotalDesc, blocksTotalDesc, blocksSyncedDesc,
+		syncActionDesc, syncCompletedDesc, syncSpeedDesc, mismatchDesc,
+		memberStateDesc, memberErrorsDesc,
+		bitmapPagesDesc, bitmapPagesUsedDesc, bitmapChunkDesc,
+	} {
+		ch <- desc
+	}
+}
+
 func (c *mdadmCollector) Update(ch chan<- prometheus.Metric) error {
 	fs, errFs := procfs.NewFS(*procPath)
 
@@ -108,6 +203,11 @@ func (c *mdadmCollector) Update(ch chan<- prometheus.Metric) error {
 		return fmt.Errorf("error parsing mdstatus: %s", err)
 	}
 
+	bitmaps, err := parseMdstatBitmaps(procFilePath("mdstat"))
+	if err != nil {
+		return fmt.Errorf("error parsing mdstat bitmaps: %s", err)
+	}
+
 	for _, mdStat := range mdStats {
 		log.Debugf("collecting metrics for device %s", mdStat.Name)
 
@@ -182,7 +282,175 @@ func (c *mdadmCollector) Update(ch chan<- prometheus.Metric) error {
 			float64(mdStat.BlocksSynced),
 			mdStat.Name,
 		)
+
+		if b, ok := bitmaps[mdStat.Name]; ok {
+			ch <- prometheus.MustNewConstMetric(bitmapPagesDesc, prometheus.GaugeValue, float64(b.pages), mdStat.Name)
+			ch <- prometheus.MustNewConstMetric(bitmapPagesUsedDesc, prometheus.GaugeValue, float64(b.pagesUsed), mdStat.Name)
+			ch <- prometheus.MustNewConstMetric(bitmapChunkDesc, prometheus.GaugeValue, float64(b.chunkSize), mdStat.Name)
+		}
+
+		if err := updateMdSysfs(ch, mdStat.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateMdSysfs exposes the array and member details found in
+// /sys/block/<device>/md. Arrays without a sysfs directory are skipped.
+func updateMdSysfs(ch chan<- prometheus.Metric, device string) error {
+	mdDir := sysFilePath(filepath.Join("block", device, "md"))
+	if _, err := os.Stat(mdDir); err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("Not collecting md sysfs details, directory does not exist: %s", mdDir)
+			return nil
+		}
+		return err
+	}
+
+	action, err := readMdSysfsString(filepath.Join(mdDir, "sync_action"))
+	if err != nil {
+		return err
+	}
+	if action != "" {
+		for _, a := range mdSyncActions {
+			v := 0.0
+			if a == action {
+				v = 1
+			}
+			ch <- prometheus.MustNewConstMetric(syncActionDesc, prometheus.GaugeValue, v, device, a)
+		}
+	}
+
+	completed, err := readMdSysfsString(filepath.Join(mdDir, "sync_completed"))
+	if err != nil {
+		return err
+	}
+	if done, total, ok := parseMdSyncCompleted(completed); ok {
+		ch <- prometheus.MustNewConstMetric(syncCompletedDesc, prometheus.GaugeValue, done/total, device)
+	}
+
+	speed, err := readMdSysfsString(filepath.Join(mdDir, "sync_speed"))
+	if err != nil {
+		return err
+	}
+	if v, err := strconv.ParseFloat(speed, 64); err == nil {
+		// sync_speed is reported in KiB/s.
+		ch <- prometheus.MustNewConstMetric(syncSpeedDesc, prometheus.GaugeValue, v*1024, device)
+	}
+
+	mismatch, err := readMdSysfsString(filepath.Join(mdDir, "mismatch_cnt"))
+	if err != nil {
+		return err
+	}
+	if v, err := strconv.ParseFloat(mismatch, 64); err == nil {
+		ch <- prometheus.MustNewConstMetric(mismatchDesc, prometheus.GaugeValue, v, device)
+	}
+
+	members, err := filepath.Glob(filepath.Join(mdDir, "dev-*"))
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		member := strings.TrimPrefix(filepath.Base(m), "dev-")
+
+		state, err := readMdSysfsString(filepath.Join(m, "state"))
+		if err != nil {
+			return err
+		}
+		flags := make(map[string]bool)
+		for _, f := range strings.Split(state, ",") {
+			flags[f] = true
+		}
+		for _, s := range mdMemberStates {
+			v := 0.0
+			if flags[s] {
+				v = 1
+			}
+			ch <- prometheus.MustNewConstMetric(memberStateDesc, prometheus.GaugeValue, v, device, member, s)
+		}
+
+		errors, err := readMdSysfsString(filepath.Join(m, "errors"))
+		if err != nil {
+			return err
+		}
+		if v, err := strconv.ParseFloat(errors, 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(memberErrorsDesc, prometheus.CounterValue, v, device, member)
+		}
 	}
 
 	return nil
 }
+
+// readMdSysfsString returns the trimmed content of an md sysfs attribute or
+// an empty string if the attribute is not present on this kernel.
+func readMdSysfsString(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// parseMdSyncCompleted parses the "done / total" sector counts of the
+// sync_completed attribute, which reads "none" while no sync is running.
+func parseMdSyncCompleted(s string) (float64, float64, bool) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	done, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	total, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || total == 0 {
+		return 0, 0, false
+	}
+	return done, total, true
+}
+
+// parseMdstatBitmaps returns the bitmap usage of all arrays in mdstat
+// which have a write-intent bitmap, keyed by device name.
+func parseMdstatBitmaps(path string) (map[string]mdBitmap, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var (
+		bitmaps = make(map[string]mdBitmap)
+		device  string
+		scanner = bufio.NewScanner(file)
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := mdstatDeviceRE.FindStringSubmatch(line); m != nil {
+			device = m[1]
+			continue
+		}
+		m := mdstatBitmapRE.FindStringSubmatch(line)
+		if m == nil || device == "" {
+			continue
+		}
+		var b mdBitmap
+		if b.pagesUsed, err = strconv.ParseUint(m[1], 10, 64); err != nil {
+			return nil, err
+		}
+		if b.pages, err = strconv.ParseUint(m[2], 10, 64); err != nil {
+			return nil, err
+		}
+		if b.chunkSize, err = strconv.ParseUint(m[3], 10, 64); err != nil {
+			return nil, err
+		}
+		b.chunkSize *= 1024
+		bitmaps[device] = b
+	}
+
+	return bitmaps, scanner.Err()
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nomdadm
+
+package collector
+
+import "testing"
+
+func TestMdstatBitmaps(t *testing.T) {
+	bitmaps, err := parseMdstatBitmaps("fixtures/proc/mdstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 1, len(bitmaps); want != got {
+		t.Fatalf("want %d bitmaps, got %d", want, got)
+	}
+
+	b, ok := bitmaps["md7"]
+	if !ok {
+		t.Fatal("missing bitmap for md7")
+	}
+	if want, got := uint64(30), b.pages; want != got {
+		t.Errorf("want bitmap pages %d, got %d", want, got)
+	}
+	if want, got := uint64(0), b.pagesUsed; want != got {
+		t.Errorf("want bitmap pages used %d, got %d", want, got)
+	}
+	if want, got := uint64(65536*1024), b.chunkSize; want != got {
+		t.Errorf("want bitmap chunk size %d, got %d", want, got)
+	}
+}
+
+func TestMdSyncCompleted(t *testing.T) {
+	for _, tt := range []struct {
+		in    string
+		done  float64
+		total float64
+		ok    bool
+	}{
+		{in: "33551104 / 390620288", done: 33551104, total: 390620288, ok: true},
+		{in: "none"},
+		{in: "delayed"},
+		{in: "0 / 0"},
+	} {
+		done, total, ok := parseMdSyncCompleted(tt.in)
+		if ok != tt.ok || done != tt.done || total != tt.total {
+			t.Errorf("%q: want (%v, %v, %v), got (%v, %v, %v)", tt.in, tt.done, tt.total, tt.ok, done, total, ok)
+		}
+	}
+}
@@ -18,17 +18,23 @@ package collector
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 const (
 	memInfoSubsystem = "memory"
 )
 
-type meminfoCollector struct{}
+var memInfoFields = kingpin.Flag("collector.meminfo.fields", "Regexp of fields to return for meminfo collector, e.g. MemTotal_bytes.").Default(".+").String()
+
+type meminfoCollector struct {
+	fieldPattern *regexp.Regexp
+}
 
 func init() {
 	registerCollector("meminfo", defaultEnabled, NewMeminfoCollector)
@@ -36,7 +42,13 @@ func init() {
 
 // NewMeminfoCollector returns a new Collector exposing memory stats.
 func NewMeminfoCollector() (Collector, error) {
-	return &meminfoCollector{}, nil
+	pattern, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", *memInfoFields))
+	if err != nil {
+		return nil, fmt.Errorf("invalid fields pattern: %s", err)
+	}
+	return &meminfoCollector{
+		fieldPattern: pattern,
+	}, nil
 }
 
 // Update calls (*meminfoCollector).getMemInfo to get the platform specific
@@ -49,6 +61,9 @@ func (c *meminfoCollector) Update(ch chan<- prometheus.Metric) error {
 	}
 	log.Debugf("Set node_mem: %#v", memInfo)
 	for k, v := range memInfo {
+		if !c.fieldPattern.MatchString(k) {
+			continue
+		}
 		if strings.HasSuffix(k, "_total") {
 			metricType = prometheus.CounterValue
 		} else {
@@ -37,4 +37,8 @@ func TestMemInfo(t *testing.T) {
 	if want, got := 3787456512.0, memInfo["DirectMap2M_bytes"]; want != got {
 		t.Errorf("want memory directMap2M %f, got %f", want, got)
 	}
+
+	if want, got := 10223616.0, memInfo["Percpu_bytes"]; want != got {
+		t.Errorf("want memory percpu %f, got %f", want, got)
+	}
 }
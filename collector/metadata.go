@@ -0,0 +1,219 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/log"
+)
+
+var (
+	descType      = reflect.TypeOf((*prometheus.Desc)(nil))
+	valueTypeType = reflect.TypeOf(prometheus.ValueType(0))
+)
+
+// MetricMetadata describes a metric family produced by a collector.
+type MetricMetadata struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Help      string `json:"help"`
+	Collector string `json:"collector"`
+	// Cardinality is the number of series the collector currently
+	// produces, as an estimate of the series it produces over time.
+	Cardinality int `json:"cardinality"`
+}
+
+// describer is implemented by collectors with descriptors which aren't held
+// in their fields, e.g. package level ones.
+type describer interface {
+	Describe(ch chan<- *prometheus.Desc)
+}
+
+// metadataCollector runs a single collector as an unchecked
+// prometheus.Collector.
+type metadataCollector struct {
+	name      string
+	collector Collector
+}
+
+// Describe implements the prometheus.Collector interface.
+func (m metadataCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements the prometheus.Collector interface.
+func (m metadataCollector) Collect(ch chan<- prometheus.Metric) {
+	if err := m.collector.Update(ch); err != nil {
+		log.Debugf("%s collector failed while collecting metadata: %s", m.name, err)
+	}
+}
+
+// Metadata returns the metric families of all collectors, sorted by name.
+// They are listed from the descriptors of the collectors, the collectors are
+// run once to count their series and to find the types of the families and
+// the families of descriptors created while collecting.
+func (n NodeCollector) Metadata() []MetricMetadata {
+	var (
+		mtx      sync.Mutex
+		metadata []MetricMetadata
+		wg       sync.WaitGroup
+	)
+	wg.Add(len(n.Collectors))
+	for name, c := range n.Collectors {
+		go func(name string, c Collector) {
+			defer wg.Done()
+			families := collectorMetadata(name, c)
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			for _, m := range families {
+				metadata = append(metadata, *m)
+			}
+		}(name, c)
+	}
+	wg.Wait()
+
+	sort.Slice(metadata, func(i, j int) bool {
+		if metadata[i].Name != metadata[j].Name {
+			return metadata[i].Name < metadata[j].Name
+		}
+		return metadata[i].Collector < metadata[j].Collector
+	})
+	return metadata
+}
+
+// collectorMetadata returns the metric families of a collector by name.
+func collectorMetadata(name string, c Collector) map[string]*MetricMetadata {
+	families := map[string]*MetricMetadata{}
+	for desc, typ := range collectorDescs(c) {
+		var fqName, help string
+		// The name and help of a descriptor are only accessible through
+		// its string representation.
+		if _, err := fmt.Sscanf(desc.String(), "Desc{fqName: %q, help: %q,", &fqName, &help); err != nil {
+			log.Debugf("Couldn't parse descriptor %s of %s collector: %s", desc, name, err)
+			continue
+		}
+		if m, ok := families[fqName]; ok && m.Type != "unknown" {
+			continue
+		}
+		families[fqName] = &MetricMetadata{Name: fqName, Type: typ, Help: help, Collector: name}
+	}
+
+	r := prometheus.NewRegistry()
+	if err := r.Register(metadataCollector{name: name, collector: c}); err != nil {
+		log.Errorf("Couldn't register %s collector for metadata: %s", name, err)
+		return families
+	}
+	// Families are returned despite inconsistent metrics, which are
+	// reported by the scrape as well.
+	mfs, err := r.Gather()
+	if err != nil {
+		log.Debugf("Couldn't gather all metrics of %s collector for metadata: %s", name, err)
+	}
+	for _, mf := range mfs {
+		m, ok := families[mf.GetName()]
+		if !ok {
+			m = &MetricMetadata{Name: mf.GetName(), Help: mf.GetHelp(), Collector: name}
+			families[m.Name] = m
+		}
+		m.Type = strings.ToLower(mf.GetType().String())
+		m.Cardinality = len(mf.Metric)
+	}
+	return families
+}
+
+// collectorDescs returns the descriptors of a collector with the type of
+// their metrics, "unknown" unless they are held together with a
+// prometheus.ValueType like in typedDesc.
+func collectorDescs(c Collector) map[*prometheus.Desc]string {
+	descs := map[*prometheus.Desc]string{}
+	addDesc := func(desc *prometheus.Desc, typ string) {
+		if t, ok := descs[desc]; !ok || t == "unknown" {
+			descs[desc] = typ
+		}
+	}
+
+	if d, ok := c.(describer); ok {
+		ch := make(chan *prometheus.Desc)
+		go func() {
+			d.Describe(ch)
+			close(ch)
+		}()
+		for desc := range ch {
+			addDesc(desc, "unknown")
+		}
+	}
+	walkDescs(reflect.ValueOf(c), addDesc, map[uintptr]bool{})
+	return descs
+}
+
+// walkDescs calls fn with the descriptors reachable from v. Unexported
+// fields can't be converted to interfaces, so descriptors are taken from
+// their pointers.
+func walkDescs(v reflect.Value, fn func(*prometheus.Desc, string), seen map[uintptr]bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			return
+		}
+		seen[v.Pointer()] = true
+		if v.Type() == descType {
+			fn((*prometheus.Desc)(unsafe.Pointer(v.Pointer())), "unknown")
+			return
+		}
+		walkDescs(v.Elem(), fn, seen)
+	case reflect.Interface:
+		walkDescs(v.Elem(), fn, seen)
+	case reflect.Struct:
+		typ := "unknown"
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.Type() == valueTypeType {
+				typ = valueTypeName(prometheus.ValueType(f.Int()))
+			}
+		}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			if f.Type() == descType && !f.IsNil() {
+				fn((*prometheus.Desc)(unsafe.Pointer(f.Pointer())), typ)
+				continue
+			}
+			walkDescs(f, fn, seen)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkDescs(v.Index(i), fn, seen)
+		}
+	case reflect.Map:
+		for iter := v.MapRange(); iter.Next(); {
+			walkDescs(iter.Value(), fn, seen)
+		}
+	}
+}
+
+// valueTypeName returns the name of the metric type of a value type.
+func valueTypeName(t prometheus.ValueType) string {
+	switch t {
+	case prometheus.CounterValue:
+		return strings.ToLower(dto.MetricType_COUNTER.String())
+	case prometheus.GaugeValue:
+		return strings.ToLower(dto.MetricType_GAUGE.String())
+	}
+	return strings.ToLower(dto.MetricType_UNTYPED.String())
+}
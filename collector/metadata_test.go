@@ -0,0 +1,103 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type testCollector struct {
+	descs []*prometheus.Desc
+	err   error
+}
+
+func (c testCollector) Update(ch chan<- prometheus.Metric) error {
+	for i, desc := range c.descs {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, 1, "a")
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(i), "b")
+	}
+	return c.err
+}
+
+// idleCollector holds the descriptors of its metrics without producing them.
+type idleCollector struct {
+	bytes typedDesc
+	info  *prometheus.Desc
+}
+
+func (c idleCollector) Update(ch chan<- prometheus.Metric) error {
+	return nil
+}
+
+var describedDesc = prometheus.NewDesc("node_described_info", "Described.", nil, nil)
+
+// describedCollector has a package level descriptor.
+type describedCollector struct{}
+
+func (c describedCollector) Update(ch chan<- prometheus.Metric) error {
+	return nil
+}
+
+func (c describedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- describedDesc
+}
+
+// dynamicCollector creates its descriptors while collecting.
+type dynamicCollector struct{}
+
+func (c dynamicCollector) Update(ch chan<- prometheus.Metric) error {
+	ch <- prometheus.MustNewConstMetric(prometheus.NewDesc("node_dynamic", "Dynamic.", nil, nil), prometheus.GaugeValue, 1)
+	return nil
+}
+
+func TestMetadata(t *testing.T) {
+	labels := []string{"device"}
+	nc := NodeCollector{Collectors: map[string]Collector{
+		"foo": testCollector{descs: []*prometheus.Desc{
+			prometheus.NewDesc("node_foo_bytes_total", "Foo bytes.", labels, nil),
+		}},
+		// Failing collectors still report the metrics they produced.
+		"bar": testCollector{
+			descs: []*prometheus.Desc{
+				prometheus.NewDesc("node_bar_total", "Bar.", labels, nil),
+			},
+			err: errors.New("failed"),
+		},
+		"empty": testCollector{},
+		// Metrics which aren't produced at the time are listed from the
+		// descriptors.
+		"idle": idleCollector{
+			bytes: typedDesc{prometheus.NewDesc("node_idle_bytes", "Idle bytes.", labels, nil), prometheus.GaugeValue},
+			info:  prometheus.NewDesc("node_idle_info", "Idle.", labels, nil),
+		},
+		"described": describedCollector{},
+		"dynamic":   dynamicCollector{},
+	}}
+
+	want := []MetricMetadata{
+		{Name: "node_bar_total", Type: "counter", Help: "Bar.", Collector: "bar", Cardinality: 2},
+		{Name: "node_described_info", Type: "unknown", Help: "Described.", Collector: "described", Cardinality: 0},
+		{Name: "node_dynamic", Type: "gauge", Help: "Dynamic.", Collector: "dynamic", Cardinality: 1},
+		{Name: "node_foo_bytes_total", Type: "counter", Help: "Foo bytes.", Collector: "foo", Cardinality: 2},
+		{Name: "node_idle_bytes", Type: "gauge", Help: "Idle bytes.", Collector: "idle", Cardinality: 0},
+		{Name: "node_idle_info", Type: "unknown", Help: "Idle.", Collector: "idle", Cardinality: 0},
+	}
+	if got := nc.Metadata(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want metadata %+v, got %+v", want, got)
+	}
+}
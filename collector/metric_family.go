@@ -0,0 +1,180 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/log"
+)
+
+// convertMetricFamily converts the parsed metrics of a family, returning an
+// error instead of panicking if they are invalid, e.g. because of invalid
+// label names.
+func convertMetricFamily(metricFamily *dto.MetricFamily) ([]prometheus.Metric, error) {
+	var (
+		valType prometheus.ValueType
+		val     float64
+		metrics []prometheus.Metric
+	)
+
+	allLabelNames := map[string]struct{}{}
+	for _, metric := range metricFamily.Metric {
+		labels := metric.GetLabel()
+		for _, label := range labels {
+			if _, ok := allLabelNames[label.GetName()]; !ok {
+				allLabelNames[label.GetName()] = struct{}{}
+			}
+		}
+	}
+
+	for _, metric := range metricFamily.Metric {
+		if metric.TimestampMs != nil {
+			log.Warnf("Ignoring unsupported custom timestamp on textfile collector metric %v", metric)
+		}
+
+		labels := metric.GetLabel()
+		var names []string
+		var values []string
+		for _, label := range labels {
+			names = append(names, label.GetName())
+			values = append(values, label.GetValue())
+		}
+
+		for k := range allLabelNames {
+			present := false
+			for _, name := range names {
+				if k == name {
+					present = true
+					break
+				}
+			}
+			if !present {
+				names = append(names, k)
+				values = append(values, "")
+			}
+		}
+
+		desc := prometheus.NewDesc(*metricFamily.Name, metricFamily.GetHelp(), names, nil)
+		var (
+			m   prometheus.Metric
+			err error
+		)
+		metricType := metricFamily.GetType()
+		switch metricType {
+		case dto.MetricType_COUNTER:
+			valType = prometheus.CounterValue
+			val = metric.Counter.GetValue()
+
+		case dto.MetricType_GAUGE:
+			valType = prometheus.GaugeValue
+			val = metric.Gauge.GetValue()
+
+		case dto.MetricType_UNTYPED:
+			valType = prometheus.UntypedValue
+			val = metric.Untyped.GetValue()
+
+		case dto.MetricType_SUMMARY:
+			quantiles := map[float64]float64{}
+			for _, q := range metric.Summary.Quantile {
+				quantiles[q.GetQuantile()] = q.GetValue()
+			}
+			m, err = prometheus.NewConstSummary(
+				desc,
+				metric.Summary.GetSampleCount(),
+				metric.Summary.GetSampleSum(),
+				quantiles, values...,
+			)
+		case dto.MetricType_HISTOGRAM:
+			buckets := map[float64]uint64{}
+			for _, b := range metric.Histogram.Bucket {
+				buckets[b.GetUpperBound()] = b.GetCumulativeCount()
+			}
+			m, err = prometheus.NewConstHistogram(
+				desc,
+				metric.Histogram.GetSampleCount(),
+				metric.Histogram.GetSampleSum(),
+				buckets, values...,
+			)
+		default:
+			return nil, fmt.Errorf("unknown metric type %s of %s", metricType, metricFamily.GetName())
+		}
+		if metricType == dto.MetricType_GAUGE || metricType == dto.MetricType_COUNTER || metricType == dto.MetricType_UNTYPED {
+			m, err = prometheus.NewConstMetric(desc, valType, val, values...)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid metric %s: %s", metricFamily.GetName(), err)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// checkDuplicateSeries returns an error if a family contains several metrics
+// with the same labels.
+func checkDuplicateSeries(mf *dto.MetricFamily) error {
+	seen := map[string]bool{}
+	for _, m := range mf.Metric {
+		key := seriesKey(m)
+		if seen[key] {
+			return fmt.Errorf("duplicate series %s{%s}", mf.GetName(), strings.Replace(key, "\xff", ",", -1))
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// seriesKey returns the sorted label pairs of a metric, which identify its
+// series within the family.
+func seriesKey(m *dto.Metric) string {
+	pairs := make([]string, 0, len(m.Label))
+	for _, l := range m.Label {
+		pairs = append(pairs, l.GetName()+"="+l.GetValue())
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\xff")
+}
+
+// countSamples returns the number of samples of a family as exposed, e.g.
+// including the _sum and _count of summaries and histograms.
+func countSamples(mf *dto.MetricFamily) int {
+	samples := 0
+	for _, m := range mf.Metric {
+		switch mf.GetType() {
+		case dto.MetricType_SUMMARY:
+			samples += len(m.GetSummary().GetQuantile()) + 2
+		case dto.MetricType_HISTOGRAM:
+			samples += len(m.GetHistogram().GetBucket()) + 2
+		default:
+			samples++
+		}
+	}
+	return samples
+}
+
+// hasTimestamps returns true when metrics contain unsupported timestamps.
+func hasTimestamps(parsedFamilies map[string]*dto.MetricFamily) bool {
+	for _, mf := range parsedFamilies {
+		for _, m := range mf.Metric {
+			if m.TimestampMs != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
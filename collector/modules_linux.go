@@ -0,0 +1,186 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nomodules
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const modulesSubsystem = "kernel_module"
+
+var (
+	modulesInclude = kingpin.Flag("collector.modules.include", "Regexp of kernel modules to expose.").Default(".+").String()
+	modulesExclude = kingpin.Flag("collector.modules.exclude", "Regexp of kernel modules not to expose.").Default("").String()
+
+	// moduleTaints are the taint flags a module can set, see
+	// Documentation/admin-guide/tainted-kernels.rst.
+	moduleTaints = map[rune]string{
+		'P': "proprietary",
+		'F': "forced",
+		'C': "staging",
+		'O': "out_of_tree",
+		'E': "unsigned",
+		'K': "livepatch",
+		'X': "auxiliary",
+		'T': "test",
+	}
+)
+
+// kernelModule is a module of /proc/modules.
+type kernelModule struct {
+	name     string
+	size     float64
+	refcount float64
+	taints   string
+}
+
+type modulesCollector struct {
+	include      *regexp.Regexp
+	exclude      *regexp.Regexp
+	infoDesc     *prometheus.Desc
+	sizeDesc     *prometheus.Desc
+	refcountDesc *prometheus.Desc
+	taintDesc    *prometheus.Desc
+}
+
+func init() {
+	registerCollector("modules", defaultDisabled, NewModulesCollector)
+}
+
+// NewModulesCollector returns a new Collector exposing the loaded kernel
+// modules.
+func NewModulesCollector() (Collector, error) {
+	include, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", *modulesInclude))
+	if err != nil {
+		return nil, fmt.Errorf("invalid module include regexp: %s", err)
+	}
+	exclude, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", *modulesExclude))
+	if err != nil {
+		return nil, fmt.Errorf("invalid module exclude regexp: %s", err)
+	}
+
+	labels := []string{"module"}
+	return &modulesCollector{
+		include: include,
+		exclude: exclude,
+		infoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, modulesSubsystem, "info"),
+			"Loaded kernel module with its version and taint flags, value is always 1.",
+			[]string{"module", "version", "srcversion", "taints"}, nil,
+		),
+		sizeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, modulesSubsystem, "size_bytes"),
+			"Memory used by the kernel module.",
+			labels, nil,
+		),
+		refcountDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, modulesSubsystem, "refcount"),
+			"Number of references to the kernel module, -1 if module unloading is disabled.",
+			labels, nil,
+		),
+		taintDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, modulesSubsystem, "taint"),
+			"Taint flag the kernel module set on the kernel.",
+			[]string{"module", "flag"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *modulesCollector) Update(ch chan<- prometheus.Metric) error {
+	f, err := os.Open(procFilePath("modules"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	modules, err := parseModules(f)
+	if err != nil {
+		return fmt.Errorf("couldn't parse modules: %s", err)
+	}
+
+	for _, m := range modules {
+		if !c.include.MatchString(m.name) || c.exclude.MatchString(m.name) {
+			continue
+		}
+
+		// Only modules declaring a MODULE_VERSION have a version and
+		// srcversion.
+		dir := sysFilePath(filepath.Join("module", m.name))
+		version, _ := readTrimmedFile(filepath.Join(dir, "version"))
+		srcversion, _ := readTrimmedFile(filepath.Join(dir, "srcversion"))
+		ch <- prometheus.MustNewConstMetric(c.infoDesc, prometheus.GaugeValue, 1, m.name, version, srcversion, m.taints)
+		ch <- prometheus.MustNewConstMetric(c.sizeDesc, prometheus.GaugeValue, m.size, m.name)
+		ch <- prometheus.MustNewConstMetric(c.refcountDesc, prometheus.GaugeValue, m.refcount, m.name)
+		for _, flag := range m.taints {
+			name, ok := moduleTaints[flag]
+			if !ok {
+				name = string(flag)
+			}
+			ch <- prometheus.MustNewConstMetric(c.taintDesc, prometheus.GaugeValue, 1, m.name, name)
+		}
+	}
+	return nil
+}
+
+// parseModules parses /proc/modules, which has a line per module with its
+// name, size, reference count, dependencies, state, address and optionally
+// its taint flags and load state, e.g.
+//
+//	nvidia 35323904 1234 nvidia_modeset,nvidia_uvm, Live 0xffffffffc1234000 (POE)
+//
+// The reference count is "-" if the kernel doesn't support unloading modules.
+func parseModules(r io.Reader) ([]kernelModule, error) {
+	var (
+		modules []kernelModule
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			return nil, fmt.Errorf("invalid line %q", scanner.Text())
+		}
+
+		m := kernelModule{name: fields[0], refcount: -1}
+		size, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size of module %s: %s", m.name, err)
+		}
+		m.size = size
+		if fields[2] != "-" {
+			if m.refcount, err = strconv.ParseFloat(fields[2], 64); err != nil {
+				return nil, fmt.Errorf("invalid refcount of module %s: %s", m.name, err)
+			}
+		}
+		if len(fields) > 6 {
+			// The flags end with + or - while the module is loaded or
+			// unloaded.
+			m.taints = strings.Trim(fields[6], "()+-")
+		}
+		modules = append(modules, m)
+	}
+	return modules, scanner.Err()
+}
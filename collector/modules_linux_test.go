@@ -0,0 +1,56 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nomodules
+
+package collector
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseModules(t *testing.T) {
+	f, err := os.Open("fixtures/proc/modules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	modules, err := parseModules(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modules) != 5 {
+		t.Fatalf("want 5 modules, got %d", len(modules))
+	}
+	want := kernelModule{name: "nvidia", size: 35323904, refcount: 1234, taints: "POE"}
+	if !reflect.DeepEqual(modules[1], want) {
+		t.Errorf("want %+v, got %+v", want, modules[1])
+	}
+
+	modules, err = parseModules(strings.NewReader("loading 16384 - - Loading 0xffffffffc09bd000 (OE+)\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = kernelModule{name: "loading", size: 16384, refcount: -1, taints: "OE"}
+	if !reflect.DeepEqual(modules[0], want) {
+		t.Errorf("want %+v, got %+v", want, modules[0])
+	}
+
+	if _, err := parseModules(strings.NewReader("broken 16384\n")); err == nil {
+		t.Error("expected error for truncated line")
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nomountinfo
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"golang.org/x/sys/unix"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const mountSubsystem = "mount"
+
+var (
+	mountinfoIgnoredFSTypes = kingpin.Flag("collector.mountinfo.ignored-fs-types", "Regexp of filesystem types to ignore for mountinfo collector.").Default("^(autofs|binfmt_misc|bpf|cgroup2?|configfs|debugfs|devpts|fusectl|hugetlbfs|mqueue|nsfs|proc|pstore|rpc_pipefs|securityfs|selinuxfs|sysfs|tracefs)$").String()
+
+	// The mount table is watched continuously, so it's only opened once
+	// even though collectors are created per request.
+	mountWatcherOnce sync.Once
+	mountWatcherErr  error
+	mountWatcherInst *mountWatcher
+)
+
+// mountWatcher counts the changes of the mount table.
+type mountWatcher struct {
+	path string
+
+	mtx     sync.Mutex
+	changes float64
+}
+
+type mountinfoCollector struct {
+	watcher         *mountWatcher
+	ignoredFSTypes  *regexp.Regexp
+	infoDesc        *prometheus.Desc
+	tableChangeDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector("mountinfo", defaultDisabled, NewMountinfoCollector)
+}
+
+// NewMountinfoCollector returns a new Collector exposing the options of the
+// mounts and the number of changes of the mount table.
+func NewMountinfoCollector() (Collector, error) {
+	ignoredFSTypes, err := regexp.Compile(*mountinfoIgnoredFSTypes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mountinfo ignored fs types regexp: %s", err)
+	}
+
+	mountWatcherOnce.Do(func() {
+		mountWatcherInst, mountWatcherErr = newMountWatcher()
+	})
+	if mountWatcherErr != nil {
+		return nil, mountWatcherErr
+	}
+
+	return &mountinfoCollector{
+		watcher:        mountWatcherInst,
+		ignoredFSTypes: ignoredFSTypes,
+		infoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, mountSubsystem, "info"),
+			"Options of the mount. The mode is ro if the mount or its filesystem is read-only.",
+			[]string{"device", "mountpoint", "fstype", "mode", "atime", "nodev", "nosuid", "noexec", "idmapped"}, nil,
+		),
+		tableChangeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, mountSubsystem, "table_changes_total"),
+			"Number of times the mount table was seen changing, several changes in a row may be counted once.",
+			nil, nil,
+		),
+	}, nil
+}
+
+func newMountWatcher() (*mountWatcher, error) {
+	w := &mountWatcher{path: mountinfoPath()}
+	// The file isn't opened with os.Open, as the runtime's poller would
+	// consume the change events.
+	fd, err := unix.Open(w.path, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open mountinfo: %s", err)
+	}
+	go w.watch(fd)
+	return w, nil
+}
+
+// watch polls the mountinfo file, which signals a change of the mount table
+// with POLLPRI.
+func (w *mountWatcher) watch(fd int) {
+	defer unix.Close(fd)
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLPRI}}
+	for {
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			log.Errorf("Error watching mount table: %s", err)
+			return
+		}
+		if fds[0].Revents&unix.POLLPRI != 0 {
+			w.mtx.Lock()
+			w.changes++
+			w.mtx.Unlock()
+		}
+	}
+}
+
+// Update implements the Collector interface.
+func (c *mountinfoCollector) Update(ch chan<- prometheus.Metric) error {
+	c.watcher.mtx.Lock()
+	ch <- prometheus.MustNewConstMetric(c.tableChangeDesc, prometheus.CounterValue, c.watcher.changes)
+	c.watcher.mtx.Unlock()
+
+	f, err := os.Open(c.watcher.path)
+	if err != nil {
+		return fmt.Errorf("couldn't open mountinfo: %s", err)
+	}
+	defer f.Close()
+	mounts, err := parseMountInfo(f)
+	if err != nil {
+		return fmt.Errorf("couldn't parse mountinfo: %s", err)
+	}
+
+	// Overmounted mount points are listed more than once.
+	seen := map[string]bool{}
+	for _, m := range mounts {
+		if c.ignoredFSTypes.MatchString(m.fsType) {
+			continue
+		}
+		labels := []string{m.device, rootfsStripPrefix(m.mountPoint), m.fsType, "rw", mountAtime(m.options), "false", "false", "false", "false"}
+		if m.options["ro"] || m.superOptions["ro"] {
+			labels[3] = "ro"
+		}
+		for i, option := range []string{"nodev", "nosuid", "noexec", "idmapped"} {
+			if m.options[option] {
+				labels[5+i] = "true"
+			}
+		}
+		key := strings.Join(labels, "\x00")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		ch <- prometheus.MustNewConstMetric(c.infoDesc, prometheus.GaugeValue, 1, labels...)
+	}
+	return nil
+}
+
+// mountAtime returns how access times are updated.
+func mountAtime(options map[string]bool) string {
+	for _, option := range []string{"noatime", "relatime"} {
+		if options[option] {
+			return option
+		}
+	}
+	// The kernel doesn't list strictatime.
+	return "strictatime"
+}
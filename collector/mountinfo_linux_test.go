@@ -0,0 +1,60 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nomountinfo
+
+package collector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMountInfo(t *testing.T) {
+	in := `21 0 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 ro,errors=remount-ro
+42 21 0:40 / /mnt/with\040space rw,nosuid,nodev,noatime,idmapped master:2 shared:3 - vfat /dev/sdb1 rw,uid=1000
+`
+	mounts, err := parseMountInfo(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []mountInfo{
+		{
+			majorMinor:   "8:1",
+			device:       "/dev/sda1",
+			mountPoint:   "/",
+			fsType:       "ext4",
+			options:      map[string]bool{"rw": true, "relatime": true},
+			superOptions: map[string]bool{"ro": true, "errors=remount-ro": true},
+		},
+		{
+			majorMinor:   "0:40",
+			device:       "/dev/sdb1",
+			mountPoint:   "/mnt/with space",
+			fsType:       "vfat",
+			options:      map[string]bool{"rw": true, "nosuid": true, "nodev": true, "noatime": true, "idmapped": true},
+			superOptions: map[string]bool{"rw": true, "uid=1000": true},
+		},
+	}
+	if !reflect.DeepEqual(mounts, want) {
+		t.Errorf("want %+v, got %+v", want, mounts)
+	}
+	if got := mountAtime(mounts[1].options); got != "noatime" {
+		t.Errorf("want noatime, got %s", got)
+	}
+
+	if _, err := parseMountInfo(strings.NewReader("21 0 8:1 / / rw shared:1 ext4 /dev/sda1 rw\n")); err == nil {
+		t.Error("want error for line without separator")
+	}
+}
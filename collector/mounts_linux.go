@@ -0,0 +1,129 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/prometheus/common/log"
+)
+
+func mountPointDetails() ([]filesystemLabels, error) {
+	file, err := os.Open(procFilePath("1/mounts"))
+	if os.IsNotExist(err) {
+		// Fallback to `/proc/mounts` if `/proc/1/mounts` is missing due hidepid.
+		log.Debugf("Got %q reading root mounts, falling back to system mounts", err)
+		file, err = os.Open(procFilePath("mounts"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseFilesystemLabels(file)
+}
+
+func parseFilesystemLabels(r io.Reader) ([]filesystemLabels, error) {
+	var filesystems []filesystemLabels
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+
+		if len(parts) < 4 {
+			return nil, fmt.Errorf("malformed mount point information: %q", scanner.Text())
+		}
+
+		// Ensure we handle the translation of \040 and \011
+		// as per fstab(5).
+		parts[1] = strings.Replace(parts[1], "\\040", " ", -1)
+		parts[1] = strings.Replace(parts[1], "\\011", "\t", -1)
+
+		filesystems = append(filesystems, filesystemLabels{
+			device:     parts[0],
+			mountPoint: rootfsStripPrefix(parts[1]),
+			fsType:     parts[2],
+			options:    parts[3],
+		})
+	}
+
+	return filesystems, scanner.Err()
+}
+
+// mountinfoPath returns the mountinfo file of init, which may be hidden by
+// hidepid, in which case the mountinfo file of the exporter is used.
+func mountinfoPath() string {
+	path := procFilePath("1/mountinfo")
+	if _, err := os.Stat(path); err != nil {
+		log.Debugf("Got %q reading root mountinfo, falling back to own mountinfo", err)
+		path = procFilePath("self/mountinfo")
+	}
+	return path
+}
+
+// mountInfo is a line of /proc/<pid>/mountinfo.
+type mountInfo struct {
+	majorMinor                 string
+	device, mountPoint, fsType string
+	options, superOptions      map[string]bool
+}
+
+// parseMountInfo parses the mountinfo lines, e.g.
+//
+//	21 0 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw,errors=remount-ro
+//
+// with a variable number of optional fields up to the separator.
+func parseMountInfo(r io.Reader) ([]mountInfo, error) {
+	var mounts []mountInfo
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep == -1 || len(fields) < sep+4 {
+			return nil, fmt.Errorf("malformed mountinfo line %q", scanner.Text())
+		}
+
+		mountPoint := strings.Replace(fields[4], "\\040", " ", -1)
+		mountPoint = strings.Replace(mountPoint, "\\011", "\t", -1)
+		mounts = append(mounts, mountInfo{
+			majorMinor:   fields[2],
+			device:       fields[sep+2],
+			mountPoint:   mountPoint,
+			fsType:       fields[sep+1],
+			options:      parseMountOptions(fields[5]),
+			superOptions: parseMountOptions(fields[sep+3]),
+		})
+	}
+	return mounts, scanner.Err()
+}
+
+// parseMountOptions returns the set of options, including their values if
+// any, e.g. uid=1000.
+func parseMountOptions(options string) map[string]bool {
+	set := map[string]bool{}
+	for _, option := range strings.Split(options, ",") {
+		set[option] = true
+	}
+	return set
+}
@@ -15,13 +15,17 @@ package collector
 
 import (
 	"fmt"
+	"regexp"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 	"github.com/prometheus/procfs"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
 )
 
 var (
+	mountPointWhitelist = kingpin.Flag("collector.mountstats.mount-point-whitelist", "Regexp of NFS mount points to include in the mountstats collector.").Default(".+").String()
+
 	// 64-bit float mantissa: https://en.wikipedia.org/wiki/Double-precision_floating-point_format
 	float64Mantissa uint64 = 9007199254740992
 )
@@ -43,6 +47,7 @@ type mountStatsCollector struct {
 	// Per-operation statistics
 	NFSOperationsRequestsTotal            *prometheus.Desc
 	NFSOperationsTransmissionsTotal       *prometheus.Desc
+	NFSOperationsRetransmissionsTotal     *prometheus.Desc
 	NFSOperationsMajorTimeoutsTotal       *prometheus.Desc
 	NFSOperationsSentBytesTotal           *prometheus.Desc
 	NFSOperationsReceivedBytesTotal       *prometheus.Desc
@@ -90,7 +95,8 @@ type mountStatsCollector struct {
 	NFSEventPNFSReadTotal            *prometheus.Desc
 	NFSEventPNFSWriteTotal           *prometheus.Desc
 
-	proc procfs.Proc
+	proc              procfs.Proc
+	mountPointPattern *regexp.Regexp
 }
 
 // used to uniquely identify an NFS mount to prevent duplicates
@@ -116,6 +122,11 @@ func NewMountStatsCollector() (Collector, error) {
 		return nil, fmt.Errorf("failed to open /proc/self: %v", err)
 	}
 
+	pattern, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", *mountPointWhitelist))
+	if err != nil {
+		return nil, fmt.Errorf("invalid mount point whitelist: %v", err)
+	}
+
 	const (
 		// For the time being, only NFS statistics are available via this mechanism.
 		subsystem = "mountstats_nfs"
@@ -274,6 +285,13 @@ func NewMountStatsCollector() (Collector, error) {
 			nil,
 		),
 
+		NFSOperationsRetransmissionsTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "operations_retransmissions_total"),
+			"Number of times an RPC request has been retransmitted for a given operation.",
+			opLabels,
+			nil,
+		),
+
 		NFSOperationsMajorTimeoutsTotal: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, subsystem, "operations_major_timeouts_total"),
 			"Number of times a request has had a major timeout for a given operation.",
@@ -498,7 +516,8 @@ func NewMountStatsCollector() (Collector, error) {
 			nil,
 		),
 
-		proc: proc,
+		proc:              proc,
+		mountPointPattern: pattern,
 	}, nil
 }
 
@@ -524,6 +543,11 @@ func (c *mountStatsCollector) Update(ch chan<- prometheus.Metric) error {
 			continue
 		}
 
+		if !c.mountPointPattern.MatchString(m.Mount) {
+			log.Debugf("Ignoring NFS mount point: %s", m.Mount)
+			continue
+		}
+
 		var mountAddress string
 		if idx < len(mountsInfo) {
 			// The mount entry order in the /proc/self/mountstats and /proc/self/mountinfo is the same.
@@ -697,6 +721,19 @@ func (c *mountStatsCollector) updateNFSStats(ch chan<- prometheus.Metric, s *pro
 			opLabelValues...,
 		)
 
+		// Every request is transmitted at least once, anything above that
+		// has been retransmitted.
+		var retransmissions uint64
+		if op.Transmissions > op.Requests {
+			retransmissions = op.Transmissions - op.Requests
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.NFSOperationsRetransmissionsTotal,
+			prometheus.CounterValue,
+			float64(retransmissions),
+			opLabelValues...,
+		)
+
 		ch <- prometheus.MustNewConstMetric(
 			c.NFSOperationsMajorTimeoutsTotal,
 			prometheus.CounterValue,
@@ -0,0 +1,206 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nomulticast
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const multicastSubsystem = "multicast"
+
+// multicastMLDCounters maps the MLD counters of /proc/net/snmp6 to the
+// direction and type labels. The kernel doesn't count IGMP messages.
+var multicastMLDCounters = map[string][2]string{
+	"Icmp6InGroupMembQueries":     {"receive", "query"},
+	"Icmp6InGroupMembResponses":   {"receive", "report_v1"},
+	"Icmp6InGroupMembReductions":  {"receive", "done"},
+	"Icmp6InMLDv2Reports":         {"receive", "report_v2"},
+	"Icmp6OutGroupMembQueries":    {"transmit", "query"},
+	"Icmp6OutGroupMembResponses":  {"transmit", "report_v1"},
+	"Icmp6OutGroupMembReductions": {"transmit", "done"},
+	"Icmp6OutMLDv2Reports":        {"transmit", "report_v2"},
+}
+
+// igmpDevice is a device of /proc/net/igmp.
+type igmpDevice struct {
+	name    string
+	groups  uint64
+	querier uint64
+}
+
+type multicastCollector struct {
+	groups      *prometheus.Desc
+	igmpVersion *prometheus.Desc
+	mldMessages *prometheus.Desc
+}
+
+func init() {
+	registerCollector(multicastSubsystem, defaultDisabled, NewMulticastCollector)
+}
+
+// NewMulticastCollector returns a new Collector exposing IGMP and MLD
+// multicast group memberships.
+func NewMulticastCollector() (Collector, error) {
+	return &multicastCollector{
+		groups: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, multicastSubsystem, "groups"),
+			"Number of multicast groups joined on the device.",
+			[]string{"device", "family"}, nil,
+		),
+		igmpVersion: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, multicastSubsystem, "igmp_version"),
+			"IGMP version used on the device, lowered to the version of the queriers on the network.",
+			[]string{"device"}, nil,
+		),
+		mldMessages: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, multicastSubsystem, "mld_messages_total"),
+			"Number of MLD messages by direction and type.",
+			[]string{"direction", "type"}, nil,
+		),
+	}, nil
+}
+
+func (c *multicastCollector) Update(ch chan<- prometheus.Metric) error {
+	if err := c.updateIGMP(ch); err != nil {
+		return err
+	}
+	return c.updateMLD(ch)
+}
+
+func (c *multicastCollector) updateIGMP(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("net/igmp"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("Not collecting IGMP memberships: %s", err)
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	devices, err := parseIGMP(file)
+	if err != nil {
+		return fmt.Errorf("couldn't parse IGMP memberships: %s", err)
+	}
+	for _, d := range devices {
+		ch <- prometheus.MustNewConstMetric(c.groups, prometheus.GaugeValue, float64(d.groups), d.name, "ipv4")
+		ch <- prometheus.MustNewConstMetric(c.igmpVersion, prometheus.GaugeValue, float64(d.querier), d.name)
+	}
+	return nil
+}
+
+func (c *multicastCollector) updateMLD(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("net/igmp6"))
+	if err != nil {
+		// The file is missing if IPv6 is disabled.
+		if os.IsNotExist(err) {
+			log.Debugf("Not collecting MLD memberships: %s", err)
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	groups, err := parseIGMP6(file)
+	if err != nil {
+		return fmt.Errorf("couldn't parse MLD memberships: %s", err)
+	}
+	for device, n := range groups {
+		ch <- prometheus.MustNewConstMetric(c.groups, prometheus.GaugeValue, float64(n), device, "ipv6")
+	}
+
+	snmp6, err := os.Open(procFilePath("net/snmp6"))
+	if err != nil {
+		return err
+	}
+	defer snmp6.Close()
+
+	scanner := bufio.NewScanner(snmp6)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		labels, ok := multicastMLDCounters[fields[0]]
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return fmt.Errorf("invalid value %q of %s: %s", fields[1], fields[0], err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.mldMessages, prometheus.CounterValue, v, labels[0], labels[1])
+	}
+	return scanner.Err()
+}
+
+// parseIGMP parses the device lines of /proc/net/igmp, e.g.
+// "2	eth0      :     3      V2", skipping the indented group lines.
+func parseIGMP(r io.Reader) ([]igmpDevice, error) {
+	var (
+		devices []igmpDevice
+		scanner = bufio.NewScanner(r)
+	)
+	scanner.Scan() // skip header
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\t\t") {
+			continue
+		}
+		// Names of 10 or more characters aren't separated from the colon.
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		device, values := strings.Fields(parts[0]), strings.Fields(parts[1])
+		if len(device) != 2 || len(values) != 2 {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		groups, err := strconv.ParseUint(values[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group count in line %q: %s", line, err)
+		}
+		querier, err := strconv.ParseUint(strings.TrimPrefix(values[1], "V"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid querier version in line %q: %s", line, err)
+		}
+		devices = append(devices, igmpDevice{name: device[1], groups: groups, querier: querier})
+	}
+	return devices, scanner.Err()
+}
+
+// parseIGMP6 counts the groups per device of /proc/net/igmp6, which has one
+// "<index> <device> <group> <users> <flags> <timer>" line per group.
+func parseIGMP6(r io.Reader) (map[string]uint64, error) {
+	groups := make(map[string]uint64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("invalid line %q", scanner.Text())
+		}
+		groups[fields[1]]++
+	}
+	return groups, scanner.Err()
+}
@@ -0,0 +1,180 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noneighbour
+
+package collector
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	neighbourSubsystem = "neighbour"
+
+	// ndmsgLength is the length of struct ndmsg of linux/neighbour.h.
+	ndmsgLength = 12
+)
+
+var (
+	// neighbourStates are the NUD_* states of linux/neighbour.h.
+	neighbourStates = []struct {
+		name string
+		mask uint16
+	}{
+		{"incomplete", unix.NUD_INCOMPLETE},
+		{"reachable", unix.NUD_REACHABLE},
+		{"stale", unix.NUD_STALE},
+		{"delay", unix.NUD_DELAY},
+		{"probe", unix.NUD_PROBE},
+		{"failed", unix.NUD_FAILED},
+		{"noarp", unix.NUD_NOARP},
+		{"permanent", unix.NUD_PERMANENT},
+	}
+	neighbourFamilies = map[uint8]string{
+		unix.AF_INET:  "ipv4",
+		unix.AF_INET6: "ipv6",
+	}
+)
+
+// neighbourKey identifies the entries of a device and address family.
+type neighbourKey struct {
+	ifindex int
+	family  string
+}
+
+type neighbourCollector struct {
+	entries   *prometheus.Desc
+	gcThresh1 *prometheus.Desc
+	gcThresh2 *prometheus.Desc
+	gcThresh3 *prometheus.Desc
+}
+
+func init() {
+	registerCollector(neighbourSubsystem, defaultDisabled, NewNeighbourCollector)
+}
+
+// NewNeighbourCollector returns a new Collector exposing neighbour table
+// (ARP and NDP) entries by state.
+func NewNeighbourCollector() (Collector, error) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, neighbourSubsystem, name),
+			help, []string{"family"}, nil,
+		)
+	}
+	return &neighbourCollector{
+		entries: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, neighbourSubsystem, "entries"),
+			"Number of neighbour table entries by device, address family and state.",
+			[]string{"device", "family", "state"}, nil,
+		),
+		gcThresh1: desc("gc_thresh1", "Number of entries below which the garbage collector doesn't remove stale entries."),
+		gcThresh2: desc("gc_thresh2", "Number of entries above which the garbage collector removes stale entries after 5 seconds."),
+		gcThresh3: desc("gc_thresh3", "Maximum number of entries of the neighbour table."),
+	}, nil
+}
+
+func (c *neighbourCollector) Update(ch chan<- prometheus.Metric) error {
+	for _, family := range []string{"ipv4", "ipv6"} {
+		for i, desc := range []*prometheus.Desc{c.gcThresh1, c.gcThresh2, c.gcThresh3} {
+			file := procFilePath(fmt.Sprintf("sys/net/%s/neigh/default/gc_thresh%d", family, i+1))
+			v, err := readUintFromFile(file)
+			if err != nil {
+				// The ipv6 directory is missing if IPv6 is disabled.
+				if os.IsNotExist(err) {
+					log.Debugf("neighbour: %s does not exist, skipping", file)
+					continue
+				}
+				return err
+			}
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(v), family)
+		}
+	}
+
+	conn, err := netlink.Dial(unix.NETLINK_ROUTE, nil)
+	if err != nil {
+		return fmt.Errorf("couldn't connect to rtnetlink: %s", err)
+	}
+	defer conn.Close()
+
+	msgs, err := conn.Execute(netlink.Message{
+		Header: netlink.Header{
+			Type:  unix.RTM_GETNEIGH,
+			Flags: netlink.Request | netlink.Dump,
+		},
+		// An all zero struct ndmsg dumps all address families.
+		Data: make([]byte, ndmsgLength),
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't dump neighbour table: %s", err)
+	}
+	entries, err := parseNeighbourMessages(msgs)
+	if err != nil {
+		return err
+	}
+
+	for key, states := range entries {
+		device := strconv.Itoa(key.ifindex)
+		if iface, err := net.InterfaceByIndex(key.ifindex); err == nil {
+			device = iface.Name
+		}
+		for _, s := range neighbourStates {
+			ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(states[s.name]), device, key.family, s.name)
+		}
+	}
+
+	return nil
+}
+
+// parseNeighbourMessages counts the RTM_NEWNEIGH messages by device,
+// address family and state. Entries of other families, e.g. bridge FDB
+// entries, are skipped.
+func parseNeighbourMessages(msgs []netlink.Message) (map[neighbourKey]map[string]int, error) {
+	entries := make(map[neighbourKey]map[string]int)
+	for _, m := range msgs {
+		if m.Header.Type != unix.RTM_NEWNEIGH {
+			continue
+		}
+		if len(m.Data) < ndmsgLength {
+			return nil, fmt.Errorf("neighbour message too short: %d bytes", len(m.Data))
+		}
+		family, ok := neighbourFamilies[m.Data[0]]
+		if !ok {
+			continue
+		}
+		key := neighbourKey{
+			ifindex: int(nlenc.Int32(m.Data[4:8])),
+			family:  family,
+		}
+		if entries[key] == nil {
+			entries[key] = make(map[string]int)
+		}
+		state := nlenc.Uint16(m.Data[8:10])
+		for _, s := range neighbourStates {
+			if state&s.mask != 0 {
+				entries[key][s.name]++
+			}
+		}
+	}
+	return entries, nil
+}
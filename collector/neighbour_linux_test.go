@@ -0,0 +1,54 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseNeighbourMessages(t *testing.T) {
+	neigh := func(family uint8, ifindex int32, state uint16) netlink.Message {
+		b := make([]byte, ndmsgLength)
+		b[0] = family
+		nlenc.PutInt32(b[4:8], ifindex)
+		nlenc.PutUint16(b[8:10], state)
+		return netlink.Message{Header: netlink.Header{Type: unix.RTM_NEWNEIGH}, Data: b}
+	}
+
+	entries, err := parseNeighbourMessages([]netlink.Message{
+		neigh(unix.AF_INET, 2, unix.NUD_REACHABLE),
+		neigh(unix.AF_INET, 2, unix.NUD_STALE),
+		neigh(unix.AF_INET, 2, unix.NUD_STALE),
+		neigh(unix.AF_INET, 3, unix.NUD_FAILED),
+		neigh(unix.AF_INET6, 2, unix.NUD_REACHABLE),
+		neigh(unix.AF_BRIDGE, 2, unix.NUD_PERMANENT),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[neighbourKey]map[string]int{
+		{ifindex: 2, family: "ipv4"}: {"reachable": 1, "stale": 2},
+		{ifindex: 3, family: "ipv4"}: {"failed": 1},
+		{ifindex: 2, family: "ipv6"}: {"reachable": 1},
+	}
+	if !reflect.DeepEqual(want, entries) {
+		t.Errorf("want %v, got %v", want, entries)
+	}
+}
@@ -11,8 +11,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// +build !nonetclass
-// +build linux
+// +build !nonetclass,linux
 
 package collector
 
@@ -27,12 +26,14 @@ import (
 
 var (
 	netclassIgnoredDevices = kingpin.Flag("collector.netclass.ignored-devices", "Regexp of net devices to ignore for netclass collector.").Default("^$").String()
+	netclassAcceptDevices  = kingpin.Flag("collector.netclass.device-whitelist", "Regexp of net devices to return for netclass collector. Devices must both match whitelist and not match ignored-devices to be included.").Default(".+").String()
 )
 
 type netClassCollector struct {
 	fs                    sysfs.FS
 	subsystem             string
 	ignoredDevicesPattern *regexp.Regexp
+	acceptDevicesPattern  *regexp.Regexp
 	metricDescs           map[string]*prometheus.Desc
 }
 
@@ -46,11 +47,19 @@ func NewNetClassCollector() (Collector, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to open sysfs: %v", err)
 	}
-	pattern := regexp.MustCompile(*netclassIgnoredDevices)
+	pattern, err := regexp.Compile(*netclassIgnoredDevices)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ignored-devices pattern: %s", err)
+	}
+	acceptPattern, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", *netclassAcceptDevices))
+	if err != nil {
+		return nil, fmt.Errorf("invalid device-whitelist pattern: %s", err)
+	}
 	return &netClassCollector{
 		fs:                    fs,
 		subsystem:             "network",
 		ignoredDevicesPattern: pattern,
+		acceptDevicesPattern:  acceptPattern,
 		metricDescs:           map[string]*prometheus.Desc{},
 	}, nil
 }
@@ -176,7 +185,7 @@ func (c *netClassCollector) getNetClassInfo() (sysfs.NetClass, error) {
 	}
 
 	for device := range netClass {
-		if c.ignoredDevicesPattern.MatchString(device) {
+		if c.ignoredDevicesPattern.MatchString(device) || !c.acceptDevicesPattern.MatchString(device) {
 			delete(netClass, device)
 		}
 	}
@@ -17,7 +17,6 @@
 package collector
 
 import (
-	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -27,8 +26,8 @@ import (
 )
 
 var (
-	netdevIgnoredDevices = kingpin.Flag("collector.netdev.device-blacklist", "Regexp of net devices to blacklist (mutually exclusive to device-whitelist).").String()
-	netdevAcceptDevices  = kingpin.Flag("collector.netdev.device-whitelist", "Regexp of net devices to whitelist (mutually exclusive to device-blacklist).").String()
+	netdevIgnoredDevices = kingpin.Flag("collector.netdev.device-blacklist", "Regexp of net devices to blacklist. Devices must both match whitelist and not match blacklist to be included.").String()
+	netdevAcceptDevices  = kingpin.Flag("collector.netdev.device-whitelist", "Regexp of net devices to whitelist. Devices must both match whitelist and not match blacklist to be included.").String()
 )
 
 type netDevCollector struct {
@@ -36,6 +35,7 @@ type netDevCollector struct {
 	ignoredDevicesPattern *regexp.Regexp
 	acceptDevicesPattern  *regexp.Regexp
 	metricDescs           map[string]*prometheus.Desc
+	netnsMetricDescs      map[string]*prometheus.Desc
 }
 
 func init() {
@@ -44,18 +44,22 @@ func init() {
 
 // NewNetDevCollector returns a new Collector exposing network device stats.
 func NewNetDevCollector() (Collector, error) {
-	if *netdevIgnoredDevices != "" && *netdevAcceptDevices != "" {
-		return nil, errors.New("device-blacklist & accept-devices are mutually exclusive")
-	}
-
 	var ignorePattern *regexp.Regexp = nil
 	if *netdevIgnoredDevices != "" {
-		ignorePattern = regexp.MustCompile(*netdevIgnoredDevices)
+		pattern, err := regexp.Compile(*netdevIgnoredDevices)
+		if err != nil {
+			return nil, fmt.Errorf("invalid device-blacklist pattern: %s", err)
+		}
+		ignorePattern = pattern
 	}
 
 	var acceptPattern *regexp.Regexp = nil
 	if *netdevAcceptDevices != "" {
-		acceptPattern = regexp.MustCompile(*netdevAcceptDevices)
+		pattern, err := regexp.Compile(*netdevAcceptDevices)
+		if err != nil {
+			return nil, fmt.Errorf("invalid device-whitelist pattern: %s", err)
+		}
+		acceptPattern = pattern
 	}
 
 	return &netDevCollector{
@@ -63,6 +67,7 @@ func NewNetDevCollector() (Collector, error) {
 		ignoredDevicesPattern: ignorePattern,
 		acceptDevicesPattern:  acceptPattern,
 		metricDescs:           map[string]*prometheus.Desc{},
+		netnsMetricDescs:      map[string]*prometheus.Desc{},
 	}, nil
 }
 
@@ -71,23 +76,41 @@ func (c *netDevCollector) Update(ch chan<- prometheus.Metric) error {
 	if err != nil {
 		return fmt.Errorf("couldn't get netstats: %s", err)
 	}
+	if err := c.updateStats(ch, netDev, ""); err != nil {
+		return err
+	}
+	return c.updateNetNamespaces(ch)
+}
+
+// updateStats exposes the device statistics of a network namespace, only
+// labeled if additional network namespaces are collected.
+func (c *netDevCollector) updateStats(ch chan<- prometheus.Metric, netDev map[string]map[string]string, netns string) error {
+	labeled := netnsEnabled()
+	descs, labels := c.metricDescs, []string{"device"}
+	if labeled {
+		descs, labels = c.netnsMetricDescs, []string{"device", "netns"}
+	}
 	for dev, devStats := range netDev {
+		values := []string{dev}
+		if labeled {
+			values = append(values, netns)
+		}
 		for key, value := range devStats {
-			desc, ok := c.metricDescs[key]
+			desc, ok := descs[key]
 			if !ok {
 				desc = prometheus.NewDesc(
 					prometheus.BuildFQName(namespace, c.subsystem, key+"_total"),
 					fmt.Sprintf("Network device statistic %s.", key),
-					[]string{"device"},
+					labels,
 					nil,
 				)
-				c.metricDescs[key] = desc
+				descs[key] = desc
 			}
 			v, err := strconv.ParseFloat(value, 64)
 			if err != nil {
 				return fmt.Errorf("invalid value %s in netstats: %s", value, err)
 			}
-			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v, dev)
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v, values...)
 		}
 	}
 	return nil
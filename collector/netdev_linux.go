@@ -20,18 +20,35 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
+	"golang.org/x/sys/unix"
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 var (
+	netdevNetlink = kingpin.Flag("collector.netdev.netlink", "Use rtnetlink instead of /proc/net/dev to get the statistics of the network devices of the host.").Default("true").Bool()
+
 	procNetDevInterfaceRE = regexp.MustCompile(`^(.+): *(.+)$`)
 	procNetDevFieldSep    = regexp.MustCompile(` +`)
 )
 
 func getNetDevStats(ignore *regexp.Regexp, accept *regexp.Regexp) (map[string]map[string]string, error) {
+	if *netdevNetlink {
+		netDev, err := getNetlinkNetDevStats(ignore, accept)
+		if err == nil {
+			return netDev, nil
+		}
+		log.Debugf("Falling back to /proc/net/dev: %s", err)
+	}
+
 	file, err := os.Open(procFilePath("net/dev"))
 	if err != nil {
 		return nil, err
@@ -41,6 +58,35 @@ func getNetDevStats(ignore *regexp.Regexp, accept *regexp.Regexp) (map[string]ma
 	return parseNetDevStats(file, ignore, accept)
 }
 
+// updateNetNamespaces exposes the device statistics of the network
+// namespaces selected with the --collector.netns flags.
+func (c *netDevCollector) updateNetNamespaces(ch chan<- prometheus.Metric) error {
+	namespaces, err := netNamespaces()
+	if err != nil {
+		return err
+	}
+	for _, ns := range namespaces {
+		var netDev map[string]map[string]string
+		err := ns.do(func(procNet string) error {
+			file, err := os.Open(filepath.Join(procNet, "dev"))
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			netDev, err = parseNetDevStats(file, c.ignoredDevicesPattern, c.acceptDevicesPattern)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't get netstats of network namespace %s: %s", ns.name, err)
+		}
+		if err := c.updateStats(ch, netDev, ns.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func parseNetDevStats(r io.Reader, ignore *regexp.Regexp, accept *regexp.Regexp) (map[string]map[string]string, error) {
 	scanner := bufio.NewScanner(r)
 	scanner.Scan() // skip first header
@@ -89,3 +135,101 @@ func parseNetDevStats(r io.Reader, ignore *regexp.Regexp, accept *regexp.Regexp)
 	}
 	return netDev, scanner.Err()
 }
+
+// getNetlinkNetDevStats dumps the links with their 64-bit statistics, which
+// is considerably faster than /proc/net/dev on hosts with many devices.
+func getNetlinkNetDevStats(ignore *regexp.Regexp, accept *regexp.Regexp) (map[string]map[string]string, error) {
+	conn, err := netlink.Dial(unix.NETLINK_ROUTE, nil)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't connect to rtnetlink: %s", err)
+	}
+	defer conn.Close()
+
+	msgs, err := conn.Execute(netlink.Message{
+		Header: netlink.Header{
+			Type:  unix.RTM_GETLINK,
+			Flags: netlink.Request | netlink.Dump,
+		},
+		// An all zero struct ifinfomsg dumps all links.
+		Data: make([]byte, unix.SizeofIfInfomsg),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't dump links: %s", err)
+	}
+	return parseNetlinkNetDevStats(msgs, ignore, accept)
+}
+
+// parseNetlinkNetDevStats converts the struct rtnl_link_stats64 of the
+// RTM_NEWLINK messages to the fields of /proc/net/dev, summing the detailed
+// errors the same way the kernel does.
+func parseNetlinkNetDevStats(msgs []netlink.Message, ignore *regexp.Regexp, accept *regexp.Regexp) (map[string]map[string]string, error) {
+	netDev := map[string]map[string]string{}
+	for _, m := range msgs {
+		if m.Header.Type != unix.RTM_NEWLINK {
+			continue
+		}
+		if len(m.Data) < unix.SizeofIfInfomsg {
+			return nil, fmt.Errorf("link message too short: %d bytes", len(m.Data))
+		}
+		ad, err := netlink.NewAttributeDecoder(m.Data[unix.SizeofIfInfomsg:])
+		if err != nil {
+			return nil, err
+		}
+		var (
+			dev   string
+			stats []uint64
+		)
+		for ad.Next() {
+			switch ad.Type() {
+			case unix.IFLA_IFNAME:
+				dev = ad.String()
+			case unix.IFLA_STATS64:
+				b := ad.Bytes()
+				for i := 0; i+8 <= len(b); i += 8 {
+					stats = append(stats, nlenc.Uint64(b[i:i+8]))
+				}
+			}
+		}
+		if err := ad.Err(); err != nil {
+			return nil, fmt.Errorf("invalid link message: %s", err)
+		}
+
+		if ignore != nil && ignore.MatchString(dev) {
+			log.Debugf("Ignoring device: %s", dev)
+			continue
+		}
+		if accept != nil && !accept.MatchString(dev) {
+			log.Debugf("Ignoring device: %s", dev)
+			continue
+		}
+		// The first 23 fields of struct rtnl_link_stats64 have been
+		// present since Linux 2.6.35.
+		if len(stats) < 23 {
+			return nil, fmt.Errorf("missing statistics of device %s", dev)
+		}
+
+		values := map[string]uint64{
+			"receive_bytes":       stats[2],
+			"receive_packets":     stats[0],
+			"receive_errs":        stats[4],
+			"receive_drop":        stats[6] + stats[15],
+			"receive_fifo":        stats[14],
+			"receive_frame":       stats[10] + stats[11] + stats[12] + stats[13],
+			"receive_compressed":  stats[21],
+			"receive_multicast":   stats[8],
+			"transmit_bytes":      stats[3],
+			"transmit_packets":    stats[1],
+			"transmit_errs":       stats[5],
+			"transmit_drop":       stats[7],
+			"transmit_fifo":       stats[18],
+			"transmit_colls":      stats[9],
+			"transmit_carrier":    stats[16] + stats[17] + stats[19] + stats[20],
+			"transmit_compressed": stats[22],
+		}
+		netDev[dev] = map[string]string{}
+		for key, value := range values {
+			netDev[dev][key] = strconv.FormatUint(value, 10)
+		}
+	}
+	return netDev, nil
+}
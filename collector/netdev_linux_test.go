@@ -17,6 +17,10 @@ import (
 	"os"
 	"regexp"
 	"testing"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"golang.org/x/sys/unix"
 )
 
 func TestNetDevStatsIgnore(t *testing.T) {
@@ -79,3 +83,53 @@ func TestNetDevStatsAccept(t *testing.T) {
 		t.Error("want fixture interface 💩0 to exist, but it does not")
 	}
 }
+
+func TestNetlinkNetDevStats(t *testing.T) {
+	link := func(name string, stats []uint64) netlink.Message {
+		b := make([]byte, len(stats)*8)
+		for i, v := range stats {
+			nlenc.PutUint64(b[i*8:i*8+8], v)
+		}
+		attrs, err := netlink.MarshalAttributes([]netlink.Attribute{
+			{Type: unix.IFLA_IFNAME, Data: nlenc.Bytes(name)},
+			{Type: unix.IFLA_STATS64, Data: b},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return netlink.Message{
+			Header: netlink.Header{Type: unix.RTM_NEWLINK},
+			Data:   append(make([]byte, unix.SizeofIfInfomsg), attrs...),
+		}
+	}
+
+	stats := make([]uint64, 24)
+	for i := range stats {
+		stats[i] = uint64(i + 1)
+	}
+	stats[2] = 1 << 40 // rx_bytes beyond 32 bits
+
+	netStats, err := parseNetlinkNetDevStats([]netlink.Message{
+		link("eth0", stats),
+		link("veth0", stats),
+	}, regexp.MustCompile("^veth"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 1, len(netStats); want != got {
+		t.Errorf("want count of devices to be %d, got %d", want, got)
+	}
+	for key, want := range map[string]string{
+		"receive_bytes":    "1099511627776",
+		"receive_packets":  "1",
+		"receive_drop":     "23",
+		"receive_frame":    "50",
+		"transmit_carrier": "76",
+		"transmit_colls":   "10",
+	} {
+		if got := netStats["eth0"][key]; want != got {
+			t.Errorf("want eth0 %s %s, got %s", key, want, got)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nonetdev
+// +build freebsd openbsd dragonfly darwin
+
+package collector
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// netnsEnabled returns false, network namespaces only exist on Linux.
+func netnsEnabled() bool {
+	return false
+}
+
+// updateNetNamespaces is a no-op, network namespaces only exist on Linux.
+func (c *netDevCollector) updateNetNamespaces(ch chan<- prometheus.Metric) error {
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	netnsDir   = kingpin.Flag("collector.netns.dir", "Directory of the named network namespaces, as created by ip netns.").Default("/var/run/netns").String()
+	netnsNames = kingpin.Flag("collector.netns.names", "Regexp of named network namespaces to additionally collect netdev, netstat and sockstat metrics of. Disabled if empty.").Default("").String()
+	netnsPIDs  = kingpin.Flag("collector.netns.pid", "Process ID whose network namespace to additionally collect netdev, netstat and sockstat metrics of, labeled as pid:<pid>. Can be repeated.").Strings()
+)
+
+// netNamespace is a network namespace other than the one of node_exporter.
+// Named namespaces are entered with setns(2), which requires CAP_SYS_ADMIN,
+// while the namespace of a process is read through /proc/<pid>/net.
+type netNamespace struct {
+	name string
+	path string
+	pid  string
+}
+
+// netnsEnabled returns whether additional network namespaces are collected,
+// in which case the one of node_exporter is labeled with an empty netns.
+func netnsEnabled() bool {
+	return *netnsNames != "" || len(*netnsPIDs) > 0
+}
+
+// netNamespaces returns the additional network namespaces to collect.
+func netNamespaces() ([]netNamespace, error) {
+	var namespaces []netNamespace
+
+	if *netnsNames != "" {
+		pattern, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", *netnsNames))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --collector.netns.names: %s", err)
+		}
+		files, err := ioutil.ReadDir(*netnsDir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, f := range files {
+			if !pattern.MatchString(f.Name()) {
+				continue
+			}
+			namespaces = append(namespaces, netNamespace{
+				name: f.Name(),
+				path: filepath.Join(*netnsDir, f.Name()),
+			})
+		}
+	}
+
+	for _, pid := range *netnsPIDs {
+		namespaces = append(namespaces, netNamespace{name: "pid:" + pid, pid: pid})
+	}
+
+	return namespaces, nil
+}
+
+// do calls fn with the /proc/net directory of the namespace.
+func (ns netNamespace) do(fn func(procNet string) error) error {
+	if ns.path == "" {
+		return fn(procFilePath(filepath.Join(ns.pid, "net")))
+	}
+
+	target, err := os.Open(ns.path)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	// Namespaces are a property of the thread, so the goroutine must not
+	// be moved while inside the namespace.
+	runtime.LockOSThread()
+	orig, err := os.Open(procFilePath("thread-self/ns/net"))
+	if err != nil {
+		runtime.UnlockOSThread()
+		return err
+	}
+	defer orig.Close()
+
+	if err := unix.Setns(int(target.Fd()), unix.CLONE_NEWNET); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("couldn't enter network namespace %s: %s", ns.name, err)
+	}
+	fnErr := fn(procFilePath("thread-self/net"))
+
+	// A thread which can't return to the original namespace is left locked,
+	// the runtime terminates it once the goroutine exits.
+	if err := unix.Setns(int(orig.Fd()), unix.CLONE_NEWNET); err != nil {
+		return fmt.Errorf("couldn't leave network namespace %s: %s", ns.name, err)
+	}
+	runtime.UnlockOSThread()
+
+	return fnErr
+}
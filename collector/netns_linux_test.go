@@ -0,0 +1,89 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestNetNamespaces(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"qrouter-1", "qrouter-2", "other"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldDir, oldNames, oldPIDs := *netnsDir, *netnsNames, *netnsPIDs
+	defer func() { *netnsDir, *netnsNames, *netnsPIDs = oldDir, oldNames, oldPIDs }()
+	*netnsDir, *netnsNames, *netnsPIDs = dir, "qrouter-.*", []string{"42"}
+
+	namespaces, err := netNamespaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netNamespace{
+		{name: "qrouter-1", path: filepath.Join(dir, "qrouter-1")},
+		{name: "qrouter-2", path: filepath.Join(dir, "qrouter-2")},
+		{name: "pid:42", pid: "42"},
+	}
+	if !reflect.DeepEqual(want, namespaces) {
+		t.Errorf("want %v, got %v", want, namespaces)
+	}
+}
+
+func TestNetnsLabel(t *testing.T) {
+	oldNames, oldPIDs := *netnsNames, *netnsPIDs
+	defer func() { *netnsNames, *netnsPIDs = oldNames, oldPIDs }()
+	sockStats := map[string]map[string]string{"TCP": {"inuse": "3"}}
+
+	for _, test := range []struct {
+		pids  []string
+		netns string
+		want  map[string]string
+	}{
+		{pids: nil, netns: "", want: map[string]string{}},
+		{pids: []string{"42"}, netns: "", want: map[string]string{"netns": ""}},
+		{pids: []string{"42"}, netns: "pid:42", want: map[string]string{"netns": "pid:42"}},
+	} {
+		*netnsNames, *netnsPIDs = "", test.pids
+
+		ch := make(chan prometheus.Metric, 1)
+		if err := (&sockStatCollector{}).updateStats(ch, sockStats, test.netns); err != nil {
+			t.Fatal(err)
+		}
+		var metric dto.Metric
+		if err := (<-ch).Write(&metric); err != nil {
+			t.Fatal(err)
+		}
+		labels := map[string]string{}
+		for _, l := range metric.Label {
+			labels[l.GetName()] = l.GetValue()
+		}
+		if !reflect.DeepEqual(test.want, labels) {
+			t.Errorf("namespaces of pids %v, netns %q: want labels %v, got %v", test.pids, test.netns, test.want, labels)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -33,11 +34,13 @@ const (
 )
 
 var (
-	netStatFields = kingpin.Flag("collector.netstat.fields", "Regexp of fields to return for netstat collector.").Default("^(.*_(InErrors|InErrs)|Ip_Forwarding|Ip(6|Ext)_(InOctets|OutOctets)|Icmp6?_(InMsgs|OutMsgs)|TcpExt_(Listen.*|Syncookies.*|TCPSynRetrans)|Tcp_(ActiveOpens|InSegs|OutSegs|PassiveOpens|RetransSegs|CurrEstab)|Udp6?_(InDatagrams|OutDatagrams|NoPorts))$").String()
+	netStatFields  = kingpin.Flag("collector.netstat.fields", "Regexp of fields to return for netstat collector.").Default("^(.*_(InErrors|InErrs)|Ip_Forwarding|Ip(6|Ext)_(InOctets|OutOctets)|Icmp6?_(InMsgs|OutMsgs)|TcpExt_(Listen.*|Syncookies.*|TCPSynRetrans|TCPOFOQueue)|Tcp_(ActiveOpens|InSegs|OutSegs|PassiveOpens|RetransSegs|CurrEstab)|Udp6?_(InDatagrams|OutDatagrams|NoPorts))$").String()
+	netStatInclude = kingpin.Flag("collector.netstat.include", "Field, e.g. TcpExt_TCPOFOQueue, to return for netstat collector in addition to those matching --collector.netstat.fields, can be repeated.").Strings()
 )
 
 type netStatCollector struct {
 	fieldPattern *regexp.Regexp
+	include      map[string]bool
 }
 
 func init() {
@@ -47,32 +50,54 @@ func init() {
 // NewNetStatCollector takes and returns
 // a new Collector exposing network stats.
 func NewNetStatCollector() (Collector, error) {
-	pattern := regexp.MustCompile(*netStatFields)
+	pattern, err := regexp.Compile(*netStatFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fields pattern: %s", err)
+	}
+	include := make(map[string]bool, len(*netStatInclude))
+	for _, field := range *netStatInclude {
+		include[field] = true
+	}
 	return &netStatCollector{
 		fieldPattern: pattern,
+		include:      include,
 	}, nil
 }
 
 func (c *netStatCollector) Update(ch chan<- prometheus.Metric) error {
-	netStats, err := getNetStats(procFilePath("net/netstat"))
+	netStats, err := getAllNetStats(procFilePath("net"))
 	if err != nil {
-		return fmt.Errorf("couldn't get netstats: %s", err)
+		return err
 	}
-	snmpStats, err := getNetStats(procFilePath("net/snmp"))
-	if err != nil {
-		return fmt.Errorf("couldn't get SNMP stats: %s", err)
+	if err := c.updateStats(ch, netStats, ""); err != nil {
+		return err
 	}
-	snmp6Stats, err := getSNMP6Stats(procFilePath("net/snmp6"))
+
+	namespaces, err := netNamespaces()
 	if err != nil {
-		return fmt.Errorf("couldn't get SNMP6 stats: %s", err)
+		return err
 	}
-	// Merge the results of snmpStats into netStats (collisions are possible, but
-	// we know that the keys are always unique for the given use case).
-	for k, v := range snmpStats {
-		netStats[k] = v
+	for _, ns := range namespaces {
+		err := ns.do(func(procNet string) error {
+			netStats, err = getAllNetStats(procNet)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("network namespace %s: %s", ns.name, err)
+		}
+		if err := c.updateStats(ch, netStats, ns.name); err != nil {
+			return err
+		}
 	}
-	for k, v := range snmp6Stats {
-		netStats[k] = v
+	return nil
+}
+
+// updateStats exposes the statistics of a network namespace, only labeled if
+// additional network namespaces are collected.
+func (c *netStatCollector) updateStats(ch chan<- prometheus.Metric, netStats map[string]map[string]string, netns string) error {
+	var labels, values []string
+	if netnsEnabled() {
+		labels, values = []string{"netns"}, []string{netns}
 	}
 	for protocol, protocolStats := range netStats {
 		for name, value := range protocolStats {
@@ -81,22 +106,48 @@ func (c *netStatCollector) Update(ch chan<- prometheus.Metric) error {
 			if err != nil {
 				return fmt.Errorf("invalid value %s in netstats: %s", value, err)
 			}
-			if !c.fieldPattern.MatchString(key) {
+			if !c.include[key] && !c.fieldPattern.MatchString(key) {
 				continue
 			}
 			ch <- prometheus.MustNewConstMetric(
 				prometheus.NewDesc(
 					prometheus.BuildFQName(namespace, netStatsSubsystem, key),
 					fmt.Sprintf("Statistic %s.", protocol+name),
-					nil, nil,
+					labels, nil,
 				),
-				prometheus.UntypedValue, v,
+				prometheus.UntypedValue, v, values...,
 			)
 		}
 	}
 	return nil
 }
 
+// getAllNetStats merges the netstat, snmp and snmp6 files of a /proc/net
+// directory.
+func getAllNetStats(procNet string) (map[string]map[string]string, error) {
+	netStats, err := getNetStats(filepath.Join(procNet, "netstat"))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get netstats: %s", err)
+	}
+	snmpStats, err := getNetStats(filepath.Join(procNet, "snmp"))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get SNMP stats: %s", err)
+	}
+	snmp6Stats, err := getSNMP6Stats(filepath.Join(procNet, "snmp6"))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get SNMP6 stats: %s", err)
+	}
+	// Merge the results of snmpStats into netStats (collisions are possible, but
+	// we know that the keys are always unique for the given use case).
+	for k, v := range snmpStats {
+		netStats[k] = v
+	}
+	for k, v := range snmp6Stats {
+		netStats[k] = v
+	}
+	return netStats, nil
+}
+
 func getNetStats(fileName string) (map[string]map[string]string, error) {
 	file, err := os.Open(fileName)
 	if err != nil {
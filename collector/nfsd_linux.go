@@ -14,8 +14,12 @@
 package collector
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
@@ -69,6 +73,9 @@ func (c *nfsdCollector) Update(ch chan<- prometheus.Metric) error {
 	c.updateNFSdFileHandlesStats(ch, &stats.FileHandles)
 	c.updateNFSdInputOutputStats(ch, &stats.InputOutput)
 	c.updateNFSdThreadsStats(ch, &stats.Threads)
+	if err := c.updateNFSdThreadUtilizationStats(ch); err != nil {
+		return err
+	}
 	c.updateNFSdReadAheadCacheStats(ch, &stats.ReadAheadCache)
 	c.updateNFSdNetworkStats(ch, &stats.Network)
 	c.updateNFSdServerRPCStats(ch, &stats.ServerRPC)
@@ -157,6 +164,41 @@ func (c *nfsdCollector) updateNFSdThreadsStats(ch chan<- prometheus.Metric, s *n
 		),
 		prometheus.GaugeValue,
 		float64(s.Threads))
+	ch <- prometheus.MustNewConstMetric(
+		prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nfsdSubsystem, "server_threads_full_total"),
+			"Total number of times all NFSd kernel threads were busy when a request arrived.",
+			nil,
+			nil,
+		),
+		prometheus.CounterValue,
+		float64(s.FullCnt))
+}
+
+// updateNFSdThreadUtilizationStats collects the thread utilization histogram,
+// which isn't exposed by procfs. Kernels since 2.6.33 always report zero.
+func (c *nfsdCollector) updateNFSdThreadUtilizationStats(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("net/rpc/nfsd"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	histogram, err := parseNFSdThreadUtilization(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse nfsd thread utilization: %v", err)
+	}
+
+	desc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, nfsdSubsystem, "server_threads_busy_seconds_total"),
+		"Total time the given ratio of NFSd kernel threads was busy.",
+		[]string{"utilization"},
+		nil,
+	)
+	for i, v := range histogram {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v, nfsdHistogramBucket(i))
+	}
+	return nil
 }
 
 // updateNFSdReadAheadCacheStats collects statistics for the read ahead cache.
@@ -179,6 +221,16 @@ func (c *nfsdCollector) updateNFSdReadAheadCacheStats(ch chan<- prometheus.Metri
 		),
 		prometheus.CounterValue,
 		float64(s.NotFound))
+
+	hitsDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, nfsdSubsystem, "read_ahead_cache_hits_total"),
+		"Total number of NFSd read ahead cache hits by the depth within the cache they were found at.",
+		[]string{"depth"},
+		nil,
+	)
+	for i, v := range s.CacheHistogram {
+		ch <- prometheus.MustNewConstMetric(hitsDesc, prometheus.CounterValue, float64(v), nfsdHistogramBucket(i))
+	}
 }
 
 // updateNFSdNetworkStats collects statistics for network packets/connections.
@@ -395,3 +447,35 @@ func (c *nfsdCollector) updateNFSdRequestsv4Stats(ch chan<- prometheus.Metric, s
 	ch <- prometheus.MustNewConstMetric(c.requestsDesc, prometheus.CounterValue,
 		float64(s.RelLockOwner), proto, "RelLockOwner")
 }
+
+// nfsdHistogramBucket returns the upper bound of the i-th 10% bucket of the
+// histograms in /proc/net/rpc/nfsd as a ratio.
+func nfsdHistogramBucket(i int) string {
+	return strconv.FormatFloat(float64(i+1)/10, 'f', -1, 64)
+}
+
+// parseNFSdThreadUtilization returns the ten buckets of the "th" line, which
+// count the seconds 10%, 20%, ... 100% of the threads were busy.
+func parseNFSdThreadUtilization(r io.Reader) ([]float64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "th" {
+			continue
+		}
+		if len(fields) != 13 {
+			return nil, fmt.Errorf("invalid th line %q", scanner.Text())
+		}
+
+		histogram := make([]float64, 0, 10)
+		for _, f := range fields[3:] {
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return nil, err
+			}
+			histogram = append(histogram, v)
+		}
+		return histogram, nil
+	}
+	return nil, scanner.Err()
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNFSdThreadUtilization(t *testing.T) {
+	file, err := os.Open("fixtures/proc/net/rpc/nfsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	histogram, err := parseNFSdThreadUtilization(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []float64{1234.567, 612.5, 205.25, 80.75, 20, 4.125, 1, 0.5, 0.25, 0.125}
+	if !reflect.DeepEqual(histogram, want) {
+		t.Errorf("want thread utilization %v, got %v", want, histogram)
+	}
+
+	if _, err := parseNFSdThreadUtilization(strings.NewReader("th 8 0 0.000\n")); err == nil {
+		t.Error("expected error for truncated th line")
+	}
+}
+
+func TestNFSdHistogramBucket(t *testing.T) {
+	for i, want := range map[int]string{0: "0.1", 4: "0.5", 9: "1"} {
+		if got := nfsdHistogramBucket(i); got != want {
+			t.Errorf("want bucket %d to be %s, got %s", i, want, got)
+		}
+	}
+}
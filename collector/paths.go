@@ -14,6 +14,7 @@
 package collector
 
 import (
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -36,6 +37,15 @@ func sysFilePath(name string) string {
 	return filepath.Join(*sysPath, name)
 }
 
+// tracefsDir returns the tracefs mountpoint, which is only mounted in debugfs
+// before Linux 4.1.
+func tracefsDir() string {
+	if _, err := os.Stat(sysFilePath("kernel/tracing/events")); err == nil {
+		return sysFilePath("kernel/tracing")
+	}
+	return sysFilePath("kernel/debug/tracing")
+}
+
 func rootfsFilePath(name string) string {
 	return filepath.Join(*rootfsPath, name)
 }
@@ -0,0 +1,141 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nopci
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const pciSubsystem = "pci"
+
+type pciCollector struct {
+	infoDesc         *prometheus.Desc
+	linkSpeedDesc    *prometheus.Desc
+	linkMaxSpeedDesc *prometheus.Desc
+	linkWidthDesc    *prometheus.Desc
+	linkMaxWidthDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector(pciSubsystem, defaultDisabled, NewPCICollector)
+}
+
+// NewPCICollector returns a new Collector exposing the PCI devices and the
+// current and maximum speed and width of their PCIe links.
+func NewPCICollector() (Collector, error) {
+	labels := []string{"device"}
+	return &pciCollector{
+		infoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pciSubsystem, "device_info"),
+			"PCI device with its IDs, class and driver.",
+			[]string{"device", "vendor_id", "device_id", "subsystem_vendor_id", "subsystem_device_id", "class", "driver"}, nil,
+		),
+		linkSpeedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pciSubsystem, "link_speed_transfers_per_second"),
+			"Current speed of the PCIe link of the device.",
+			labels, nil,
+		),
+		linkMaxSpeedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pciSubsystem, "link_max_speed_transfers_per_second"),
+			"Maximum speed of the PCIe link of the device.",
+			labels, nil,
+		),
+		linkWidthDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pciSubsystem, "link_width_lanes"),
+			"Current number of lanes of the PCIe link of the device.",
+			labels, nil,
+		),
+		linkMaxWidthDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pciSubsystem, "link_max_width_lanes"),
+			"Maximum number of lanes of the PCIe link of the device.",
+			labels, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *pciCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("bus/pci/devices/*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range devices {
+		device := filepath.Base(dir)
+
+		labels := []string{device}
+		for _, file := range []string{"vendor", "device", "subsystem_vendor", "subsystem_device", "class"} {
+			value, _ := readTrimmedFile(filepath.Join(dir, file))
+			labels = append(labels, value)
+		}
+		// Devices without driver have no driver link.
+		var driver string
+		if link, err := os.Readlink(filepath.Join(dir, "driver")); err == nil {
+			driver = filepath.Base(link)
+		}
+		labels = append(labels, driver)
+		ch <- prometheus.MustNewConstMetric(c.infoDesc, prometheus.GaugeValue, 1, labels...)
+
+		// Only PCIe devices have links, whose speed and width may be
+		// unknown, e.g. for virtual functions.
+		for _, link := range []struct {
+			file  string
+			desc  *prometheus.Desc
+			parse func(string) (float64, bool)
+		}{
+			{"current_link_speed", c.linkSpeedDesc, parsePCILinkSpeed},
+			{"max_link_speed", c.linkMaxSpeedDesc, parsePCILinkSpeed},
+			{"current_link_width", c.linkWidthDesc, parsePCILinkWidth},
+			{"max_link_width", c.linkMaxWidthDesc, parsePCILinkWidth},
+		} {
+			value, err := readTrimmedFile(filepath.Join(dir, link.file))
+			if err != nil {
+				continue
+			}
+			if v, ok := link.parse(value); ok {
+				ch <- prometheus.MustNewConstMetric(link.desc, prometheus.GaugeValue, v, device)
+			}
+		}
+	}
+	return nil
+}
+
+// parsePCILinkSpeed parses a link speed like "8.0 GT/s PCIe", or "8 GT/s" on
+// older kernels, to transfers per second.
+func parsePCILinkSpeed(speed string) (float64, bool) {
+	fields := strings.Fields(speed)
+	if len(fields) < 2 || fields[1] != "GT/s" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	return v * 1e9, true
+}
+
+// parsePCILinkWidth parses a link width, which is 0 or 255 if unknown.
+func parsePCILinkWidth(width string) (float64, bool) {
+	v, err := strconv.ParseUint(width, 10, 8)
+	if err != nil || v == 0 || v == 255 {
+		return 0, false
+	}
+	return float64(v), true
+}
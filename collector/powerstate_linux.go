@@ -0,0 +1,181 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nopowerstate
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// powerSleepStates are the system sleep states listed in /sys/power/state.
+var powerSleepStates = []string{"freeze", "standby", "mem", "disk"}
+
+type powerstateCollector struct {
+	sleepStateDesc      *prometheus.Desc
+	memSleepModeDesc    *prometheus.Desc
+	cpuidleInfoDesc     *prometheus.Desc
+	cpuidleTimeDesc     *prometheus.Desc
+	cpuidleUsageDesc    *prometheus.Desc
+	cpuidleLatencyDesc  *prometheus.Desc
+	cpuidleDisabledDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector("powerstate", defaultDisabled, NewPowerstateCollector)
+}
+
+// NewPowerstateCollector returns a new Collector exposing the supported system
+// sleep states and the CPU idle states.
+func NewPowerstateCollector() (Collector, error) {
+	stateLabels := []string{"cpu", "state"}
+	return &powerstateCollector{
+		sleepStateDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "power", "sleep_state_supported"),
+			"Whether the system sleep state is supported, 1 if it is.",
+			[]string{"state"}, nil,
+		),
+		memSleepModeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "power", "mem_sleep_mode"),
+			"Supported suspend modes of the mem sleep state, 1 for the one in use and 0 for the others.",
+			[]string{"mode"}, nil,
+		),
+		cpuidleInfoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cpuidle", "info"),
+			"CPU idle driver and governor in use, value is always 1.",
+			[]string{"driver", "governor"}, nil,
+		),
+		cpuidleTimeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cpuidle", "state_time_seconds_total"),
+			"Seconds the CPU spent in the idle state.",
+			stateLabels, nil,
+		),
+		cpuidleUsageDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cpuidle", "state_usage_total"),
+			"Number of times the CPU entered the idle state.",
+			stateLabels, nil,
+		),
+		cpuidleLatencyDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cpuidle", "state_exit_latency_seconds"),
+			"Time the CPU takes to exit the idle state.",
+			stateLabels, nil,
+		),
+		cpuidleDisabledDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cpuidle", "state_disabled"),
+			"Whether the idle state is disabled for the CPU, 1 if it is.",
+			stateLabels, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *powerstateCollector) Update(ch chan<- prometheus.Metric) error {
+	if err := c.updateSleepStates(ch); err != nil {
+		return err
+	}
+	return c.updateCPUIdle(ch)
+}
+
+func (c *powerstateCollector) updateSleepStates(ch chan<- prometheus.Metric) error {
+	states, err := readTrimmedFile(sysFilePath("power/state"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("Not collecting sleep states: %s", err)
+			return nil
+		}
+		return fmt.Errorf("couldn't get sleep states: %s", err)
+	}
+	supported := map[string]bool{}
+	for _, state := range strings.Fields(states) {
+		supported[state] = true
+	}
+	for _, state := range powerSleepStates {
+		v := 0.0
+		if supported[state] {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.sleepStateDesc, prometheus.GaugeValue, v, state)
+	}
+
+	// The suspend modes are only selectable since Linux 4.10.
+	modes, err := readTrimmedFile(sysFilePath("power/mem_sleep"))
+	if err != nil {
+		log.Debugf("Not collecting suspend modes: %s", err)
+		return nil
+	}
+	for _, mode := range strings.Fields(modes) {
+		v := 0.0
+		if strings.HasPrefix(mode, "[") {
+			v = 1
+			mode = strings.Trim(mode, "[]")
+		}
+		ch <- prometheus.MustNewConstMetric(c.memSleepModeDesc, prometheus.GaugeValue, v, mode)
+	}
+	return nil
+}
+
+// updateCPUIdle exposes the idle driver and the idle states of each CPU.
+// The times are in microseconds.
+func (c *powerstateCollector) updateCPUIdle(ch chan<- prometheus.Metric) error {
+	driver, err := readTrimmedFile(sysFilePath("devices/system/cpu/cpuidle/current_driver"))
+	if err != nil {
+		log.Debugf("Not collecting CPU idle states: %s", err)
+		return nil
+	}
+	// The governor is only writable with cpuidle_sysfs_switch before Linux 5.0.
+	governor, err := readTrimmedFile(sysFilePath("devices/system/cpu/cpuidle/current_governor"))
+	if err != nil {
+		governor, err = readTrimmedFile(sysFilePath("devices/system/cpu/cpuidle/current_governor_ro"))
+		if err != nil {
+			return fmt.Errorf("couldn't get CPU idle governor: %s", err)
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.cpuidleInfoDesc, prometheus.GaugeValue, 1, driver, governor)
+
+	states, err := filepath.Glob(sysFilePath("devices/system/cpu/cpu[0-9]*/cpuidle/state[0-9]*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range states {
+		cpu := strings.TrimPrefix(filepath.Base(filepath.Dir(filepath.Dir(dir))), "cpu")
+		name, err := readTrimmedFile(filepath.Join(dir, "name"))
+		if err != nil {
+			return fmt.Errorf("couldn't get name of idle state %s of cpu %s: %s", filepath.Base(dir), cpu, err)
+		}
+
+		for file, m := range map[string]struct {
+			desc       *prometheus.Desc
+			valueType  prometheus.ValueType
+			multiplier float64
+		}{
+			"time":    {c.cpuidleTimeDesc, prometheus.CounterValue, 1e-6},
+			"usage":   {c.cpuidleUsageDesc, prometheus.CounterValue, 1},
+			"latency": {c.cpuidleLatencyDesc, prometheus.GaugeValue, 1e-6},
+			"disable": {c.cpuidleDisabledDesc, prometheus.GaugeValue, 1},
+		} {
+			value, err := readUintFromFile(filepath.Join(dir, file))
+			if err != nil {
+				return fmt.Errorf("couldn't get %s of idle state %s of cpu %s: %s", file, name, cpu, err)
+			}
+			ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, float64(value)*m.multiplier, cpu, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noprobe
+
+package collector
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	probeTCPAddresses = kingpin.Flag("collector.probe.tcp", "TCP address as host:port to check is accepting connections. Can be repeated.").Strings()
+	probeUnixSockets  = kingpin.Flag("collector.probe.unix", "Path of a unix socket to check is accepting connections. Can be repeated.").Strings()
+	probeTimeout      = kingpin.Flag("collector.probe.timeout", "Timeout of each probe.").Default("1s").Duration()
+)
+
+type probeCollector struct {
+	upDesc       *prometheus.Desc
+	durationDesc *prometheus.Desc
+}
+
+type probeTarget struct {
+	network string
+	address string
+}
+
+func init() {
+	registerCollector("probe", defaultDisabled, NewProbeCollector)
+}
+
+// NewProbeCollector returns a new Collector checking whether the configured
+// local TCP ports and unix sockets accept connections.
+func NewProbeCollector() (Collector, error) {
+	return &probeCollector{
+		upDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "probe", "up"),
+			"1 if a connection to the target could be established, 0 otherwise.",
+			[]string{"type", "target"}, nil,
+		),
+		durationDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "probe", "duration_seconds"),
+			"Duration of the probe.",
+			[]string{"type", "target"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *probeCollector) Update(ch chan<- prometheus.Metric) error {
+	var targets []probeTarget
+	for _, address := range *probeTCPAddresses {
+		targets = append(targets, probeTarget{network: "tcp", address: address})
+	}
+	for _, path := range *probeUnixSockets {
+		targets = append(targets, probeTarget{network: "unix", address: path})
+	}
+
+	// Probes run concurrently, so that unresponsive targets only delay the
+	// scrape by a single timeout.
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target probeTarget) {
+			defer wg.Done()
+			up, duration := probe(target, *probeTimeout)
+			ch <- prometheus.MustNewConstMetric(c.upDesc, prometheus.GaugeValue, up, target.network, target.address)
+			ch <- prometheus.MustNewConstMetric(c.durationDesc, prometheus.GaugeValue, duration.Seconds(), target.network, target.address)
+		}(target)
+	}
+	wg.Wait()
+	return nil
+}
+
+// probe connects to the target and closes the connection right away.
+func probe(target probeTarget, timeout time.Duration) (float64, time.Duration) {
+	begin := time.Now()
+	conn, err := net.DialTimeout(target.network, target.address, timeout)
+	duration := time.Since(begin)
+	if err != nil {
+		log.Debugf("Probe of %s %q failed: %s", target.network, target.address, err)
+		return 0, duration
+	}
+	conn.Close()
+	return 1, duration
+}
@@ -0,0 +1,68 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noprobe
+
+package collector
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProbe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "probe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcp.Close()
+	socket := filepath.Join(dir, "agent.sock")
+	unix, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close()
+
+	// A socket file without a listener, as left behind by a crashed agent.
+	stale := filepath.Join(dir, "stale.sock")
+	l, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	for _, tc := range []struct {
+		target probeTarget
+		up     float64
+	}{
+		{probeTarget{"tcp", tcp.Addr().String()}, 1},
+		{probeTarget{"unix", socket}, 1},
+		{probeTarget{"unix", stale}, 0},
+		{probeTarget{"unix", filepath.Join(dir, "missing.sock")}, 0},
+	} {
+		if up, _ := probe(tc.target, time.Second); up != tc.up {
+			t.Errorf("%s %s: want %v, got %v", tc.target.network, tc.target.address, tc.up, up)
+		}
+	}
+}
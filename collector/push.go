@@ -0,0 +1,254 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/log"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	pushSubsystem = "push"
+	// PushPath is the path prefix under which metrics are pushed, followed
+	// by the name of the job pushing them.
+	PushPath = "/push/"
+)
+
+var (
+	pushTokenFile = kingpin.Flag("collector.push.token-file", "File containing the bearer token required to push metrics.").Default("").String()
+	pushTTL       = kingpin.Flag("collector.push.ttl", "Time after which pushed metrics expire unless pushed again.").Default("10m").Duration()
+	pushMaxBytes  = kingpin.Flag("collector.push.max-bytes", "Maximum size of a push request body.").Default("64KiB").Bytes()
+	pushMaxJobs   = kingpin.Flag("collector.push.max-jobs", "Maximum number of jobs with pushed metrics, pushes of further jobs are rejected.").Default("100").Int()
+
+	pushedGroups = &pushStore{groups: map[string]pushGroup{}}
+
+	errPushTooManyJobs = errors.New("too many jobs with pushed metrics")
+)
+
+// pushGroup holds the metrics last pushed by a job.
+type pushGroup struct {
+	families map[string][]prometheus.Metric
+	samples  int
+	time     time.Time
+}
+
+type pushStore struct {
+	mtx    sync.Mutex
+	groups map[string]pushGroup
+}
+
+type pushCollector struct {
+	lastPushDesc *prometheus.Desc
+	samplesDesc  *prometheus.Desc
+}
+
+func init() {
+	registerCollector("push", defaultDisabled, NewPushCollector)
+}
+
+// NewPushCollector returns a new Collector exposing the metrics pushed to
+// the push endpoint.
+func NewPushCollector() (Collector, error) {
+	return &pushCollector{
+		lastPushDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pushSubsystem, "last_push_timestamp_seconds"),
+			"Unixtime the job last pushed metrics.",
+			[]string{"job"}, nil,
+		),
+		samplesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pushSubsystem, "samples"),
+			"Number of samples last pushed by the job.",
+			[]string{"job"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *pushCollector) Update(ch chan<- prometheus.Metric) error {
+	pushedGroups.mtx.Lock()
+	defer pushedGroups.mtx.Unlock()
+	pushedGroups.expire(time.Now())
+
+	jobs := make([]string, 0, len(pushedGroups.groups))
+	for job := range pushedGroups.groups {
+		jobs = append(jobs, job)
+	}
+	sort.Strings(jobs)
+
+	for _, job := range jobs {
+		group := pushedGroups.groups[job]
+		for _, metrics := range group.families {
+			for _, m := range metrics {
+				ch <- m
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(c.lastPushDesc, prometheus.GaugeValue, float64(group.time.UnixNano())/1e9, job)
+		ch <- prometheus.MustNewConstMetric(c.samplesDesc, prometheus.GaugeValue, float64(group.samples), job)
+	}
+	return nil
+}
+
+// expire drops the groups which haven't been pushed within the TTL. The
+// caller has to hold the lock.
+func (s *pushStore) expire(now time.Time) {
+	for job, group := range s.groups {
+		if now.Sub(group.time) > *pushTTL {
+			delete(s.groups, job)
+		}
+	}
+}
+
+// set replaces the metrics of a job, a metric may only be pushed by one job.
+// It returns errPushTooManyJobs for a new job if there are already
+// --collector.push.max-jobs jobs.
+func (s *pushStore) set(job string, group pushGroup) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.expire(group.time)
+
+	if _, ok := s.groups[job]; !ok && len(s.groups) >= *pushMaxJobs {
+		return errPushTooManyJobs
+	}
+
+	for other, g := range s.groups {
+		if other == job {
+			continue
+		}
+		for name := range group.families {
+			if _, ok := g.families[name]; ok {
+				return fmt.Errorf("metric %s is already pushed by job %q", name, other)
+			}
+		}
+	}
+	s.groups[job] = group
+	return nil
+}
+
+func (s *pushStore) delete(job string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.groups, job)
+}
+
+type pushHandler struct {
+	token []byte
+}
+
+// NewPushHandler returns the handler of the push endpoint, nil if the push
+// collector is disabled. Metrics are pushed in the text format with PUT or
+// POST to PushPath followed by the job name, replacing the metrics previously
+// pushed by the job, and deleted with DELETE.
+func NewPushHandler() (http.Handler, error) {
+	if !*collectorState["push"] {
+		return nil, nil
+	}
+	if *pushTokenFile == "" {
+		return nil, fmt.Errorf("the push collector requires --collector.push.token-file")
+	}
+	token, err := ioutil.ReadFile(*pushTokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read push token: %s", err)
+	}
+	token = []byte(strings.TrimSpace(string(token)))
+	if len(token) == 0 {
+		return nil, fmt.Errorf("push token file %q is empty", *pushTokenFile)
+	}
+	return &pushHandler{token: token}, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *pushHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), h.token) != 1 {
+		http.Error(w, "invalid or missing bearer token", http.StatusUnauthorized)
+		return
+	}
+
+	job := strings.TrimPrefix(r.URL.Path, PushPath)
+	if job == "" || strings.Contains(job, "/") {
+		http.Error(w, "the path has to end with the job name", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut, http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, int64(*pushMaxBytes))
+		group, err := parsePushedMetrics(job, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := pushedGroups.set(job, group); err == errPushTooManyJobs {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Debugf("Job %q pushed %d samples", job, group.samples)
+	case http.MethodDelete:
+		pushedGroups.delete(job)
+	default:
+		w.Header().Set("Allow", "PUT, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parsePushedMetrics parses and validates pushed metrics the same way the
+// textfile collector validates a file.
+func parsePushedMetrics(job string, r *http.Request) (pushGroup, error) {
+	var parser expfmt.TextParser
+	parsedFamilies, err := parser.TextToMetricFamilies(r.Body)
+	if err != nil {
+		return pushGroup{}, fmt.Errorf("error parsing metrics: %s", err)
+	}
+	if hasTimestamps(parsedFamilies) {
+		return pushGroup{}, fmt.Errorf("client-side timestamps are not supported")
+	}
+
+	group := pushGroup{
+		families: make(map[string][]prometheus.Metric, len(parsedFamilies)),
+		time:     time.Now(),
+	}
+	for name, mf := range parsedFamilies {
+		if mf.Help == nil {
+			help := fmt.Sprintf("Metric pushed by job %s", job)
+			mf.Help = &help
+		}
+		if err := checkDuplicateSeries(mf); err != nil {
+			return pushGroup{}, err
+		}
+		metrics, err := convertMetricFamily(mf)
+		if err != nil {
+			return pushGroup{}, err
+		}
+		group.families[name] = metrics
+		group.samples += countSamples(mf)
+	}
+	return group, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+func TestPushHandler(t *testing.T) {
+	if _, err := kingpin.CommandLine.Parse([]string{}); err != nil {
+		t.Fatal(err)
+	}
+	pushedGroups = &pushStore{groups: map[string]pushGroup{}}
+	h := &pushHandler{token: []byte("secret")}
+	*pushMaxJobs = 2
+
+	for _, tc := range []struct {
+		method, path, auth, body string
+		code                     int
+	}{
+		{"POST", "/push/backup", "", "runs 1\n", http.StatusUnauthorized},
+		{"POST", "/push/backup", "Bearer wrong", "runs 1\n", http.StatusUnauthorized},
+		{"POST", "/push/backup", "secret", "runs 1\n", http.StatusUnauthorized},
+		{"POST", "/push/backup", "Basic secret", "runs 1\n", http.StatusUnauthorized},
+		{"POST", "/push/", "Bearer secret", "runs 1\n", http.StatusNotFound},
+		{"GET", "/push/backup", "Bearer secret", "", http.StatusMethodNotAllowed},
+		{"POST", "/push/backup", "Bearer secret", "runs{ 1\n", http.StatusBadRequest},
+		{"POST", "/push/backup", "Bearer secret", "runs 1 1000\n", http.StatusBadRequest},
+		{"POST", "/push/backup", "Bearer secret", "# TYPE runs counter\nruns{a=\"b\"} 1\nruns{a=\"c\"} 2\n", http.StatusNoContent},
+		{"PUT", "/push/cleanup", "Bearer secret", "runs 1\n", http.StatusConflict},
+		{"PUT", "/push/cleanup", "Bearer secret", "cleaned 1\n", http.StatusNoContent},
+		// Jobs past --collector.push.max-jobs are rejected, existing ones
+		// can still push.
+		{"PUT", "/push/rotate", "Bearer secret", "rotated 1\n", http.StatusTooManyRequests},
+		{"PUT", "/push/cleanup", "Bearer secret", "cleaned 2\n", http.StatusNoContent},
+		{"DELETE", "/push/cleanup", "Bearer secret", "", http.StatusNoContent},
+		{"PUT", "/push/rotate", "Bearer secret", "rotated 1\n", http.StatusNoContent},
+		{"DELETE", "/push/rotate", "Bearer secret", "", http.StatusNoContent},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		if tc.auth != "" {
+			req.Header.Set("Authorization", tc.auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("%s %s %q %q: expected status %d, got %d: %s", tc.method, tc.path, tc.auth, tc.body, tc.code, rec.Code, rec.Body)
+		}
+	}
+
+	if len(pushedGroups.groups) != 1 || pushedGroups.groups["backup"].samples != 2 {
+		t.Fatalf("unexpected pushed groups %+v", pushedGroups.groups)
+	}
+
+	pushedGroups.mtx.Lock()
+	pushedGroups.expire(time.Now().Add(*pushTTL + time.Second))
+	pushedGroups.mtx.Unlock()
+	if len(pushedGroups.groups) != 0 {
+		t.Errorf("expected pushed metrics to expire, got %+v", pushedGroups.groups)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noquota
+
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"golang.org/x/sys/unix"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	quotaSubsystem = "quota"
+
+	// qGetNextQuota is Q_GETNEXTQUOTA of linux/quota.h, supported by ext4
+	// and XFS since Linux 4.6.
+	qGetNextQuota = 0x800009
+	// quotaBlockSize is QIF_DQBLKSIZE, the unit of the block limits.
+	quotaBlockSize = 1024
+)
+
+var (
+	quotaTypes     = kingpin.Flag("collector.quota.type", "Quota type to expose, one of user, group and project. Can be repeated, defaults to all types.").Enums("user", "group", "project")
+	quotaIDInclude = kingpin.Flag("collector.quota.id-include", "Regexp of user, group and project IDs to expose the quotas of.").Default(".+").String()
+	quotaIDExclude = kingpin.Flag("collector.quota.id-exclude", "Regexp of user, group and project IDs not to expose the quotas of.").Default("").String()
+
+	// quotaTypeIDs are the USRQUOTA, GRPQUOTA and PRJQUOTA values of
+	// linux/quota.h.
+	quotaTypeIDs = map[string]int{"user": 0, "group": 1, "project": 2}
+)
+
+// quotaNextDqblk is struct if_nextdqblk of linux/quota.h.
+type quotaNextDqblk struct {
+	bHardLimit uint64
+	bSoftLimit uint64
+	curSpace   uint64
+	iHardLimit uint64
+	iSoftLimit uint64
+	curInodes  uint64
+	bTime      uint64
+	iTime      uint64
+	valid      uint32
+	id         uint32
+}
+
+type quotaCollector struct {
+	idInclude          *regexp.Regexp
+	idExclude          *regexp.Regexp
+	usedBytesDesc      *prometheus.Desc
+	softLimitBytesDesc *prometheus.Desc
+	hardLimitBytesDesc *prometheus.Desc
+	usedFilesDesc      *prometheus.Desc
+	softLimitFilesDesc *prometheus.Desc
+	hardLimitFilesDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector(quotaSubsystem, defaultDisabled, NewQuotaCollector)
+}
+
+// NewQuotaCollector returns a new Collector exposing the usage and limits of
+// user, group and project quotas of ext4 and XFS filesystems.
+func NewQuotaCollector() (Collector, error) {
+	idInclude, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", *quotaIDInclude))
+	if err != nil {
+		return nil, fmt.Errorf("invalid quota ID include regexp: %s", err)
+	}
+	idExclude, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", *quotaIDExclude))
+	if err != nil {
+		return nil, fmt.Errorf("invalid quota ID exclude regexp: %s", err)
+	}
+
+	labels := []string{"device", "mountpoint", "type", "id"}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, quotaSubsystem, name), help, labels, nil)
+	}
+	return &quotaCollector{
+		idInclude:          idInclude,
+		idExclude:          idExclude,
+		usedBytesDesc:      desc("used_bytes", "Space used by the user, group or project."),
+		softLimitBytesDesc: desc("soft_limit_bytes", "Soft limit of the space of the user, group or project, 0 if unlimited."),
+		hardLimitBytesDesc: desc("hard_limit_bytes", "Hard limit of the space of the user, group or project, 0 if unlimited."),
+		usedFilesDesc:      desc("used_files", "Number of inodes used by the user, group or project."),
+		softLimitFilesDesc: desc("soft_limit_files", "Soft limit of the number of inodes of the user, group or project, 0 if unlimited."),
+		hardLimitFilesDesc: desc("hard_limit_files", "Hard limit of the number of inodes of the user, group or project, 0 if unlimited."),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *quotaCollector) Update(ch chan<- prometheus.Metric) error {
+	mounts, err := mountPointDetails()
+	if err != nil {
+		return fmt.Errorf("couldn't get mounts: %s", err)
+	}
+
+	types := *quotaTypes
+	if len(types) == 0 {
+		types = []string{"user", "group", "project"}
+	}
+	// Filesystems may be mounted more than once.
+	seen := map[string]bool{}
+	for _, m := range mounts {
+		if (m.fsType != "ext4" && m.fsType != "xfs") || seen[m.device] {
+			continue
+		}
+		seen[m.device] = true
+
+		for _, t := range types {
+			quotas, err := getQuotas(m.device, quotaTypeIDs[t])
+			switch err {
+			case nil:
+			// Quotas of the type aren't enabled, or the kernel doesn't
+			// support iterating over them.
+			case unix.ESRCH, unix.ENOSYS, unix.EINVAL, unix.ENOTSUP:
+				log.Debugf("Not collecting %s quotas of %s: %s", t, m.mountPoint, err)
+				continue
+			default:
+				return fmt.Errorf("couldn't get %s quotas of %s: %s", t, m.mountPoint, err)
+			}
+
+			for _, q := range quotas {
+				id := strconv.FormatUint(uint64(q.id), 10)
+				if !c.idInclude.MatchString(id) || c.idExclude.MatchString(id) {
+					continue
+				}
+				labels := []string{m.device, m.mountPoint, t, id}
+				ch <- prometheus.MustNewConstMetric(c.usedBytesDesc, prometheus.GaugeValue, float64(q.curSpace), labels...)
+				ch <- prometheus.MustNewConstMetric(c.softLimitBytesDesc, prometheus.GaugeValue, float64(q.bSoftLimit*quotaBlockSize), labels...)
+				ch <- prometheus.MustNewConstMetric(c.hardLimitBytesDesc, prometheus.GaugeValue, float64(q.bHardLimit*quotaBlockSize), labels...)
+				ch <- prometheus.MustNewConstMetric(c.usedFilesDesc, prometheus.GaugeValue, float64(q.curInodes), labels...)
+				ch <- prometheus.MustNewConstMetric(c.softLimitFilesDesc, prometheus.GaugeValue, float64(q.iSoftLimit), labels...)
+				ch <- prometheus.MustNewConstMetric(c.hardLimitFilesDesc, prometheus.GaugeValue, float64(q.iHardLimit), labels...)
+			}
+		}
+	}
+	return nil
+}
+
+// getQuotas returns the quotas of the given type of all IDs having one on
+// the block device.
+func getQuotas(device string, quotaType int) ([]quotaNextDqblk, error) {
+	dev, err := unix.BytePtrFromString(device)
+	if err != nil {
+		return nil, err
+	}
+	// The command is QCMD(Q_GETNEXTQUOTA, type).
+	cmd := uintptr(uint32(qGetNextQuota<<8) | uint32(quotaType&0xff))
+
+	var quotas []quotaNextDqblk
+	for id := uint64(0); id <= 0xffffffff; {
+		var q quotaNextDqblk
+		_, _, errno := unix.Syscall6(unix.SYS_QUOTACTL, cmd, uintptr(unsafe.Pointer(dev)), uintptr(id), uintptr(unsafe.Pointer(&q)), 0, 0)
+		if errno == unix.ENOENT {
+			// There is no quota with a higher ID.
+			break
+		}
+		if errno != 0 {
+			return nil, errno
+		}
+		quotas = append(quotas, q)
+		id = uint64(q.id) + 1
+	}
+	return quotas, nil
+}
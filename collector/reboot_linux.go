@@ -0,0 +1,215 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noreboot
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const rebootSubsystem = "reboot"
+
+type rebootCollector struct {
+	requiredDesc          *prometheus.Desc
+	requiredTimeDesc      *prometheus.Desc
+	requiredPackagesDesc  *prometheus.Desc
+	kernelOutdatedDesc    *prometheus.Desc
+	kernelInfoDesc        *prometheus.Desc
+	kernelInstallTimeDesc *prometheus.Desc
+}
+
+// installedKernel is a kernel image found in /boot.
+type installedKernel struct {
+	version     string
+	installTime time.Time
+}
+
+func init() {
+	registerCollector("reboot", defaultDisabled, NewRebootCollector)
+}
+
+// NewRebootCollector returns a new Collector exposing whether a reboot is
+// required after package updates.
+func NewRebootCollector() (Collector, error) {
+	return &rebootCollector{
+		requiredDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, rebootSubsystem, "required"),
+			"1 if /var/run/reboot-required exists, 0 otherwise.",
+			nil, nil,
+		),
+		requiredTimeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, rebootSubsystem, "required_timestamp_seconds"),
+			"Unixtime /var/run/reboot-required was last modified at.",
+			nil, nil,
+		),
+		requiredPackagesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, rebootSubsystem, "required_packages"),
+			"Number of packages listed in /var/run/reboot-required.pkgs.",
+			nil, nil,
+		),
+		kernelOutdatedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, rebootSubsystem, "kernel_outdated"),
+			"1 if a newer kernel than the running one is installed, 0 otherwise.",
+			nil, nil,
+		),
+		kernelInfoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, rebootSubsystem, "kernel_info"),
+			"Versions of the running and the newest installed kernel.",
+			[]string{"running", "newest"}, nil,
+		),
+		kernelInstallTimeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, rebootSubsystem, "newest_kernel_install_timestamp_seconds"),
+			"Unixtime the newest installed kernel was installed at.",
+			nil, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *rebootCollector) Update(ch chan<- prometheus.Metric) error {
+	required := 0.0
+	if fi, err := os.Stat(rootfsFilePath("var/run/reboot-required")); err == nil {
+		required = 1
+		ch <- prometheus.MustNewConstMetric(c.requiredTimeDesc, prometheus.GaugeValue, float64(fi.ModTime().Unix()))
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("couldn't stat reboot-required: %s", err)
+	}
+	ch <- prometheus.MustNewConstMetric(c.requiredDesc, prometheus.GaugeValue, required)
+
+	if required == 1 {
+		if pkgs, err := ioutil.ReadFile(rootfsFilePath("var/run/reboot-required.pkgs")); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.requiredPackagesDesc, prometheus.GaugeValue, float64(len(strings.Fields(string(pkgs)))))
+		}
+	}
+
+	running, err := ioutil.ReadFile(procFilePath("sys/kernel/osrelease"))
+	if err != nil {
+		return fmt.Errorf("couldn't get running kernel version: %s", err)
+	}
+	newest, err := newestInstalledKernel(rootfsFilePath("boot"))
+	if err != nil {
+		return err
+	}
+	if newest == nil {
+		log.Debugf("No versioned kernel image found in /boot")
+		return nil
+	}
+
+	runningVersion := strings.TrimSpace(string(running))
+	outdated := 0.0
+	if compareKernelVersions(newest.version, runningVersion) > 0 {
+		outdated = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.kernelOutdatedDesc, prometheus.GaugeValue, outdated)
+	ch <- prometheus.MustNewConstMetric(c.kernelInfoDesc, prometheus.GaugeValue, 1, runningVersion, newest.version)
+	ch <- prometheus.MustNewConstMetric(c.kernelInstallTimeDesc, prometheus.GaugeValue, float64(newest.installTime.Unix()))
+	return nil
+}
+
+// newestInstalledKernel returns the newest of the vmlinuz-<version> kernel
+// images in dir, nil if there is none.
+func newestInstalledKernel(dir string) (*installedKernel, error) {
+	images, err := filepath.Glob(filepath.Join(dir, "vmlinuz-*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var newest *installedKernel
+	for _, image := range images {
+		version := strings.TrimPrefix(filepath.Base(image), "vmlinuz-")
+		// Skip unversioned images, e.g. vmlinuz-linux on Arch Linux,
+		// and rescue images.
+		if version == "" || !unicode.IsDigit(rune(version[0])) || strings.Contains(version, "rescue") {
+			continue
+		}
+		fi, err := os.Stat(image)
+		if err != nil {
+			log.Debugf("Couldn't stat kernel image %q: %s", image, err)
+			continue
+		}
+		if newest == nil || compareKernelVersions(version, newest.version) > 0 {
+			newest = &installedKernel{version: version, installTime: fi.ModTime()}
+		}
+	}
+	return newest, nil
+}
+
+// compareKernelVersions compares two kernel versions like rpmvercmp, segment
+// by segment of digits or letters, with numeric segments being newer than
+// alphabetic ones. It returns 1 if a is newer, -1 if b is newer, 0 otherwise.
+func compareKernelVersions(a, b string) int {
+	as, bs := versionSegments(a), versionSegments(b)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an > bn {
+					return 1
+				}
+				return -1
+			}
+		case aErr == nil:
+			return 1
+		case bErr == nil:
+			return -1
+		case as[i] != bs[i]:
+			if as[i] > bs[i] {
+				return 1
+			}
+			return -1
+		}
+	}
+	switch {
+	case len(as) > len(bs):
+		return 1
+	case len(as) < len(bs):
+		return -1
+	}
+	return 0
+}
+
+// versionSegments splits a version into runs of digits and letters.
+func versionSegments(version string) []string {
+	var (
+		segments []string
+		current  []rune
+	)
+	for _, r := range version {
+		isDigit, isLetter := unicode.IsDigit(r), unicode.IsLetter(r)
+		if len(current) > 0 && (!(isDigit || isLetter) || unicode.IsDigit(current[0]) != isDigit) {
+			segments = append(segments, string(current))
+			current = nil
+		}
+		if isDigit || isLetter {
+			current = append(current, r)
+		}
+	}
+	if len(current) > 0 {
+		segments = append(segments, string(current))
+	}
+	return segments
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noreboot
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCompareKernelVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"5.3.0-20-generic", "5.3.0-19-generic", 1},
+		{"5.3.0-19-generic", "5.3.0-19-generic", 0},
+		{"4.19.0-6-amd64", "4.19.0-10-amd64", -1},
+		{"4.18.0-147.el8.x86_64", "4.18.0-80.11.2.el8_0.x86_64", 1},
+		{"5.3.7-301.fc31.x86_64", "5.3.7-301.fc31.x86_64", 0},
+		{"4.12.14-lp151.28.20-default", "4.12.14-lp151.28.16-default", 1},
+	} {
+		if got := compareKernelVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareKernelVersions(%q, %q): want %d, got %d", tc.a, tc.b, tc.want, got)
+		}
+	}
+}
+
+func TestNewestInstalledKernel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"vmlinuz-4.19.0-6-amd64", "vmlinuz-4.19.0-10-amd64", "vmlinuz-0-rescue-1234", "vmlinuz-linux", "config-4.19.0-10-amd64"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	newest, err := newestInstalledKernel(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newest == nil || newest.version != "4.19.0-10-amd64" {
+		t.Errorf("want newest kernel 4.19.0-10-amd64, got %+v", newest)
+	}
+}
+
+func TestRebootCollectorRequired(t *testing.T) {
+	// The reboot collector only looks at /var/run/reboot-required, whether
+	// the updates collector runs or not.
+	rootfs, err := ioutil.TempDir("", "rootfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootfs)
+	for _, dir := range []string{"var/run", "boot", "proc/sys/kernel"} {
+		if err := os.MkdirAll(filepath.Join(rootfs, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range map[string]string{
+		"var/run/reboot-required":      "*** System restart required ***\n",
+		"var/run/reboot-required.pkgs": "linux-base\nlibc6\n",
+		"proc/sys/kernel/osrelease":    "4.19.0-6-amd64\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(rootfs, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldRootfs, oldProc := *rootfsPath, *procPath
+	defer func() { *rootfsPath, *procPath = oldRootfs, oldProc }()
+	*rootfsPath, *procPath = rootfs, filepath.Join(rootfs, "proc")
+
+	c, err := NewRebootCollector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectorAdapter{c})
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	for _, mf := range mfs {
+		got[mf.GetName()] = mf.GetMetric()[0].GetGauge().GetValue()
+	}
+	for name, want := range map[string]float64{
+		"node_reboot_required":          1,
+		"node_reboot_required_packages": 2,
+	} {
+		if got[name] != want {
+			t.Errorf("want %s %f, got %f", name, want, got[name])
+		}
+	}
+	if _, ok := got["node_updates_reboot_required"]; ok {
+		t.Error("unexpected node_updates_reboot_required of the updates collector")
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noremovable
+
+package collector
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const (
+	removableSubsystem = "removable"
+
+	// SCSI peripheral device types of optical drives.
+	scsiTypeCDROM = 5
+	scsiTypeMOD   = 7
+)
+
+type removableCollector struct {
+	info     *prometheus.Desc
+	present  *prometheus.Desc
+	readonly *prometheus.Desc
+	size     *prometheus.Desc
+}
+
+func init() {
+	registerCollector(removableSubsystem, defaultDisabled, NewRemovableCollector)
+}
+
+// NewRemovableCollector returns a new Collector exposing whether removable
+// block devices like optical drives and card readers contain media.
+func NewRemovableCollector() (Collector, error) {
+	labels := []string{"device"}
+	return &removableCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, removableSubsystem, "device_info"),
+			"Removable block device, value is always 1.",
+			[]string{"device", "type", "vendor", "model"}, nil,
+		),
+		present: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, removableSubsystem, "media_present"),
+			"1 if the removable device contains media, 0 otherwise.",
+			labels, nil,
+		),
+		readonly: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, removableSubsystem, "media_readonly"),
+			"1 if the media in the removable device is read-only, 0 otherwise.",
+			labels, nil,
+		),
+		size: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, removableSubsystem, "media_size_bytes"),
+			"Size of the media in the removable device.",
+			labels, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface. The kernel only notices media
+// changes when polling the device, so the size of a drive is 0 until it
+// checked for media after an insertion.
+func (c *removableCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("block/*/removable"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range devices {
+		dir := filepath.Dir(file)
+		device := filepath.Base(dir)
+
+		removable, err := readUintFromFile(file)
+		if err != nil {
+			return fmt.Errorf("couldn't get removable flag of %s: %s", device, err)
+		}
+		if removable == 0 {
+			continue
+		}
+
+		var vendor, model string
+		if v, err := readTrimmedFile(filepath.Join(dir, "device/vendor")); err == nil {
+			vendor = v
+		}
+		if m, err := readTrimmedFile(filepath.Join(dir, "device/model")); err == nil {
+			model = m
+		}
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, device, removableType(dir), vendor, model)
+
+		sectors, err := readUintFromFile(filepath.Join(dir, "size"))
+		if err != nil {
+			return fmt.Errorf("couldn't get size of %s: %s", device, err)
+		}
+		present := 0.0
+		if sectors > 0 {
+			present = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.present, prometheus.GaugeValue, present, device)
+		if sectors == 0 {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(sectors*512), device)
+
+		ro, err := readUintFromFile(filepath.Join(dir, "ro"))
+		if err != nil {
+			log.Debugf("Couldn't get read-only flag of %s: %s", device, err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.readonly, prometheus.GaugeValue, float64(ro), device)
+	}
+
+	return nil
+}
+
+// removableType returns optical for optical drives, as reported by the SCSI
+// layer, and disk for other removable devices like card readers.
+func removableType(dir string) string {
+	t, err := readUintFromFile(filepath.Join(dir, "device/type"))
+	if err == nil && (t == scsiTypeCDROM || t == scsiTypeMOD) {
+		return "optical"
+	}
+	return "disk"
+}
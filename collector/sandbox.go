@@ -0,0 +1,118 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SandboxPolicy is the access the enabled collectors need from the sandbox
+// of --security.sandbox.
+type SandboxPolicy struct {
+	// ReadPaths are the files and directories the collectors read.
+	ReadPaths []string
+	// ReadWritePaths are the devices the collectors open for writing,
+	// e.g. to send commands.
+	ReadWritePaths []string
+	// Setns is whether collectors enter other network namespaces.
+	Setns bool
+}
+
+// sandboxRequirement is the access a collector needs beyond reading procfs
+// and sysfs. The functions are called after the flags are parsed.
+type sandboxRequirement struct {
+	readPaths      func() []string
+	readWritePaths func() []string
+	// exec returns whether the collector runs commands with its current
+	// flags, which the sandbox can't allow.
+	exec  func() bool
+	setns func() bool
+}
+
+// NewSandboxPolicy returns the access the enabled collectors need with
+// their current flags. It fails if a collector runs commands.
+func NewSandboxPolicy() (SandboxPolicy, error) {
+	policy := SandboxPolicy{ReadPaths: []string{*procPath, *sysPath}}
+	var exec []string
+	for collector, r := range collectorSandboxRequirements {
+		if enabled, ok := collectorState[collector]; !ok || !*enabled {
+			continue
+		}
+		if r.exec != nil && r.exec() {
+			exec = append(exec, collector)
+		}
+		if r.readPaths != nil {
+			policy.ReadPaths = append(policy.ReadPaths, r.readPaths()...)
+		}
+		if r.readWritePaths != nil {
+			policy.ReadWritePaths = append(policy.ReadWritePaths, r.readWritePaths()...)
+		}
+		if r.setns != nil && r.setns() {
+			policy.Setns = true
+		}
+	}
+	if len(exec) > 0 {
+		sort.Strings(exec)
+		return policy, fmt.Errorf("the sandbox doesn't allow running commands, disable the collectors %s or their commands", strings.Join(exec, ", "))
+	}
+	policy.ReadPaths = uniquePaths(policy.ReadPaths)
+	policy.ReadWritePaths = uniquePaths(policy.ReadWritePaths)
+	return policy, nil
+}
+
+// globBase returns the longest leading directory of the glob pattern
+// without meta characters, which contains all paths matching it.
+func globBase(pattern string) string {
+	dir := pattern
+	for strings.ContainsAny(dir, `*?[\`) {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+func globBases(patterns []string) []string {
+	var dirs []string
+	for _, p := range patterns {
+		if p != "" {
+			dirs = append(dirs, globBase(p))
+		}
+	}
+	return dirs
+}
+
+// globPaths returns the paths currently matching the glob pattern.
+func globPaths(pattern string) func() []string {
+	return func() []string {
+		paths, _ := filepath.Glob(pattern)
+		return paths
+	}
+}
+
+// uniquePaths returns the sorted paths without empty and duplicate ones.
+func uniquePaths(paths []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, p := range paths {
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		unique = append(unique, p)
+	}
+	sort.Strings(unique)
+	return unique
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import "path/filepath"
+
+// collectorSandboxRequirements are the access collectors need beyond
+// reading procfs and sysfs.
+var collectorSandboxRequirements = map[string]sandboxRequirement{
+	"btrfs": {readPaths: func() []string {
+		return []string{rootfsFilePath("var/lib/btrfs")}
+	}},
+	"certificate": {readPaths: func() []string { return globBases(*certificatePaths) }},
+	"cgroup": {readPaths: func() []string {
+		return []string{*cgroupContainerdState}
+	}},
+	"clocksource": {readPaths: globPaths("/dev/ptp[0-9]*")},
+	"dirsize":     {readPaths: func() []string { return *dirSizePaths }},
+	"dmcache":     {readWritePaths: func() []string { return []string{"/dev/mapper/control"} }},
+	"filestat":    {readPaths: func() []string { return globBases(*fileStatPaths) }},
+	"firewall": {exec: func() bool {
+		return *firewallNftPath != "" || *firewallIptablesSavePath != ""
+	}},
+	"gpu":     {exec: func() bool { return *gpuNvidiaSmiPath != "" }},
+	"iscsi":   {exec: func() bool { return *iscsiadmPath != "" }},
+	"journal": {exec: func() bool { return true }},
+	"kmsg":    {readPaths: func() []string { return []string{*kmsgPath} }},
+	"logins": {readPaths: func() []string {
+		return []string{*loginsBtmpPath, *loginsWtmpPath}
+	}},
+	"netdev":   netnsSandboxRequirement,
+	"netstat":  netnsSandboxRequirement,
+	"sockstat": netnsSandboxRequirement,
+	"reboot": {readPaths: func() []string {
+		return []string{rootfsFilePath("var/run"), rootfsFilePath("boot")}
+	}},
+	"script":   {exec: func() bool { return len(*scriptCommands) > 0 }},
+	"tape":     {readPaths: globPaths("/dev/nst[0-9]*")},
+	"textfile": {readPaths: func() []string { return globBases(*textFileDirectories) }},
+	"tpm":      {readWritePaths: func() []string { return globPaths(filepath.Join(*tpmDevicePath, "tpmrm[0-9]*"))() }},
+	"updates":  {exec: func() bool { return true }},
+	"zfs":      {exec: func() bool { return *zpoolPath != "" }},
+}
+
+// netnsSandboxRequirement is the access needed to collect the network
+// namespaces of --collector.netns.names.
+var netnsSandboxRequirement = sandboxRequirement{
+	readPaths: func() []string {
+		if *netnsNames == "" {
+			return nil
+		}
+		return []string{*netnsDir}
+	},
+	setns: func() bool { return *netnsNames != "" },
+}
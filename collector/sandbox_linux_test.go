@@ -0,0 +1,83 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGlobBase(t *testing.T) {
+	for pattern, want := range map[string]string{
+		"/var/lib/node_exporter":        "/var/lib/node_exporter",
+		"/var/lib/node_exporter/*.prom": "/var/lib/node_exporter",
+		"/etc/ssl/*/certs/[a-z]*.pem":   "/etc/ssl",
+		"*.pem":                         ".",
+	} {
+		if got := globBase(pattern); got != want {
+			t.Errorf("%s: want %s, got %s", pattern, want, got)
+		}
+	}
+}
+
+func TestNewSandboxPolicy(t *testing.T) {
+	enable := func(collector string, enabled bool) func() {
+		orig := collectorState[collector]
+		collectorState[collector] = &enabled
+		return func() { collectorState[collector] = orig }
+	}
+	set := func(flag *string, value string) func() {
+		orig := *flag
+		*flag = value
+		return func() { *flag = orig }
+	}
+
+	defer set(procPath, "/proc")()
+	defer set(sysPath, "/sys")()
+	defer enable("netdev", true)()
+	defer enable("script", false)()
+	defer enable("kmsg", true)()
+	defer set(kmsgPath, "/dev/kmsg")()
+	defer set(netnsNames, "blue")()
+	defer set(netnsDir, "/var/run/netns")()
+	defer enable("textfile", true)()
+	origTextfile := *textFileDirectories
+	defer func() { *textFileDirectories = origTextfile }()
+	*textFileDirectories = []string{"/var/lib/node_exporter/textfile_collector/*.d"}
+
+	policy, err := NewSandboxPolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !policy.Setns {
+		t.Error("want setns allowed for --collector.netns.names")
+	}
+	for _, want := range []string{*procPath, *sysPath, "/dev/kmsg", "/var/run/netns", "/var/lib/node_exporter/textfile_collector"} {
+		found := false
+		for _, p := range policy.ReadPaths {
+			found = found || p == want
+		}
+		if !found {
+			t.Errorf("want %s in read paths %q", want, policy.ReadPaths)
+		}
+	}
+
+	defer enable("script", true)()
+	orig := *scriptCommands
+	defer func() { *scriptCommands = orig }()
+	*scriptCommands = []string{"backup=/usr/local/bin/backup-status"}
+	if _, err := NewSandboxPolicy(); err == nil || !strings.Contains(err.Error(), "script") {
+		t.Errorf("want error for the script collector, got %v", err)
+	}
+}
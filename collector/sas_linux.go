@@ -0,0 +1,147 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nosas
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const sasSubsystem = "sas"
+
+// sasPhyCounters are the error counters of a SAS phy by file.
+var sasPhyCounters = []struct {
+	file, name, help string
+}{
+	{"invalid_dword_count", "invalid_dwords_total", "Number of invalid dwords received by the phy."},
+	{"running_disparity_error_count", "running_disparity_errors_total", "Number of dwords with running disparity errors received by the phy."},
+	{"loss_of_dword_sync_count", "loss_of_dword_sync_total", "Number of times the phy lost dword synchronization."},
+	{"phy_reset_problem_count", "reset_problems_total", "Number of times a phy reset failed."},
+}
+
+type sasCollector struct {
+	phyCounterDescs     []*prometheus.Desc
+	phyLinkRateDesc     *prometheus.Desc
+	phyMaxLinkRateDesc  *prometheus.Desc
+	ataLinkSpeedDesc    *prometheus.Desc
+	ataLinkMaxSpeedDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector(sasSubsystem, defaultDisabled, NewSASCollector)
+}
+
+// NewSASCollector returns a new Collector exposing the error counters and link
+// rates of SAS phys and the link speeds of SATA links.
+func NewSASCollector() (Collector, error) {
+	c := &sasCollector{
+		phyLinkRateDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, sasSubsystem, "phy_link_rate_bits_per_second"),
+			"Negotiated link rate of the SAS phy.",
+			[]string{"phy"}, nil,
+		),
+		phyMaxLinkRateDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, sasSubsystem, "phy_max_link_rate_bits_per_second"),
+			"Maximum link rate of the SAS phy.",
+			[]string{"phy"}, nil,
+		),
+		ataLinkSpeedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "ata", "link_speed_bits_per_second"),
+			"Negotiated speed of the SATA link.",
+			[]string{"link"}, nil,
+		),
+		ataLinkMaxSpeedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "ata", "link_max_speed_bits_per_second"),
+			"Maximum speed supported by the SATA link.",
+			[]string{"link"}, nil,
+		),
+	}
+	for _, counter := range sasPhyCounters {
+		c.phyCounterDescs = append(c.phyCounterDescs, prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, sasSubsystem, "phy_"+counter.name),
+			counter.help, []string{"phy"}, nil,
+		))
+	}
+	return c, nil
+}
+
+// Update implements the Collector interface.
+func (c *sasCollector) Update(ch chan<- prometheus.Metric) error {
+	phys, err := filepath.Glob(sysFilePath("class/sas_phy/*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range phys {
+		phy := filepath.Base(dir)
+		for i, counter := range sasPhyCounters {
+			value, err := readUintFromFile(filepath.Join(dir, counter.file))
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return fmt.Errorf("couldn't read %s of %s: %s", counter.file, phy, err)
+			}
+			ch <- prometheus.MustNewConstMetric(c.phyCounterDescs[i], prometheus.CounterValue, float64(value), phy)
+		}
+		if rate, ok := readLinkRate(filepath.Join(dir, "negotiated_linkrate"), "Gbit"); ok {
+			ch <- prometheus.MustNewConstMetric(c.phyLinkRateDesc, prometheus.GaugeValue, rate, phy)
+		}
+		if rate, ok := readLinkRate(filepath.Join(dir, "maximum_linkrate"), "Gbit"); ok {
+			ch <- prometheus.MustNewConstMetric(c.phyMaxLinkRateDesc, prometheus.GaugeValue, rate, phy)
+		}
+	}
+
+	// libata doesn't expose error counters of SATA links, but links
+	// negotiating a lower speed than supported hint at cabling issues.
+	links, err := filepath.Glob(sysFilePath("class/ata_link/*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range links {
+		link := filepath.Base(dir)
+		if speed, ok := readLinkRate(filepath.Join(dir, "sata_spd"), "Gbps"); ok {
+			ch <- prometheus.MustNewConstMetric(c.ataLinkSpeedDesc, prometheus.GaugeValue, speed, link)
+		}
+		if speed, ok := readLinkRate(filepath.Join(dir, "hw_sata_spd_limit"), "Gbps"); ok {
+			ch <- prometheus.MustNewConstMetric(c.ataLinkMaxSpeedDesc, prometheus.GaugeValue, speed, link)
+		}
+	}
+	return nil
+}
+
+// readLinkRate reads a link rate like "6.0 Gbit" in bits per second. It
+// returns false if the rate is unknown, e.g. "<unknown>" if no device is
+// connected or "Phy disabled".
+func readLinkRate(path, unit string) (float64, bool) {
+	rate, err := readTrimmedFile(path)
+	if err != nil {
+		return 0, false
+	}
+	fields := strings.Fields(rate)
+	if len(fields) != 2 || fields[1] != unit {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	return v * 1e9, true
+}
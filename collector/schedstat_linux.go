@@ -14,7 +14,12 @@
 package collector
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs"
@@ -43,6 +48,41 @@ var (
 		[]string{"cpu"},
 		nil,
 	)
+
+	// schedstatCPUFields are the counters of the cpu lines not parsed by
+	// procfs, by their position in the line.
+	schedstatCPUFields = map[int]*prometheus.Desc{
+		0: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "schedstat", "yields_total"),
+			"Number of times sched_yield() was called on the CPU.",
+			[]string{"cpu"},
+			nil,
+		),
+		2: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "schedstat", "schedule_calls_total"),
+			"Number of times schedule() was called on the CPU, i.e. context switch attempts.",
+			[]string{"cpu"},
+			nil,
+		),
+		3: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "schedstat", "schedule_idle_total"),
+			"Number of times schedule() left the CPU idle.",
+			[]string{"cpu"},
+			nil,
+		),
+		4: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "schedstat", "wakeups_total"),
+			"Number of times a task was woken up by the CPU.",
+			[]string{"cpu"},
+			nil,
+		),
+		5: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "schedstat", "local_wakeups_total"),
+			"Number of times the CPU woke up a task which was running on the same CPU.",
+			[]string{"cpu"},
+			nil,
+		),
+	}
 )
 
 // NewSchedstatCollector returns a new Collector exposing task scheduler statistics
@@ -92,5 +132,54 @@ func (c *schedstatCollector) Update(ch chan<- prometheus.Metric) error {
 		)
 	}
 
+	return c.updateCPUFields(ch)
+}
+
+func (c *schedstatCollector) updateCPUFields(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("schedstat"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	cpus, err := parseSchedstatCPUFields(file)
+	if err != nil {
+		return fmt.Errorf("couldn't parse schedstat: %s", err)
+	}
+	for cpu, fields := range cpus {
+		for i, desc := range schedstatCPUFields {
+			if i >= len(fields) {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(fields[i]), cpu)
+		}
+	}
+
 	return nil
 }
+
+// parseSchedstatCPUFields returns the counters of each cpu line of
+// /proc/schedstat, keyed by CPU number.
+func parseSchedstatCPUFields(r io.Reader) (map[string][]uint64, error) {
+	var (
+		cpus    = make(map[string][]uint64)
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 0 || !strings.HasPrefix(parts[0], "cpu") {
+			continue
+		}
+		fields := make([]uint64, 0, len(parts)-1)
+		for _, part := range parts[1:] {
+			v, err := strconv.ParseUint(part, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q in line %q: %s", part, scanner.Text(), err)
+			}
+			fields = append(fields, v)
+		}
+		cpus[strings.TrimPrefix(parts[0], "cpu")] = fields
+	}
+
+	return cpus, scanner.Err()
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseSchedstatCPUFields(t *testing.T) {
+	file, err := os.Open("fixtures/proc/schedstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	cpus, err := parseSchedstatCPUFields(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 2, len(cpus); want != got {
+		t.Fatalf("want %d cpus, got %d", want, got)
+	}
+	if want, got := uint64(4155211005), cpus["1"][2]; want != got {
+		t.Errorf("want schedule calls %d, got %d", want, got)
+	}
+	if want, got := uint64(2465731542), cpus["0"][5]; want != got {
+		t.Errorf("want local wakeups %d, got %d", want, got)
+	}
+}
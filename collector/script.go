@@ -0,0 +1,353 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+// +build !noscript
+
+package collector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/log"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const scriptSubsystem = "script"
+
+var (
+	scriptCommands       = kingpin.Flag("collector.script.command", "Command to run, as name=/absolute/path [args...], printing metrics in the text format to stdout. Can be repeated.").Strings()
+	scriptInterval       = kingpin.Flag("collector.script.interval", "Interval between two runs of each command.").Default("1m").Duration()
+	scriptTimeout        = kingpin.Flag("collector.script.timeout", "Time after which a command is killed.").Default("30s").Duration()
+	scriptMaxOutputBytes = kingpin.Flag("collector.script.max-output-bytes", "Maximum size of the output of a command, larger outputs are discarded.").Default("1MiB").Bytes()
+	scriptUser           = kingpin.Flag("collector.script.user", "Non-root user to run the commands as when node_exporter runs as root.").Default("nobody").String()
+
+	// Scripts run on their own schedule independently of scrapes, so they
+	// are started once even though collectors are created per request.
+	scriptRunnerOnce sync.Once
+	scriptRunnerErr  error
+	scriptRunnerInst *scriptRunner
+)
+
+type script struct {
+	name string
+	args []string
+}
+
+// scriptResult holds the outcome of the last run of a script, its metrics
+// are keyed by family name and converted once per run rather than per scrape.
+type scriptResult struct {
+	families  map[string][]prometheus.Metric
+	success   bool
+	duration  time.Duration
+	timestamp time.Time
+}
+
+type scriptRunner struct {
+	scripts    []script
+	credential *syscall.Credential
+
+	mtx     sync.Mutex
+	results map[string]scriptResult
+}
+
+type scriptCollector struct {
+	runner       *scriptRunner
+	successDesc  *prometheus.Desc
+	durationDesc *prometheus.Desc
+	lastRunDesc  *prometheus.Desc
+	samplesDesc  *prometheus.Desc
+}
+
+func init() {
+	registerCollector("script", defaultDisabled, NewScriptCollector)
+}
+
+// NewScriptCollector returns a new Collector exposing the metrics printed by
+// the configured commands.
+func NewScriptCollector() (Collector, error) {
+	scriptRunnerOnce.Do(func() {
+		scriptRunnerInst, scriptRunnerErr = newScriptRunner()
+		if scriptRunnerErr == nil {
+			scriptRunnerInst.start()
+		}
+	})
+	if scriptRunnerErr != nil {
+		return nil, scriptRunnerErr
+	}
+
+	return &scriptCollector{
+		runner: scriptRunnerInst,
+		successDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, scriptSubsystem, "success"),
+			"1 if the last run of the command succeeded and its output was valid, 0 otherwise.",
+			[]string{"script"}, nil,
+		),
+		durationDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, scriptSubsystem, "duration_seconds"),
+			"Duration of the last run of the command.",
+			[]string{"script"}, nil,
+		),
+		lastRunDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, scriptSubsystem, "last_run_timestamp_seconds"),
+			"Unixtime the last run of the command finished.",
+			[]string{"script"}, nil,
+		),
+		samplesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, scriptSubsystem, "samples"),
+			"Number of metrics read from the last successful run of the command.",
+			[]string{"script"}, nil,
+		),
+	}, nil
+}
+
+func newScriptRunner() (*scriptRunner, error) {
+	scripts, err := parseScriptCommands(*scriptCommands)
+	if err != nil {
+		return nil, err
+	}
+	if *scriptTimeout <= 0 || *scriptTimeout > *scriptInterval {
+		return nil, fmt.Errorf("script timeout %s must be positive and not exceed the interval %s", *scriptTimeout, *scriptInterval)
+	}
+
+	r := &scriptRunner{
+		scripts: scripts,
+		results: map[string]scriptResult{},
+	}
+	if os.Geteuid() == 0 {
+		if r.credential, err = scriptCredential(*scriptUser); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// parseScriptCommands parses the name=command flags, commands are run
+// directly rather than through a shell and have to be given with an absolute
+// path.
+func parseScriptCommands(commands []string) ([]script, error) {
+	var (
+		scripts []script
+		names   = map[string]bool{}
+	)
+	for _, command := range commands {
+		parts := strings.SplitN(command, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid script command %q, expected name=command", command)
+		}
+		name, args := parts[0], strings.Fields(parts[1])
+		if len(args) == 0 || !filepath.IsAbs(args[0]) {
+			return nil, fmt.Errorf("script %q command must be an absolute path", name)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate script name %q", name)
+		}
+		names[name] = true
+		scripts = append(scripts, script{name: name, args: args})
+	}
+	return scripts, nil
+}
+
+// scriptCredential returns the credential of the user to run commands as,
+// refusing to run them as root.
+func scriptCredential(name string) (*syscall.Credential, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't look up script user %q: %s", name, err)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uid %q of script user %q: %s", u.Uid, name, err)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gid %q of script user %q: %s", u.Gid, name, err)
+	}
+	if uid == 0 {
+		return nil, fmt.Errorf("refusing to run scripts as root user %q", name)
+	}
+	// An empty list of groups drops the supplementary groups of root.
+	return &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: []uint32{}}, nil
+}
+
+// start runs every script right away and then at every interval.
+func (r *scriptRunner) start() {
+	for _, s := range r.scripts {
+		go func(s script) {
+			ticker := time.NewTicker(*scriptInterval)
+			defer ticker.Stop()
+			for {
+				r.run(s)
+				<-ticker.C
+			}
+		}(s)
+	}
+}
+
+func (r *scriptRunner) run(s script) {
+	begin := time.Now()
+	out, err := runScript(s, r.credential, *scriptTimeout, int64(*scriptMaxOutputBytes))
+	result := scriptResult{duration: time.Since(begin), timestamp: time.Now()}
+	if err == nil {
+		result.families, err = parseScriptOutput(s.name, out)
+	}
+	if err != nil {
+		log.Errorf("Error running script %q: %s", s.name, err)
+	} else {
+		result.success = true
+	}
+
+	// A failed run replaces the metrics of the previous one, so that no
+	// stale metrics are exposed.
+	r.mtx.Lock()
+	r.results[s.name] = result
+	r.mtx.Unlock()
+}
+
+// runScript runs a command and returns its output, killing the command and
+// all its children when it exceeds the timeout or the maximum output size.
+func runScript(s script, credential *syscall.Credential, timeout time.Duration, maxOutput int64) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.Command(s.args[0], s.args[1:]...)
+	cmd.Dir = "/"
+	cmd.Env = []string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Credential: credential}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		case <-done:
+		}
+	}()
+
+	out, err := ioutil.ReadAll(io.LimitReader(stdout, maxOutput+1))
+	if int64(len(out)) > maxOutput {
+		cancel()
+		cmd.Wait()
+		return nil, fmt.Errorf("output exceeds %d bytes", maxOutput)
+	}
+	if err != nil {
+		cancel()
+		cmd.Wait()
+		return nil, err
+	}
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %s", timeout)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// parseScriptOutput parses and validates the output of a script the same way
+// the textfile collector validates a file.
+func parseScriptOutput(name string, out []byte) (map[string][]prometheus.Metric, error) {
+	var parser expfmt.TextParser
+	parsedFamilies, err := parser.TextToMetricFamilies(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing output: %s", err)
+	}
+	if hasTimestamps(parsedFamilies) {
+		return nil, fmt.Errorf("output contains unsupported client-side timestamps")
+	}
+
+	families := make(map[string][]prometheus.Metric, len(parsedFamilies))
+	for familyName, mf := range parsedFamilies {
+		if mf.Help == nil {
+			help := fmt.Sprintf("Metric read from script %s", name)
+			mf.Help = &help
+		}
+		if err := checkDuplicateSeries(mf); err != nil {
+			return nil, err
+		}
+		metrics, err := convertMetricFamily(mf)
+		if err != nil {
+			return nil, err
+		}
+		families[familyName] = metrics
+	}
+	return families, nil
+}
+
+// Update implements the Collector interface.
+func (c *scriptCollector) Update(ch chan<- prometheus.Metric) error {
+	c.runner.mtx.Lock()
+	defer c.runner.mtx.Unlock()
+
+	names := make([]string, 0, len(c.runner.results))
+	for name := range c.runner.results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// A metric may only be provided by one script.
+	owners := map[string]string{}
+	for _, name := range names {
+		result := c.runner.results[name]
+		success, samples := 0.0, 0
+		if result.success {
+			success = 1
+			for familyName := range result.families {
+				if owner, ok := owners[familyName]; ok {
+					log.Errorf("Script %q provides metric %s already provided by script %q, skipping its output", name, familyName, owner)
+					success = 0
+					break
+				}
+			}
+		}
+		if success == 1 {
+			for familyName, metrics := range result.families {
+				owners[familyName] = name
+				samples += len(metrics)
+				for _, m := range metrics {
+					ch <- m
+				}
+			}
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.successDesc, prometheus.GaugeValue, success, name)
+		ch <- prometheus.MustNewConstMetric(c.durationDesc, prometheus.GaugeValue, result.duration.Seconds(), name)
+		ch <- prometheus.MustNewConstMetric(c.lastRunDesc, prometheus.GaugeValue, float64(result.timestamp.UnixNano())/1e9, name)
+		ch <- prometheus.MustNewConstMetric(c.samplesDesc, prometheus.GaugeValue, float64(samples), name)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+// +build !noscript
+
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseScriptCommands(t *testing.T) {
+	scripts, err := parseScriptCommands([]string{"raid=/usr/local/bin/raid-status --all", "smart=/usr/bin/smart"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) != 2 || scripts[0].name != "raid" || len(scripts[0].args) != 2 || scripts[0].args[1] != "--all" {
+		t.Errorf("unexpected scripts %+v", scripts)
+	}
+
+	for _, commands := range [][]string{
+		{"raid-status"},
+		{"=/usr/bin/raid"},
+		{"raid=raid-status"},
+		{"raid=/usr/bin/a", "raid=/usr/bin/b"},
+	} {
+		if _, err := parseScriptCommands(commands); err == nil {
+			t.Errorf("expected error for %q", commands)
+		}
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	out, err := runScript(script{name: "ok", args: []string{"/bin/sh", "-c", "echo 'script_metric{a=\"b\"} 1'"}}, nil, time.Second, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	families, err := parseScriptOutput("ok", out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families["script_metric"]) != 1 {
+		t.Errorf("unexpected metrics %v", families)
+	}
+
+	if _, err := runScript(script{name: "large", args: []string{"/bin/sh", "-c", "yes"}}, nil, time.Second, 1024); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("expected output size error, got %v", err)
+	}
+
+	begin := time.Now()
+	if _, err := runScript(script{name: "slow", args: []string{"/bin/sh", "-c", "sleep 10 & sleep 10"}}, nil, 100*time.Millisecond, 1024); err == nil {
+		t.Error("expected timeout error")
+	}
+	if time.Since(begin) > 5*time.Second {
+		t.Error("children of the script weren't killed on timeout")
+	}
+
+	if _, err := runScript(script{name: "fail", args: []string{"/bin/sh", "-c", "exit 1"}}, nil, time.Second, 1024); err == nil {
+		t.Error("expected error for failing script")
+	}
+}
+
+func TestParseScriptOutput(t *testing.T) {
+	for _, out := range []string{
+		"metric 1 1000\n",
+		"metric{a=\"b\"} 1\nmetric{a=\"b\"} 2\n",
+		"metric{\n",
+	} {
+		if _, err := parseScriptOutput("invalid", []byte(out)); err == nil {
+			t.Errorf("expected error for %q", out)
+		}
+	}
+}
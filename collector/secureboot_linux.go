@@ -0,0 +1,162 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nosecureboot
+
+package collector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const (
+	securebootSubsystem = "secureboot"
+
+	// Vendor GUIDs of the EFI variables.
+	efiGlobalVariableGUID = "8be4df61-93ca-11d2-aa0d-00e098032b8c"
+	efiLoaderGUID         = "4a67b082-0a4c-41cf-b6c7-440b29bb8c4f"
+)
+
+type securebootCollector struct {
+	efiDesc        *prometheus.Desc
+	enabledDesc    *prometheus.Desc
+	setupModeDesc  *prometheus.Desc
+	lockdownDesc   *prometheus.Desc
+	loaderInfoDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector(securebootSubsystem, defaultDisabled, NewSecurebootCollector)
+}
+
+// NewSecurebootCollector returns a new Collector exposing the Secure Boot
+// state, the firmware the system booted with and the kernel lockdown mode.
+func NewSecurebootCollector() (Collector, error) {
+	return &securebootCollector{
+		efiDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, securebootSubsystem, "efi_boot"),
+			"1 if the system booted with EFI, 0 if it booted with a legacy BIOS.",
+			nil, nil,
+		),
+		enabledDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, securebootSubsystem, "enabled"),
+			"1 if Secure Boot is enabled, 0 otherwise.",
+			nil, nil,
+		),
+		setupModeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, securebootSubsystem, "setup_mode"),
+			"1 if the firmware is in setup mode, i.e. no platform key is enrolled, 0 otherwise.",
+			nil, nil,
+		),
+		lockdownDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, securebootSubsystem, "lockdown_mode"),
+			"Kernel lockdown modes, 1 for the one in use and 0 for the others.",
+			[]string{"mode"}, nil,
+		),
+		loaderInfoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, securebootSubsystem, "loader_info"),
+			"Boot loader as reported to the operating system by boot loaders implementing the boot loader interface, e.g. systemd-boot, value is always 1.",
+			[]string{"loader"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *securebootCollector) Update(ch chan<- prometheus.Metric) error {
+	if err := c.updateEFI(ch); err != nil {
+		return err
+	}
+
+	// The lockdown mode is only exposed since Linux 5.4, in the securityfs.
+	modes, err := readTrimmedFile(sysFilePath("kernel/security/lockdown"))
+	if err != nil {
+		log.Debugf("Not collecting lockdown mode: %s", err)
+		return nil
+	}
+	for _, mode := range strings.Fields(modes) {
+		v := 0.0
+		if strings.HasPrefix(mode, "[") {
+			v = 1
+			mode = strings.Trim(mode, "[]")
+		}
+		ch <- prometheus.MustNewConstMetric(c.lockdownDesc, prometheus.GaugeValue, v, mode)
+	}
+	return nil
+}
+
+func (c *securebootCollector) updateEFI(ch chan<- prometheus.Metric) error {
+	if _, err := os.Stat(sysFilePath("firmware/efi")); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("couldn't detect EFI: %s", err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.efiDesc, prometheus.GaugeValue, 0)
+		return nil
+	}
+	ch <- prometheus.MustNewConstMetric(c.efiDesc, prometheus.GaugeValue, 1)
+
+	// The Secure Boot variables don't exist if the firmware doesn't
+	// support it.
+	for name, desc := range map[string]*prometheus.Desc{
+		"SecureBoot": c.enabledDesc,
+		"SetupMode":  c.setupModeDesc,
+	} {
+		data, err := readEFIVariable(name, efiGlobalVariableGUID)
+		if err != nil {
+			log.Debugf("Couldn't get EFI variable %s: %s", name, err)
+			continue
+		}
+		if len(data) != 1 {
+			return fmt.Errorf("invalid EFI variable %s %v", name, data)
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(data[0]))
+	}
+
+	if data, err := readEFIVariable("LoaderInfo", efiLoaderGUID); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.loaderInfoDesc, prometheus.GaugeValue, 1, decodeEFIString(data))
+	}
+	return nil
+}
+
+// readEFIVariable returns the data of an EFI variable from the efivarfs,
+// whose files start with the 4 byte attributes of the variable.
+func readEFIVariable(name, guid string) ([]byte, error) {
+	data, err := ioutil.ReadFile(sysFilePath("firmware/efi/efivars/" + name + "-" + guid))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 {
+		return nil, fmt.Errorf("EFI variable %s too short", name)
+	}
+	return data[4:], nil
+}
+
+// decodeEFIString decodes a NUL terminated UTF-16LE string.
+func decodeEFIString(data []byte) string {
+	s := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		r := binary.LittleEndian.Uint16(data[i:])
+		if r == 0 {
+			break
+		}
+		s = append(s, r)
+	}
+	return string(utf16.Decode(s))
+}
@@ -0,0 +1,149 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noselinux
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const selinuxSubsystem = "selinux"
+
+type selinuxCollector struct {
+	enabled       *prometheus.Desc
+	enforcing     *prometheus.Desc
+	policyVersion *prometheus.Desc
+	denyUnknown   *prometheus.Desc
+	avcCache      *prometheus.Desc
+}
+
+func init() {
+	registerCollector(selinuxSubsystem, defaultDisabled, NewSelinuxCollector)
+}
+
+// NewSelinuxCollector returns a new Collector exposing the SELinux mode and
+// access vector cache statistics.
+func NewSelinuxCollector() (Collector, error) {
+	return &selinuxCollector{
+		enabled: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, selinuxSubsystem, "enabled"),
+			"Whether SELinux is enabled, 1 if it is.",
+			nil, nil,
+		),
+		enforcing: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, selinuxSubsystem, "enforcing"),
+			"Whether SELinux is in enforcing mode, 1 if it is and 0 if it is in permissive mode.",
+			nil, nil,
+		),
+		policyVersion: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, selinuxSubsystem, "policy_version"),
+			"Version of the loaded SELinux policy format.",
+			nil, nil,
+		),
+		denyUnknown: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, selinuxSubsystem, "deny_unknown"),
+			"Whether permissions of classes unknown to the policy are denied, 1 if they are.",
+			nil, nil,
+		),
+		avcCache: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, selinuxSubsystem, "avc_cache_total"),
+			"Access vector cache statistics summed over all CPUs. Denials themselves are only reported to the audit log.",
+			[]string{"stat"}, nil,
+		),
+	}, nil
+}
+
+func (c *selinuxCollector) Update(ch chan<- prometheus.Metric) error {
+	enforce, err := readUintFromFile(sysFilePath("fs/selinux/enforce"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			ch <- prometheus.MustNewConstMetric(c.enabled, prometheus.GaugeValue, 0)
+			return nil
+		}
+		return fmt.Errorf("couldn't get SELinux mode: %s", err)
+	}
+	ch <- prometheus.MustNewConstMetric(c.enabled, prometheus.GaugeValue, 1)
+	ch <- prometheus.MustNewConstMetric(c.enforcing, prometheus.GaugeValue, float64(enforce))
+
+	version, err := readUintFromFile(sysFilePath("fs/selinux/policyvers"))
+	if err != nil {
+		return fmt.Errorf("couldn't get SELinux policy version: %s", err)
+	}
+	ch <- prometheus.MustNewConstMetric(c.policyVersion, prometheus.GaugeValue, float64(version))
+
+	denyUnknown, err := readUintFromFile(sysFilePath("fs/selinux/deny_unknown"))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("couldn't get SELinux deny_unknown: %s", err)
+	}
+	if err == nil {
+		ch <- prometheus.MustNewConstMetric(c.denyUnknown, prometheus.GaugeValue, float64(denyUnknown))
+	}
+
+	// The AVC cache statistics require CONFIG_SECURITY_SELINUX_AVC_STATS.
+	file, err := os.Open(sysFilePath("fs/selinux/avc/cache_stats"))
+	if os.IsNotExist(err) {
+		log.Debugf("Not collecting SELinux AVC cache statistics: %s", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stats, err := parseSelinuxCacheStats(file)
+	if err != nil {
+		return fmt.Errorf("couldn't parse SELinux cache_stats: %s", err)
+	}
+	for stat, v := range stats {
+		ch <- prometheus.MustNewConstMetric(c.avcCache, prometheus.CounterValue, v, stat)
+	}
+
+	return nil
+}
+
+// parseSelinuxCacheStats sums the per CPU lines of avc/cache_stats by the
+// column names of the header line.
+func parseSelinuxCacheStats(r io.Reader) (map[string]float64, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("cache_stats empty")
+	}
+	header := strings.Fields(scanner.Text())
+
+	stats := make(map[string]float64)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != len(header) {
+			return nil, fmt.Errorf("invalid line %q", scanner.Text())
+		}
+		for i, field := range fields {
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, err
+			}
+			stats[header[i]] += v
+		}
+	}
+
+	return stats, scanner.Err()
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseSelinuxCacheStats(t *testing.T) {
+	file, err := os.Open("fixtures/sys/fs/selinux/avc/cache_stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	stats, err := parseSelinuxCacheStats(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for stat, want := range map[string]float64{
+		"lookups": 2505344,
+		"misses":  5115,
+		"frees":   3092,
+	} {
+		if got := stats[stat]; want != got {
+			t.Errorf("want %s %f, got %f", stat, want, got)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -49,6 +50,36 @@ func (c *sockStatCollector) Update(ch chan<- prometheus.Metric) error {
 	if err != nil {
 		return fmt.Errorf("couldn't get sockstats: %s", err)
 	}
+	if err := c.updateStats(ch, sockStats, ""); err != nil {
+		return err
+	}
+
+	namespaces, err := netNamespaces()
+	if err != nil {
+		return err
+	}
+	for _, ns := range namespaces {
+		err := ns.do(func(procNet string) error {
+			sockStats, err = getSockStats(filepath.Join(procNet, "sockstat"))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't get sockstats of network namespace %s: %s", ns.name, err)
+		}
+		if err := c.updateStats(ch, sockStats, ns.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateStats exposes the socket statistics of a network namespace, only
+// labeled if additional network namespaces are collected.
+func (c *sockStatCollector) updateStats(ch chan<- prometheus.Metric, sockStats map[string]map[string]string, netns string) error {
+	var labels, values []string
+	if netnsEnabled() {
+		labels, values = []string{"netns"}, []string{netns}
+	}
 	for protocol, protocolStats := range sockStats {
 		for name, value := range protocolStats {
 			v, err := strconv.ParseFloat(value, 64)
@@ -59,13 +90,13 @@ func (c *sockStatCollector) Update(ch chan<- prometheus.Metric) error {
 				prometheus.NewDesc(
 					prometheus.BuildFQName(namespace, sockStatSubsystem, protocol+"_"+name),
 					fmt.Sprintf("Number of %s sockets in state %s.", protocol, name),
-					nil, nil,
+					labels, nil,
 				),
-				prometheus.GaugeValue, v,
+				prometheus.GaugeValue, v, values...,
 			)
 		}
 	}
-	return err
+	return nil
 }
 
 func getSockStats(fileName string) (map[string]map[string]string, error) {
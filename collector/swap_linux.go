@@ -0,0 +1,133 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noswap
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const swapSubsystem = "swap"
+
+type swapCollector struct {
+	size     *prometheus.Desc
+	used     *prometheus.Desc
+	priority *prometheus.Desc
+}
+
+// swapDevice is a line of /proc/swaps.
+type swapDevice struct {
+	device   string
+	swapType string
+	size     uint64
+	used     uint64
+	priority int64
+}
+
+func init() {
+	registerCollector(swapSubsystem, defaultDisabled, NewSwapCollector)
+}
+
+// NewSwapCollector returns a new Collector exposing per device swap usage.
+func NewSwapCollector() (Collector, error) {
+	labels := []string{"device", "type"}
+	return &swapCollector{
+		size: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, swapSubsystem, "size_bytes"),
+			"Size of the swap device.",
+			labels, nil,
+		),
+		used: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, swapSubsystem, "used_bytes"),
+			"Amount of the swap device in use.",
+			labels, nil,
+		),
+		priority: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, swapSubsystem, "priority"),
+			"Priority of the swap device, higher priority devices are used first.",
+			labels, nil,
+		),
+	}, nil
+}
+
+func (c *swapCollector) Update(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("swaps"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	devices, err := parseSwaps(file)
+	if err != nil {
+		return fmt.Errorf("couldn't parse swaps: %s", err)
+	}
+
+	for _, d := range devices {
+		ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(d.size), d.device, d.swapType)
+		ch <- prometheus.MustNewConstMetric(c.used, prometheus.GaugeValue, float64(d.used), d.device, d.swapType)
+		ch <- prometheus.MustNewConstMetric(c.priority, prometheus.GaugeValue, float64(d.priority), d.device, d.swapType)
+	}
+
+	return nil
+}
+
+// parseSwaps parses /proc/swaps. Sizes are given in KiB.
+func parseSwaps(r io.Reader) ([]swapDevice, error) {
+	var (
+		devices []swapDevice
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "Filename" {
+			continue
+		}
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("invalid line %q", scanner.Text())
+		}
+
+		// The filename may contain spaces, e.g. " (deleted)" is appended
+		// to swap files deleted while in use, so the other fields are
+		// taken from the right. Whitespace in the path is escaped.
+		n := len(fields) - 4
+		device := strings.Join(fields[:n], " ")
+		device = strings.Replace(device, "\\040", " ", -1)
+		device = strings.Replace(device, "\\011", "\t", -1)
+
+		d := swapDevice{device: device, swapType: fields[n]}
+		size, err := strconv.ParseUint(fields[n+1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		used, err := strconv.ParseUint(fields[n+2], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if d.priority, err = strconv.ParseInt(fields[n+3], 10, 64); err != nil {
+			return nil, err
+		}
+		d.size, d.used = size*1024, used*1024
+		devices = append(devices, d)
+	}
+
+	return devices, scanner.Err()
+}
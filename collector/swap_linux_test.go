@@ -0,0 +1,44 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseSwaps(t *testing.T) {
+	file, err := os.Open("fixtures/proc/swaps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	devices, err := parseSwaps(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []swapDevice{
+		{device: "/dev/dm-1", swapType: "partition", size: 16430076 * 1024, used: 1024 * 1024, priority: -2},
+		{device: "/dev/loop0", swapType: "partition", size: 1048572 * 1024, used: 524288 * 1024, priority: -3},
+		{device: "/var/swapfile", swapType: "file", size: 2097148 * 1024, used: 0, priority: -4},
+		{device: "/var/swapfile.old (deleted)", swapType: "file", size: 524284 * 1024, used: 4096 * 1024, priority: -5},
+		{device: "/srv/swap files/swapfile", swapType: "file", size: 262140 * 1024, used: 0, priority: -6},
+	}
+	if !reflect.DeepEqual(devices, want) {
+		t.Errorf("want swap devices %+v, got %+v", want, devices)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nosysctl
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const sysctlSubsystem = "sysctl"
+
+var (
+	sysctlInclude = kingpin.Flag("collector.sysctl.include", "Sysctl to expose the value of, e.g. net.core.somaxconn, with / for dots in a component, e.g. net.ipv4.conf.eth0/100.rp_filter. Can be repeated.").Strings()
+
+	sysctlInvalidChars = regexp.MustCompile("[^a-zA-Z0-9_]")
+)
+
+type sysctlCollector struct {
+	names    []string
+	infoDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector(sysctlSubsystem, defaultDisabled, NewSysctlCollector)
+}
+
+// NewSysctlCollector returns a new Collector exposing the values of the
+// configured sysctls.
+func NewSysctlCollector() (Collector, error) {
+	for _, name := range *sysctlInclude {
+		if name == "" {
+			return nil, fmt.Errorf("invalid sysctl %q, expected a name like net.core.somaxconn", name)
+		}
+		for _, component := range strings.Split(sysctlPath(name), "/") {
+			if component == ".." {
+				return nil, fmt.Errorf("invalid sysctl %q, expected a name like net.core.somaxconn", name)
+			}
+		}
+	}
+	return &sysctlCollector{
+		names: *sysctlInclude,
+		infoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, sysctlSubsystem, "info"),
+			"Value of a sysctl which isn't numeric, value is always 1.",
+			[]string{"name", "value"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface. Numeric sysctls are exposed as
+// node_sysctl_<name>, with an index label for sysctls with several values
+// like net.ipv4.tcp_rmem, all others as node_sysctl_info.
+func (c *sysctlCollector) Update(ch chan<- prometheus.Metric) error {
+	for _, name := range c.names {
+		value, err := readTrimmedFile(procFilePath("sys/" + sysctlPath(name)))
+		if err != nil {
+			// Sysctls of modules which aren't loaded don't exist.
+			if os.IsNotExist(err) {
+				log.Debugf("Sysctl %s doesn't exist", name)
+				continue
+			}
+			return fmt.Errorf("couldn't get sysctl %s: %s", name, err)
+		}
+
+		values, ok := parseSysctlValues(value)
+		if !ok {
+			ch <- prometheus.MustNewConstMetric(c.infoDesc, prometheus.GaugeValue, 1, name, value)
+			continue
+		}
+
+		fqName := prometheus.BuildFQName(namespace, sysctlSubsystem, sysctlInvalidChars.ReplaceAllString(name, "_"))
+		help := fmt.Sprintf("Value of the sysctl %s.", name)
+		if len(values) == 1 {
+			desc := prometheus.NewDesc(fqName, help, nil, nil)
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, values[0])
+			continue
+		}
+		desc := prometheus.NewDesc(fqName, help, []string{"index"}, nil)
+		for i, v := range values {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, strconv.Itoa(i))
+		}
+	}
+	return nil
+}
+
+// sysctlPath returns the path of a sysctl below /proc/sys. Like with
+// sysctl(8), a "/" in the name stands for a "." in a path component, e.g.
+// net.ipv4.conf.eth0/100.rp_filter for the VLAN device eth0.100.
+func sysctlPath(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.':
+			return '/'
+		case '/':
+			return '.'
+		}
+		return r
+	}, name)
+}
+
+// parseSysctlValues parses the whitespace separated numbers of a sysctl. It
+// returns false if the sysctl isn't numeric.
+func parseSysctlValues(value string) ([]float64, bool) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil, false
+	}
+	values := make([]float64, 0, len(fields))
+	for _, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, false
+		}
+		values = append(values, v)
+	}
+	return values, true
+}
@@ -0,0 +1,68 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nosysctl
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSysctlValues(t *testing.T) {
+	for _, tc := range []struct {
+		value   string
+		values  []float64
+		numeric bool
+	}{
+		{"4096", []float64{4096}, true},
+		{"4096\t131072\t6291456", []float64{4096, 131072, 6291456}, true},
+		{"-1", []float64{-1}, true},
+		{"cubic", nil, false},
+		{"reno cubic", nil, false},
+		{"", nil, false},
+	} {
+		values, numeric := parseSysctlValues(tc.value)
+		if numeric != tc.numeric || !reflect.DeepEqual(values, tc.values) {
+			t.Errorf("%q: want %v, %v, got %v, %v", tc.value, tc.values, tc.numeric, values, numeric)
+		}
+	}
+}
+
+func TestSysctlPath(t *testing.T) {
+	for name, want := range map[string]string{
+		"net.core.somaxconn":               "net/core/somaxconn",
+		"net.ipv4.conf.eth0/100.rp_filter": "net/ipv4/conf/eth0.100/rp_filter",
+	} {
+		if got := sysctlPath(name); got != want {
+			t.Errorf("%s: want path %s, got %s", name, want, got)
+		}
+	}
+}
+
+func TestNewSysctlCollectorInvalidName(t *testing.T) {
+	orig := *sysctlInclude
+	defer func() { *sysctlInclude = orig }()
+
+	for _, name := range []string{"", "net.//.core", "//"} {
+		*sysctlInclude = []string{name}
+		if _, err := NewSysctlCollector(); err == nil {
+			t.Errorf("%q: want error", name)
+		}
+	}
+	*sysctlInclude = []string{"net.ipv4.conf.eth0/100.rp_filter"}
+	if _, err := NewSysctlCollector(); err != nil {
+		t.Error(err)
+	}
+}
@@ -59,6 +59,7 @@ type systemdCollector struct {
 	socketCurrentConnectionsDesc  *prometheus.Desc
 	socketRefusedConnectionsDesc  *prometheus.Desc
 	systemdVersionDesc            *prometheus.Desc
+	bootPhaseDesc                 *prometheus.Desc
 	systemdVersion                int
 	unitWhitelistPattern          *regexp.Regexp
 	unitBlacklistPattern          *regexp.Regexp
@@ -116,6 +117,9 @@ func NewSystemdCollector() (Collector, error) {
 	systemdVersionDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, subsystem, "version"),
 		"Detected systemd version", []string{}, nil)
+	bootPhaseDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "boot_phase_duration_seconds"),
+		"Duration of the boot phases as shown by systemd-analyze", []string{"phase"}, nil)
 	unitWhitelistPattern := regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *unitWhitelist))
 	unitBlacklistPattern := regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *unitBlacklist))
 
@@ -138,6 +142,7 @@ func NewSystemdCollector() (Collector, error) {
 		socketCurrentConnectionsDesc:  socketCurrentConnectionsDesc,
 		socketRefusedConnectionsDesc:  socketRefusedConnectionsDesc,
 		systemdVersionDesc:            systemdVersionDesc,
+		bootPhaseDesc:                 bootPhaseDesc,
 		systemdVersion:                systemdVersion,
 		unitWhitelistPattern:          unitWhitelistPattern,
 		unitBlacklistPattern:          unitBlacklistPattern,
@@ -224,6 +229,12 @@ func (c *systemdCollector) Update(ch chan<- prometheus.Metric) error {
 		log.Debugf("systemd collectSystemState took %f", time.Since(begin).Seconds())
 	}
 
+	begin = time.Now()
+	if bootErr := c.collectBootPhases(conn, ch); bootErr != nil {
+		log.Debugf("couldn't get systemd boot phases: %s", bootErr)
+	}
+	log.Debugf("systemd collectBootPhases took %f", time.Since(begin).Seconds())
+
 	ch <- prometheus.MustNewConstMetric(
 		c.systemdVersionDesc, prometheus.GaugeValue, float64(c.systemdVersion))
 
@@ -398,6 +409,69 @@ func (c *systemdCollector) collectSystemState(conn *dbus.Conn, ch chan<- prometh
 	return nil
 }
 
+// bootTimestampProperties are the manager properties holding the monotonic
+// timestamps of the boot phases in microseconds. Firmware and loader
+// timestamps count backwards from the start of the kernel and are only set
+// when booted with a boot loader supporting the boot loader interface.
+var bootTimestampProperties = []string{
+	"FirmwareTimestampMonotonic",
+	"LoaderTimestampMonotonic",
+	"InitRDTimestampMonotonic",
+	"UserspaceTimestampMonotonic",
+	"FinishTimestampMonotonic",
+}
+
+func (c *systemdCollector) collectBootPhases(conn *dbus.Conn, ch chan<- prometheus.Metric) error {
+	timestamps := make(map[string]uint64, len(bootTimestampProperties))
+	for _, property := range bootTimestampProperties {
+		value, err := conn.GetManagerProperty(property)
+		if err != nil {
+			return fmt.Errorf("couldn't get %s: %s", property, err)
+		}
+		// uint64 variants are formatted with their type, e.g. "@t 1234".
+		timestamp, err := strconv.ParseUint(strings.TrimPrefix(value, "@t "), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %s", property, value, err)
+		}
+		timestamps[property] = timestamp
+	}
+
+	for phase, duration := range bootPhases(timestamps) {
+		ch <- prometheus.MustNewConstMetric(c.bootPhaseDesc, prometheus.GaugeValue, duration, phase)
+	}
+	return nil
+}
+
+// bootPhases computes the duration of the boot phases in seconds like
+// systemd-analyze does. The userspace phase is missing while the boot hasn't
+// finished.
+func bootPhases(timestamps map[string]uint64) map[string]float64 {
+	var (
+		firmware  = timestamps["FirmwareTimestampMonotonic"]
+		loader    = timestamps["LoaderTimestampMonotonic"]
+		initrd    = timestamps["InitRDTimestampMonotonic"]
+		userspace = timestamps["UserspaceTimestampMonotonic"]
+		finish    = timestamps["FinishTimestampMonotonic"]
+		phases    = map[string]float64{}
+	)
+	if firmware > 0 {
+		phases["firmware"] = float64(firmware-loader) / 1e6
+	}
+	if loader > 0 {
+		phases["loader"] = float64(loader) / 1e6
+	}
+	if initrd > 0 {
+		phases["kernel"] = float64(initrd) / 1e6
+		phases["initrd"] = float64(userspace-initrd) / 1e6
+	} else {
+		phases["kernel"] = float64(userspace) / 1e6
+	}
+	if finish > 0 {
+		phases["userspace"] = float64(finish-userspace) / 1e6
+	}
+	return phases
+}
+
 func newSystemdDbusConn() (*dbus.Conn, error) {
 	if *systemdPrivate {
 		return dbus.NewSystemdConnection()
@@ -14,6 +14,7 @@
 package collector
 
 import (
+	"reflect"
 	"regexp"
 	"testing"
 
@@ -131,3 +132,29 @@ func testSummaryHelper(t *testing.T, state string, actual float64, expected floa
 		t.Errorf("Summary mode didn't count %s jobs correctly. Actual: %f, expected: %f", state, actual, expected)
 	}
 }
+
+func TestSystemdBootPhases(t *testing.T) {
+	phases := bootPhases(map[string]uint64{
+		"FirmwareTimestampMonotonic":  7496130,
+		"LoaderTimestampMonotonic":    3217402,
+		"InitRDTimestampMonotonic":    1632094,
+		"UserspaceTimestampMonotonic": 4231957,
+		"FinishTimestampMonotonic":    19785232,
+	})
+	expected := map[string]float64{
+		"firmware":  4.278728,
+		"loader":    3.217402,
+		"kernel":    1.632094,
+		"initrd":    2.599863,
+		"userspace": 15.553275,
+	}
+	if !reflect.DeepEqual(phases, expected) {
+		t.Errorf("Expected boot phases %v, got %v", expected, phases)
+	}
+
+	// Without boot loader support and initrd, while still booting.
+	phases = bootPhases(map[string]uint64{"UserspaceTimestampMonotonic": 1500000})
+	if !reflect.DeepEqual(phases, map[string]float64{"kernel": 1.5}) {
+		t.Errorf("Unexpected boot phases %v", phases)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !notape
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"golang.org/x/sys/unix"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	tapeSubsystem = "tape"
+
+	// Generic status bits of linux/mtio.h.
+	gmtWrProt = 0x04000000
+	gmtOnline = 0x01000000
+	gmtCln    = 0x00008000
+
+	// mtDensityShift and mtBlksizeMask split mt_dsreg.
+	mtDensityShift = 24
+	mtBlksizeMask  = 0xffffff
+	// mtSofterrMask is the count of recovered errors in mt_erreg.
+	mtSofterrMask = 0xffff
+)
+
+var (
+	tapeDeviceStatus = kingpin.Flag("collector.tape.device-status", "Query the status of the tape drives. Tape devices can only be opened once, so this fails opening them for backup software running at the same time.").Default("false").Bool()
+
+	// mtiocget is MTIOCGET, _IOR('m', 2, struct mtget).
+	mtiocget = ioctlReadRequest('m', 2, unsafe.Sizeof(mtget{}))
+
+	// tapeStats are the I/O statistics of a tape drive by file, available
+	// since Linux 4.2. Times are in nanoseconds.
+	tapeStats = []struct {
+		file, name, help string
+		factor           float64
+		valueType        prometheus.ValueType
+	}{
+		{"read_cnt", "reads_total", "Number of reads issued to the tape drive.", 1, prometheus.CounterValue},
+		{"read_byte_cnt", "read_bytes_total", "Number of bytes read from the tape drive.", 1, prometheus.CounterValue},
+		{"read_ns", "read_seconds_total", "Time spent reading from the tape drive.", 1e-9, prometheus.CounterValue},
+		{"write_cnt", "writes_total", "Number of writes issued to the tape drive.", 1, prometheus.CounterValue},
+		{"write_byte_cnt", "written_bytes_total", "Number of bytes written to the tape drive.", 1, prometheus.CounterValue},
+		{"write_ns", "write_seconds_total", "Time spent writing to the tape drive.", 1e-9, prometheus.CounterValue},
+		{"other_cnt", "other_operations_total", "Number of operations other than reads and writes issued to the tape drive, e.g. rewinds.", 1, prometheus.CounterValue},
+		{"io_ns", "io_seconds_total", "Time spent on all operations of the tape drive.", 1e-9, prometheus.CounterValue},
+		{"resid_cnt", "residuals_total", "Number of reads and writes that transferred less than requested, e.g. at the end of a file or due to errors.", 1, prometheus.CounterValue},
+		{"in_flight", "in_flight", "Number of operations in progress on the tape drive.", 1, prometheus.GaugeValue},
+	}
+)
+
+// mtget is struct mtget of linux/mtio.h.
+type mtget struct {
+	mtType  int
+	resid   int
+	dsreg   int
+	gstat   int
+	erreg   int
+	fileno  int32
+	blockno int32
+}
+
+type tapeCollector struct {
+	statDescs            []*prometheus.Desc
+	onlineDesc           *prometheus.Desc
+	writeProtectedDesc   *prometheus.Desc
+	cleaningRequiredDesc *prometheus.Desc
+	densityDesc          *prometheus.Desc
+	blockSizeDesc        *prometheus.Desc
+	softErrorsDesc       *prometheus.Desc
+	changerDesc          *prometheus.Desc
+}
+
+func init() {
+	registerCollector(tapeSubsystem, defaultDisabled, NewTapeCollector)
+}
+
+// NewTapeCollector returns a new Collector exposing the statistics and
+// status of SCSI tape drives and the medium changers of tape libraries.
+func NewTapeCollector() (Collector, error) {
+	labels := []string{"device"}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, tapeSubsystem, name), help, labels, nil)
+	}
+	c := &tapeCollector{
+		onlineDesc:           desc("online", "1 if the tape drive is online with a tape loaded, 0 otherwise."),
+		writeProtectedDesc:   desc("write_protected", "1 if the loaded tape is write protected, 0 otherwise."),
+		cleaningRequiredDesc: desc("cleaning_required", "1 if the tape drive requests cleaning, 0 otherwise."),
+		densityDesc:          desc("density_code", "Density code of the loaded tape, identifying its format."),
+		blockSizeDesc:        desc("block_size_bytes", "Block size of the tape drive, 0 for variable block size."),
+		softErrorsDesc:       desc("recovered_errors", "Number of recovered errors reported by the tape drive."),
+		changerDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, tapeSubsystem, "changer_info"),
+			"Medium changer of a tape library.",
+			[]string{"changer", "vendor", "model"}, nil,
+		),
+	}
+	for _, s := range tapeStats {
+		c.statDescs = append(c.statDescs, desc(s.name, s.help))
+	}
+	return c, nil
+}
+
+// Update implements the Collector interface.
+func (c *tapeCollector) Update(ch chan<- prometheus.Metric) error {
+	// Every drive has rewinding and non-rewinding devices for each of its
+	// modes, e.g. st0, nst0, st0l and nst0l, st0 identifies the drive.
+	drives, err := filepath.Glob(sysFilePath("class/scsi_tape/st*[0-9]"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range drives {
+		device := filepath.Base(dir)
+		for i, s := range tapeStats {
+			value, err := readUintFromFile(filepath.Join(dir, "stats", s.file))
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return fmt.Errorf("couldn't read %s of %s: %s", s.file, device, err)
+			}
+			ch <- prometheus.MustNewConstMetric(c.statDescs[i], s.valueType, float64(value)*s.factor, device)
+		}
+
+		if *tapeDeviceStatus {
+			c.updateStatus(ch, device)
+		}
+	}
+
+	changers, err := filepath.Glob(sysFilePath("class/scsi_changer/*"))
+	if err != nil {
+		return err
+	}
+	for _, dir := range changers {
+		vendor, _ := readTrimmedFile(filepath.Join(dir, "device/vendor"))
+		model, _ := readTrimmedFile(filepath.Join(dir, "device/model"))
+		ch <- prometheus.MustNewConstMetric(c.changerDesc, prometheus.GaugeValue, 1, filepath.Base(dir), vendor, model)
+	}
+	return nil
+}
+
+// updateStatus exposes the status of the tape drive, which is skipped if the
+// drive is in use.
+func (c *tapeCollector) updateStatus(ch chan<- prometheus.Metric, device string) {
+	// The non-rewinding device doesn't move the tape when closed, and
+	// O_NONBLOCK allows opening drives without a tape.
+	fd, err := unix.Open(filepath.Join("/dev", "n"+device), unix.O_RDONLY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		log.Debugf("Couldn't open tape drive %s: %s", device, err)
+		return
+	}
+	defer unix.Close(fd)
+
+	var status mtget
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), mtiocget, uintptr(unsafe.Pointer(&status))); errno != 0 {
+		log.Debugf("Couldn't get status of tape drive %s: %s", device, errno)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.onlineDesc, prometheus.GaugeValue, tapeStatusBit(status.gstat, gmtOnline), device)
+	ch <- prometheus.MustNewConstMetric(c.writeProtectedDesc, prometheus.GaugeValue, tapeStatusBit(status.gstat, gmtWrProt), device)
+	ch <- prometheus.MustNewConstMetric(c.cleaningRequiredDesc, prometheus.GaugeValue, tapeStatusBit(status.gstat, gmtCln), device)
+	ch <- prometheus.MustNewConstMetric(c.densityDesc, prometheus.GaugeValue, float64(uint(status.dsreg)>>mtDensityShift&0xff), device)
+	ch <- prometheus.MustNewConstMetric(c.blockSizeDesc, prometheus.GaugeValue, float64(status.dsreg&mtBlksizeMask), device)
+	ch <- prometheus.MustNewConstMetric(c.softErrorsDesc, prometheus.GaugeValue, float64(status.erreg&mtSofterrMask), device)
+}
+
+func tapeStatusBit(gstat, bit int) float64 {
+	if gstat&bit != 0 {
+		return 1
+	}
+	return 0
+}
@@ -16,7 +16,10 @@
 package collector
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -32,17 +35,30 @@ import (
 )
 
 var (
-	textFileDirectory = kingpin.Flag("collector.textfile.directory", "Directory to read text files with metrics from.").Default("").String()
-	mtimeDesc         = prometheus.NewDesc(
+	textFileDirectories = kingpin.Flag("collector.textfile.directory", "Directory to read text files with metrics from, may be a glob pattern. Can be repeated.").Default("").Strings()
+	textFileTimestamps  = kingpin.Flag("collector.textfile.timestamps", "Handling of samples with timestamps, reject skips the whole file, ignore exposes them without the timestamp.").Default("reject").Enum("reject", "ignore")
+	mtimeDesc           = prometheus.NewDesc(
 		"node_textfile_mtime_seconds",
 		"Unixtime mtime of textfiles successfully read.",
 		[]string{"file"},
 		nil,
 	)
+	samplesDesc = prometheus.NewDesc(
+		"node_textfile_samples",
+		"Number of samples read from the textfile.",
+		[]string{"file"},
+		nil,
+	)
+	scrapeErrorDesc = prometheus.NewDesc(
+		"node_textfile_scrape_error",
+		"1 if there was an error opening, reading or validating the file or directory, 0 otherwise.",
+		[]string{"file"},
+		nil,
+	)
 )
 
 type textFileCollector struct {
-	path string
+	paths []string
 	// Only set for testing to get predictable output.
 	mtime *float64
 }
@@ -55,112 +71,11 @@ func init() {
 // in the given textfile directory.
 func NewTextFileCollector() (Collector, error) {
 	c := &textFileCollector{
-		path: *textFileDirectory,
+		paths: *textFileDirectories,
 	}
 	return c, nil
 }
 
-func convertMetricFamily(metricFamily *dto.MetricFamily, ch chan<- prometheus.Metric) {
-	var valType prometheus.ValueType
-	var val float64
-
-	allLabelNames := map[string]struct{}{}
-	for _, metric := range metricFamily.Metric {
-		labels := metric.GetLabel()
-		for _, label := range labels {
-			if _, ok := allLabelNames[label.GetName()]; !ok {
-				allLabelNames[label.GetName()] = struct{}{}
-			}
-		}
-	}
-
-	for _, metric := range metricFamily.Metric {
-		if metric.TimestampMs != nil {
-			log.Warnf("Ignoring unsupported custom timestamp on textfile collector metric %v", metric)
-		}
-
-		labels := metric.GetLabel()
-		var names []string
-		var values []string
-		for _, label := range labels {
-			names = append(names, label.GetName())
-			values = append(values, label.GetValue())
-		}
-
-		for k := range allLabelNames {
-			present := false
-			for _, name := range names {
-				if k == name {
-					present = true
-					break
-				}
-			}
-			if !present {
-				names = append(names, k)
-				values = append(values, "")
-			}
-		}
-
-		metricType := metricFamily.GetType()
-		switch metricType {
-		case dto.MetricType_COUNTER:
-			valType = prometheus.CounterValue
-			val = metric.Counter.GetValue()
-
-		case dto.MetricType_GAUGE:
-			valType = prometheus.GaugeValue
-			val = metric.Gauge.GetValue()
-
-		case dto.MetricType_UNTYPED:
-			valType = prometheus.UntypedValue
-			val = metric.Untyped.GetValue()
-
-		case dto.MetricType_SUMMARY:
-			quantiles := map[float64]float64{}
-			for _, q := range metric.Summary.Quantile {
-				quantiles[q.GetQuantile()] = q.GetValue()
-			}
-			ch <- prometheus.MustNewConstSummary(
-				prometheus.NewDesc(
-					*metricFamily.Name,
-					metricFamily.GetHelp(),
-					names, nil,
-				),
-				metric.Summary.GetSampleCount(),
-				metric.Summary.GetSampleSum(),
-				quantiles, values...,
-			)
-		case dto.MetricType_HISTOGRAM:
-			buckets := map[float64]uint64{}
-			for _, b := range metric.Histogram.Bucket {
-				buckets[b.GetUpperBound()] = b.GetCumulativeCount()
-			}
-			ch <- prometheus.MustNewConstHistogram(
-				prometheus.NewDesc(
-					*metricFamily.Name,
-					metricFamily.GetHelp(),
-					names, nil,
-				),
-				metric.Histogram.GetSampleCount(),
-				metric.Histogram.GetSampleSum(),
-				buckets, values...,
-			)
-		default:
-			panic("unknown metric type")
-		}
-		if metricType == dto.MetricType_GAUGE || metricType == dto.MetricType_COUNTER || metricType == dto.MetricType_UNTYPED {
-			ch <- prometheus.MustNewConstMetric(
-				prometheus.NewDesc(
-					*metricFamily.Name,
-					metricFamily.GetHelp(),
-					names, nil,
-				),
-				valType, val, values...,
-			)
-		}
-	}
-}
-
 func (c *textFileCollector) exportMTimes(mtimes map[string]time.Time, ch chan<- prometheus.Metric) {
 	// Export the mtimes of the successful files.
 	if len(mtimes) > 0 {
@@ -183,85 +98,187 @@ func (c *textFileCollector) exportMTimes(mtimes map[string]time.Time, ch chan<-
 
 // Update implements the Collector interface.
 func (c *textFileCollector) Update(ch chan<- prometheus.Metric) error {
-	error := 0.0
-	mtimes := map[string]time.Time{}
+	var (
+		errors  = map[string]float64{}
+		mtimes  = map[string]time.Time{}
+		samples = map[string]float64{}
+		// Metrics of the files read so far, which further files may
+		// add series to.
+		known = map[string]*textFileMetric{}
+	)
 
-	// Iterate over files and accumulate their metrics.
-	files, err := ioutil.ReadDir(c.path)
-	if err != nil && c.path != "" {
-		log.Errorf("Error reading textfile collector directory %q: %s", c.path, err)
-		error = 1.0
-	}
+	for _, dir := range c.directories() {
+		// Iterate over files and accumulate their metrics.
+		files, err := ioutil.ReadDir(dir)
+		if err != nil && dir != "" {
+			log.Errorf("Error reading textfile collector directory %q: %s", dir, err)
+			errors[dir] = 1.0
+		}
 
-	for _, f := range files {
-		if !strings.HasSuffix(f.Name(), ".prom") {
-			continue
+		for _, f := range files {
+			if !strings.HasSuffix(f.Name(), ".prom") && !strings.HasSuffix(f.Name(), ".prom.gz") {
+				continue
+			}
+			path := filepath.Join(dir, f.Name())
+			metrics, n, mtime, err := c.processFile(path, known)
+			if err != nil {
+				log.Error(err)
+				errors[path] = 1.0
+				continue
+			}
+			errors[path] = 0.0
+			mtimes[path] = mtime
+			samples[path] = float64(n)
+			for _, m := range metrics {
+				ch <- m
+			}
 		}
-		path := filepath.Join(c.path, f.Name())
-		file, err := os.Open(path)
-		if err != nil {
-			log.Errorf("Error opening %q: %v", path, err)
-			error = 1.0
-			continue
+	}
+
+	c.exportMTimes(mtimes, ch)
+	exportPerFile(ch, samplesDesc, samples)
+	exportPerFile(ch, scrapeErrorDesc, errors)
+	return nil
+}
+
+// exportPerFile exposes a gauge per file, sorted for predictable output
+// comparison in tests.
+func exportPerFile(ch chan<- prometheus.Metric, desc *prometheus.Desc, values map[string]float64) {
+	files := make([]string, 0, len(values))
+	for file := range values {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, values[file], file)
+	}
+}
+
+// directories expands the glob patterns of the configured directories.
+// Directories which don't match anything are kept as they are, so that
+// reading them reports the error.
+func (c *textFileCollector) directories() []string {
+	var dirs []string
+	for _, path := range c.paths {
+		matches, err := filepath.Glob(path)
+		if err != nil || len(matches) == 0 {
+			matches = []string{path}
 		}
-		defer file.Close()
-		var parser expfmt.TextParser
-		parsedFamilies, err := parser.TextToMetricFamilies(file)
+		dirs = append(dirs, matches...)
+	}
+	return dirs
+}
+
+// textFileMetric is a metric read from the textfiles, with the file first
+// providing it and the series read so far.
+type textFileMetric struct {
+	help       string
+	metricType dto.MetricType
+	file       string
+	series     map[string]string
+}
+
+// processFile parses and validates a file, returning its metrics, the number
+// of samples and its mtime. Files may add series to metrics of already read
+// files, like one file per batch job with the same metrics. Files whose help
+// or type of such a metric differ, or which repeat one of its series, are
+// rejected as a whole, otherwise their metrics are added to known.
+func (c *textFileCollector) processFile(path string, known map[string]*textFileMetric) ([]prometheus.Metric, int, time.Time, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, time.Time{}, fmt.Errorf("Error opening %q: %v", path, err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
 		if err != nil {
-			log.Errorf("Error parsing %q: %v", path, err)
-			error = 1.0
-			continue
+			return nil, 0, time.Time{}, fmt.Errorf("Error decompressing %q: %v", path, err)
 		}
-		if hasTimestamps(parsedFamilies) {
-			log.Errorf("Textfile %q contains unsupported client-side timestamps, skipping entire file", path)
-			error = 1.0
-			continue
+		defer gz.Close()
+		r = gz
+	}
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, 0, time.Time{}, fmt.Errorf("Error reading %q: %v", path, err)
+	}
+	if isOpenMetrics(content) {
+		if content, err = openMetricsToText(content); err != nil {
+			return nil, 0, time.Time{}, fmt.Errorf("Error parsing %q: %v", path, err)
 		}
+	}
 
+	var parser expfmt.TextParser
+	parsedFamilies, err := parser.TextToMetricFamilies(bytes.NewReader(content))
+	if err != nil {
+		return nil, 0, time.Time{}, fmt.Errorf("Error parsing %q: %v", path, err)
+	}
+	if hasTimestamps(parsedFamilies) {
+		if *textFileTimestamps == "reject" {
+			return nil, 0, time.Time{}, fmt.Errorf("Textfile %q contains unsupported client-side timestamps, skipping entire file", path)
+		}
 		for _, mf := range parsedFamilies {
-			if mf.Help == nil {
-				help := fmt.Sprintf("Metric read from %s", path)
-				mf.Help = &help
+			for _, m := range mf.Metric {
+				m.TimestampMs = nil
 			}
 		}
+	}
 
-		// Only set this once it has been parsed and validated, so that
-		// a failure does not appear fresh.
-		stat, err := file.Stat()
-		if err != nil {
-			log.Errorf("Error stat'ing %q: %v", path, err)
-			error = 1.0
-			continue
+	var (
+		metrics []prometheus.Metric
+		samples int
+	)
+	for name, mf := range parsedFamilies {
+		if k, ok := known[name]; ok {
+			// Files without help for the metric get the one it already has.
+			if mf.Help == nil {
+				mf.Help = &k.help
+			}
+			if mf.GetHelp() != k.help {
+				return nil, 0, time.Time{}, fmt.Errorf("Textfile %q contains metric %s with help %q, but %q has %q, skipping entire file", path, name, mf.GetHelp(), k.file, k.help)
+			}
+			if mf.GetType() != k.metricType {
+				return nil, 0, time.Time{}, fmt.Errorf("Textfile %q contains metric %s of type %s, but %q has %s, skipping entire file", path, name, mf.GetType(), k.file, k.metricType)
+			}
+			for _, m := range mf.Metric {
+				if file, ok := k.series[seriesKey(m)]; ok {
+					return nil, 0, time.Time{}, fmt.Errorf("Textfile %q contains series of metric %s already read from %q, skipping entire file", path, name, file)
+				}
+			}
 		}
-		mtimes[f.Name()] = stat.ModTime()
-
-		for _, mf := range parsedFamilies {
-			convertMetricFamily(mf, ch)
+		if mf.Help == nil {
+			help := fmt.Sprintf("Metric read from %s", path)
+			mf.Help = &help
+		}
+		if err := checkDuplicateSeries(mf); err != nil {
+			return nil, 0, time.Time{}, fmt.Errorf("Textfile %q is invalid: %s", path, err)
 		}
+		m, err := convertMetricFamily(mf)
+		if err != nil {
+			return nil, 0, time.Time{}, fmt.Errorf("Textfile %q is invalid: %s", path, err)
+		}
+		metrics = append(metrics, m...)
+		samples += countSamples(mf)
 	}
 
-	c.exportMTimes(mtimes, ch)
-
-	// Export if there were errors.
-	ch <- prometheus.MustNewConstMetric(
-		prometheus.NewDesc(
-			"node_textfile_scrape_error",
-			"1 if there was an error opening or reading a file, 0 otherwise",
-			nil, nil,
-		),
-		prometheus.GaugeValue, error,
-	)
-	return nil
-}
+	// Only set this once it has been parsed and validated, so that
+	// a failure does not appear fresh.
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, 0, time.Time{}, fmt.Errorf("Error stat'ing %q: %v", path, err)
+	}
 
-// hasTimestamps returns true when metrics contain unsupported timestamps.
-func hasTimestamps(parsedFamilies map[string]*dto.MetricFamily) bool {
-	for _, mf := range parsedFamilies {
+	for name, mf := range parsedFamilies {
+		k, ok := known[name]
+		if !ok {
+			k = &textFileMetric{help: mf.GetHelp(), metricType: mf.GetType(), file: path, series: map[string]string{}}
+			known[name] = k
+		}
 		for _, m := range mf.Metric {
-			if m.TimestampMs != nil {
-				return true
-			}
+			k.series[seriesKey(m)] = path
 		}
 	}
-	return false
+	return metrics, samples, stat.ModTime(), nil
 }
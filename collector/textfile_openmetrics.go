@@ -0,0 +1,168 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !notextfile
+
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// isOpenMetrics returns true if the content is in the OpenMetrics format,
+// which unlike the text format has to end with "# EOF".
+func isOpenMetrics(content []byte) bool {
+	return bytes.HasSuffix(bytes.TrimRight(content, "\n"), []byte("\n# EOF")) ||
+		bytes.Equal(bytes.TrimRight(content, "\n"), []byte("# EOF"))
+}
+
+// openMetricsToText converts OpenMetrics to the text format, so that it can
+// be parsed by expfmt.TextParser:
+//   - counters and info metrics are named after their samples, i.e. with the
+//     _total and _info suffix, info metrics and state sets become gauges,
+//   - _created samples, UNIT metadata and exemplars are dropped,
+//   - gauge histograms become untyped metrics,
+//   - timestamps are converted from seconds to milliseconds.
+func openMetricsToText(content []byte) ([]byte, error) {
+	lines := strings.Split(string(content), "\n")
+
+	types := map[string]string{}
+	for _, line := range lines {
+		if fields := strings.Fields(line); len(fields) == 4 && fields[0] == "#" && fields[1] == "TYPE" {
+			types[fields[2]] = fields[3]
+		}
+	}
+
+	var out bytes.Buffer
+	for i, line := range lines {
+		if line == "# EOF" {
+			break
+		}
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("line %d: invalid metadata %q", i+1, line)
+			}
+			name, typ := fields[2], types[fields[2]]
+			switch typ {
+			case "counter":
+				name += "_total"
+			case "info":
+				name += "_info"
+			case "gaugehistogram":
+				continue
+			}
+			switch fields[1] {
+			case "HELP":
+				help := ""
+				if len(fields) == 4 {
+					help = fields[3]
+				}
+				fmt.Fprintf(&out, "# HELP %s %s\n", name, help)
+			case "TYPE":
+				switch typ {
+				case "info", "stateset":
+					typ = "gauge"
+				case "unknown":
+					typ = "untyped"
+				}
+				fmt.Fprintf(&out, "# TYPE %s %s\n", name, typ)
+			}
+			continue
+		}
+
+		series, value, timestamp, err := splitOpenMetricsSample(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err)
+		}
+		name := series
+		if i := strings.IndexByte(series, '{'); i >= 0 {
+			name = series[:i]
+		}
+		if strings.HasSuffix(name, "_created") {
+			switch types[strings.TrimSuffix(name, "_created")] {
+			case "counter", "histogram", "summary":
+				continue
+			}
+		}
+
+		if timestamp != "" {
+			seconds, err := strconv.ParseFloat(timestamp, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid timestamp %q", i+1, timestamp)
+			}
+			timestamp = " " + strconv.FormatInt(int64(math.Round(seconds*1000)), 10)
+		}
+		fmt.Fprintf(&out, "%s %s%s\n", series, value, timestamp)
+	}
+
+	return out.Bytes(), nil
+}
+
+// splitOpenMetricsSample splits a sample line into the series, i.e. the
+// metric name and labels, the value and the optional timestamp, dropping the
+// exemplar.
+func splitOpenMetricsSample(line string) (series, value, timestamp string, err error) {
+	end := strings.IndexAny(line, "{ ")
+	if end < 0 {
+		return "", "", "", fmt.Errorf("invalid sample %q", line)
+	}
+	if line[end] == '{' {
+		if end = openMetricsLabelsEnd(line, end); end < 0 {
+			return "", "", "", fmt.Errorf("unterminated labels in sample %q", line)
+		}
+	}
+	series = line[:end]
+
+	rest := line[end:]
+	if i := strings.Index(rest, " # "); i >= 0 {
+		rest = rest[:i]
+	}
+	fields := strings.Fields(rest)
+	switch len(fields) {
+	case 1:
+		return series, fields[0], "", nil
+	case 2:
+		return series, fields[0], fields[1], nil
+	}
+	return "", "", "", fmt.Errorf("invalid sample %q", line)
+}
+
+// openMetricsLabelsEnd returns the index after the closing brace of the labels
+// starting at start, -1 if they aren't terminated. Label values may contain
+// any character, including } and #.
+func openMetricsLabelsEnd(line string, start int) int {
+	quoted, escaped := false, false
+	for i := start + 1; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = quoted
+		case c == '"':
+			quoted = !quoted
+		case c == '}' && !quoted:
+			return i + 1
+		}
+	}
+	return -1
+}
@@ -47,8 +47,9 @@ func (a collectorAdapter) Collect(ch chan<- prometheus.Metric) {
 
 func TestTextfileCollector(t *testing.T) {
 	tests := []struct {
-		path string
-		out  string
+		path       string
+		out        string
+		timestamps string
 	}{
 		{
 			path: "fixtures/textfile/no_metric_files",
@@ -58,6 +59,10 @@ func TestTextfileCollector(t *testing.T) {
 			path: "fixtures/textfile/two_metric_files",
 			out:  "fixtures/textfile/two_metric_files.out",
 		},
+		{
+			path: "fixtures/textfile/*_metric_files",
+			out:  "fixtures/textfile/two_metric_files.out",
+		},
 		{
 			path: "fixtures/textfile/nonexistent_path",
 			out:  "fixtures/textfile/nonexistent_path.out",
@@ -66,6 +71,19 @@ func TestTextfileCollector(t *testing.T) {
 			path: "fixtures/textfile/client_side_timestamp",
 			out:  "fixtures/textfile/client_side_timestamp.out",
 		},
+		{
+			path:       "fixtures/textfile/client_side_timestamp_ignored",
+			out:        "fixtures/textfile/client_side_timestamp_ignored.out",
+			timestamps: "ignore",
+		},
+		{
+			path: "fixtures/textfile/openmetrics",
+			out:  "fixtures/textfile/openmetrics.out",
+		},
+		{
+			path: "fixtures/textfile/compressed",
+			out:  "fixtures/textfile/compressed.out",
+		},
 		{
 			path: "fixtures/textfile/different_metric_types",
 			out:  "fixtures/textfile/different_metric_types.out",
@@ -82,6 +100,10 @@ func TestTextfileCollector(t *testing.T) {
 			path: "fixtures/textfile/histogram_extra_dimension",
 			out:  "fixtures/textfile/histogram_extra_dimension.out",
 		},
+		{
+			path: "fixtures/textfile/invalid_files",
+			out:  "fixtures/textfile/invalid_files.out",
+		},
 		{
 			path: "fixtures/textfile/summary",
 			out:  "fixtures/textfile/summary.out",
@@ -95,7 +117,7 @@ func TestTextfileCollector(t *testing.T) {
 	for i, test := range tests {
 		mtime := 1.0
 		c := &textFileCollector{
-			path:  test.path,
+			paths: []string{test.path},
 			mtime: &mtime,
 		}
 
@@ -106,6 +128,9 @@ func TestTextfileCollector(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
+		if test.timestamps != "" {
+			*textFileTimestamps = test.timestamps
+		}
 
 		registry := prometheus.NewRegistry()
 		registry.MustRegister(collectorAdapter{c})
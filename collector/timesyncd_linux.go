@@ -0,0 +1,177 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !notimesyncd
+
+package collector
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/godbus/dbus"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	timesyncdSubsystem = "timesyncd"
+	timesyncdDbusName  = "org.freedesktop.timesync1"
+	timesyncdDbusPath  = "/org/freedesktop/timesync1"
+	timedatedDbusName  = "org.freedesktop.timedate1"
+	timedatedDbusPath  = "/org/freedesktop/timedate1"
+)
+
+type timesyncdCollector struct {
+	synchronized, ntpEnabled, serverInfo, lastSync, stratum, offset,
+	rootDelay, rootDispersion, jitter, pollInterval, packets typedDesc
+}
+
+// timesyncdNTPMessage is the NTPMessage property of timesyncd, the last NTP
+// response received. All times are in microseconds.
+type timesyncdNTPMessage struct {
+	Leap                 uint32
+	Version              uint32
+	Mode                 uint32
+	Stratum              uint32
+	Precision            int32
+	RootDelay            uint64
+	RootDispersion       uint64
+	Reference            []byte
+	OriginateTimestamp   uint64
+	ReceiveTimestamp     uint64
+	TransmitTimestamp    uint64
+	DestinationTimestamp uint64
+	Ignored              bool
+	PacketCount          uint64
+	Jitter               uint64
+}
+
+func init() {
+	registerCollector(timesyncdSubsystem, defaultDisabled, NewTimesyncdCollector)
+}
+
+// NewTimesyncdCollector returns a new Collector exposing the synchronization
+// state of systemd-timesyncd.
+func NewTimesyncdCollector() (Collector, error) {
+	desc := func(name, help string, valueType prometheus.ValueType, labels ...string) typedDesc {
+		return typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, timesyncdSubsystem, name),
+			help, labels, nil,
+		), valueType}
+	}
+	return &timesyncdCollector{
+		synchronized:   desc("synchronized", "Whether the kernel considers the system clock synchronized, 1 if it does. This is set by any NTP daemon.", prometheus.GaugeValue),
+		ntpEnabled:     desc("ntp_enabled", "Whether network time synchronization is enabled, 1 if it is.", prometheus.GaugeValue),
+		serverInfo:     desc("server_info", "NTP server currently used, value is always 1.", prometheus.GaugeValue, "server_name", "server_address"),
+		lastSync:       desc("last_sync_timestamp_seconds", "Time the last NTP response was received.", prometheus.GaugeValue),
+		stratum:        desc("stratum", "Stratum of the NTP server.", prometheus.GaugeValue),
+		offset:         desc("offset_seconds", "Offset of the system clock to the NTP server measured by the last response.", prometheus.GaugeValue),
+		rootDelay:      desc("root_delay_seconds", "Total round-trip delay from the NTP server to the stratum-1 computer.", prometheus.GaugeValue),
+		rootDispersion: desc("root_dispersion_seconds", "Total dispersion from the NTP server to the stratum-1 computer.", prometheus.GaugeValue),
+		jitter:         desc("jitter_seconds", "Jitter of the offsets measured to the NTP server.", prometheus.GaugeValue),
+		pollInterval:   desc("poll_interval_seconds", "Interval in which the NTP server is polled.", prometheus.GaugeValue),
+		packets:        desc("packets_total", "Number of NTP responses received from the server.", prometheus.CounterValue),
+	}, nil
+}
+
+func (c *timesyncdCollector) Update(ch chan<- prometheus.Metric) error {
+	conn, err := dbus.SystemBusPrivate()
+	if err != nil {
+		return fmt.Errorf("unable to connect to dbus: %s", err)
+	}
+	defer conn.Close()
+	if err := conn.Auth([]dbus.Auth{dbus.AuthExternal(strconv.Itoa(os.Getuid()))}); err != nil {
+		return fmt.Errorf("unable to authenticate to dbus: %s", err)
+	}
+	if err := conn.Hello(); err != nil {
+		return fmt.Errorf("unable to connect to dbus: %s", err)
+	}
+
+	var timedate map[string]dbus.Variant
+	err = conn.Object(timedatedDbusName, timedatedDbusPath).
+		Call("org.freedesktop.DBus.Properties.GetAll", 0, timedatedDbusName).Store(&timedate)
+	if err != nil {
+		return fmt.Errorf("unable to get timedated properties: %s", err)
+	}
+	if err := c.updateTimedate(ch, timedate); err != nil {
+		return err
+	}
+
+	var manager map[string]dbus.Variant
+	err = conn.Object(timesyncdDbusName, timesyncdDbusPath).
+		Call("org.freedesktop.DBus.Properties.GetAll", 0, timesyncdDbusName+".Manager").Store(&manager)
+	if err != nil {
+		return fmt.Errorf("unable to get timesyncd properties: %s", err)
+	}
+	return c.updateManager(ch, manager)
+}
+
+func (c *timesyncdCollector) updateTimedate(ch chan<- prometheus.Metric, props map[string]dbus.Variant) error {
+	for desc, name := range map[*typedDesc]string{&c.synchronized: "NTPSynchronized", &c.ntpEnabled: "NTP"} {
+		v, ok := props[name].Value().(bool)
+		if !ok {
+			return fmt.Errorf("invalid timedated property %s: %s", name, props[name])
+		}
+		value := 0.0
+		if v {
+			value = 1
+		}
+		ch <- desc.mustNewConstMetric(value)
+	}
+	return nil
+}
+
+func (c *timesyncdCollector) updateManager(ch chan<- prometheus.Metric, props map[string]dbus.Variant) error {
+	serverName, _ := props["ServerName"].Value().(string)
+	var serverAddress string
+	if addr, ok := props["ServerAddress"].Value().([]interface{}); ok && len(addr) == 2 {
+		if ip, ok := addr[1].([]byte); ok && len(ip) > 0 {
+			serverAddress = net.IP(ip).String()
+		}
+	}
+	ch <- c.serverInfo.mustNewConstMetric(1, serverName, serverAddress)
+
+	if poll, ok := props["PollIntervalUSec"].Value().(uint64); ok {
+		ch <- c.pollInterval.mustNewConstMetric(float64(poll) / 1e6)
+	}
+
+	fields, ok := props["NTPMessage"].Value().([]interface{})
+	if !ok {
+		return fmt.Errorf("invalid timesyncd property NTPMessage: %s", props["NTPMessage"])
+	}
+	var m timesyncdNTPMessage
+	err := dbus.Store(fields, &m.Leap, &m.Version, &m.Mode, &m.Stratum, &m.Precision,
+		&m.RootDelay, &m.RootDispersion, &m.Reference, &m.OriginateTimestamp, &m.ReceiveTimestamp,
+		&m.TransmitTimestamp, &m.DestinationTimestamp, &m.Ignored, &m.PacketCount, &m.Jitter)
+	if err != nil {
+		return fmt.Errorf("invalid timesyncd property NTPMessage: %s", err)
+	}
+	// No response has been received yet.
+	if m.DestinationTimestamp == 0 {
+		return nil
+	}
+
+	offset := (float64(m.ReceiveTimestamp) - float64(m.OriginateTimestamp) +
+		float64(m.TransmitTimestamp) - float64(m.DestinationTimestamp)) / 2
+	ch <- c.lastSync.mustNewConstMetric(float64(m.DestinationTimestamp) / 1e6)
+	ch <- c.stratum.mustNewConstMetric(float64(m.Stratum))
+	ch <- c.offset.mustNewConstMetric(offset / 1e6)
+	ch <- c.rootDelay.mustNewConstMetric(float64(m.RootDelay) / 1e6)
+	ch <- c.rootDispersion.mustNewConstMetric(float64(m.RootDispersion) / 1e6)
+	ch <- c.jitter.mustNewConstMetric(float64(m.Jitter) / 1e6)
+	ch <- c.packets.mustNewConstMetric(float64(m.PacketCount))
+
+	return nil
+}
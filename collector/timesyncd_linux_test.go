@@ -0,0 +1,71 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestTimesyncdManager(t *testing.T) {
+	c, err := NewTimesyncdCollector()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	props := map[string]dbus.Variant{
+		"ServerName":       dbus.MakeVariant("ntp.example.com"),
+		"ServerAddress":    dbus.MakeVariant([]interface{}{int32(2), []byte{192, 0, 2, 1}}),
+		"PollIntervalUSec": dbus.MakeVariant(uint64(2048000000)),
+		"NTPMessage": dbus.MakeVariant([]interface{}{
+			uint32(0), uint32(4), uint32(4), uint32(2), int32(-23),
+			uint64(1500), uint64(2500), []byte("GPS\x00"),
+			uint64(1000000000), uint64(1000003000), uint64(1000003100), uint64(1000001100),
+			false, uint64(42), uint64(300),
+		}),
+	}
+
+	ch := make(chan prometheus.Metric, 20)
+	if err := c.(*timesyncdCollector).updateManager(ch, props); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	values := map[string]float64{}
+	for m := range ch {
+		var metric dto.Metric
+		if err := m.Write(&metric); err != nil {
+			t.Fatal(err)
+		}
+		v := metric.GetGauge().GetValue()
+		if metric.Counter != nil {
+			v = metric.GetCounter().GetValue()
+		}
+		values[m.Desc().String()] = v
+	}
+
+	for desc, want := range map[*typedDesc]float64{
+		&c.(*timesyncdCollector).offset:       0.0025,
+		&c.(*timesyncdCollector).lastSync:     1000.0011,
+		&c.(*timesyncdCollector).pollInterval: 2048,
+		&c.(*timesyncdCollector).packets:      42,
+	} {
+		if got := values[desc.desc.String()]; want != got {
+			t.Errorf("want %f for %s, got %f", want, desc.desc, got)
+		}
+	}
+}
@@ -11,8 +11,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// +build linux
-// +build !notimex
+// +build linux,!notimex
 
 package collector
 
@@ -36,6 +35,35 @@ const (
 	microSeconds = 1000000
 )
 
+var (
+	// timexStatusFlags are the STA_* bits of timex.Status, see adjtimex(2).
+	timexStatusFlags = []struct {
+		name string
+		bit  int32
+	}{
+		{"pll", 0x0001},
+		{"ppsfreq", 0x0002},
+		{"ppstime", 0x0004},
+		{"fll", 0x0008},
+		{"ins", 0x0010},
+		{"del", 0x0020},
+		{"unsync", 0x0040},
+		{"freqhold", 0x0080},
+		{"ppssignal", 0x0100},
+		{"ppsjitter", 0x0200},
+		{"ppswander", 0x0400},
+		{"ppserror", 0x0800},
+		{"clockerr", 0x1000},
+		{"nano", staNano},
+		{"mode", 0x4000},
+		{"clk", 0x8000},
+	}
+
+	// timexLeapStates are the clock states returned by adjtimex, TIME_OK to
+	// TIME_ERROR, which include the state of leap second handling.
+	timexLeapStates = []string{"ok", "insert", "delete", "in_progress", "wait", "error"}
+)
+
 type timexCollector struct {
 	offset,
 	freq,
@@ -54,6 +82,8 @@ type timexCollector struct {
 	stbcnt,
 	tai,
 	syncStatus typedDesc
+	statusFlag *prometheus.Desc
+	clockState *prometheus.Desc
 }
 
 func init() {
@@ -150,6 +180,16 @@ func NewTimexCollector() (Collector, error) {
 			"Is clock synchronized to a reliable server (1 = yes, 0 = no).",
 			nil, nil,
 		), prometheus.GaugeValue},
+		statusFlag: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "status_flag"),
+			"Bits of the status, 1 if the flag is set and 0 otherwise.",
+			[]string{"flag"}, nil,
+		),
+		clockState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "clock_state"),
+			"States of the clock including pending leap seconds, 1 for the current one and 0 for the others.",
+			[]string{"state"}, nil,
+		),
 	}, nil
 }
 
@@ -194,5 +234,20 @@ func (c *timexCollector) Update(ch chan<- prometheus.Metric) error {
 	ch <- c.stbcnt.mustNewConstMetric(float64(timex.Stbcnt))
 	ch <- c.tai.mustNewConstMetric(float64(timex.Tai))
 
+	for _, f := range timexStatusFlags {
+		v := 0.0
+		if timex.Status&f.bit != 0 {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.statusFlag, prometheus.GaugeValue, v, f.name)
+	}
+	for i, state := range timexLeapStates {
+		v := 0.0
+		if status == i {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.clockState, prometheus.GaugeValue, v, state)
+	}
+
 	return nil
 }
@@ -0,0 +1,247 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !notpm
+
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	tpmSubsystem = "tpm"
+
+	// TPM 2.0 commands and their constants, see part 2 and 3 of the TPM 2.0
+	// library specification.
+	tpmSTNoSessions        = 0x8001
+	tpmCCGetCapability     = 0x0000017a
+	tpmCCGetTestResult     = 0x0000017c
+	tpmCapTPMProperties    = 0x00000006
+	tpmPTManufacturer      = 0x00000105
+	tpmPTFirmwareVersion1  = 0x0000010b
+	tpmPTFirmwareVersion2  = 0x0000010c
+	tpmPTPermanent         = 0x00000200
+	tpmPTLockoutCounter    = 0x0000020e
+	tpmPTMaxAuthFail       = 0x0000020f
+	tpmPTLockoutInterval   = 0x00000210
+	tpmPTLockoutRecovery   = 0x00000211
+	tpmPermanentInLockout  = 1 << 9
+	tpmMaxResponseSize     = 4096
+	tpmPropertiesRequested = 32
+)
+
+var tpmDevicePath = kingpin.Flag("collector.tpm.device-path", "Directory of the TPM resource manager devices.").Default("/dev").String()
+
+type tpmCollector struct {
+	infoDesc            *prometheus.Desc
+	firmwareInfoDesc    *prometheus.Desc
+	selfTestPassedDesc  *prometheus.Desc
+	inLockoutDesc       *prometheus.Desc
+	lockoutCounterDesc  *prometheus.Desc
+	maxAuthFailDesc     *prometheus.Desc
+	lockoutIntervalDesc *prometheus.Desc
+	lockoutRecoveryDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector(tpmSubsystem, defaultDisabled, NewTPMCollector)
+}
+
+// NewTPMCollector returns a new Collector exposing the TPMs of the system
+// and, for TPM 2.0, their self-test result and dictionary attack lockout
+// state.
+func NewTPMCollector() (Collector, error) {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, tpmSubsystem, name), help, append([]string{"device"}, labels...), nil)
+	}
+	return &tpmCollector{
+		infoDesc:            desc("info", "TPM and the major version of the specification it implements, value is always 1.", "version"),
+		firmwareInfoDesc:    desc("firmware_info", "Manufacturer and firmware version of the TPM, value is always 1.", "manufacturer", "firmware_version"),
+		selfTestPassedDesc:  desc("self_test_passed", "1 if the self-test of the TPM passed, 0 otherwise."),
+		inLockoutDesc:       desc("in_lockout", "1 if the TPM refuses authorizations because of too many failures, 0 otherwise."),
+		lockoutCounterDesc:  desc("lockout_counter", "Number of authorization failures counted by the dictionary attack protection."),
+		maxAuthFailDesc:     desc("max_auth_fail", "Number of authorization failures after which the TPM enters lockout."),
+		lockoutIntervalDesc: desc("lockout_interval_seconds", "Time after which an authorization failure is forgotten."),
+		lockoutRecoveryDesc: desc("lockout_recovery_seconds", "Time after a lockout authorization failure before lockout authorization can be tried again."),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *tpmCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("class/tpm/tpm[0-9]*"))
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range devices {
+		device := filepath.Base(dir)
+
+		// The specification version is exposed since Linux 5.6, TPM 1.2
+		// devices expose their capabilities instead.
+		version, err := readTrimmedFile(filepath.Join(dir, "tpm_version_major"))
+		if err != nil {
+			if _, err := os.Stat(filepath.Join(dir, "device/caps")); err == nil {
+				version = "1"
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(c.infoDesc, prometheus.GaugeValue, 1, device, version)
+		if version == "1" {
+			continue
+		}
+
+		// The resource manager device multiplexes the TPM between users,
+		// so querying it doesn't interfere with other software.
+		rm := filepath.Join(*tpmDevicePath, strings.Replace(device, "tpm", "tpmrm", 1))
+		if err := c.updateTPM2(ch, device, rm); err != nil {
+			log.Debugf("Couldn't query %s: %s", rm, err)
+		}
+	}
+	return nil
+}
+
+func (c *tpmCollector) updateTPM2(ch chan<- prometheus.Metric, device, path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rsp, err := tpmCommand(f, tpmCCGetTestResult, nil)
+	if err != nil {
+		return fmt.Errorf("couldn't get self-test result: %s", err)
+	}
+	testResult, err := parseTPMTestResult(rsp)
+	if err != nil {
+		return err
+	}
+	passed := 0.0
+	if testResult == 0 {
+		passed = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.selfTestPassedDesc, prometheus.GaugeValue, passed, device)
+
+	props := map[uint32]uint32{}
+	for _, first := range []uint32{tpmPTManufacturer, tpmPTPermanent} {
+		params := make([]byte, 12)
+		binary.BigEndian.PutUint32(params[0:], tpmCapTPMProperties)
+		binary.BigEndian.PutUint32(params[4:], first)
+		binary.BigEndian.PutUint32(params[8:], tpmPropertiesRequested)
+		rsp, err := tpmCommand(f, tpmCCGetCapability, params)
+		if err != nil {
+			return fmt.Errorf("couldn't get properties: %s", err)
+		}
+		if err := parseTPMProperties(rsp, props); err != nil {
+			return err
+		}
+	}
+
+	manufacturer := make([]byte, 4)
+	binary.BigEndian.PutUint32(manufacturer, props[tpmPTManufacturer])
+	firmware := fmt.Sprintf("%d.%d.%d.%d",
+		props[tpmPTFirmwareVersion1]>>16, props[tpmPTFirmwareVersion1]&0xffff,
+		props[tpmPTFirmwareVersion2]>>16, props[tpmPTFirmwareVersion2]&0xffff)
+	ch <- prometheus.MustNewConstMetric(c.firmwareInfoDesc, prometheus.GaugeValue, 1,
+		device, strings.TrimRight(string(manufacturer), " \x00"), firmware)
+
+	inLockout := 0.0
+	if props[tpmPTPermanent]&tpmPermanentInLockout != 0 {
+		inLockout = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.inLockoutDesc, prometheus.GaugeValue, inLockout, device)
+	ch <- prometheus.MustNewConstMetric(c.lockoutCounterDesc, prometheus.GaugeValue, float64(props[tpmPTLockoutCounter]), device)
+	ch <- prometheus.MustNewConstMetric(c.maxAuthFailDesc, prometheus.GaugeValue, float64(props[tpmPTMaxAuthFail]), device)
+	ch <- prometheus.MustNewConstMetric(c.lockoutIntervalDesc, prometheus.GaugeValue, float64(props[tpmPTLockoutInterval]), device)
+	ch <- prometheus.MustNewConstMetric(c.lockoutRecoveryDesc, prometheus.GaugeValue, float64(props[tpmPTLockoutRecovery]), device)
+	return nil
+}
+
+// tpmCommand sends a command without sessions to the TPM and returns the
+// parameters of a successful response.
+func tpmCommand(rw io.ReadWriter, code uint32, params []byte) ([]byte, error) {
+	cmd := make([]byte, 10, 10+len(params))
+	binary.BigEndian.PutUint16(cmd[0:], tpmSTNoSessions)
+	binary.BigEndian.PutUint32(cmd[2:], uint32(10+len(params)))
+	binary.BigEndian.PutUint32(cmd[6:], code)
+	if _, err := rw.Write(append(cmd, params...)); err != nil {
+		return nil, err
+	}
+
+	rsp := make([]byte, tpmMaxResponseSize)
+	n, err := rw.Read(rsp)
+	if err != nil {
+		return nil, err
+	}
+	rsp = rsp[:n]
+	if len(rsp) < 10 || int(binary.BigEndian.Uint32(rsp[2:])) != len(rsp) {
+		return nil, fmt.Errorf("invalid response of %d bytes", len(rsp))
+	}
+	if rc := binary.BigEndian.Uint32(rsp[6:]); rc != 0 {
+		return nil, fmt.Errorf("response code %#x", rc)
+	}
+	return rsp[10:], nil
+}
+
+// parseTPMTestResult parses the TPM2_GetTestResult response parameters, the
+// manufacturer specific test data followed by the test result.
+func parseTPMTestResult(rsp []byte) (uint32, error) {
+	r := bytes.NewReader(rsp)
+	var size uint16
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return 0, fmt.Errorf("invalid self-test result: %s", err)
+	}
+	if _, err := r.Seek(int64(size), io.SeekCurrent); err != nil {
+		return 0, fmt.Errorf("invalid self-test result: %s", err)
+	}
+	var result uint32
+	if err := binary.Read(r, binary.BigEndian, &result); err != nil {
+		return 0, fmt.Errorf("invalid self-test result: %s", err)
+	}
+	return result, nil
+}
+
+// parseTPMProperties parses the TPM2_GetCapability response parameters for
+// TPM properties, whether there is more data, the capability and the
+// property-value pairs, into props.
+func parseTPMProperties(rsp []byte, props map[uint32]uint32) error {
+	r := bytes.NewReader(rsp)
+	var header struct {
+		MoreData   uint8
+		Capability uint32
+		Count      uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return fmt.Errorf("invalid properties: %s", err)
+	}
+	if header.Capability != tpmCapTPMProperties {
+		return fmt.Errorf("unexpected capability %#x", header.Capability)
+	}
+	for i := uint32(0); i < header.Count; i++ {
+		var prop [2]uint32
+		if err := binary.Read(r, binary.BigEndian, &prop); err != nil {
+			return fmt.Errorf("invalid properties: %s", err)
+		}
+		props[prop[0]] = prop[1]
+	}
+	return nil
+}
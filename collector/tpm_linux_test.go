@@ -0,0 +1,90 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !notpm
+
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// fakeTPM answers every command with a fixed response.
+type fakeTPM struct {
+	cmd, rsp []byte
+}
+
+func (t *fakeTPM) Write(p []byte) (int, error) {
+	t.cmd = append([]byte{}, p...)
+	return len(p), nil
+}
+
+func (t *fakeTPM) Read(p []byte) (int, error) {
+	return copy(p, t.rsp), nil
+}
+
+func tpmResponse(rc uint32, params []byte) []byte {
+	rsp := make([]byte, 10)
+	binary.BigEndian.PutUint16(rsp[0:], tpmSTNoSessions)
+	binary.BigEndian.PutUint32(rsp[2:], uint32(10+len(params)))
+	binary.BigEndian.PutUint32(rsp[6:], rc)
+	return append(rsp, params...)
+}
+
+func TestTPMCommand(t *testing.T) {
+	// TPM2_GetTestResult with 2 bytes of test data and a passed test.
+	tpm := &fakeTPM{rsp: tpmResponse(0, []byte{0, 2, 0xab, 0xcd, 0, 0, 0, 0})}
+	rsp, err := tpmCommand(tpm, tpmCCGetTestResult, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x80, 0x01, 0, 0, 0, 10, 0, 0, 0x01, 0x7c}
+	if !bytes.Equal(tpm.cmd, want) {
+		t.Errorf("want command %x, got %x", want, tpm.cmd)
+	}
+	result, err := parseTPMTestResult(rsp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != 0 {
+		t.Errorf("want test result 0, got %#x", result)
+	}
+
+	tpm.rsp = tpmResponse(0x101, nil)
+	if _, err := tpmCommand(tpm, tpmCCGetTestResult, nil); err == nil {
+		t.Error("expected error for failed command")
+	}
+}
+
+func TestParseTPMProperties(t *testing.T) {
+	rsp := []byte{
+		0,          // moreData
+		0, 0, 0, 6, // TPM_CAP_TPM_PROPERTIES
+		0, 0, 0, 2, // count
+		0, 0, 0x01, 0x05, 'I', 'F', 'X', 0,
+		0, 0, 0x02, 0x0e, 0, 0, 0, 3,
+	}
+	props := map[uint32]uint32{}
+	if err := parseTPMProperties(rsp, props); err != nil {
+		t.Fatal(err)
+	}
+	if props[tpmPTManufacturer] != 0x49465800 || props[tpmPTLockoutCounter] != 3 {
+		t.Errorf("unexpected properties %v", props)
+	}
+
+	if err := parseTPMProperties(rsp[:15], props); err == nil {
+		t.Error("expected error for truncated properties")
+	}
+}
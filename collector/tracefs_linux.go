@@ -0,0 +1,139 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !notracefs
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	tracefsSubsystem = "tracefs"
+
+	// tracefsTrigger is the hist trigger counting the hits of an event. All
+	// hits of an event have the same common_type, so there is only one
+	// entry.
+	tracefsTrigger = "hist:keys=common_type"
+)
+
+var (
+	tracefsEvents = kingpin.Flag("collector.tracefs.event", "Tracepoint to count the hits of, e.g. oom/mark_victim, can be repeated. A hist trigger is added to the event, which requires CONFIG_HIST_TRIGGERS, and left in place.").Strings()
+
+	tracefsEventRE = regexp.MustCompile(`^[a-z0-9_]+/[a-z0-9_]+$`)
+)
+
+type tracefsCollector struct {
+	hitsDesc    *prometheus.Desc
+	droppedDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector(tracefsSubsystem, defaultDisabled, NewTracefsCollector)
+}
+
+// NewTracefsCollector returns a new Collector exposing the number of hits of
+// the configured tracepoints, adding hist triggers to them as needed.
+func NewTracefsCollector() (Collector, error) {
+	for _, event := range *tracefsEvents {
+		if !tracefsEventRE.MatchString(event) {
+			return nil, fmt.Errorf("invalid event %q, must be <system>/<event>", event)
+		}
+		// Adding the same trigger again fails with EEXIST, e.g. after a
+		// restart.
+		trigger := filepath.Join(tracefsDir(), "events", event, "trigger")
+		err := ioutil.WriteFile(trigger, []byte(tracefsTrigger), 0644)
+		if err != nil && !os.IsExist(err) {
+			return nil, fmt.Errorf("couldn't add hist trigger to event %s: %s", event, err)
+		}
+	}
+	return &tracefsCollector{
+		hitsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, tracefsSubsystem, "event_hits_total"),
+			"Number of hits of the tracepoint since the collector added its hist trigger.",
+			[]string{"event"}, nil,
+		),
+		droppedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, tracefsSubsystem, "event_dropped_total"),
+			"Number of hits of the tracepoint which weren't counted because the histogram was full.",
+			[]string{"event"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *tracefsCollector) Update(ch chan<- prometheus.Metric) error {
+	for _, event := range *tracefsEvents {
+		file, err := os.Open(filepath.Join(tracefsDir(), "events", event, "hist"))
+		if err != nil {
+			return err
+		}
+		hits, dropped, err := parseTracefsHist(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("couldn't parse histogram of event %s: %s", event, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.hitsDesc, prometheus.CounterValue, hits, event)
+		ch <- prometheus.MustNewConstMetric(c.droppedDesc, prometheus.CounterValue, dropped, event)
+	}
+	return nil
+}
+
+// parseTracefsHist returns the totals of the hist file of an event, e.g.
+//
+//	Totals:
+//	    Hits: 3
+//	    Entries: 1
+//	    Dropped: 0
+func parseTracefsHist(r io.Reader) (hits, dropped float64, err error) {
+	var (
+		scanner  = bufio.NewScanner(r)
+		hasTotal bool
+	)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "Hits:":
+			if hits, err = strconv.ParseFloat(fields[1], 64); err != nil {
+				return 0, 0, fmt.Errorf("invalid line %q", scanner.Text())
+			}
+			hasTotal = true
+		case "Dropped:":
+			if dropped, err = strconv.ParseFloat(fields[1], 64); err != nil {
+				return 0, 0, fmt.Errorf("invalid line %q", scanner.Text())
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+	if !hasTotal {
+		return 0, 0, fmt.Errorf("no hist trigger")
+	}
+	return hits, dropped, nil
+}
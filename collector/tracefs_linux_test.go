@@ -0,0 +1,47 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !notracefs
+
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTracefsHist(t *testing.T) {
+	hist := `# event histogram
+#
+# trigger info: hist:keys=common_type:vals=hitcount:sort=hitcount:size=2048 [active]
+#
+
+{ common_type:        442 } hitcount:          3
+
+Totals:
+    Hits: 3
+    Entries: 1
+    Dropped: 0
+`
+	hits, dropped, err := parseTracefsHist(strings.NewReader(hist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits != 3 || dropped != 0 {
+		t.Errorf("want 3 hits and 0 dropped, got %f and %f", hits, dropped)
+	}
+
+	if _, _, err := parseTracefsHist(strings.NewReader("# event histogram\n")); err == nil {
+		t.Error("want error for histogram without totals")
+	}
+}
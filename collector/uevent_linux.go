@@ -0,0 +1,173 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nouevent
+
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ueventSubsystem = "uevent"
+
+	// ueventKernelGroup is the multicast group of the uevents sent by the
+	// kernel, as opposed to the ones forwarded by udev.
+	ueventKernelGroup = 1
+	ueventBufferSize  = 1 << 20
+)
+
+var (
+	// The uevent socket is read continuously, so it's only opened once
+	// even though collectors are created per request.
+	ueventListenerOnce sync.Once
+	ueventListenerErr  error
+	ueventListenerInst *ueventListener
+)
+
+type ueventKey struct {
+	action, subsystem string
+}
+
+type ueventListener struct {
+	mtx      sync.Mutex
+	events   map[ueventKey]float64
+	overruns float64
+}
+
+type ueventCollector struct {
+	listener     *ueventListener
+	eventsDesc   *prometheus.Desc
+	overrunsDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector(ueventSubsystem, defaultDisabled, NewUeventCollector)
+}
+
+// NewUeventCollector returns a new Collector counting the device events of
+// the kernel, e.g. devices being added or removed.
+func NewUeventCollector() (Collector, error) {
+	ueventListenerOnce.Do(func() {
+		ueventListenerInst, ueventListenerErr = newUeventListener()
+	})
+	if ueventListenerErr != nil {
+		return nil, ueventListenerErr
+	}
+
+	return &ueventCollector{
+		listener: ueventListenerInst,
+		eventsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ueventSubsystem, "events_total"),
+			"Number of device events by action, e.g. add, remove, change or move, and subsystem.",
+			[]string{"action", "subsystem"}, nil,
+		),
+		overrunsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ueventSubsystem, "overruns_total"),
+			"Number of times device events were lost because they arrived faster than they were read.",
+			nil, nil,
+		),
+	}, nil
+}
+
+func newUeventListener() (*ueventListener, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open uevent socket: %s", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: ueventKernelGroup}); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("couldn't bind uevent socket: %s", err)
+	}
+	// Adding many devices at once, e.g. at boot, causes bursts of events.
+	if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_RCVBUF, ueventBufferSize); err != nil {
+		log.Debugf("Couldn't set uevent socket receive buffer size: %s", err)
+	}
+
+	l := &ueventListener{events: map[ueventKey]float64{}}
+	go l.listen(fd)
+	return l, nil
+}
+
+func (l *ueventListener) listen(fd int) {
+	defer unix.Close(fd)
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		switch err {
+		case nil:
+		case unix.EINTR:
+			continue
+		case unix.ENOBUFS:
+			l.mtx.Lock()
+			l.overruns++
+			l.mtx.Unlock()
+			continue
+		default:
+			log.Errorf("Error reading uevent socket: %s", err)
+			return
+		}
+
+		action, subsystem, ok := parseUevent(buf[:n])
+		if !ok {
+			log.Debugf("Ignoring malformed uevent %q", buf[:n])
+			continue
+		}
+		l.mtx.Lock()
+		l.events[ueventKey{action, subsystem}]++
+		l.mtx.Unlock()
+	}
+}
+
+// parseUevent returns the action and subsystem of a uevent, which consists
+// of a header followed by NUL separated properties, e.g.
+//
+//	add@/devices/pci0000:00/0000:00:14.0/usb1/1-2\x00ACTION=add\x00DEVPATH=...\x00SUBSYSTEM=usb\x00
+func parseUevent(msg []byte) (string, string, bool) {
+	var action, subsystem string
+	for i, field := range bytes.Split(msg, []byte{0}) {
+		// The first field is the header.
+		if i == 0 {
+			if !bytes.Contains(field, []byte("@")) {
+				return "", "", false
+			}
+			continue
+		}
+		switch {
+		case bytes.HasPrefix(field, []byte("ACTION=")):
+			action = string(field[len("ACTION="):])
+		case bytes.HasPrefix(field, []byte("SUBSYSTEM=")):
+			subsystem = string(field[len("SUBSYSTEM="):])
+		}
+	}
+	return action, subsystem, action != ""
+}
+
+// Update implements the Collector interface.
+func (c *ueventCollector) Update(ch chan<- prometheus.Metric) error {
+	c.listener.mtx.Lock()
+	defer c.listener.mtx.Unlock()
+
+	for key, v := range c.listener.events {
+		ch <- prometheus.MustNewConstMetric(c.eventsDesc, prometheus.CounterValue, v, key.action, key.subsystem)
+	}
+	ch <- prometheus.MustNewConstMetric(c.overrunsDesc, prometheus.CounterValue, c.listener.overruns)
+	return nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nouevent
+
+package collector
+
+import "testing"
+
+func TestParseUevent(t *testing.T) {
+	for _, tc := range []struct {
+		msg               string
+		action, subsystem string
+		ok                bool
+	}{
+		{"add@/devices/pci0000:00/0000:00:14.0/usb1/1-2\x00ACTION=add\x00DEVPATH=/devices/pci0000:00/0000:00:14.0/usb1/1-2\x00SUBSYSTEM=usb\x00SEQNUM=4711\x00", "add", "usb", true},
+		{"move@/devices/virtual/net/eth1\x00ACTION=move\x00DEVPATH_OLD=/devices/virtual/net/eth0\x00SUBSYSTEM=net\x00", "move", "net", true},
+		{"remove@/module/foo\x00ACTION=remove\x00", "remove", "", true},
+		{"libudev\x00\xfe\xed\xca\xfe", "", "", false},
+	} {
+		action, subsystem, ok := parseUevent([]byte(tc.msg))
+		if action != tc.action || subsystem != tc.subsystem || ok != tc.ok {
+			t.Errorf("%q: want %q, %q, %t, got %q, %q, %t", tc.msg, tc.action, tc.subsystem, tc.ok, action, subsystem, ok)
+		}
+	}
+}
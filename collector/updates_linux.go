@@ -0,0 +1,360 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noupdates
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const updatesSubsystem = "updates"
+
+var (
+	updatesBackend  = kingpin.Flag("collector.updates.backend", "Package manager to check for updates with, auto detects the one installed.").Default("auto").Enum("auto", "apt", "dnf", "zypper")
+	updatesInterval = kingpin.Flag("collector.updates.interval", "Interval between two checks for updates.").Default("6h").Duration()
+	updatesTimeout  = kingpin.Flag("collector.updates.timeout", "Time after which a check for updates is aborted.").Default("10m").Duration()
+
+	// Checking for updates is expensive, so it's done in the background
+	// and only once even though collectors are created per request.
+	updatesCheckerOnce sync.Once
+	updatesCheckerErr  error
+	updatesCheckerInst *updatesChecker
+
+	// updatesBackends are the package managers by name, in the order they
+	// are detected in.
+	updatesBackends = []struct {
+		name   string
+		binary string
+		check  func() (updatesStatus, error)
+	}{
+		{"apt", "apt-get", checkAptUpdates},
+		{"dnf", "dnf", checkDnfUpdates},
+		{"zypper", "zypper", checkZypperUpdates},
+	}
+)
+
+// updatesStatus is the result of a check for updates. rebootRequired is nil
+// if the backend can't tell.
+type updatesStatus struct {
+	pending        float64
+	security       float64
+	rebootRequired *float64
+}
+
+type updatesChecker struct {
+	backend string
+	check   func() (updatesStatus, error)
+
+	mtx       sync.Mutex
+	status    updatesStatus
+	success   bool
+	timestamp time.Time
+}
+
+type updatesCollector struct {
+	checker            *updatesChecker
+	pendingDesc        *prometheus.Desc
+	securityDesc       *prometheus.Desc
+	rebootRequiredDesc *prometheus.Desc
+	successDesc        *prometheus.Desc
+	lastCheckDesc      *prometheus.Desc
+}
+
+func init() {
+	registerCollector("updates", defaultDisabled, NewUpdatesCollector)
+}
+
+// NewUpdatesCollector returns a new Collector exposing the number of pending
+// package updates as of the last check.
+func NewUpdatesCollector() (Collector, error) {
+	updatesCheckerOnce.Do(func() {
+		updatesCheckerInst, updatesCheckerErr = newUpdatesChecker()
+		if updatesCheckerErr == nil {
+			go updatesCheckerInst.run()
+		}
+	})
+	if updatesCheckerErr != nil {
+		return nil, updatesCheckerErr
+	}
+	return newUpdatesCollector(updatesCheckerInst), nil
+}
+
+// newUpdatesCollector returns a Collector exposing the results of checker.
+func newUpdatesCollector(checker *updatesChecker) *updatesCollector {
+	labels := []string{"backend"}
+	return &updatesCollector{
+		checker: checker,
+		pendingDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, updatesSubsystem, "pending"),
+			"Number of pending package updates.",
+			labels, nil,
+		),
+		securityDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, updatesSubsystem, "security_pending"),
+			"Number of pending security updates.",
+			labels, nil,
+		),
+		rebootRequiredDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, updatesSubsystem, "reboot_required"),
+			"1 if the package manager reports that a reboot is required, 0 otherwise.",
+			labels, nil,
+		),
+		successDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, updatesSubsystem, "check_success"),
+			"1 if the last check for updates succeeded, 0 otherwise.",
+			labels, nil,
+		),
+		lastCheckDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, updatesSubsystem, "last_check_timestamp_seconds"),
+			"Unixtime the last check for updates finished.",
+			labels, nil,
+		),
+	}
+}
+
+func newUpdatesChecker() (*updatesChecker, error) {
+	for _, b := range updatesBackends {
+		if *updatesBackend != "auto" && *updatesBackend != b.name {
+			continue
+		}
+		if _, err := exec.LookPath(b.binary); err != nil {
+			if *updatesBackend == b.name {
+				return nil, fmt.Errorf("couldn't find %s: %s", b.binary, err)
+			}
+			continue
+		}
+		return &updatesChecker{backend: b.name, check: b.check}, nil
+	}
+	return nil, fmt.Errorf("no supported package manager found")
+}
+
+// run checks for updates right away and then at every interval.
+func (c *updatesChecker) run() {
+	ticker := time.NewTicker(*updatesInterval)
+	defer ticker.Stop()
+	for {
+		status, err := c.check()
+		if err != nil {
+			log.Errorf("Error checking for %s updates: %s", c.backend, err)
+		}
+
+		c.mtx.Lock()
+		// A failed check keeps the previous result, but is reported
+		// as such.
+		if err == nil {
+			c.status = status
+		}
+		c.success = err == nil
+		c.timestamp = time.Now()
+		c.mtx.Unlock()
+
+		<-ticker.C
+	}
+}
+
+// runUpdatesCommand runs a package manager command, returning its output
+// and exit code. Package managers use exit codes to signal available updates,
+// so it's up to the caller to interpret them.
+func runUpdatesCommand(name string, args ...string) ([]byte, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), *updatesTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && ctx.Err() == nil {
+		return out, exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s %s: %s", name, strings.Join(args, " "), err)
+	}
+	return out, 0, nil
+}
+
+func checkAptUpdates() (updatesStatus, error) {
+	out, code, err := runUpdatesCommand("apt-get", "--just-print", "dist-upgrade")
+	if err != nil {
+		return updatesStatus{}, err
+	}
+	if code != 0 {
+		return updatesStatus{}, fmt.Errorf("apt-get exited with %d", code)
+	}
+
+	status := parseAptUpgrade(out)
+	rebootRequired := 0.0
+	if _, err := os.Stat("/var/run/reboot-required"); err == nil {
+		rebootRequired = 1
+	}
+	status.rebootRequired = &rebootRequired
+	return status, nil
+}
+
+// parseAptUpgrade counts the packages apt-get would install, e.g.
+//
+//	Inst libssl1.1 [1.1.1c-1] (1.1.1d-0+deb10u1 Debian-Security:10/stable [amd64])
+//
+// Updates are security updates if they come from a security suite.
+func parseAptUpgrade(out []byte) updatesStatus {
+	var status updatesStatus
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Inst ") {
+			continue
+		}
+		status.pending++
+		if strings.Contains(strings.ToLower(line), "-security") {
+			status.security++
+		}
+	}
+	return status
+}
+
+func checkDnfUpdates() (updatesStatus, error) {
+	var status updatesStatus
+	for _, check := range []struct {
+		value *float64
+		args  []string
+	}{
+		{&status.pending, []string{"--quiet", "check-update"}},
+		{&status.security, []string{"--quiet", "check-update", "--security"}},
+	} {
+		out, code, err := runUpdatesCommand("dnf", check.args...)
+		if err != nil {
+			return updatesStatus{}, err
+		}
+		// check-update exits with 100 if updates are available.
+		if code != 0 && code != 100 {
+			return updatesStatus{}, fmt.Errorf("dnf exited with %d", code)
+		}
+		*check.value = parseDnfCheckUpdate(out)
+	}
+
+	// needs-restarting is part of dnf-plugins-core which may not be
+	// installed, it exits with 1 if a reboot is required.
+	if _, code, err := runUpdatesCommand("dnf", "--quiet", "needs-restarting", "--reboothint"); err == nil && (code == 0 || code == 1) {
+		rebootRequired := float64(code)
+		status.rebootRequired = &rebootRequired
+	}
+	return status, nil
+}
+
+// parseDnfCheckUpdate counts the packages listed by dnf check-update, e.g.
+//
+//	kernel.x86_64    5.3.7-301.fc31    updates
+//
+// stopping at the list of obsoleted packages.
+func parseDnfCheckUpdate(out []byte) float64 {
+	var pending float64
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Obsoleting") {
+			break
+		}
+		if fields := strings.Fields(line); len(fields) == 3 && strings.Contains(fields[0], ".") {
+			pending++
+		}
+	}
+	return pending
+}
+
+func checkZypperUpdates() (updatesStatus, error) {
+	var status updatesStatus
+	for _, check := range []struct {
+		value *float64
+		args  []string
+	}{
+		{&status.pending, []string{"--non-interactive", "--quiet", "list-updates"}},
+		{&status.security, []string{"--non-interactive", "--quiet", "list-patches", "--category", "security"}},
+	} {
+		out, code, err := runUpdatesCommand("zypper", check.args...)
+		if err != nil {
+			return updatesStatus{}, err
+		}
+		// 100 and 101 signal that (security) patches are needed.
+		if code != 0 && code != 100 && code != 101 {
+			return updatesStatus{}, fmt.Errorf("zypper exited with %d", code)
+		}
+		*check.value = parseZypperTable(out)
+	}
+
+	// needs-rebooting exits with 102 if a reboot is required.
+	if _, code, err := runUpdatesCommand("zypper", "--non-interactive", "--quiet", "needs-rebooting"); err == nil && (code == 0 || code == 102) {
+		rebootRequired := 0.0
+		if code == 102 {
+			rebootRequired = 1
+		}
+		status.rebootRequired = &rebootRequired
+	}
+	return status, nil
+}
+
+// parseZypperTable counts the rows of a zypper table, skipping the header
+// and its separator line.
+func parseZypperTable(out []byte) float64 {
+	var (
+		rows   float64
+		header = true
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.Contains(line, "-+-"):
+			header = false
+		case !header && strings.Contains(line, "|"):
+			rows++
+		}
+	}
+	return rows
+}
+
+// Update implements the Collector interface.
+func (c *updatesCollector) Update(ch chan<- prometheus.Metric) error {
+	c.checker.mtx.Lock()
+	defer c.checker.mtx.Unlock()
+
+	// Nothing is known before the first check finished.
+	if c.checker.timestamp.IsZero() {
+		return nil
+	}
+
+	backend := c.checker.backend
+	success := 0.0
+	if c.checker.success {
+		success = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.successDesc, prometheus.GaugeValue, success, backend)
+	ch <- prometheus.MustNewConstMetric(c.lastCheckDesc, prometheus.GaugeValue, float64(c.checker.timestamp.UnixNano())/1e9, backend)
+	ch <- prometheus.MustNewConstMetric(c.pendingDesc, prometheus.GaugeValue, c.checker.status.pending, backend)
+	ch <- prometheus.MustNewConstMetric(c.securityDesc, prometheus.GaugeValue, c.checker.status.security, backend)
+	if c.checker.status.rebootRequired != nil {
+		ch <- prometheus.MustNewConstMetric(c.rebootRequiredDesc, prometheus.GaugeValue, *c.checker.status.rebootRequired, backend)
+	}
+	return nil
+}
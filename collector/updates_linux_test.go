@@ -0,0 +1,99 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noupdates
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestParseAptUpgrade(t *testing.T) {
+	out := `NOTE: This is only a simulation!
+Reading package lists...
+Building dependency tree...
+The following packages will be upgraded:
+  libssl1.1 openssl tzdata
+3 upgraded, 0 newly installed, 0 to remove and 0 not upgraded.
+Inst libssl1.1 [1.1.1c-1] (1.1.1d-0+deb10u1 Debian-Security:10/stable [amd64])
+Inst openssl [1.1.1c-1] (1.1.1d-0+deb10u1 Debian-Security:10/stable [amd64])
+Inst tzdata [2019b-0+deb10u1] (2019c-0+deb10u1 Debian:10.1/stable [all])
+Conf libssl1.1 (1.1.1d-0+deb10u1 Debian-Security:10/stable [amd64])
+Conf openssl (1.1.1d-0+deb10u1 Debian-Security:10/stable [amd64])
+Conf tzdata (2019c-0+deb10u1 Debian:10.1/stable [all])
+`
+	status := parseAptUpgrade([]byte(out))
+	if status.pending != 3 || status.security != 2 {
+		t.Errorf("want 3 pending and 2 security updates, got %+v", status)
+	}
+}
+
+func TestParseDnfCheckUpdate(t *testing.T) {
+	out := `
+kernel.x86_64                     5.3.7-301.fc31                updates
+openssl-libs.x86_64               1:1.1.1d-2.fc31               updates
+Obsoleting Packages
+grub2-tools.x86_64                1:2.02-100.fc31               updates
+    grub2-tools.x86_64            1:2.02-96.fc31                @updates
+`
+	if pending := parseDnfCheckUpdate([]byte(out)); pending != 2 {
+		t.Errorf("want 2 pending updates, got %v", pending)
+	}
+}
+
+func TestParseZypperTable(t *testing.T) {
+	out := `S | Repository | Name    | Current Version | Available Version | Arch
+--+------------+---------+-----------------+-------------------+-------
+v | Updates    | openssl | 1.1.1d-1.1      | 1.1.1d-2.1        | x86_64
+v | Updates    | vim     | 8.1.2-1.1       | 8.1.3-1.1         | x86_64
+`
+	if rows := parseZypperTable([]byte(out)); rows != 2 {
+		t.Errorf("want 2 rows, got %v", rows)
+	}
+	if rows := parseZypperTable([]byte("No updates found.\n")); rows != 0 {
+		t.Errorf("want no rows, got %v", rows)
+	}
+}
+
+func TestUpdatesCollectorRebootRequired(t *testing.T) {
+	// The reboot hint of the package manager is exposed whether the reboot
+	// collector runs or not.
+	rebootRequired := 1.0
+	c := newUpdatesCollector(&updatesChecker{
+		backend:   "dnf",
+		status:    updatesStatus{pending: 3, rebootRequired: &rebootRequired},
+		success:   true,
+		timestamp: time.Now(),
+	})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectorAdapter{c})
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	for _, mf := range mfs {
+		got[mf.GetName()] = mf.GetMetric()[0].GetGauge().GetValue()
+	}
+	if want := 1.0; got["node_updates_reboot_required"] != want {
+		t.Errorf("want node_updates_reboot_required %f, got %f", want, got["node_updates_reboot_required"])
+	}
+	if _, ok := got["node_reboot_required"]; ok {
+		t.Error("unexpected node_reboot_required of the reboot collector")
+	}
+}
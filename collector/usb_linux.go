@@ -0,0 +1,105 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nousb
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const usbSubsystem = "usb"
+
+type usbCollector struct {
+	infoDesc        *prometheus.Desc
+	urbsDesc        *prometheus.Desc
+	overCurrentDesc *prometheus.Desc
+}
+
+func init() {
+	registerCollector(usbSubsystem, defaultDisabled, NewUSBCollector)
+}
+
+// NewUSBCollector returns a new Collector exposing the connected USB devices
+// and over-current conditions of USB ports.
+func NewUSBCollector() (Collector, error) {
+	return &usbCollector{
+		infoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, usbSubsystem, "device_info"),
+			"Connected USB device. The speed is in Mbit/s.",
+			[]string{"device", "vendor_id", "product_id", "manufacturer", "product", "class", "speed"}, nil,
+		),
+		urbsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, usbSubsystem, "device_urbs_total"),
+			"Number of USB request blocks submitted to the device.",
+			[]string{"device"}, nil,
+		),
+		overCurrentDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, usbSubsystem, "port_over_current_total"),
+			"Number of over-current conditions of the USB port.",
+			[]string{"port"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *usbCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("bus/usb/devices/*/idVendor"))
+	if err != nil {
+		return err
+	}
+	for _, path := range devices {
+		dir := filepath.Dir(path)
+		device := filepath.Base(dir)
+
+		labels := []string{device}
+		// Devices without manufacturer or product strings are still
+		// exposed.
+		for _, file := range []string{"idVendor", "idProduct", "manufacturer", "product", "bDeviceClass", "speed"} {
+			value, err := readTrimmedFile(filepath.Join(dir, file))
+			if err != nil && !os.IsNotExist(err) {
+				// The device may have been disconnected in the
+				// meantime.
+				log.Debugf("Couldn't read %s of USB device %s: %s", file, device, err)
+			}
+			labels = append(labels, value)
+		}
+		ch <- prometheus.MustNewConstMetric(c.infoDesc, prometheus.GaugeValue, 1, labels...)
+
+		if urbs, err := readUintFromFile(filepath.Join(dir, "urbnum")); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.urbsDesc, prometheus.CounterValue, float64(urbs), device)
+		}
+	}
+
+	// Ports are children of the interfaces of hubs, over-current counts
+	// are available since Linux 4.13.
+	ports, err := filepath.Glob(sysFilePath("bus/usb/devices/*/*-port*/over_current_count"))
+	if err != nil {
+		return err
+	}
+	for _, path := range ports {
+		port := filepath.Base(filepath.Dir(path))
+		count, err := readUintFromFile(path)
+		if err != nil {
+			return fmt.Errorf("couldn't read over-current count of USB port %s: %s", port, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.overCurrentDesc, prometheus.CounterValue, float64(count), port)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !novirtualization
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/procfs"
+)
+
+const virtualizationSubsystem = "virtualization"
+
+var (
+	// virtualizationVendors maps substrings of DMI fields to hypervisors,
+	// in the order they are checked in.
+	virtualizationVendors = []struct {
+		field, substring, hypervisor string
+	}{
+		{"product_name", "KVM", "kvm"},
+		{"sys_vendor", "QEMU", "kvm"},
+		{"sys_vendor", "Amazon EC2", "kvm"},
+		{"product_name", "Google Compute Engine", "kvm"},
+		{"sys_vendor", "VMware", "vmware"},
+		{"product_name", "Virtual Machine", "hyperv"},
+		{"sys_vendor", "Xen", "xen"},
+		{"product_name", "VirtualBox", "virtualbox"},
+		{"sys_vendor", "Parallels", "parallels"},
+		{"bios_vendor", "BHYVE", "bhyve"},
+	}
+
+	// virtualizationAgents are the process names of guest agents by agent.
+	virtualizationAgents = map[string]string{
+		"qemu-ga":       "qemu-guest-agent",
+		"vmtoolsd":      "vmware-tools",
+		"hv_kvp_daemon": "hyperv-kvp",
+		"VBoxService":   "virtualbox-guest-additions",
+	}
+)
+
+type virtualizationCollector struct {
+	fs                  procfs.FS
+	infoDesc            *prometheus.Desc
+	stealDesc           *prometheus.Desc
+	balloonTargetDesc   *prometheus.Desc
+	balloonCurrentDesc  *prometheus.Desc
+	balloonInflatedDesc *prometheus.Desc
+	agentDesc           *prometheus.Desc
+}
+
+func init() {
+	registerCollector("virtualization", defaultDisabled, NewVirtualizationCollector)
+}
+
+// NewVirtualizationCollector returns a new Collector exposing the hypervisor
+// a guest runs on, its steal time, memory balloon and guest agents.
+func NewVirtualizationCollector() (Collector, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %v", err)
+	}
+
+	return &virtualizationCollector{
+		fs: fs,
+		infoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, virtualizationSubsystem, "info"),
+			"Hypervisor the system runs on, none on bare metal and other for unknown hypervisors.",
+			[]string{"hypervisor"}, nil,
+		),
+		stealDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, virtualizationSubsystem, "steal_seconds_total"),
+			"Seconds the virtual CPUs waited for the hypervisor, summed over all CPUs.",
+			nil, nil,
+		),
+		balloonTargetDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, virtualizationSubsystem, "balloon_target_bytes"),
+			"Memory the hypervisor requested the guest to have.",
+			[]string{"driver"}, nil,
+		),
+		balloonCurrentDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, virtualizationSubsystem, "balloon_current_bytes"),
+			"Memory the guest currently has according to the balloon driver.",
+			[]string{"driver"}, nil,
+		),
+		balloonInflatedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, virtualizationSubsystem, "balloon_inflated_bytes"),
+			"Memory taken from the guest by the balloon driver.",
+			[]string{"driver"}, nil,
+		),
+		agentDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, virtualizationSubsystem, "guest_agent_running"),
+			"1 if the guest agent is running, 0 otherwise.",
+			[]string{"agent"}, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *virtualizationCollector) Update(ch chan<- prometheus.Metric) error {
+	hypervisor, err := c.detectHypervisor()
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.infoDesc, prometheus.GaugeValue, 1, hypervisor)
+
+	stat, err := c.fs.Stat()
+	if err != nil {
+		return fmt.Errorf("couldn't get stat: %s", err)
+	}
+	ch <- prometheus.MustNewConstMetric(c.stealDesc, prometheus.CounterValue, stat.CPUTotal.Steal)
+
+	if err := c.updateBalloon(ch); err != nil {
+		return err
+	}
+	return c.updateAgents(ch)
+}
+
+// detectHypervisor detects the hypervisor from the Xen hypervisor type, the
+// DMI system information and the hypervisor CPU flag.
+func (c *virtualizationCollector) detectHypervisor() (string, error) {
+	if t, err := readTrimmedFile(sysFilePath("hypervisor/type")); err == nil && t == "xen" {
+		return "xen", nil
+	}
+
+	dmi := map[string]string{}
+	for _, field := range []string{"sys_vendor", "product_name", "bios_vendor"} {
+		if value, err := readTrimmedFile(sysFilePath("class/dmi/id/" + field)); err == nil {
+			dmi[field] = value
+		}
+	}
+	for _, v := range virtualizationVendors {
+		if strings.Contains(dmi[v.field], v.substring) {
+			return v.hypervisor, nil
+		}
+	}
+
+	cpus, err := c.fs.CPUInfo()
+	if err != nil {
+		return "", fmt.Errorf("couldn't get cpuinfo: %s", err)
+	}
+	if len(cpus) > 0 {
+		for _, flag := range cpus[0].Flags {
+			if flag == "hypervisor" {
+				return "other", nil
+			}
+		}
+	}
+	return "none", nil
+}
+
+// updateBalloon exposes the state of the memory balloon drivers. The Xen
+// driver exposes its target and current size in sysfs, the VMware driver in
+// debugfs, while the virtio driver only counts inflated and deflated pages.
+func (c *virtualizationCollector) updateBalloon(ch chan<- prometheus.Metric) error {
+	xen := sysFilePath("devices/system/xen_memory/xen_memory0")
+	if target, err := readUintFromFile(xen + "/target_kb"); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.balloonTargetDesc, prometheus.GaugeValue, float64(target*1024), "xen")
+		if current, err := readUintFromFile(xen + "/info/current_kb"); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.balloonCurrentDesc, prometheus.GaugeValue, float64(current*1024), "xen")
+		}
+	}
+
+	if f, err := os.Open(sysFilePath("kernel/debug/vmmemctl")); err == nil {
+		defer f.Close()
+		target, current, err := parseVMwareBalloon(f)
+		if err != nil {
+			return fmt.Errorf("couldn't parse vmmemctl: %s", err)
+		}
+		pageSize := float64(os.Getpagesize())
+		ch <- prometheus.MustNewConstMetric(c.balloonTargetDesc, prometheus.GaugeValue, target*pageSize, "vmware")
+		ch <- prometheus.MustNewConstMetric(c.balloonCurrentDesc, prometheus.GaugeValue, current*pageSize, "vmware")
+	}
+
+	if _, err := os.Stat(sysFilePath("bus/virtio/drivers/virtio_balloon")); err == nil {
+		inflated, err := virtioBalloonPages(procFilePath("vmstat"))
+		if err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(c.balloonInflatedDesc, prometheus.GaugeValue, inflated*float64(os.Getpagesize()), "virtio")
+	}
+	return nil
+}
+
+// parseVMwareBalloon parses the target and current size in pages from the
+// vmmemctl debugfs file of the VMware balloon driver, e.g.
+//
+//	target:             12345 pages
+//	current:            12345 pages
+func parseVMwareBalloon(r io.Reader) (float64, float64, error) {
+	var (
+		values  = map[string]float64{}
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[2] != "pages" {
+			continue
+		}
+		key := strings.TrimSuffix(fields[0], ":")
+		if key != "target" && key != "current" {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid %s %q", key, fields[1])
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+	target, ok := values["target"]
+	current, ok2 := values["current"]
+	if !ok || !ok2 {
+		return 0, 0, fmt.Errorf("target or current size missing")
+	}
+	return target, current, nil
+}
+
+// virtioBalloonPages returns the number of pages the virtio balloon currently
+// holds, i.e. the pages inflated minus the pages deflated since boot.
+func virtioBalloonPages(path string) (float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var inflated, deflated float64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		var target *float64
+		switch fields[0] {
+		case "balloon_inflate":
+			target = &inflated
+		case "balloon_deflate":
+			target = &deflated
+		default:
+			continue
+		}
+		if *target, err = strconv.ParseFloat(fields[1], 64); err != nil {
+			return 0, fmt.Errorf("invalid %s %q", fields[0], fields[1])
+		}
+	}
+	return inflated - deflated, scanner.Err()
+}
+
+func (c *virtualizationCollector) updateAgents(ch chan<- prometheus.Metric) error {
+	procs, err := c.fs.AllProcs()
+	if err != nil {
+		return fmt.Errorf("couldn't get processes: %s", err)
+	}
+
+	running := make(map[string]float64, len(virtualizationAgents))
+	for _, agent := range virtualizationAgents {
+		running[agent] = 0
+	}
+	for _, p := range procs {
+		comm, err := p.Comm()
+		if err != nil {
+			// The process may have exited in the meantime.
+			log.Debugf("Couldn't get comm of process %d: %s", p.PID, err)
+			continue
+		}
+		if agent, ok := virtualizationAgents[comm]; ok {
+			running[agent] = 1
+		}
+	}
+
+	for agent, value := range running {
+		ch <- prometheus.MustNewConstMetric(c.agentDesc, prometheus.GaugeValue, value, agent)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !novirtualization
+
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVMwareBalloon(t *testing.T) {
+	vmmemctl := `balloon capabilities:   0x1e
+used capabilities:      0x6
+is resetting:           n
+target:                  26214 pages
+current:                 25600 pages
+rateSleepAlloc:           2048 pages/sec
+`
+	target, current, err := parseVMwareBalloon(strings.NewReader(vmmemctl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != 26214 || current != 25600 {
+		t.Errorf("want target 26214 and current 25600, got %v and %v", target, current)
+	}
+
+	if _, _, err := parseVMwareBalloon(strings.NewReader("target: 1 pages\n")); err == nil {
+		t.Error("expected error for missing current size")
+	}
+}
+
+func TestVirtioBalloonPages(t *testing.T) {
+	pages, err := virtioBalloonPages("fixtures/proc/vmstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages != 0 {
+		t.Errorf("want 0 balloon pages, got %v", pages)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -32,11 +33,13 @@ const (
 )
 
 var (
-	vmStatFields = kingpin.Flag("collector.vmstat.fields", "Regexp of fields to return for vmstat collector.").Default("^(oom_kill|pgpg|pswp|pg.*fault).*").String()
+	vmStatFields  = kingpin.Flag("collector.vmstat.fields", "Regexp of fields to return for vmstat collector.").Default("^(oom_kill|pgpg|pswp|pg.*fault|allocstall|pgscan_direct|pgsteal_direct|compact_stall).*").String()
+	vmStatInclude = kingpin.Flag("collector.vmstat.include", "Field to return for vmstat collector in addition to those matching --collector.vmstat.fields, can be repeated.").Strings()
 )
 
 type vmStatCollector struct {
 	fieldPattern *regexp.Regexp
+	include      map[string]bool
 }
 
 func init() {
@@ -45,38 +48,86 @@ func init() {
 
 // NewvmStatCollector returns a new Collector exposing vmstat stats.
 func NewvmStatCollector() (Collector, error) {
-	pattern := regexp.MustCompile(*vmStatFields)
-	return &vmStatCollector{
+	pattern, err := regexp.Compile(*vmStatFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fields pattern: %s", err)
+	}
+	include := make(map[string]bool, len(*vmStatInclude))
+	for _, field := range *vmStatInclude {
+		include[field] = true
+	}
+	c := &vmStatCollector{
 		fieldPattern: pattern,
-	}, nil
+		include:      include,
+	}
+
+	// Log the fields actually exported, since they depend on the kernel
+	// version and configuration.
+	values, err := getVMStat()
+	if err != nil {
+		log.Debugf("Couldn't get vmstat fields: %s", err)
+		return c, nil
+	}
+	var fields []string
+	for _, v := range values {
+		if c.matches(v.field) {
+			fields = append(fields, v.field)
+		}
+	}
+	log.Infof("Exporting vmstat fields: %s", strings.Join(fields, ", "))
+	return c, nil
+}
+
+func (c *vmStatCollector) matches(field string) bool {
+	return c.include[field] || c.fieldPattern.MatchString(field)
 }
 
 func (c *vmStatCollector) Update(ch chan<- prometheus.Metric) error {
-	file, err := os.Open(procFilePath("vmstat"))
+	values, err := getVMStat()
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		parts := strings.Fields(scanner.Text())
-		value, err := strconv.ParseFloat(parts[1], 64)
-		if err != nil {
-			return err
-		}
-		if !c.fieldPattern.MatchString(parts[0]) {
+	for _, v := range values {
+		if !c.matches(v.field) {
 			continue
 		}
 
 		ch <- prometheus.MustNewConstMetric(
 			prometheus.NewDesc(
-				prometheus.BuildFQName(namespace, vmStatSubsystem, parts[0]),
-				fmt.Sprintf("/proc/vmstat information field %s.", parts[0]),
+				prometheus.BuildFQName(namespace, vmStatSubsystem, v.field),
+				fmt.Sprintf("/proc/vmstat information field %s.", v.field),
 				nil, nil),
 			prometheus.UntypedValue,
-			value,
+			v.value,
 		)
 	}
-	return scanner.Err()
+	return nil
+}
+
+type vmStatValue struct {
+	field string
+	value float64
+}
+
+// getVMStat returns the fields of /proc/vmstat in the order of the file.
+func getVMStat() ([]vmStatValue, error) {
+	file, err := os.Open(procFilePath("vmstat"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var (
+		values  []vmStatValue
+		scanner = bufio.NewScanner(file)
+	)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		value, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, vmStatValue{field: parts[0], value: value})
+	}
+	return values, scanner.Err()
 }
@@ -0,0 +1,176 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nowatchdog
+
+package collector
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/procfs"
+)
+
+const watchdogSubsystem = "watchdog"
+
+type watchdogCollector struct {
+	fs             procfs.FS
+	infoDesc       *prometheus.Desc
+	activeDesc     *prometheus.Desc
+	nowayoutDesc   *prometheus.Desc
+	openDesc       *prometheus.Desc
+	timeoutDesc    *prometheus.Desc
+	pretimeoutDesc *prometheus.Desc
+	timeleftDesc   *prometheus.Desc
+}
+
+func init() {
+	registerCollector(watchdogSubsystem, defaultDisabled, NewWatchdogCollector)
+}
+
+// NewWatchdogCollector returns a new Collector exposing the configuration of
+// the watchdog devices and whether they are armed.
+func NewWatchdogCollector() (Collector, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %v", err)
+	}
+
+	labels := []string{"device"}
+	return &watchdogCollector{
+		fs: fs,
+		infoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, watchdogSubsystem, "info"),
+			"Watchdog device and the identity of its driver, value is always 1.",
+			[]string{"device", "identity"}, nil,
+		),
+		activeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, watchdogSubsystem, "active"),
+			"1 if the watchdog timer is running, 0 otherwise.",
+			labels, nil,
+		),
+		nowayoutDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, watchdogSubsystem, "nowayout"),
+			"1 if the watchdog can't be stopped once started, 0 otherwise.",
+			labels, nil,
+		),
+		openDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, watchdogSubsystem, "open"),
+			"1 if a process holds the watchdog device open, 0 otherwise.",
+			labels, nil,
+		),
+		timeoutDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, watchdogSubsystem, "timeout_seconds"),
+			"Time after which the watchdog resets the system if it isn't pinged.",
+			labels, nil,
+		),
+		pretimeoutDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, watchdogSubsystem, "pretimeout_seconds"),
+			"Time before the timeout at which the watchdog raises a pretimeout interrupt, 0 if disabled.",
+			labels, nil,
+		),
+		timeleftDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, watchdogSubsystem, "timeleft_seconds"),
+			"Time left until the watchdog resets the system.",
+			labels, nil,
+		),
+	}, nil
+}
+
+// Update implements the Collector interface.
+func (c *watchdogCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("class/watchdog/watchdog*"))
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		return nil
+	}
+
+	open, err := c.openWatchdogs()
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range devices {
+		device := filepath.Base(dir)
+
+		identity, err := readTrimmedFile(filepath.Join(dir, "identity"))
+		if err != nil {
+			return fmt.Errorf("couldn't get identity of %s: %s", device, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.infoDesc, prometheus.GaugeValue, 1, device, identity)
+		ch <- prometheus.MustNewConstMetric(c.openDesc, prometheus.GaugeValue, open[device], device)
+
+		// The state and nowayout attributes were only added in Linux 4.9.
+		if state, err := readTrimmedFile(filepath.Join(dir, "state")); err == nil {
+			active := 0.0
+			if state == "active" {
+				active = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.activeDesc, prometheus.GaugeValue, active, device)
+		}
+		if nowayout, err := readUintFromFile(filepath.Join(dir, "nowayout")); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.nowayoutDesc, prometheus.GaugeValue, float64(nowayout), device)
+		}
+
+		// The timeouts only exist if supported by the driver.
+		for file, desc := range map[string]*prometheus.Desc{
+			"timeout":    c.timeoutDesc,
+			"pretimeout": c.pretimeoutDesc,
+			"timeleft":   c.timeleftDesc,
+		} {
+			value, err := readUintFromFile(filepath.Join(dir, file))
+			if err != nil {
+				log.Debugf("Couldn't get %s of %s: %s", file, device, err)
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value), device)
+		}
+	}
+	return nil
+}
+
+// openWatchdogs returns the watchdog devices open by any process. The legacy
+// /dev/watchdog device is the first watchdog, watchdog0.
+func (c *watchdogCollector) openWatchdogs() (map[string]float64, error) {
+	procs, err := c.fs.AllProcs()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get processes: %s", err)
+	}
+
+	open := map[string]float64{}
+	for _, p := range procs {
+		targets, err := p.FileDescriptorTargets()
+		if err != nil {
+			// The process may have exited in the meantime.
+			log.Debugf("Couldn't get file descriptors of process %d: %s", p.PID, err)
+			continue
+		}
+		for _, target := range targets {
+			if !strings.HasPrefix(target, "/dev/watchdog") {
+				continue
+			}
+			device := strings.TrimPrefix(target, "/dev/")
+			if device == "watchdog" {
+				device = "watchdog0"
+			}
+			open[device] = 1
+		}
+	}
+	return open, nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nowatchdog
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/procfs"
+)
+
+func TestOpenWatchdogs(t *testing.T) {
+	fs, err := procfs.NewFS("fixtures/proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &watchdogCollector{fs: fs}
+
+	open, err := c.openWatchdogs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 1 || open["watchdog0"] != 1 {
+		t.Errorf("want only watchdog0 open, got %v", open)
+	}
+}
@@ -0,0 +1,347 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nowireguard
+
+package collector
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/mdlayher/genetlink"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	wireguardSubsystem = "wireguard"
+
+	// Generic netlink family, command and attributes of linux/wireguard.h.
+	wireguardGenlName      = "wireguard"
+	wireguardGenlVersion   = 1
+	wireguardCmdGetDevice  = 0
+	wireguardDeviceIfname  = 2
+	wireguardDevicePubKey  = 4
+	wireguardDeviceListen  = 6
+	wireguardDevicePeers   = 8
+	wireguardPeerPubKey    = 1
+	wireguardPeerEndpoint  = 4
+	wireguardPeerKeepalive = 5
+	wireguardPeerHandshake = 6
+	wireguardPeerRxBytes   = 7
+	wireguardPeerTxBytes   = 8
+)
+
+// wireguardDevice is the configuration and peer statistics of a WireGuard
+// interface.
+type wireguardDevice struct {
+	name       string
+	publicKey  string
+	listenPort uint16
+	peers      []wireguardPeer
+}
+
+type wireguardPeer struct {
+	publicKey     string
+	endpoint      string
+	keepalive     time.Duration
+	lastHandshake time.Time
+	rxBytes       uint64
+	txBytes       uint64
+}
+
+type wireguardCollector struct {
+	deviceInfo    *prometheus.Desc
+	peerInfo      *prometheus.Desc
+	lastHandshake *prometheus.Desc
+	handshakeAge  *prometheus.Desc
+	rxBytes       *prometheus.Desc
+	txBytes       *prometheus.Desc
+	keepalive     *prometheus.Desc
+}
+
+func init() {
+	registerCollector(wireguardSubsystem, defaultDisabled, NewWireGuardCollector)
+}
+
+// NewWireGuardCollector returns a new Collector exposing WireGuard peer statistics.
+func NewWireGuardCollector() (Collector, error) {
+	peerDesc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, wireguardSubsystem, name),
+			help, []string{"device", "public_key"}, nil,
+		)
+	}
+	return &wireguardCollector{
+		deviceInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, wireguardSubsystem, "device_info"),
+			"Public key and listen port of the WireGuard interface, value is always 1.",
+			[]string{"device", "public_key", "listen_port"}, nil,
+		),
+		peerInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, wireguardSubsystem, "peer_info"),
+			"Current endpoint of the WireGuard peer, value is always 1.",
+			[]string{"device", "public_key", "endpoint"}, nil,
+		),
+		lastHandshake: peerDesc("peer_last_handshake_timestamp_seconds", "Time of the last handshake with the peer, 0 if there was none."),
+		handshakeAge:  peerDesc("peer_last_handshake_age_seconds", "Seconds since the last handshake with the peer, not exposed if there was none."),
+		rxBytes:       peerDesc("peer_receive_bytes_total", "Number of bytes received from the peer."),
+		txBytes:       peerDesc("peer_transmit_bytes_total", "Number of bytes transmitted to the peer."),
+		keepalive:     peerDesc("peer_persistent_keepalive_interval_seconds", "Configured persistent keepalive interval of the peer, 0 if disabled."),
+	}, nil
+}
+
+func (c *wireguardCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := wireguardDevices()
+	if err != nil {
+		return err
+	}
+	// Avoid talking to generic netlink on hosts without WireGuard.
+	if len(devices) == 0 {
+		return nil
+	}
+
+	conn, err := genetlink.Dial(nil)
+	if err != nil {
+		return fmt.Errorf("couldn't connect to generic netlink: %s", err)
+	}
+	defer conn.Close()
+
+	family, err := conn.GetFamily(wireguardGenlName)
+	if err != nil {
+		return fmt.Errorf("couldn't get wireguard generic netlink family: %s", err)
+	}
+
+	now := time.Now()
+	for _, name := range devices {
+		device, err := getWireGuardDevice(conn, family.ID, name)
+		if err != nil {
+			return err
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.deviceInfo, prometheus.GaugeValue, 1,
+			device.name, device.publicKey, strconv.Itoa(int(device.listenPort)))
+
+		for _, p := range device.peers {
+			ch <- prometheus.MustNewConstMetric(c.peerInfo, prometheus.GaugeValue, 1, device.name, p.publicKey, p.endpoint)
+			ch <- prometheus.MustNewConstMetric(c.rxBytes, prometheus.CounterValue, float64(p.rxBytes), device.name, p.publicKey)
+			ch <- prometheus.MustNewConstMetric(c.txBytes, prometheus.CounterValue, float64(p.txBytes), device.name, p.publicKey)
+			ch <- prometheus.MustNewConstMetric(c.keepalive, prometheus.GaugeValue, p.keepalive.Seconds(), device.name, p.publicKey)
+
+			if p.lastHandshake.IsZero() {
+				ch <- prometheus.MustNewConstMetric(c.lastHandshake, prometheus.GaugeValue, 0, device.name, p.publicKey)
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(c.lastHandshake, prometheus.GaugeValue,
+				float64(p.lastHandshake.UnixNano())/1e9, device.name, p.publicKey)
+			ch <- prometheus.MustNewConstMetric(c.handshakeAge, prometheus.GaugeValue,
+				now.Sub(p.lastHandshake).Seconds(), device.name, p.publicKey)
+		}
+	}
+
+	return nil
+}
+
+// wireguardDevices returns the names of the WireGuard interfaces.
+func wireguardDevices() ([]string, error) {
+	files, err := filepath.Glob(sysFilePath("class/net/*/uevent"))
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []string
+	for _, file := range files {
+		uevent, err := ioutil.ReadFile(file)
+		if err != nil {
+			// Interfaces may be removed while iterating.
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if bytes.Contains(uevent, []byte("DEVTYPE=wireguard\n")) {
+			devices = append(devices, filepath.Base(filepath.Dir(file)))
+		}
+	}
+	return devices, nil
+}
+
+func getWireGuardDevice(conn *genetlink.Conn, family uint16, name string) (*wireguardDevice, error) {
+	ae := netlink.NewAttributeEncoder()
+	ae.String(wireguardDeviceIfname, name)
+	attrs, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	// Requires CAP_NET_ADMIN. Devices with many peers are split over
+	// several messages.
+	msgs, err := conn.Execute(genetlink.Message{
+		Header: genetlink.Header{
+			Command: wireguardCmdGetDevice,
+			Version: wireguardGenlVersion,
+		},
+		Data: attrs,
+	}, family, netlink.Request|netlink.Dump)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get wireguard device %s: %s", name, err)
+	}
+
+	device, err := parseWireGuardDevice(msgs)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse wireguard device %s: %s", name, err)
+	}
+	return device, nil
+}
+
+// parseWireGuardDevice merges the WG_CMD_GET_DEVICE reply messages. A peer
+// whose allowed IPs don't fit in one message is continued in the next one,
+// repeating only its public key, so peers are merged by public key.
+func parseWireGuardDevice(msgs []genetlink.Message) (*wireguardDevice, error) {
+	device := &wireguardDevice{}
+	seen := map[string]int{}
+	for _, m := range msgs {
+		ad, err := netlink.NewAttributeDecoder(m.Data)
+		if err != nil {
+			return nil, err
+		}
+		for ad.Next() {
+			switch ad.Type() &^ unix.NLA_F_NESTED {
+			case wireguardDeviceIfname:
+				device.name = ad.String()
+			case wireguardDevicePubKey:
+				device.publicKey = base64.StdEncoding.EncodeToString(ad.Bytes())
+			case wireguardDeviceListen:
+				device.listenPort = ad.Uint16()
+			case wireguardDevicePeers:
+				ad.Do(func(b []byte) error {
+					peers, err := parseWireGuardPeers(b)
+					for _, p := range peers {
+						if i, ok := seen[p.publicKey]; ok {
+							device.peers[i].merge(p)
+							continue
+						}
+						seen[p.publicKey] = len(device.peers)
+						device.peers = append(device.peers, p)
+					}
+					return err
+				})
+			}
+		}
+		if err := ad.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return device, nil
+}
+
+// merge copies the attributes set in a continuation of the same peer.
+func (p *wireguardPeer) merge(o wireguardPeer) {
+	if o.endpoint != "" {
+		p.endpoint = o.endpoint
+	}
+	if o.keepalive != 0 {
+		p.keepalive = o.keepalive
+	}
+	if !o.lastHandshake.IsZero() {
+		p.lastHandshake = o.lastHandshake
+	}
+	if o.rxBytes != 0 {
+		p.rxBytes = o.rxBytes
+	}
+	if o.txBytes != 0 {
+		p.txBytes = o.txBytes
+	}
+}
+
+// parseWireGuardPeers parses the nested WGDEVICE_A_PEERS attribute.
+func parseWireGuardPeers(b []byte) ([]wireguardPeer, error) {
+	ad, err := netlink.NewAttributeDecoder(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var peers []wireguardPeer
+	for ad.Next() {
+		var p wireguardPeer
+		ad.Do(func(b []byte) error {
+			pad, err := netlink.NewAttributeDecoder(b)
+			if err != nil {
+				return err
+			}
+			for pad.Next() {
+				switch pad.Type() &^ unix.NLA_F_NESTED {
+				case wireguardPeerPubKey:
+					p.publicKey = base64.StdEncoding.EncodeToString(pad.Bytes())
+				case wireguardPeerEndpoint:
+					p.endpoint = parseWireGuardEndpoint(pad.Bytes())
+				case wireguardPeerKeepalive:
+					p.keepalive = time.Duration(pad.Uint16()) * time.Second
+				case wireguardPeerHandshake:
+					// struct __kernel_timespec, all zero if there was no handshake.
+					ts := pad.Bytes()
+					if len(ts) != 16 {
+						return fmt.Errorf("invalid last handshake time length %d", len(ts))
+					}
+					sec, nsec := int64(nlenc.Uint64(ts[0:8])), int64(nlenc.Uint64(ts[8:16]))
+					if sec != 0 || nsec != 0 {
+						p.lastHandshake = time.Unix(sec, nsec)
+					}
+				case wireguardPeerRxBytes:
+					p.rxBytes = pad.Uint64()
+				case wireguardPeerTxBytes:
+					p.txBytes = pad.Uint64()
+				}
+			}
+			return pad.Err()
+		})
+		peers = append(peers, p)
+	}
+	return peers, ad.Err()
+}
+
+// parseWireGuardEndpoint converts a struct sockaddr_in or sockaddr_in6 to
+// "host:port".
+func parseWireGuardEndpoint(b []byte) string {
+	if len(b) < 4 {
+		return ""
+	}
+	var ip net.IP
+	switch nlenc.Uint16(b[0:2]) {
+	case unix.AF_INET:
+		if len(b) < 8 {
+			return ""
+		}
+		ip = net.IP(b[4:8])
+	case unix.AF_INET6:
+		if len(b) < 24 {
+			return ""
+		}
+		ip = net.IP(b[8:24])
+	default:
+		return ""
+	}
+	port := binary.BigEndian.Uint16(b[2:4])
+	return net.JoinHostPort(ip.String(), strconv.Itoa(int(port)))
+}
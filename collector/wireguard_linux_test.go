@@ -0,0 +1,154 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mdlayher/genetlink"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseWireGuardDevice(t *testing.T) {
+	peer := func(key byte, endpoint []byte, handshake int64, rx, tx uint64) func() ([]byte, error) {
+		return func() ([]byte, error) {
+			ae := netlink.NewAttributeEncoder()
+			ae.Bytes(wireguardPeerPubKey, append(make([]byte, 31), key))
+			if endpoint != nil {
+				ae.Bytes(wireguardPeerEndpoint, endpoint)
+			}
+			ae.Uint16(wireguardPeerKeepalive, 25)
+			ts := make([]byte, 16)
+			nlenc.PutUint64(ts[0:8], uint64(handshake))
+			ae.Bytes(wireguardPeerHandshake, ts)
+			ae.Uint64(wireguardPeerRxBytes, rx)
+			ae.Uint64(wireguardPeerTxBytes, tx)
+			return ae.Encode()
+		}
+	}
+	message := func(peers ...func() ([]byte, error)) genetlink.Message {
+		ae := netlink.NewAttributeEncoder()
+		ae.String(wireguardDeviceIfname, "wg0")
+		ae.Uint16(wireguardDeviceListen, 51820)
+		ae.Do(wireguardDevicePeers|unix.NLA_F_NESTED, func() ([]byte, error) {
+			pae := netlink.NewAttributeEncoder()
+			for i, p := range peers {
+				pae.Do(uint16(i)|unix.NLA_F_NESTED, p)
+			}
+			return pae.Encode()
+		})
+		b, err := ae.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return genetlink.Message{Data: b}
+	}
+
+	sockaddr := make([]byte, 16)
+	nlenc.PutUint16(sockaddr[0:2], unix.AF_INET)
+	copy(sockaddr[2:8], []byte{0xca, 0x6c, 192, 0, 2, 1})
+
+	device, err := parseWireGuardDevice([]genetlink.Message{
+		message(peer(1, sockaddr, 1565000000, 100, 200)),
+		message(peer(2, nil, 0, 0, 0)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "wg0", device.name; want != got {
+		t.Errorf("want name %s, got %s", want, got)
+	}
+	if want, got := uint16(51820), device.listenPort; want != got {
+		t.Errorf("want listen port %d, got %d", want, got)
+	}
+	if want, got := 2, len(device.peers); want != got {
+		t.Fatalf("want %d peers, got %d", want, got)
+	}
+
+	p := device.peers[0]
+	if want, got := "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAE=", p.publicKey; want != got {
+		t.Errorf("want public key %s, got %s", want, got)
+	}
+	if want, got := "192.0.2.1:51820", p.endpoint; want != got {
+		t.Errorf("want endpoint %s, got %s", want, got)
+	}
+	if want, got := 25*time.Second, p.keepalive; want != got {
+		t.Errorf("want keepalive %s, got %s", want, got)
+	}
+	if want, got := time.Unix(1565000000, 0), p.lastHandshake; !want.Equal(got) {
+		t.Errorf("want last handshake %s, got %s", want, got)
+	}
+	if p.rxBytes != 100 || p.txBytes != 200 {
+		t.Errorf("want 100 received and 200 transmitted bytes, got %d and %d", p.rxBytes, p.txBytes)
+	}
+
+	if !device.peers[1].lastHandshake.IsZero() {
+		t.Errorf("want no handshake for second peer, got %s", device.peers[1].lastHandshake)
+	}
+	if want, got := "", device.peers[1].endpoint; want != got {
+		t.Errorf("want endpoint %q, got %q", want, got)
+	}
+}
+
+func TestParseWireGuardDeviceSplitPeer(t *testing.T) {
+	key := append(make([]byte, 31), 1)
+	message := func(peer func(ae *netlink.AttributeEncoder)) genetlink.Message {
+		ae := netlink.NewAttributeEncoder()
+		ae.String(wireguardDeviceIfname, "wg0")
+		ae.Do(wireguardDevicePeers|unix.NLA_F_NESTED, func() ([]byte, error) {
+			pae := netlink.NewAttributeEncoder()
+			pae.Do(unix.NLA_F_NESTED, func() ([]byte, error) {
+				ae := netlink.NewAttributeEncoder()
+				ae.Bytes(wireguardPeerPubKey, key)
+				peer(ae)
+				return ae.Encode()
+			})
+			return pae.Encode()
+		})
+		b, err := ae.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return genetlink.Message{Data: b}
+	}
+
+	// The continuation only repeats the public key, followed by the
+	// remaining allowed IPs which aren't exported.
+	device, err := parseWireGuardDevice([]genetlink.Message{
+		message(func(ae *netlink.AttributeEncoder) {
+			ae.Uint16(wireguardPeerKeepalive, 25)
+			ae.Uint64(wireguardPeerRxBytes, 100)
+			ae.Uint64(wireguardPeerTxBytes, 200)
+		}),
+		message(func(ae *netlink.AttributeEncoder) {}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 1, len(device.peers); want != got {
+		t.Fatalf("want %d peers, got %d", want, got)
+	}
+	p := device.peers[0]
+	if want, got := 25*time.Second, p.keepalive; want != got {
+		t.Errorf("want keepalive %s, got %s", want, got)
+	}
+	if p.rxBytes != 100 || p.txBytes != 200 {
+		t.Errorf("want 100 received and 200 transmitted bytes, got %d and %d", p.rxBytes, p.txBytes)
+	}
+}
@@ -11,8 +11,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// +build linux
-// +build !nozfs
+// +build linux,!nozfs
 
 package collector
 
@@ -72,7 +71,14 @@ func (c *zfsCollector) Update(ch chan<- prometheus.Metric) error {
 	}
 
 	// Pool stats
-	return c.updatePoolStats(ch)
+	poolStates, err := c.updatePoolStats(ch)
+	if err != nil {
+		return err
+	}
+
+	// Pool capacity and vdev errors are only available through the zpool
+	// command. Its health column is only used on releases without state kstats.
+	return updateZpoolCommandStats(ch, poolStates == 0)
 }
 
 func (s zfsSysctl) metricName() string {
@@ -160,6 +160,97 @@ func NewZfsCollector() (Collector, error) {
 				dataType:    bsdSysctlTypeUint64,
 				valueType:   prometheus.CounterValue,
 			},
+			{
+				name:        "arcstats_l2_asize_bytes",
+				description: "ZFS L2ARC allocated size",
+				mib:         "kstat.zfs.misc.arcstats.l2_asize",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.GaugeValue,
+			},
+			{
+				name:        "arcstats_l2_cksum_bad_total",
+				description: "ZFS L2ARC checksum failures",
+				mib:         "kstat.zfs.misc.arcstats.l2_cksum_bad",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.CounterValue,
+			},
+			{
+				name:        "arcstats_l2_hdr_bytes",
+				description: "ZFS L2ARC header size",
+				mib:         "kstat.zfs.misc.arcstats.l2_hdr_size",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.GaugeValue,
+			},
+			{
+				name:        "arcstats_l2_hits_total",
+				description: "ZFS L2ARC hits",
+				mib:         "kstat.zfs.misc.arcstats.l2_hits",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.CounterValue,
+			},
+			{
+				name:        "arcstats_l2_io_error_total",
+				description: "ZFS L2ARC I/O errors",
+				mib:         "kstat.zfs.misc.arcstats.l2_io_error",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.CounterValue,
+			},
+			{
+				name:        "arcstats_l2_misses_total",
+				description: "ZFS L2ARC misses",
+				mib:         "kstat.zfs.misc.arcstats.l2_misses",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.CounterValue,
+			},
+			{
+				name:        "arcstats_l2_read_bytes_total",
+				description: "ZFS L2ARC bytes read",
+				mib:         "kstat.zfs.misc.arcstats.l2_read_bytes",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.CounterValue,
+			},
+			{
+				name:        "arcstats_l2_size_bytes",
+				description: "ZFS L2ARC size",
+				mib:         "kstat.zfs.misc.arcstats.l2_size",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.GaugeValue,
+			},
+			{
+				name:        "arcstats_l2_write_bytes_total",
+				description: "ZFS L2ARC bytes written",
+				mib:         "kstat.zfs.misc.arcstats.l2_write_bytes",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.CounterValue,
+			},
+			{
+				name:        "arcstats_l2_writes_error_total",
+				description: "ZFS L2ARC write errors",
+				mib:         "kstat.zfs.misc.arcstats.l2_writes_error",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.CounterValue,
+			},
+			{
+				name:        "arcstats_memory_throttle_count_total",
+				description: "ZFS ARC memory throttles",
+				mib:         "kstat.zfs.misc.arcstats.memory_throttle_count",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.CounterValue,
+			},
+			{
+				name:        "arcstats_meta_limit_bytes",
+				description: "ZFS ARC metadata limit",
+				mib:         "kstat.zfs.misc.arcstats.arc_meta_limit",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.GaugeValue,
+			},
+			{
+				name:        "arcstats_meta_used_bytes",
+				description: "ZFS ARC metadata used",
+				mib:         "kstat.zfs.misc.arcstats.arc_meta_used",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.GaugeValue,
+			},
 			{
 				name:        "arcstats_mfu_ghost_hits_total",
 				description: "ZFS ARC MFU ghost hits",
@@ -216,6 +307,34 @@ func NewZfsCollector() (Collector, error) {
 				dataType:    bsdSysctlTypeUint64,
 				valueType:   prometheus.GaugeValue,
 			},
+			{
+				name:        "arcstats_prefetch_data_hits_total",
+				description: "ZFS ARC prefetch data hits",
+				mib:         "kstat.zfs.misc.arcstats.prefetch_data_hits",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.CounterValue,
+			},
+			{
+				name:        "arcstats_prefetch_data_misses_total",
+				description: "ZFS ARC prefetch data misses",
+				mib:         "kstat.zfs.misc.arcstats.prefetch_data_misses",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.CounterValue,
+			},
+			{
+				name:        "arcstats_prefetch_metadata_hits_total",
+				description: "ZFS ARC prefetch metadata hits",
+				mib:         "kstat.zfs.misc.arcstats.prefetch_metadata_hits",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.CounterValue,
+			},
+			{
+				name:        "arcstats_prefetch_metadata_misses_total",
+				description: "ZFS ARC prefetch metadata misses",
+				mib:         "kstat.zfs.misc.arcstats.prefetch_metadata_misses",
+				dataType:    bsdSysctlTypeUint64,
+				valueType:   prometheus.CounterValue,
+			},
 			{
 				name:        "arcstats_size_bytes",
 				description: "ZFS ARC size",
@@ -256,5 +375,6 @@ func (c *zfsCollector) Update(ch chan<- prometheus.Metric) error {
 			), m.valueType, v)
 	}
 
-	return nil
+	// Pool health, capacity and vdev errors are not exposed via sysctl.
+	return updateZpoolCommandStats(ch, true)
 }
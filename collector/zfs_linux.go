@@ -17,6 +17,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -63,22 +64,25 @@ func (c *zfsCollector) updateZfsStats(subsystem string, ch chan<- prometheus.Met
 	})
 }
 
-func (c *zfsCollector) updatePoolStats(ch chan<- prometheus.Metric) error {
+// updatePoolStats exposes the pool io and state kstats and returns the
+// number of pools a state was found for.
+func (c *zfsCollector) updatePoolStats(ch chan<- prometheus.Metric) (int, error) {
 	zpoolPaths, err := filepath.Glob(procFilePath(filepath.Join(c.linuxProcpathBase, c.linuxZpoolIoPath)))
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	if zpoolPaths == nil {
-		return nil
+		return 0, nil
 	}
 
+	states := 0
 	for _, zpoolPath := range zpoolPaths {
 		file, err := os.Open(zpoolPath)
 		if err != nil {
 			// this file should exist, but there is a race where an exporting pool can remove the files -- ok to ignore
 			log.Debugf("Cannot open %q for reading", zpoolPath)
-			return errZFSNotAvailable
+			return 0, errZFSNotAvailable
 		}
 
 		err = c.parsePoolProcfsFile(file, zpoolPath, func(poolName string, s zfsSysctl, v uint64) {
@@ -86,11 +90,21 @@ func (c *zfsCollector) updatePoolStats(ch chan<- prometheus.Metric) error {
 		})
 		file.Close()
 		if err != nil {
-			return err
+			return 0, err
+		}
+
+		// The state kstat is available since ZFS on Linux 0.8.
+		poolDir := filepath.Dir(zpoolPath)
+		state, err := ioutil.ReadFile(filepath.Join(poolDir, "state"))
+		if err != nil {
+			log.Debugf("Cannot read state of zpool %q: %s", filepath.Base(poolDir), err)
+			continue
 		}
+		zpoolStateMetrics(ch, zpoolStateDesc, strings.TrimSpace(string(state)), filepath.Base(poolDir))
+		states++
 	}
 
-	return nil
+	return states, nil
 }
 
 func (c *zfsCollector) parseProcfsFile(reader io.Reader, fmtExt string, handler func(zfsSysctl, uint64)) error {
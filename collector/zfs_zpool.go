@@ -0,0 +1,257 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build freebsd linux
+// +build !nozfs
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const zpoolCommandTimeout = 10 * time.Second
+
+var (
+	zpoolPath = kingpin.Flag("collector.zfs.zpool-path", "Path to the zpool command used to collect pool capacity and vdev errors. Disabled if empty.").Default("").String()
+
+	zpoolStates = []string{"online", "degraded", "faulted", "offline", "removed", "unavail", "suspended"}
+
+	zpoolStateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "zfs_zpool", "state"),
+		"Health state of the zpool.",
+		[]string{"zpool", "state"}, nil,
+	)
+	zpoolSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "zfs_zpool", "size_bytes"),
+		"Total size of the zpool.",
+		[]string{"zpool"}, nil,
+	)
+	zpoolAllocatedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "zfs_zpool", "allocated_bytes"),
+		"Amount of storage allocated within the zpool.",
+		[]string{"zpool"}, nil,
+	)
+	zpoolFreeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "zfs_zpool", "free_bytes"),
+		"Amount of unallocated storage within the zpool.",
+		[]string{"zpool"}, nil,
+	)
+	zpoolFragmentationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "zfs_zpool", "fragmentation_ratio"),
+		"Fragmentation of the free space within the zpool.",
+		[]string{"zpool"}, nil,
+	)
+	zpoolVdevStateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "zfs_zpool", "vdev_state"),
+		"Health state of the vdev.",
+		[]string{"zpool", "vdev", "state"}, nil,
+	)
+	zpoolVdevReadErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "zfs_zpool", "vdev_read_errors"),
+		"Number of read errors on the vdev since the last zpool clear.",
+		[]string{"zpool", "vdev"}, nil,
+	)
+	zpoolVdevWriteErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "zfs_zpool", "vdev_write_errors"),
+		"Number of write errors on the vdev since the last zpool clear.",
+		[]string{"zpool", "vdev"}, nil,
+	)
+	zpoolVdevChecksumErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "zfs_zpool", "vdev_checksum_errors"),
+		"Number of checksum errors on the vdev since the last zpool clear.",
+		[]string{"zpool", "vdev"}, nil,
+	)
+)
+
+// zpoolListEntry is a single line of `zpool list -Hp`.
+type zpoolListEntry struct {
+	name          string
+	size          float64
+	allocated     float64
+	free          float64
+	fragmentation float64
+	health        string
+}
+
+// zpoolVdev is a single vdev line of the config section of `zpool status -p`.
+type zpoolVdev struct {
+	pool        string
+	name        string
+	state       string
+	readErrors  float64
+	writeErrors float64
+	cksumErrors float64
+}
+
+func zpoolStateMetrics(ch chan<- prometheus.Metric, desc *prometheus.Desc, state string, labels ...string) {
+	state = strings.ToLower(state)
+	for _, s := range zpoolStates {
+		v := 0.0
+		if s == state {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, append(labels, s)...)
+	}
+}
+
+func runZpool(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), zpoolCommandTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, *zpoolPath, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %s", *zpoolPath, strings.Join(args, " "), err)
+	}
+	return out, nil
+}
+
+// updateZpoolCommandStats exposes pool capacity and vdev health as reported
+// by the zpool command. The pool health is only exposed if withHealth is set,
+// as it may already be known from the kernel statistics.
+func updateZpoolCommandStats(ch chan<- prometheus.Metric, withHealth bool) error {
+	if *zpoolPath == "" {
+		return nil
+	}
+
+	out, err := runZpool("list", "-Hp", "-o", "name,size,allocated,free,fragmentation,health")
+	if err != nil {
+		return err
+	}
+	pools, err := parseZpoolList(bytes.NewReader(out))
+	if err != nil {
+		return err
+	}
+	for _, p := range pools {
+		ch <- prometheus.MustNewConstMetric(zpoolSizeDesc, prometheus.GaugeValue, p.size, p.name)
+		ch <- prometheus.MustNewConstMetric(zpoolAllocatedDesc, prometheus.GaugeValue, p.allocated, p.name)
+		ch <- prometheus.MustNewConstMetric(zpoolFreeDesc, prometheus.GaugeValue, p.free, p.name)
+		if !math.IsNaN(p.fragmentation) {
+			ch <- prometheus.MustNewConstMetric(zpoolFragmentationDesc, prometheus.GaugeValue, p.fragmentation, p.name)
+		}
+		if withHealth {
+			zpoolStateMetrics(ch, zpoolStateDesc, p.health, p.name)
+		}
+	}
+
+	out, err = runZpool("status", "-p")
+	if err != nil {
+		return err
+	}
+	vdevs, err := parseZpoolStatus(bytes.NewReader(out))
+	if err != nil {
+		return err
+	}
+	for _, v := range vdevs {
+		zpoolStateMetrics(ch, zpoolVdevStateDesc, v.state, v.pool, v.name)
+		ch <- prometheus.MustNewConstMetric(zpoolVdevReadErrorsDesc, prometheus.GaugeValue, v.readErrors, v.pool, v.name)
+		ch <- prometheus.MustNewConstMetric(zpoolVdevWriteErrorsDesc, prometheus.GaugeValue, v.writeErrors, v.pool, v.name)
+		ch <- prometheus.MustNewConstMetric(zpoolVdevChecksumErrorsDesc, prometheus.GaugeValue, v.cksumErrors, v.pool, v.name)
+	}
+
+	return nil
+}
+
+func parseZpoolList(r io.Reader) ([]zpoolListEntry, error) {
+	var (
+		pools   []zpoolListEntry
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("unexpected zpool list line %q", scanner.Text())
+		}
+
+		p := zpoolListEntry{name: fields[0], health: fields[5]}
+		for i, v := range []*float64{&p.size, &p.allocated, &p.free, &p.fragmentation} {
+			s := strings.TrimSuffix(fields[i+1], "%")
+			if s == "-" {
+				*v = math.NaN()
+				continue
+			}
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q in zpool list: %s", fields[i+1], err)
+			}
+			*v = f
+		}
+		p.fragmentation /= 100
+		pools = append(pools, p)
+	}
+
+	return pools, scanner.Err()
+}
+
+func parseZpoolStatus(r io.Reader) ([]zpoolVdev, error) {
+	var (
+		vdevs    []zpoolVdev
+		pool     string
+		inConfig bool
+		scanner  = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+
+		switch {
+		case strings.HasPrefix(line, "pool:"):
+			pool = strings.TrimSpace(strings.TrimPrefix(line, "pool:"))
+			inConfig = false
+			continue
+		case len(fields) == 5 && fields[0] == "NAME" && fields[1] == "STATE":
+			inConfig = true
+			continue
+		case line == "spares":
+			// Spares only have a state and a description, e.g.
+			// "sdc  INUSE  currently in use", and no error counters.
+			inConfig = false
+			continue
+		case line == "" || strings.HasSuffix(line, ":") || strings.Contains(line, ": "):
+			if line != "" {
+				inConfig = false
+			}
+			continue
+		}
+
+		// Skip vdev group headers such as "logs" or "cache", which don't
+		// report error counters.
+		if !inConfig || len(fields) < 5 {
+			continue
+		}
+
+		v := zpoolVdev{pool: pool, name: fields[0], state: fields[1]}
+		for i, dst := range []*float64{&v.readErrors, &v.writeErrors, &v.cksumErrors} {
+			f, err := strconv.ParseFloat(fields[i+2], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid error count %q for vdev %s: %s", fields[i+2], v.name, err)
+			}
+			*dst = f
+		}
+		vdevs = append(vdevs, v)
+	}
+
+	return vdevs, scanner.Err()
+}
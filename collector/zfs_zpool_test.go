@@ -0,0 +1,93 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build freebsd linux
+// +build !nozfs
+
+package collector
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseZpoolList(t *testing.T) {
+	out := "pool1\t1992864825344\t1056789504\t1991808035840\t12\tONLINE\n" +
+		"poolz1\t3985729650688\t3985729650688\t0\t-\tDEGRADED\n"
+
+	pools, err := parseZpoolList(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 2, len(pools); want != got {
+		t.Fatalf("want %d pools, got %d", want, got)
+	}
+
+	p := pools[0]
+	if p.name != "pool1" || p.size != 1992864825344 || p.allocated != 1056789504 ||
+		p.free != 1991808035840 || p.fragmentation != 0.12 || p.health != "ONLINE" {
+		t.Errorf("unexpected pool %+v", p)
+	}
+	if !math.IsNaN(pools[1].fragmentation) {
+		t.Errorf("want unknown fragmentation, got %v", pools[1].fragmentation)
+	}
+}
+
+func TestParseZpoolStatus(t *testing.T) {
+	out := `  pool: poolz1
+ state: DEGRADED
+status: One or more devices has experienced an unrecoverable error.
+  scan: scrub repaired 0B in 0 days 00:00:01 with 0 errors on Sun Oct 13 00:24:02 2019
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	poolz1      DEGRADED     0     0     0
+	  raidz1-0  DEGRADED     0     0     0
+	    sda     ONLINE       0     0     0
+	    spare-1   DEGRADED     0     0     0
+	      sdb     FAULTED     12     3     0  too many errors
+	      sdf     ONLINE       0     0     0
+	    sdc     ONLINE       0     0     7
+	logs
+	  sdd       ONLINE       0     0     0
+	spares
+	  sdf       INUSE     currently in use
+	  sde       AVAIL
+
+errors: No known data errors
+`
+	vdevs, err := parseZpoolStatus(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []zpoolVdev{
+		{pool: "poolz1", name: "poolz1", state: "DEGRADED"},
+		{pool: "poolz1", name: "raidz1-0", state: "DEGRADED"},
+		{pool: "poolz1", name: "sda", state: "ONLINE"},
+		{pool: "poolz1", name: "spare-1", state: "DEGRADED"},
+		{pool: "poolz1", name: "sdb", state: "FAULTED", readErrors: 12, writeErrors: 3},
+		{pool: "poolz1", name: "sdf", state: "ONLINE"},
+		{pool: "poolz1", name: "sdc", state: "ONLINE", cksumErrors: 7},
+		{pool: "poolz1", name: "sdd", state: "ONLINE"},
+	}
+	if len(vdevs) != len(want) {
+		t.Fatalf("want %d vdevs, got %d: %+v", len(want), len(vdevs), vdevs)
+	}
+	for i := range want {
+		if vdevs[i] != want[i] {
+			t.Errorf("want vdev %+v, got %+v", want[i], vdevs[i])
+		}
+	}
+}
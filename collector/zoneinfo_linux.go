@@ -0,0 +1,164 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nozoneinfo
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const zoneinfoSubsystem = "zoneinfo"
+
+var zoneinfoZoneRE = regexp.MustCompile(`^Node (\d+), zone\s+(\S+)$`)
+
+type zoneinfoCollector struct {
+	watermark *prometheus.Desc
+	pages     map[string]*prometheus.Desc
+}
+
+// zoneinfoZone holds the page counts of a single zone. Zones which aren't
+// populated, e.g. Movable, only report the watermarks and sizes.
+type zoneinfoZone struct {
+	node, zone string
+	values     map[string]float64
+	// Zone statistics such as nr_zone_active_anon or numa_hit in the order
+	// they are listed.
+	stats []string
+}
+
+func init() {
+	registerCollector(zoneinfoSubsystem, defaultDisabled, NewZoneinfoCollector)
+}
+
+// NewZoneinfoCollector returns a new Collector exposing /proc/zoneinfo statistics.
+func NewZoneinfoCollector() (Collector, error) {
+	labels := []string{"node", "zone"}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneinfoSubsystem, name),
+			help, labels, nil,
+		)
+	}
+	return &zoneinfoCollector{
+		watermark: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneinfoSubsystem, "watermark_pages"),
+			"Watermark of the zone. Below low kswapd starts reclaiming, below min allocations enter direct reclaim.",
+			[]string{"node", "zone", "watermark"}, nil,
+		),
+		pages: map[string]*prometheus.Desc{
+			"free":    desc("free_pages", "Number of free pages in the zone."),
+			"spanned": desc("spanned_pages", "Number of pages spanned by the zone, including holes."),
+			"present": desc("present_pages", "Number of physical pages existing within the zone."),
+			"managed": desc("managed_pages", "Number of present pages managed by the buddy allocator."),
+		},
+	}, nil
+}
+
+func (c *zoneinfoCollector) Update(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("zoneinfo"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zones, err := parseZoneinfo(file)
+	if err != nil {
+		return fmt.Errorf("couldn't parse zoneinfo: %s", err)
+	}
+
+	for _, z := range zones {
+		for _, w := range []string{"min", "low", "high"} {
+			if v, ok := z.values[w]; ok {
+				ch <- prometheus.MustNewConstMetric(c.watermark, prometheus.GaugeValue, v, z.node, z.zone, w)
+			}
+		}
+		for name, desc := range c.pages {
+			if v, ok := z.values[name]; ok {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, z.node, z.zone)
+			}
+		}
+		for _, name := range z.stats {
+			ch <- prometheus.MustNewConstMetric(
+				prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, zoneinfoSubsystem, name),
+					fmt.Sprintf("/proc/zoneinfo information field %s.", name),
+					[]string{"node", "zone"}, nil),
+				prometheus.UntypedValue,
+				z.values[name],
+				z.node, z.zone,
+			)
+		}
+	}
+
+	return nil
+}
+
+// parseZoneinfo parses the zone statistics of /proc/zoneinfo. The per-node
+// statistics printed with the first zone of a node are skipped, they are
+// available from the meminfo_numa collector.
+func parseZoneinfo(r io.Reader) ([]zoneinfoZone, error) {
+	var (
+		zones   []zoneinfoZone
+		zone    *zoneinfoZone
+		inZone  bool
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := zoneinfoZoneRE.FindStringSubmatch(line); m != nil {
+			zones = append(zones, zoneinfoZone{node: m[1], zone: m[2], values: make(map[string]float64)})
+			zone = &zones[len(zones)-1]
+			inZone = false
+			continue
+		}
+		if zone == nil {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 3 && fields[0] == "pages" && fields[1] == "free":
+			// The zone statistics start after the per-node statistics.
+			inZone = true
+			fields = fields[1:]
+		case line == "pagesets":
+			inZone = false
+			continue
+		}
+		if !inZone || len(fields) != 2 {
+			continue
+		}
+
+		v, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value in line %q: %s", line, err)
+		}
+		name := fields[0]
+		zone.values[name] = v
+		if strings.HasPrefix(name, "nr_") || strings.HasPrefix(name, "numa_") {
+			zone.stats = append(zone.stats, name)
+		}
+	}
+
+	return zones, scanner.Err()
+}
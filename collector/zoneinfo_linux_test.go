@@ -0,0 +1,68 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseZoneinfo(t *testing.T) {
+	file, err := os.Open("fixtures/proc/zoneinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	zones, err := parseZoneinfo(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 4, len(zones); want != got {
+		t.Fatalf("want %d zones, got %d", want, got)
+	}
+
+	dma := zones[0]
+	if dma.node != "0" || dma.zone != "DMA" {
+		t.Errorf("unexpected first zone %s/%s", dma.node, dma.zone)
+	}
+	if _, ok := dma.values["nr_inactive_anon"]; ok {
+		t.Error("per-node statistics must not be attributed to the zone")
+	}
+	// The pageset "high:" must not override the watermark.
+	if want, got := 14.0, dma.values["high"]; want != got {
+		t.Errorf("want high watermark %v, got %v", want, got)
+	}
+
+	normal := zones[2]
+	for name, want := range map[string]float64{
+		"free":                  18553,
+		"min":                   11412,
+		"low":                   14265,
+		"managed":               7652719,
+		"nr_zone_write_pending": 82,
+		"numa_miss":             2,
+	} {
+		if got := normal.values[name]; want != got {
+			t.Errorf("want Normal %s %v, got %v", name, want, got)
+		}
+	}
+	if want, got := 16, len(normal.stats); want != got {
+		t.Errorf("want %d zone statistics, got %d", want, got)
+	}
+
+	if want, got := 0, len(zones[3].stats); want != got {
+		t.Errorf("want no statistics for unpopulated zone, got %d", got)
+	}
+}
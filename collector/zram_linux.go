@@ -0,0 +1,140 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nozram
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const zramSubsystem = "zram"
+
+// zramStat is a column of a zram stat file, see
+// Documentation/admin-guide/blockdev/zram.rst.
+type zramStat struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+}
+
+type zramCollector struct {
+	diskSize *prometheus.Desc
+	mmStat   []zramStat
+	ioStat   []zramStat
+}
+
+func init() {
+	registerCollector(zramSubsystem, defaultDisabled, NewZramCollector)
+}
+
+// NewZramCollector returns a new Collector exposing zram device statistics.
+func NewZramCollector() (Collector, error) {
+	stat := func(name, help string, valueType prometheus.ValueType) zramStat {
+		return zramStat{
+			desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, zramSubsystem, name),
+				help, []string{"device"}, nil,
+			),
+			valueType: valueType,
+		}
+	}
+	return &zramCollector{
+		diskSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zramSubsystem, "disksize_bytes"),
+			"Maximum amount of uncompressed data the device can store.",
+			[]string{"device"}, nil,
+		),
+		mmStat: []zramStat{
+			stat("orig_data_bytes", "Uncompressed size of the data stored on the device.", prometheus.GaugeValue),
+			stat("compressed_data_bytes", "Compressed size of the data stored on the device.", prometheus.GaugeValue),
+			stat("memory_used_bytes", "Memory allocated for the device, including allocator fragmentation and metadata overhead.", prometheus.GaugeValue),
+			stat("memory_limit_bytes", "Maximum amount of memory the device can use, 0 if unlimited.", prometheus.GaugeValue),
+			stat("memory_used_max_bytes", "Maximum amount of memory the device has used.", prometheus.GaugeValue),
+			stat("same_pages", "Number of same element filled pages stored without allocating memory.", prometheus.GaugeValue),
+			stat("pages_compacted_total", "Number of pages freed during compaction.", prometheus.CounterValue),
+			stat("huge_pages", "Number of incompressible pages.", prometheus.GaugeValue),
+		},
+		ioStat: []zramStat{
+			stat("failed_reads_total", "Number of failed reads.", prometheus.CounterValue),
+			stat("failed_writes_total", "Number of failed writes, e.g. because memory could not be allocated.", prometheus.CounterValue),
+			stat("invalid_io_total", "Number of non-page-size-aligned I/O requests.", prometheus.CounterValue),
+			stat("notify_free_total", "Number of freed pages reported by swap or discard requests.", prometheus.CounterValue),
+		},
+	}, nil
+}
+
+func (c *zramCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("block/zram*"))
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range devices {
+		device := filepath.Base(dir)
+
+		size, err := readUintFromFile(filepath.Join(dir, "disksize"))
+		if err != nil {
+			return fmt.Errorf("couldn't get disksize of %s: %s", device, err)
+		}
+		// Devices which haven't been initialized don't have any statistics.
+		if size == 0 {
+			log.Debugf("Skipping uninitialized zram device %s", device)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.diskSize, prometheus.GaugeValue, float64(size), device)
+
+		if err := updateZramStats(ch, filepath.Join(dir, "mm_stat"), device, c.mmStat); err != nil {
+			return err
+		}
+		if err := updateZramStats(ch, filepath.Join(dir, "io_stat"), device, c.ioStat); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateZramStats exposes the columns of a stat file. Columns added by newer
+// kernels are ignored and missing columns of older kernels are skipped.
+func updateZramStats(ch chan<- prometheus.Metric, path, device string, stats []zramStat) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("zram: %s does not exist, skipping", path)
+			return nil
+		}
+		return err
+	}
+
+	for i, field := range strings.Fields(string(content)) {
+		if i >= len(stats) {
+			break
+		}
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid value %q in %s: %s", field, path, err)
+		}
+		ch <- prometheus.MustNewConstMetric(stats[i].desc, stats[i].valueType, float64(v), device)
+	}
+
+	return nil
+}
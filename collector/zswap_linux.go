@@ -0,0 +1,86 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nozswap
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const zswapSubsystem = "zswap"
+
+// zswapGauges are the statistics in /sys/kernel/debug/zswap which aren't
+// counters. All other statistics are exposed as <name>_total.
+var zswapGauges = map[string]string{
+	"pool_total_size":   "pool_total_size_bytes",
+	"same_filled_pages": "same_filled_pages",
+	"stored_pages":      "stored_pages",
+}
+
+type zswapCollector struct{}
+
+func init() {
+	registerCollector(zswapSubsystem, defaultDisabled, NewZswapCollector)
+}
+
+// NewZswapCollector returns a new Collector exposing zswap pool statistics.
+func NewZswapCollector() (Collector, error) {
+	return &zswapCollector{}, nil
+}
+
+func (c *zswapCollector) Update(ch chan<- prometheus.Metric) error {
+	// The statistics are only available in debugfs, which is usually only
+	// readable by root.
+	dir := sysFilePath("kernel/debug/zswap")
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) || os.IsPermission(err) {
+			log.Debugf("Not collecting zswap statistics: %s", err)
+			return nil
+		}
+		return err
+	}
+
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		value, err := readUintFromFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return fmt.Errorf("couldn't get zswap %s: %s", f.Name(), err)
+		}
+
+		name, valueType := f.Name()+"_total", prometheus.CounterValue
+		if gauge, ok := zswapGauges[f.Name()]; ok {
+			name, valueType = gauge, prometheus.GaugeValue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, zswapSubsystem, name),
+				fmt.Sprintf("zswap statistic %s.", f.Name()),
+				nil, nil,
+			),
+			valueType, float64(value),
+		)
+	}
+
+	return nil
+}
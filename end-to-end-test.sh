@@ -3,48 +3,92 @@
 set -euf -o pipefail
 
 enabled_collectors=$(cat << COLLECTORS
+  aer
+  apparmor
   arp
+  autofs
   bcache
+  btrfs
   buddyinfo
+  ceph
+  cgroup
+  cifs
+  clocksource
   conntrack
   cpu
   cpufreq
+  cputopology
+  crypto
   diskstats
   drbd
   edac
   entropy
+  epoll
+  ext4
+  fibrechannel
   filefd
+  fserrors
+  fuse
+  gpu
   hwmon
+  hwraid
   infiniband
   interrupts
+  irqaffinity
+  iscsi
   ipvs
+  kernellimits
+  kernelstalls
   ksmd
+  laptop
   loadavg
+  locks
+  loop
+  mce
   mdadm
   meminfo
   meminfo_numa
+  modules
+  mountinfo
   mountstats
+  multicast
   netdev
   netstat
   nfs
   nfsd
+  pci
+  powerstate
   pressure
   qdisc
+  removable
+  sas
   schedstat
+  secureboot
+  selinux
   sockstat
   stat
+  swap
+  sysctl
   thermal_zone
+  tape
   textfile
+  tpm
   bonding
+  usb
   vmstat
+  watchdog
   wifi
   xfs
+  zram
   zfs
+  zoneinfo
+  zswap
   processes
 COLLECTORS
 )
 disabled_collectors=$(cat << COLLECTORS
   filesystem
+  neighbour
   time
   timex
   uname
@@ -55,7 +99,7 @@ cd "$(dirname $0)"
 port="$((10000 + (RANDOM % 10000)))"
 tmpdir=$(mktemp -d /tmp/node_exporter_e2e_test.XXXXXX)
 
-skip_re="^(go_|node_exporter_build_info|node_scrape_collector_duration_seconds|process_|node_textfile_mtime_seconds)"
+skip_re="^(go_|node_exporter_build_info|node_scrape_collector_duration_seconds|node_scrape_collector_capability_missing|process_|node_textfile_mtime_seconds)"
 
 arch="$(uname -m)"
 
@@ -101,9 +145,18 @@ fi
   $(for c in ${disabled_collectors}; do echo --no-collector.${c}  ; done) \
   --collector.textfile.directory="collector/fixtures/textfile/two_metric_files/" \
   --collector.wifi.fixtures="collector/fixtures/wifi" \
+  --collector.interrupts.per-cpu-include="NMI|LOC|iwlwifi" \
   --collector.qdisc.fixtures="collector/fixtures/qdisc/" \
   --collector.netclass.ignored-devices="(bond0|dmz|int)" \
   --collector.cpu.info \
+  --collector.vmstat.include="pgscan_kswapd_normal" \
+  --collector.netstat.include="TcpExt_TCPTimeouts" \
+  --no-collector.netdev.netlink \
+  --collector.tpm.device-path="collector/fixtures/dev" \
+  --collector.sysctl.include="net.core.somaxconn" \
+  --collector.sysctl.include="net.ipv4.tcp_rmem" \
+  --collector.sysctl.include="kernel.core_pattern" \
+  --collector.sysctl.include="net.ipv4.conf.all.rp_filter" \
   --web.listen-address "127.0.0.1:${port}" \
   --log.level="debug" > "${tmpdir}/node_exporter.log" 2>&1 &
 
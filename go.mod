@@ -5,11 +5,13 @@ require (
 	github.com/coreos/go-systemd v0.0.0-20190321100706-95778dfbb74e
 	github.com/ema/qdisc v0.0.0-20190904071900-b82c76788043
 	github.com/godbus/dbus v0.0.0-20190402143921-271e53dc4968
+	github.com/golang/protobuf v1.3.2
 	github.com/hodgesds/perf-utils v0.0.7
 	github.com/konsorten/go-windows-terminal-sequences v1.0.2 // indirect
 	github.com/lufia/iostat v0.0.0-20170605150913-9f7362b77ad3
 	github.com/mattn/go-xmlrpc v0.0.1
-	github.com/mdlayher/genetlink v0.0.0-20190828143517-e35f2bf499b9 // indirect
+	github.com/mdlayher/genetlink v0.0.0-20190828143517-e35f2bf499b9
+	github.com/mdlayher/netlink v0.0.0-20190828143259-340058475d09
 	github.com/mdlayher/wifi v0.0.0-20190303161829-b1436901ddee
 	github.com/prometheus/client_golang v1.0.0
 	github.com/prometheus/client_model v0.0.0-20190129233127-fd36f4220a90
@@ -14,6 +14,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	_ "net/http/pprof"
@@ -37,13 +38,24 @@ type handler struct {
 	exporterMetricsRegistry *prometheus.Registry
 	includeExporterMetrics  bool
 	maxRequests             int
+	// inFlightSem limits the concurrent requests for metrics and metadata
+	// to maxRequests.
+	inFlightSem       chan struct{}
+	enableOpenMetrics bool
+	// nodeCollector is the collector of the unfiltered handler, which also
+	// serves the metadata.
+	nodeCollector *collector.NodeCollector
 }
 
-func newHandler(includeExporterMetrics bool, maxRequests int) *handler {
+func newHandler(includeExporterMetrics bool, maxRequests int, enableOpenMetrics bool) *handler {
 	h := &handler{
 		exporterMetricsRegistry: prometheus.NewRegistry(),
 		includeExporterMetrics:  includeExporterMetrics,
 		maxRequests:             maxRequests,
+		enableOpenMetrics:       enableOpenMetrics,
+	}
+	if maxRequests > 0 {
+		h.inFlightSem = make(chan struct{}, maxRequests)
 	}
 	if h.includeExporterMetrics {
 		h.exporterMetricsRegistry.MustRegister(
@@ -61,6 +73,12 @@ func newHandler(includeExporterMetrics bool, maxRequests int) *handler {
 
 // ServeHTTP implements http.Handler.
 func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	done, ok := h.admit(w)
+	if !ok {
+		return
+	}
+	defer done()
+
 	filters := r.URL.Query()["collect[]"]
 	log.Debugln("collect query:", filters)
 
@@ -80,6 +98,21 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	filteredHandler.ServeHTTP(w, r)
 }
 
+// admit starts a request if less than maxRequests are in flight, or
+// responds with 503 otherwise. The returned function ends the request.
+func (h *handler) admit(w http.ResponseWriter) (func(), bool) {
+	if h.inFlightSem == nil {
+		return func() {}, true
+	}
+	select {
+	case h.inFlightSem <- struct{}{}:
+		return func() { <-h.inFlightSem }, true
+	default:
+		http.Error(w, fmt.Sprintf("Limit of concurrent requests reached (%d), try again later.", h.maxRequests), http.StatusServiceUnavailable)
+		return nil, false
+	}
+}
+
 // innerHandler is used to create buth the one unfiltered http.Handler to be
 // wrapped by the outer handler and also the filtered handlers created on the
 // fly. The former is accomplished by calling innerHandler without any arguments
@@ -103,6 +136,7 @@ func (h *handler) innerHandler(filters ...string) (http.Handler, error) {
 		for _, n := range collectors {
 			log.Infof(" - %s", n)
 		}
+		h.nodeCollector = nc
 	}
 
 	r := prometheus.NewRegistry()
@@ -110,15 +144,18 @@ func (h *handler) innerHandler(filters ...string) (http.Handler, error) {
 	if err := r.Register(nc); err != nil {
 		return nil, fmt.Errorf("couldn't register node collector: %s", err)
 	}
+	gatherer := prometheus.Gatherers{h.exporterMetricsRegistry, r}
 	handler := promhttp.HandlerFor(
-		prometheus.Gatherers{h.exporterMetricsRegistry, r},
+		gatherer,
 		promhttp.HandlerOpts{
-			ErrorLog:            log.NewErrorLogger(),
-			ErrorHandling:       promhttp.ContinueOnError,
-			MaxRequestsInFlight: h.maxRequests,
-			Registry:            h.exporterMetricsRegistry,
+			ErrorLog:      log.NewErrorLogger(),
+			ErrorHandling: promhttp.ContinueOnError,
+			Registry:      h.exporterMetricsRegistry,
 		},
 	)
+	if h.enableOpenMetrics {
+		handler = newOpenMetricsHandler(gatherer, handler)
+	}
 	if h.includeExporterMetrics {
 		// Note that we have to use h.exporterMetricsRegistry here to
 		// use the same promhttp metrics for all expositions.
@@ -129,6 +166,38 @@ func (h *handler) innerHandler(filters ...string) (http.Handler, error) {
 	return handler, nil
 }
 
+// metadataPath is the path under which the metadata of the metrics of the
+// enabled collectors is served.
+const metadataPath = "/metadata"
+
+// serveMetadata runs the collectors of the unfiltered handler, or those of
+// the collect[] parameters, and serves the metric families they produce as
+// JSON. It shares the limit of concurrent requests with the metrics.
+func (h *handler) serveMetadata(w http.ResponseWriter, r *http.Request) {
+	done, ok := h.admit(w)
+	if !ok {
+		return
+	}
+	defer done()
+
+	nc := h.nodeCollector
+	if filters := r.URL.Query()["collect[]"]; len(filters) > 0 {
+		nc = &collector.NodeCollector{Collectors: map[string]collector.Collector{}}
+		for _, f := range filters {
+			c, ok := h.nodeCollector.Collectors[f]
+			if !ok {
+				http.Error(w, fmt.Sprintf("Couldn't create collector: missing or disabled collector: %s", f), http.StatusBadRequest)
+				return
+			}
+			nc.Collectors[f] = c
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(nc.Metadata()); err != nil {
+		log.Errorln("Couldn't encode metadata:", err)
+	}
+}
+
 func main() {
 	var (
 		listenAddress = kingpin.Flag(
@@ -145,8 +214,20 @@ func main() {
 		).Bool()
 		maxRequests = kingpin.Flag(
 			"web.max-requests",
-			"Maximum number of parallel scrape requests. Use 0 to disable.",
+			"Maximum number of parallel scrape and metadata requests. Use 0 to disable.",
 		).Default("40").Int()
+		enableOpenMetrics = kingpin.Flag(
+			"web.enable-openmetrics",
+			"Serve the metrics in the OpenMetrics text format to clients preferring it, like Prometheus.",
+		).Bool()
+		sandbox = kingpin.Flag(
+			"security.sandbox",
+			"Restrict the exporter after startup with Landlock to the procfs and sysfs mount points, the paths used by the enabled collectors and the paths of --security.sandbox.read-path, and deny system calls like execve and mount with seccomp. Fails to start if an enabled collector runs commands.",
+		).Bool()
+		sandboxReadPaths = kingpin.Flag(
+			"security.sandbox.read-path",
+			"Additional file or directory the exporter may read with --security.sandbox, beyond the paths of the enabled collectors like the textfile directory. Can be repeated.",
+		).Strings()
 	)
 
 	log.AddFlags(kingpin.CommandLine)
@@ -157,17 +238,41 @@ func main() {
 	log.Infoln("Starting node_exporter", version.Info())
 	log.Infoln("Build context", version.BuildContext())
 
-	http.Handle(*metricsPath, newHandler(!*disableExporterMetrics, *maxRequests))
+	h := newHandler(!*disableExporterMetrics, *maxRequests, *enableOpenMetrics)
+	http.Handle(*metricsPath, h)
+	pushHandler, err := collector.NewPushHandler()
+	if err != nil {
+		log.Fatalf("Couldn't create push handler: %s", err)
+	}
+	if pushHandler != nil {
+		http.Handle(collector.PushPath, pushHandler)
+	}
+	http.HandleFunc(metadataPath, h.serveMetadata)
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
 			<head><title>Node Exporter</title></head>
 			<body>
 			<h1>Node Exporter</h1>
 			<p><a href="` + *metricsPath + `">Metrics</a></p>
+			<p><a href="` + metadataPath + `">Metadata</a></p>
 			</body>
 			</html>`))
 	})
 
+	if *sandbox {
+		policy, err := collector.NewSandboxPolicy()
+		if err != nil {
+			log.Fatalf("Couldn't apply sandbox: %s", err)
+		}
+		// The process's own procfs directory is needed for the process_*
+		// metrics even if --path.procfs points to the procfs of the host.
+		policy.ReadPaths = append(policy.ReadPaths, "/proc/self")
+		policy.ReadPaths = append(policy.ReadPaths, *sandboxReadPaths...)
+		if err := applySandbox(policy); err != nil {
+			log.Fatalf("Couldn't apply sandbox: %s", err)
+		}
+	}
+
 	log.Infoln("Listening on", *listenAddress)
 	if err := http.ListenAndServe(*listenAddress, nil); err != nil {
 		log.Fatal(err)
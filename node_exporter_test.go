@@ -17,12 +17,15 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector"
 	"github.com/prometheus/procfs"
 )
 
@@ -108,6 +111,42 @@ func TestHandlingOfDuplicatedMetrics(t *testing.T) {
 	}
 }
 
+type metadataTestCollector struct{}
+
+func (metadataTestCollector) Update(ch chan<- prometheus.Metric) error {
+	ch <- prometheus.MustNewConstMetric(prometheus.NewDesc("node_test", "Test.", nil, nil), prometheus.GaugeValue, 1)
+	return nil
+}
+
+func TestServeMetadata(t *testing.T) {
+	h := &handler{
+		maxRequests:   1,
+		inFlightSem:   make(chan struct{}, 1),
+		nodeCollector: &collector.NodeCollector{Collectors: map[string]collector.Collector{"test": metadataTestCollector{}}},
+	}
+	get := func(url string) int {
+		rw := httptest.NewRecorder()
+		h.serveMetadata(rw, httptest.NewRequest("GET", url, nil))
+		return rw.Code
+	}
+
+	for url, want := range map[string]int{
+		"/metadata":                 http.StatusOK,
+		"/metadata?collect[]=test":  http.StatusOK,
+		"/metadata?collect[]=other": http.StatusBadRequest,
+	} {
+		if got := get(url); got != want {
+			t.Errorf("%s: want status %d, got %d", url, want, got)
+		}
+	}
+
+	// Metadata requests count against the limit of scrapes in flight.
+	h.inFlightSem <- struct{}{}
+	if want, got := http.StatusServiceUnavailable, get("/metadata"); want != got {
+		t.Errorf("want status %d with a scrape in flight, got %d", want, got)
+	}
+}
+
 func queryExporter(address string) error {
 	resp, err := http.Get(fmt.Sprintf("http://%s/metrics", address))
 	if err != nil {
@@ -0,0 +1,253 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/log"
+)
+
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// openMetricsUnits are the base units of OpenMetrics. Metric families
+// whose names end with one of them get it as their unit.
+var openMetricsUnits = []string{"seconds", "bytes", "joules", "grams", "meters", "ratio", "volts", "amperes", "celsius"}
+
+// openMetricsHandler serves the metrics in the OpenMetrics text format to
+// clients preferring it and uses the fallback handler for all others. The
+// limit of concurrent requests is enforced by the wrapping handler.
+type openMetricsHandler struct {
+	gatherer prometheus.Gatherer
+	fallback http.Handler
+}
+
+func newOpenMetricsHandler(gatherer prometheus.Gatherer, fallback http.Handler) *openMetricsHandler {
+	return &openMetricsHandler{gatherer: gatherer, fallback: fallback}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *openMetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The format and the compression of the response depend on the
+	// request, which caches have to take into account.
+	w.Header().Add("Vary", "Accept")
+	w.Header().Add("Vary", "Accept-Encoding")
+	if !acceptsOpenMetrics(r.Header) {
+		h.fallback.ServeHTTP(w, r)
+		return
+	}
+
+	// Like the text format handler, metrics are served despite errors of
+	// single collectors.
+	mfs, err := h.gatherer.Gather()
+	if err != nil {
+		log.Errorln("error gathering metrics:", err)
+		if len(mfs) == 0 {
+			http.Error(w, "An error has occurred while serving metrics:\n\n"+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", openMetricsContentType)
+	out := io.Writer(w)
+	if gzipAccepted(r.Header) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	}
+	if err := writeOpenMetrics(out, mfs); err != nil {
+		log.Errorln("error encoding and sending metrics:", err)
+	}
+}
+
+// acceptsOpenMetrics returns whether the client prefers the OpenMetrics text
+// format over all other formats it accepts, like Prometheus 2.5 and later.
+func acceptsOpenMetrics(header http.Header) bool {
+	var openMetrics, other float64
+	for _, accept := range header["Accept"] {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			params := strings.Split(mediaRange, ";")
+			q := 1.0
+			for _, param := range params[1:] {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				if len(kv) == 2 && kv[0] == "q" {
+					if v, err := strconv.ParseFloat(kv[1], 64); err == nil {
+						q = v
+					}
+				}
+			}
+			if strings.TrimSpace(params[0]) == "application/openmetrics-text" {
+				if q > openMetrics {
+					openMetrics = q
+				}
+			} else if q > other {
+				other = q
+			}
+		}
+	}
+	return openMetrics > 0 && openMetrics >= other
+}
+
+// gzipAccepted returns whether the client accepts gzip encoded content.
+func gzipAccepted(header http.Header) bool {
+	for _, part := range strings.Split(header.Get("Accept-Encoding"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "gzip" || strings.HasPrefix(part, "gzip;") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeOpenMetrics writes the metric families in the OpenMetrics text
+// format. Counter families are named without their _total suffix, which
+// their samples always have, and untyped ones have the unknown type.
+func writeOpenMetrics(out io.Writer, mfs []*dto.MetricFamily) error {
+	names := make(map[string]bool, len(mfs))
+	for _, mf := range mfs {
+		names[mf.GetName()] = true
+	}
+
+	w := bufio.NewWriter(out)
+	for _, mf := range mfs {
+		name := mf.GetName()
+		metricType := mf.GetType()
+		// Counters whose family name would clash with another family, like
+		// go_memstats_alloc_bytes_total, keep their name without a type.
+		if metricType == dto.MetricType_COUNTER && names[strings.TrimSuffix(name, "_total")] {
+			metricType = dto.MetricType_UNTYPED
+		}
+
+		var typ string
+		switch metricType {
+		case dto.MetricType_COUNTER:
+			name = strings.TrimSuffix(name, "_total")
+			typ = "counter"
+		case dto.MetricType_GAUGE:
+			typ = "gauge"
+		case dto.MetricType_SUMMARY:
+			typ = "summary"
+		case dto.MetricType_HISTOGRAM:
+			typ = "histogram"
+		default:
+			typ = "unknown"
+		}
+
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+		for _, unit := range openMetricsUnits {
+			if strings.HasSuffix(name, "_"+unit) {
+				fmt.Fprintf(w, "# UNIT %s %s\n", name, unit)
+				break
+			}
+		}
+		if mf.Help != nil {
+			fmt.Fprintf(w, "# HELP %s %s\n", name, escapeOpenMetrics(mf.GetHelp()))
+		}
+
+		for _, m := range mf.Metric {
+			switch metricType {
+			case dto.MetricType_COUNTER:
+				writeOpenMetricsSample(w, name+"_total", m, "", 0, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				writeOpenMetricsSample(w, name, m, "", 0, m.GetGauge().GetValue())
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.Quantile {
+					writeOpenMetricsSample(w, name, m, "quantile", q.GetQuantile(), q.GetValue())
+				}
+				writeOpenMetricsSample(w, name+"_sum", m, "", 0, s.GetSampleSum())
+				writeOpenMetricsSample(w, name+"_count", m, "", 0, float64(s.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				inf := false
+				for _, b := range h.Bucket {
+					writeOpenMetricsSample(w, name+"_bucket", m, "le", b.GetUpperBound(), float64(b.GetCumulativeCount()))
+					inf = math.IsInf(b.GetUpperBound(), 1)
+				}
+				// The +Inf bucket is mandatory in OpenMetrics.
+				if !inf {
+					writeOpenMetricsSample(w, name+"_bucket", m, "le", math.Inf(1), float64(h.GetSampleCount()))
+				}
+				writeOpenMetricsSample(w, name+"_sum", m, "", 0, h.GetSampleSum())
+				writeOpenMetricsSample(w, name+"_count", m, "", 0, float64(h.GetSampleCount()))
+			default:
+				value := m.GetUntyped().GetValue()
+				if m.Counter != nil {
+					value = m.GetCounter().GetValue()
+				}
+				writeOpenMetricsSample(w, name, m, "", 0, value)
+			}
+		}
+	}
+	w.WriteString("# EOF\n")
+	return w.Flush()
+}
+
+// writeOpenMetricsSample writes a sample with the labels of the metric and
+// the additional label, if any.
+func writeOpenMetricsSample(w *bufio.Writer, name string, m *dto.Metric, extraName string, extraValue float64, value float64) {
+	w.WriteString(name)
+	if len(m.Label) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, l := range m.Label {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", l.GetName(), escapeOpenMetrics(l.GetValue()))
+		}
+		if extraName != "" {
+			if len(m.Label) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", extraName, formatOpenMetricsFloat(extraValue))
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatOpenMetricsFloat(value))
+	// OpenMetrics timestamps are in seconds.
+	if m.TimestampMs != nil {
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatFloat(float64(m.GetTimestampMs())/1000, 'f', -1, 64))
+	}
+	w.WriteByte('\n')
+}
+
+var openMetricsEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeOpenMetrics(s string) string {
+	return openMetricsEscaper.Replace(s)
+}
+
+func formatOpenMetricsFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
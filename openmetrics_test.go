@@ -0,0 +1,155 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	label := func(name, value string) *dto.LabelPair {
+		return &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)}
+	}
+	mfs := []*dto.MetricFamily{
+		{
+			Name: proto.String("node_cpu_seconds_total"),
+			Help: proto.String("Seconds the cpus spent in each mode."),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{{
+				Label:   []*dto.LabelPair{label("cpu", "0"), label("mode", "idle")},
+				Counter: &dto.Counter{Value: proto.Float64(1234.5)},
+			}},
+		},
+		{
+			Name: proto.String("node_textfile_info"),
+			Help: proto.String("Help with \"quotes\"\nand a newline."),
+			Type: dto.MetricType_UNTYPED.Enum(),
+			Metric: []*dto.Metric{{
+				Label:       []*dto.LabelPair{label("path", `C:\dir`)},
+				Untyped:     &dto.Untyped{Value: proto.Float64(math.Inf(1))},
+				TimestampMs: proto.Int64(1500),
+			}},
+		},
+		{
+			Name: proto.String("node_latency"),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{
+				Gauge: &dto.Gauge{Value: proto.Float64(1)},
+			}},
+		},
+		{
+			Name: proto.String("node_latency_total"),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{{
+				Counter: &dto.Counter{Value: proto.Float64(2)},
+			}},
+		},
+		{
+			Name: proto.String("node_request_duration_seconds"),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{{
+				Histogram: &dto.Histogram{
+					SampleCount: proto.Uint64(3),
+					SampleSum:   proto.Float64(0.6),
+					Bucket: []*dto.Bucket{
+						{UpperBound: proto.Float64(0.1), CumulativeCount: proto.Uint64(1)},
+						{UpperBound: proto.Float64(0.5), CumulativeCount: proto.Uint64(2)},
+					},
+				},
+			}},
+		},
+	}
+
+	want := `# TYPE node_cpu_seconds counter
+# UNIT node_cpu_seconds seconds
+# HELP node_cpu_seconds Seconds the cpus spent in each mode.
+node_cpu_seconds_total{cpu="0",mode="idle"} 1234.5
+# TYPE node_textfile_info unknown
+# HELP node_textfile_info Help with \"quotes\"\nand a newline.
+node_textfile_info{path="C:\\dir"} +Inf 1.5
+# TYPE node_latency gauge
+node_latency 1
+# TYPE node_latency_total unknown
+node_latency_total 2
+# TYPE node_request_duration_seconds histogram
+# UNIT node_request_duration_seconds seconds
+node_request_duration_seconds_bucket{le="0.1"} 1
+node_request_duration_seconds_bucket{le="0.5"} 2
+node_request_duration_seconds_bucket{le="+Inf"} 3
+node_request_duration_seconds_sum 0.6
+node_request_duration_seconds_count 3
+# EOF
+`
+	var buf bytes.Buffer
+	if err := writeOpenMetrics(&buf, mfs); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestAcceptsOpenMetrics(t *testing.T) {
+	for accept, want := range map[string]bool{
+		"":                         false,
+		"*/*":                      false,
+		"text/plain;version=0.0.4": false,
+		"application/openmetrics-text; version=1.0.0": true,
+		"application/openmetrics-text;version=1.0.0,application/openmetrics-text;version=0.0.1;q=0.75,text/plain;version=0.0.4;q=0.5,*/*;q=0.1": true,
+		"application/openmetrics-text;q=0.5,text/plain": false,
+		"application/openmetrics-text;q=0":              false,
+	} {
+		header := http.Header{}
+		if accept != "" {
+			header.Set("Accept", accept)
+		}
+		if got := acceptsOpenMetrics(header); got != want {
+			t.Errorf("%q: want %t, got %t", accept, want, got)
+		}
+	}
+}
+
+func TestOpenMetricsHandlerNegotiation(t *testing.T) {
+	r := prometheus.NewRegistry()
+	r.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "node_test", Help: "Test gauge."}))
+	h := newOpenMetricsHandler(r, promhttp.HandlerFor(r, promhttp.HandlerOpts{}))
+
+	for accept, want := range map[string]string{
+		"application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5": openMetricsContentType,
+		"text/plain;version=0.0.4": string(expfmt.FmtText),
+	} {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Content-Type"); got != want {
+			t.Errorf("%q: want content type %q, got %q", accept, want, got)
+		}
+		if want, got := []string{"Accept", "Accept-Encoding"}, rec.Header()["Vary"]; !reflect.DeepEqual(want, got) {
+			t.Errorf("%q: want Vary headers %q, got %q", accept, want, got)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/node_exporter/collector"
+	"golang.org/x/sys/unix"
+)
+
+// The Landlock system calls have the same numbers on all architectures
+// using the generic system call table, MIPS adds the offset of its ABI.
+var (
+	sysLandlockCreateRuleset = landlockSyscall(444)
+	sysLandlockAddRule       = landlockSyscall(445)
+	sysLandlockRestrictSelf  = landlockSyscall(446)
+)
+
+func landlockSyscall(nr uintptr) uintptr {
+	switch runtime.GOARCH {
+	case "mips", "mipsle":
+		// o32 system calls start at 4000.
+		return 4000 + nr
+	case "mips64", "mips64le":
+		// n64 system calls start at 5000.
+		return 5000 + nr
+	}
+	return nr
+}
+
+const (
+	landlockCreateRulesetVersion = 1 << 0
+	landlockRulePathBeneath      = 1
+
+	landlockAccessFSExecute    = 1 << 0
+	landlockAccessFSWriteFile  = 1 << 1
+	landlockAccessFSReadFile   = 1 << 2
+	landlockAccessFSReadDir    = 1 << 3
+	landlockAccessFSRemoveDir  = 1 << 4
+	landlockAccessFSRemoveFile = 1 << 5
+	landlockAccessFSMakeChar   = 1 << 6
+	landlockAccessFSMakeDir    = 1 << 7
+	landlockAccessFSMakeReg    = 1 << 8
+	landlockAccessFSMakeSock   = 1 << 9
+	landlockAccessFSMakeFifo   = 1 << 10
+	landlockAccessFSMakeBlock  = 1 << 11
+	landlockAccessFSMakeSym    = 1 << 12
+	landlockAccessFSRefer      = 1 << 13
+	landlockAccessFSTruncate   = 1 << 14
+
+	seccompSetModeFilter   = 1
+	seccompFilterFlagTSync = 1 << 0
+	seccompRetAllow        = 0x7fff0000
+	seccompRetErrno        = 0x00050000
+
+	// x32 system calls on amd64 have this bit set in their number.
+	x32SyscallBit = 0x40000000
+)
+
+// sandboxAuditArchs are the AUDIT_ARCH_* values of linux/audit.h, which
+// seccomp filters have to check to tell system call numbers apart.
+var sandboxAuditArchs = map[string]uint32{
+	"386":      0x40000003,
+	"amd64":    0xc000003e,
+	"arm":      0x40000028,
+	"arm64":    0xc00000b7,
+	"mips":     0x00000008,
+	"mipsle":   0x40000008,
+	"mips64":   0x80000008,
+	"mips64le": 0xc0000008,
+	"ppc64":    0x80000015,
+	"ppc64le":  0xc0000015,
+	"riscv64":  0xc00000f3,
+	"s390x":    0x80000016,
+}
+
+// sandboxDeniedSyscalls are the system calls which would allow a
+// compromised exporter to run code or to change the system. Only setns is
+// allowed if collectors need it.
+var sandboxDeniedSyscalls = []uintptr{
+	unix.SYS_EXECVE,
+	unix.SYS_EXECVEAT,
+	unix.SYS_PTRACE,
+	unix.SYS_PROCESS_VM_WRITEV,
+	unix.SYS_MOUNT,
+	unix.SYS_UMOUNT2,
+	unix.SYS_PIVOT_ROOT,
+	unix.SYS_CHROOT,
+	unix.SYS_UNSHARE,
+	unix.SYS_SETNS,
+	unix.SYS_KEXEC_LOAD,
+	unix.SYS_INIT_MODULE,
+	unix.SYS_FINIT_MODULE,
+	unix.SYS_DELETE_MODULE,
+	unix.SYS_REBOOT,
+	unix.SYS_SWAPON,
+	unix.SYS_SWAPOFF,
+	unix.SYS_ACCT,
+	unix.SYS_SETTIMEOFDAY,
+	unix.SYS_CLOCK_SETTIME,
+	unix.SYS_ADD_KEY,
+	unix.SYS_REQUEST_KEY,
+	unix.SYS_KEYCTL,
+	unix.SYS_OPEN_BY_HANDLE_AT,
+	unix.SYS_USERFAULTFD,
+}
+
+type landlockRulesetAttr struct {
+	handledAccessFS uint64
+}
+
+// landlockPathBeneathAttr is the packed struct landlock_path_beneath_attr,
+// of which the kernel only reads the first 12 bytes.
+type landlockPathBeneathAttr struct {
+	allowedAccess uint64
+	parentFD      int32
+}
+
+// applySandbox restricts the exporter to the paths of the policy and
+// denies the system calls it doesn't need. It applies to all threads and
+// can't be undone.
+func applySandbox(policy collector.SandboxPolicy) error {
+	// Both Landlock and unprivileged seccomp filters require no_new_privs,
+	// which also prevents gaining privileges through setuid binaries.
+	if _, _, errno := syscall.AllThreadsSyscall(unix.SYS_PRCTL, unix.PR_SET_NO_NEW_PRIVS, 1, 0); errno != 0 {
+		if errno == unix.ENOTSUP {
+			return fmt.Errorf("couldn't set no_new_privs on all threads, the sandbox requires a build without cgo")
+		}
+		return fmt.Errorf("couldn't set no_new_privs: %s", errno)
+	}
+	if err := applyLandlock(policy.ReadPaths, policy.ReadWritePaths); err != nil {
+		return fmt.Errorf("couldn't apply Landlock ruleset: %s", err)
+	}
+	if err := applySeccomp(sandboxSyscalls(policy)); err != nil {
+		return fmt.Errorf("couldn't apply seccomp filter: %s", err)
+	}
+	return nil
+}
+
+// sandboxSyscalls returns the system calls to deny with the policy.
+func sandboxSyscalls(policy collector.SandboxPolicy) []uintptr {
+	var denied []uintptr
+	for _, nr := range sandboxDeniedSyscalls {
+		if nr == unix.SYS_SETNS && policy.Setns {
+			continue
+		}
+		denied = append(denied, nr)
+	}
+	return denied
+}
+
+// applyLandlock allows reading and writing the given paths and denies all
+// other filesystem access of the exporter. Paths which don't exist are
+// skipped. Paths opened before, like the listening socket and the log
+// output, aren't affected.
+func applyLandlock(readPaths, readWritePaths []string) error {
+	abi, _, errno := unix.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno == unix.ENOSYS || errno == unix.EOPNOTSUPP {
+		// Landlock is only supported since Linux 5.13 and has to be
+		// enabled with the lsm boot parameter.
+		log.Warnln("Landlock isn't supported by the kernel, filesystem access isn't restricted")
+		return nil
+	}
+	if errno != 0 {
+		return errno
+	}
+
+	attr := landlockRulesetAttr{handledAccessFS: landlockAccessFSExecute | landlockAccessFSWriteFile |
+		landlockAccessFSReadFile | landlockAccessFSReadDir | landlockAccessFSRemoveDir |
+		landlockAccessFSRemoveFile | landlockAccessFSMakeChar | landlockAccessFSMakeDir |
+		landlockAccessFSMakeReg | landlockAccessFSMakeSock | landlockAccessFSMakeFifo |
+		landlockAccessFSMakeBlock | landlockAccessFSMakeSym}
+	// Renaming and linking files across directories and truncating them are
+	// only handled since the second and third Landlock ABI versions.
+	if abi >= 2 {
+		attr.handledAccessFS |= landlockAccessFSRefer
+	}
+	if abi >= 3 {
+		attr.handledAccessFS |= landlockAccessFSTruncate
+	}
+	ruleset, _, errno := unix.Syscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return errno
+	}
+	defer unix.Close(int(ruleset))
+
+	var allowed []string
+	for _, path := range readPaths {
+		if err := addLandlockRule(int(ruleset), path, landlockAccessFSReadFile); err != nil {
+			if os.IsNotExist(err) {
+				log.Debugf("Not allowing reading %q, it doesn't exist", path)
+				continue
+			}
+			return fmt.Errorf("couldn't allow reading %q: %s", path, err)
+		}
+		allowed = append(allowed, path)
+	}
+	for _, path := range readWritePaths {
+		if err := addLandlockRule(int(ruleset), path, landlockAccessFSReadFile|landlockAccessFSWriteFile); err != nil {
+			if os.IsNotExist(err) {
+				log.Debugf("Not allowing writing %q, it doesn't exist", path)
+				continue
+			}
+			return fmt.Errorf("couldn't allow writing %q: %s", path, err)
+		}
+		allowed = append(allowed, path)
+	}
+
+	if _, _, errno := syscall.AllThreadsSyscall(sysLandlockRestrictSelf, ruleset, 0, 0); errno != 0 {
+		return errno
+	}
+	log.Infof("Restricted filesystem access to %q with Landlock ABI version %d", allowed, abi)
+	return nil
+}
+
+// addLandlockRule allows the file access rights beneath the path, and
+// listing it if it's a directory.
+func addLandlockRule(ruleset int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	defer unix.Close(fd)
+
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err != nil {
+		return err
+	}
+	// Rules for files may only allow file access rights.
+	attr := landlockPathBeneathAttr{allowedAccess: access, parentFD: int32(fd)}
+	if st.Mode&unix.S_IFMT == unix.S_IFDIR {
+		attr.allowedAccess |= landlockAccessFSReadDir
+	}
+	if _, _, errno := unix.Syscall6(sysLandlockAddRule, uintptr(ruleset), landlockRulePathBeneath, uintptr(unsafe.Pointer(&attr)), 0, 0, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// applySeccomp installs a filter failing the denied system calls with
+// EPERM on all threads.
+func applySeccomp(denied []uintptr) error {
+	filter, err := sandboxSeccompFilter(runtime.GOARCH, denied)
+	if err != nil {
+		return err
+	}
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	r, _, errno := unix.Syscall(unix.SYS_SECCOMP, seccompSetModeFilter, seccompFilterFlagTSync, uintptr(unsafe.Pointer(&prog)))
+	runtime.KeepAlive(filter)
+	if errno != 0 {
+		return errno
+	}
+	if r != 0 {
+		return fmt.Errorf("couldn't synchronize filter to thread %d", r)
+	}
+	log.Infof("Denied %d system calls with seccomp", len(denied))
+	return nil
+}
+
+// sandboxSeccompFilter returns the BPF program denying the given system
+// calls and all system calls of other architectures.
+func sandboxSeccompFilter(goarch string, denied []uintptr) ([]unix.SockFilter, error) {
+	arch, ok := sandboxAuditArchs[goarch]
+	if !ok {
+		return nil, fmt.Errorf("unsupported architecture %s", goarch)
+	}
+	stmt := func(code uint16, k uint32) unix.SockFilter {
+		return unix.SockFilter{Code: code, K: k}
+	}
+	jump := func(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+		return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+	}
+
+	// The program loads fields of struct seccomp_data, which starts with
+	// the system call number followed by the architecture, and jumps to
+	// the final deny instruction on a match.
+	n := uint8(len(denied))
+	filter := []unix.SockFilter{
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, 4),
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, arch, 0, n+3),
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, 0),
+		jump(unix.BPF_JMP|unix.BPF_JSET|unix.BPF_K, x32SyscallBit, n+1, 0),
+	}
+	for i, nr := range denied {
+		filter = append(filter, jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, uint32(nr), n-uint8(i), 0))
+	}
+	filter = append(filter,
+		stmt(unix.BPF_RET|unix.BPF_K, seccompRetAllow),
+		stmt(unix.BPF_RET|unix.BPF_K, seccompRetErrno|uint32(unix.EPERM)),
+	)
+	return filter, nil
+}
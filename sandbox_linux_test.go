@@ -0,0 +1,51 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSandboxSeccompFilter(t *testing.T) {
+	for goarch := range sandboxAuditArchs {
+		filter, err := sandboxSeccompFilter(goarch, sandboxDeniedSyscalls)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deny := len(filter) - 1
+		if got, want := filter[deny].K, uint32(seccompRetErrno|unix.EPERM); got != want {
+			t.Fatalf("%s: want last instruction to return %#x, got %#x", goarch, want, got)
+		}
+		// All jumps taken on a denied system call or a foreign architecture
+		// must end at the last instruction.
+		for i, ins := range filter {
+			if ins.Code&0x07 != unix.BPF_JMP {
+				continue
+			}
+			target := i + 1 + int(ins.Jt)
+			if ins.Code&0xf0 == unix.BPF_JEQ && i == 1 {
+				target = i + 1 + int(ins.Jf)
+			}
+			if target != deny {
+				t.Errorf("%s: instruction %d jumps to %d, want %d", goarch, i, target, deny)
+			}
+		}
+	}
+
+	if _, err := sandboxSeccompFilter("sparc", sandboxDeniedSyscalls); err == nil {
+		t.Error("want error for unsupported architecture")
+	}
+}